@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/7c/gopm/internal/config"
+	"github.com/7c/gopm/internal/display"
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyPrune  bool
+	applyDryRun bool
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <ecosystem.json>",
+	Short: "Make the running processes match an ecosystem file",
+	Long: `Compare an ecosystem file to the processes the daemon runs and make them
+match: apps that aren't running yet are started, and apps whose settings
+changed are restarted with the new ones, keeping their ID and history. Apps
+that didn't change are left alone. Only the instance count of a group
+changing scales it instead of restarting it.
+
+With --prune, processes that aren't in the file are deleted as well.
+--dry-run shows what would change, setting by setting, without changing
+anything; with --json, as a JSON list.`,
+	Example: `  # See what a deploy would change
+  gopm apply ecosystem.json --dry-run
+
+  # Apply it, deleting apps removed from the file
+  gopm apply ecosystem.json --prune`,
+	Args: cobra.ExactArgs(1),
+	Run:  runApply,
+}
+
+func init() {
+	applyCmd.Flags().BoolVar(&applyPrune, "prune", false, "delete processes that aren't in the file")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "show what would change without changing anything")
+}
+
+func runApply(cmd *cobra.Command, args []string) {
+	eco, err := config.LoadEcosystem(args[0])
+	if err != nil {
+		exitError(fmt.Sprintf("failed to load ecosystem config: %v", err))
+	}
+	// LoadEcosystem already rejected dependency cycles.
+	apps, _ := eco.StartOrder()
+	params := protocol.ApplyParams{Prune: applyPrune, DryRun: applyDryRun}
+	for _, app := range apps {
+		params.Apps = append(params.Apps, app.ToStartParams())
+	}
+
+	c, err := newClient()
+	if err != nil {
+		exitError(fmt.Sprintf("cannot connect to daemon: %v", err))
+	}
+	defer c.Close()
+
+	resp, err := c.Send(protocol.MethodApply, params)
+	if err != nil {
+		exitError(fmt.Sprintf("failed to apply: %v", err))
+	}
+	if !resp.Success {
+		exitError(resp.Error)
+	}
+
+	var changes []protocol.ApplyChange
+	if err := json.Unmarshal(resp.Data, &changes); err != nil {
+		exitError(fmt.Sprintf("failed to parse response: %v", err))
+	}
+	failed := 0
+	for _, ch := range changes {
+		if ch.Error != "" {
+			failed++
+		}
+	}
+
+	if jsonOutput {
+		fmt.Println(string(resp.Data))
+	} else {
+		printApply(changes, applyDryRun)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printApply prints what apply did, or would do with dryRun, to each app,
+// with the settings that changed.
+func printApply(changes []protocol.ApplyChange, dryRun bool) {
+	counts := make(map[protocol.ApplyAction]int)
+	for _, ch := range changes {
+		counts[ch.Action]++
+		var mark, what string
+		switch ch.Action {
+		case protocol.ApplyCreate:
+			mark, what = display.Green("+"), "start"
+		case protocol.ApplyUpdate:
+			mark, what = display.Yellow("~"), "restart"
+		case protocol.ApplyScale:
+			mark, what = display.Yellow("~"), "scale"
+		case protocol.ApplyDelete:
+			mark, what = display.Red("-"), "delete"
+		default:
+			mark, what = display.Dim("="), display.Dim("unchanged")
+		}
+		line := fmt.Sprintf("%s %s  %s", mark, display.Bold(ch.Name), what)
+		if ch.Error != "" {
+			line += fmt.Sprintf("  %s %s", display.Red("FAIL"), ch.Error)
+		}
+		fmt.Println(line)
+		for _, f := range ch.Diff {
+			fmt.Printf("    %s: %s → %s\n", f.Field, applyValue(f.Old), applyValue(f.New))
+		}
+	}
+
+	var parts []string
+	for _, a := range []struct {
+		action     protocol.ApplyAction
+		done, todo string
+	}{
+		{protocol.ApplyCreate, "started", "to start"},
+		{protocol.ApplyUpdate, "restarted", "to restart"},
+		{protocol.ApplyScale, "scaled", "to scale"},
+		{protocol.ApplyDelete, "deleted", "to delete"},
+		{protocol.ApplyUnchanged, "unchanged", "unchanged"},
+	} {
+		if n := counts[a.action]; n > 0 {
+			what := a.done
+			if dryRun {
+				what = a.todo
+			}
+			parts = append(parts, fmt.Sprintf("%d %s", n, what))
+		}
+	}
+	if dryRun {
+		fmt.Printf("\nDry run, nothing changed: %s\n", strings.Join(parts, ", "))
+	} else {
+		fmt.Printf("\nApplied: %s\n", strings.Join(parts, ", "))
+	}
+}
+
+// applyValue renders a setting's JSON value for a diff line.
+func applyValue(v json.RawMessage) string {
+	if len(v) == 0 {
+		return display.Dim("(unset)")
+	}
+	return string(v)
+}
@@ -63,6 +63,13 @@ var statusCmd = &cobra.Command{
 				out["telegraf_addr"] = resolved.TelegrafAddr.String()
 				out["telegraf_measurement"] = resolved.TelegrafMeas
 			}
+			if len(resolved.Webhooks) > 0 {
+				var hooks []map[string]interface{}
+				for _, wh := range resolved.Webhooks {
+					hooks = append(hooks, map[string]interface{}{"name": wh.Name, "url": wh.URL, "events": wh.Events})
+				}
+				out["webhooks"] = hooks
+			}
 			if daemonPing != nil {
 				out["daemon_pid"] = daemonPing.PID
 				out["daemon_uptime"] = daemonPing.UptimeMs
@@ -130,6 +137,15 @@ var statusCmd = &cobra.Command{
 			fmt.Printf("  Telegraf:     disabled\n")
 		}
 
+		fmt.Printf("\n%s\n", display.Bold("Notifications:"))
+		if len(resolved.Webhooks) > 0 {
+			for _, wh := range resolved.Webhooks {
+				fmt.Printf("  %-13s %s %v\n", wh.Name+":", wh.URL, wh.Events)
+			}
+		} else {
+			fmt.Printf("  Webhooks:     none\n")
+		}
+
 		fmt.Printf("\n%s\n", display.Bold("Systemd:"))
 		fmt.Printf("  Unit file:    %s\n", unitFilePath)
 		if isSystemdInstalled() {
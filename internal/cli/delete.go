@@ -12,12 +12,12 @@ import (
 )
 
 var deleteCmd = &cobra.Command{
-	Use:     "delete <name|id|all>",
+	Use:     "delete <name|id|all|selector>",
 	Aliases: []string{"del"},
 	Short:   "Stop and remove a process from the list",
-	Args:    cobra.ExactArgs(1),
+	Args:    targetArgs(1, false),
 	Run: func(cmd *cobra.Command, args []string) {
-		target := args[0]
+		target := targetFrom(args, 1)
 
 		c, err := newClient()
 		if err != nil {
@@ -41,6 +41,10 @@ var deleteCmd = &cobra.Command{
 	},
 }
 
+func init() {
+	addSelectorFlag(deleteCmd)
+}
+
 // --- helpers ---
 
 func outputJSON(data json.RawMessage) {
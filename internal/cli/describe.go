@@ -11,11 +11,13 @@ import (
 )
 
 var describeCmd = &cobra.Command{
-	Use:   "describe <name|id>",
+	Use:   "describe <name|id|selector>",
 	Short: "Show detailed info about a process",
-	Args:  cobra.ExactArgs(1),
+	Long: `Show detailed info about a process. Given the name of an instance group,
+or a selector that matches several processes, each of them is shown.`,
+	Args: targetArgs(1, false),
 	Run: func(cmd *cobra.Command, args []string) {
-		target := args[0]
+		target := targetFrom(args, 1)
 
 		c, err := newClient()
 		if err != nil {
@@ -36,6 +38,18 @@ var describeCmd = &cobra.Command{
 			return
 		}
 
+		// An instance group is described as the list of its instances.
+		var group []protocol.ProcessInfo
+		if err := json.Unmarshal(resp.Data, &group); err == nil {
+			for i, proc := range group {
+				if i > 0 {
+					fmt.Println()
+				}
+				display.RenderDescribe(os.Stdout, proc)
+			}
+			return
+		}
+
 		var proc protocol.ProcessInfo
 		if err := json.Unmarshal(resp.Data, &proc); err != nil {
 			outputError(fmt.Sprintf("failed to parse process info: %s", err))
@@ -44,3 +58,7 @@ var describeCmd = &cobra.Command{
 		display.RenderDescribe(os.Stdout, proc)
 	},
 }
+
+func init() {
+	addSelectorFlag(describeCmd)
+}
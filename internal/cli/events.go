@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/7c/gopm/internal/display"
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+var eventsFilter string
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Stream daemon events as they happen",
+	Long: `Print process and daemon events as the daemon pushes them, until
+interrupted or the daemon stops. Unlike watch, nothing is polled.
+
+Event types:
+  start     a process started
+  exit      a process exited, with its exit code or signal and what the
+            daemon did about it
+  restart   a process was restarted, by the daemon or a client
+  status    a process changed status
+  health    a health check changed state
+  config    a process was added or deleted
+  daemon    the daemon is shutting down or rebooting
+
+With --json each event is printed as one JSON object per line.`,
+	Example: `  # Everything
+  gopm events
+
+  # Only one process, or every instance of a group
+  gopm events --filter api
+
+  # Machine-readable, one JSON object per line
+  gopm events --json | jq 'select(.type == "exit")'`,
+	Args: cobra.NoArgs,
+	Run:  runEvents,
+}
+
+func init() {
+	eventsCmd.Flags().StringVar(&eventsFilter, "filter", "", "only show events of this process (name, instance group, or id)")
+}
+
+func runEvents(cmd *cobra.Command, args []string) {
+	c, err := newClient()
+	if err != nil {
+		outputError(err.Error())
+	}
+	defer c.Close()
+
+	err = c.Subscribe(protocol.SubscribeParams{Target: eventsFilter}, func(ev protocol.Event) error {
+		if jsonOutput {
+			data, err := json.Marshal(ev)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		fmt.Println(display.FormatEvent(ev))
+		return nil
+	})
+	if err != nil {
+		outputError(err.Error())
+	}
+}
@@ -9,14 +9,18 @@ import (
 )
 
 var flushCmd = &cobra.Command{
-	Use:   "flush <name|id|all>",
+	Use:   "flush <name|id|all|selector>",
 	Short: "Clear log files",
-	Args:  cobra.ExactArgs(1),
+	Args:  targetArgs(1, false),
 	Run:   runFlush,
 }
 
+func init() {
+	addSelectorFlag(flushCmd)
+}
+
 func runFlush(cmd *cobra.Command, args []string) {
-	target := args[0]
+	target := targetFrom(args, 1)
 
 	c, err := newClient()
 	if err != nil {
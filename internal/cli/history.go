@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/7c/gopm/internal/display"
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+var historyLimit int
+
+var historyCmd = &cobra.Command{
+	Use:   "history <name|id>",
+	Short: "Show the start and exit history of a process",
+	Long: `List the recent starts and exits of a process, newest first. Each exit
+shows the exit code or the signal that killed it (and whether it dumped
+core), how long it ran, and whether the daemon restarted it and why.
+
+A restart the daemon caused (max_memory, health check, watch...) names its
+reason, so a SIGKILL from the kernel OOM killer stands out. The history is
+kept across daemon restarts, next to dump.json.`,
+	Example: `  # Recent starts and exits
+  gopm history api
+
+  # Only the last 10 events, as JSON
+  gopm history api --limit 10 --json`,
+	Args: cobra.ExactArgs(1),
+	Run:  runHistory,
+}
+
+func init() {
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "number of events to show (0 = all kept)")
+}
+
+func runHistory(cmd *cobra.Command, args []string) {
+	target := args[0]
+
+	c, err := newClient()
+	if err != nil {
+		outputError(err.Error())
+	}
+	defer c.Close()
+
+	resp, err := c.Send(protocol.MethodHistory, protocol.HistoryParams{Target: target, Limit: historyLimit})
+	if err != nil {
+		outputError(err.Error())
+	}
+	if !resp.Success {
+		outputError(resp.Error)
+	}
+
+	if jsonOutput {
+		outputJSON(resp.Data)
+		return
+	}
+	var events []protocol.ProcessEvent
+	if err := json.Unmarshal(resp.Data, &events); err != nil {
+		outputError(fmt.Sprintf("failed to parse history: %s", err))
+	}
+	if len(events) == 0 {
+		fmt.Println("No history yet")
+		return
+	}
+	display.RenderHistory(os.Stdout, events)
+}
@@ -35,7 +35,9 @@ func runImport(cmd *cobra.Command, args []string) {
 			fmt.Printf("%s %s: %v\n", display.Red("WARN"), display.Bold(path), err)
 			continue
 		}
-		allApps = append(allApps, eco.Apps...)
+		// Dependencies first; LoadEcosystem already rejected cycles.
+		apps, _ := eco.StartOrder()
+		allApps = append(allApps, apps...)
 	}
 	if len(allApps) == 0 {
 		exitError("no valid apps found in any input file")
@@ -102,8 +104,8 @@ func runImport(cmd *cobra.Command, args []string) {
 			continue
 		}
 
-		var info protocol.ProcessInfo
-		if err := json.Unmarshal(resp.Data, &info); err == nil {
+		infos, _ := protocol.DecodeStarted(resp.Data)
+		for _, info := range infos {
 			fmt.Printf("%s %s (PID: %s)\n",
 				display.Green("OK"), display.Bold(info.Name),
 				display.Cyan(fmt.Sprintf("%d", info.PID)))
@@ -29,6 +29,7 @@ Restart=always
 RestartSec=5
 LimitNOFILE=65536
 LimitNPROC=65536
+Delegate=yes
 
 [Install]
 WantedBy=multi-user.target
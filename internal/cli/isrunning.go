@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/7c/gopm/internal/display"
 	"github.com/7c/gopm/internal/protocol"
@@ -50,7 +51,7 @@ var isrunningCmd = &cobra.Command{
 			if jsonOutput {
 				outputJSON(resp.Data)
 			} else {
-				fmt.Printf("%s: %s (PID %s, uptime %s)\n", display.Bold(result.Name), display.Green("online"), display.Cyan(fmt.Sprintf("%d", result.PID)), result.Uptime)
+				fmt.Printf("%s: %s (PID %s, uptime %s)\n", display.Bold(result.Name), display.StatusColor(string(result.Status)), display.Cyan(fmt.Sprintf("%d", result.PID)), result.Uptime)
 			}
 			os.Exit(0)
 		}
@@ -58,6 +59,8 @@ var isrunningCmd = &cobra.Command{
 		// Not running
 		if jsonOutput {
 			outputJSON(resp.Data)
+		} else if result.RestartAt != nil {
+			fmt.Printf("%s: %s (exit code %d, restarting in %s)\n", display.Bold(result.Name), display.StatusColor(string(result.Status)), result.ExitCode, protocol.FormatDuration(time.Until(*result.RestartAt)))
 		} else {
 			fmt.Printf("%s: %s (exit code %d, %d restarts)\n", display.Bold(result.Name), display.StatusColor(string(result.Status)), result.ExitCode, result.Restarts)
 		}
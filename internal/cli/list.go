@@ -24,7 +24,14 @@ var listCmd = &cobra.Command{
 		}
 		defer c.Close()
 
-		resp, err := c.Send("list", nil)
+		var params interface{}
+		if selectorFlag != "" {
+			if _, err := protocol.ParseSelector(selectorFlag); err != nil {
+				outputError(err.Error())
+			}
+			params = protocol.ListParams{Selector: selectorFlag}
+		}
+		resp, err := c.Send("list", params)
 		if err != nil {
 			outputError(err.Error())
 		}
@@ -43,6 +50,10 @@ var listCmd = &cobra.Command{
 		}
 
 		if len(procs) == 0 {
+			if selectorFlag != "" {
+				fmt.Printf("No processes match %s\n", selectorFlag)
+				return
+			}
 			fmt.Println("No processes running")
 			return
 		}
@@ -53,4 +64,5 @@ var listCmd = &cobra.Command{
 
 func init() {
 	listCmd.Flags().BoolVarP(&listPorts, "ports", "p", false, "show listening ports column")
+	addSelectorFlag(listCmd)
 }
@@ -19,13 +19,14 @@ import (
 )
 
 var logsCmd = &cobra.Command{
-	Use:   "logs [name|id|all]",
+	Use:   "logs [name|id|all|selector]",
 	Short: "Display process log output",
 	Long: `Display recent log output for a process or all processes.
 
 Each log line is prefixed with an ISO-8601 timestamp by the daemon.
 Use "all" as the target to display logs from every managed process,
-with a header separating each process.
+with a header separating each process. The name of an instance group, or a
+selector that matches several processes, shows their logs the same way.
 
 If only one process is managed, the target can be omitted.`,
 	Example: `  # Show last 20 lines of stdout (default)
@@ -47,10 +48,14 @@ If only one process is managed, the target can be omitted.`,
   # Follow all processes
   gopm logs all -f
 
+  # Processes matching a name pattern or labels
+  gopm logs 'api-*'
+  gopm logs -l team=payments --err
+
   # Omit target when only one process exists
   gopm logs
   gopm logs -f`,
-	Args: cobra.MaximumNArgs(1),
+	Args: targetArgs(1, true),
 	Run:  runLogs,
 }
 
@@ -67,6 +72,7 @@ func init() {
 	f.BoolVarP(&logsFollow, "follow", "f", false, "follow log output")
 	f.BoolVar(&logsErr, "err", false, "show only error log")
 	f.BoolVarP(&logsDaemon, "daemon", "d", false, "show daemon system log")
+	addSelectorFlag(logsCmd)
 }
 
 func runLogs(cmd *cobra.Command, args []string) {
@@ -75,10 +81,8 @@ func runLogs(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	target := ""
-	if len(args) > 0 {
-		target = args[0]
-	} else {
+	target := targetFrom(args, 1)
+	if target == "" {
 		// Infer target with a separate connection (each connection is one request)
 		c, err := newClient()
 		if err != nil {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"syscall"
 
 	"github.com/7c/gopm/internal/config"
 	"github.com/7c/gopm/internal/protocol"
@@ -27,6 +28,13 @@ const defaultConfig = `{
       "udp": "127.0.0.1:8094",
       "measurement": "gopm"
     }
+  },
+  "notifications": {
+    "webhooks": [],
+    "events": ["errored", "crash-loop", "health-failed"],
+    "stderr_lines": 20,
+    "crash_loop_restarts": 3,
+    "processes": {}
   }
 }`
 
@@ -62,6 +70,7 @@ Set a section to null to disable it:
 
   "mcpserver": null      — disable the MCP HTTP server
   "telemetry": null      — disable telegraf telemetry
+  "notifications": null  — disable webhook notifications
 
 Omitting a section entirely uses defaults (MCP enabled on 127.0.0.1:18999).
 
@@ -114,16 +123,24 @@ func runExport(cmd *cobra.Command, args []string) {
 		outputError("no processes defined")
 	}
 
-	// Filter processes by targets.
+	// Instances of a group are exported as one app with its instance count.
+	grouped := protocol.GroupInstances(procs)
+
+	// Filter processes by targets. An instance's name or ID selects its group.
 	var selected []protocol.ProcessInfo
 	if len(args) == 1 && args[0] == "all" {
-		selected = procs
+		selected = grouped
 	} else {
 		byName := make(map[string]protocol.ProcessInfo)
 		byID := make(map[int]protocol.ProcessInfo)
-		for _, p := range procs {
+		for _, p := range grouped {
 			byName[p.Name] = p
-			byID[p.ID] = p
+		}
+		for _, p := range procs {
+			if p.App != "" {
+				byName[p.Name] = byName[p.App]
+			}
+			byID[p.ID] = byName[p.Name]
 		}
 		seen := make(map[string]bool)
 		for _, target := range args {
@@ -189,6 +206,9 @@ func processToAppConfig(p protocol.ProcessInfo, full bool) config.AppConfig {
 	if len(p.Env) > 0 {
 		app.Env = p.Env
 	}
+	if len(p.Labels) > 0 {
+		app.Labels = p.Labels
+	}
 
 	defaults := protocol.DefaultRestartPolicy()
 	rp := p.RestartPolicy
@@ -215,6 +235,31 @@ func processToAppConfig(p protocol.ProcessInfo, full bool) config.AppConfig {
 	if full || rp.KillTimeout.Duration != defaults.KillTimeout.Duration {
 		app.KillTimeout = rp.KillTimeout.Duration.String()
 	}
+	if rp.KillSignal > 0 && (full || rp.KillSignal != defaults.KillSignal) {
+		app.KillSignal = protocol.SignalName(syscall.Signal(rp.KillSignal))
+	}
+	app.StopCommand = p.StopCommand
+	if p.ReloadSignal > 0 {
+		app.ReloadSignal = protocol.SignalName(syscall.Signal(p.ReloadSignal))
+	}
+	app.RestartOnExit = rp.RestartOnExit
+	app.NoRestartOnExit = rp.NoRestartOnExit
+	app.User = p.User
+	app.Group = p.Group
+	app.Groups = p.Groups
+	if rp.RestartBudget > 0 {
+		app.RestartBudget = protocol.FormatRestartBudget(rp.RestartBudget, rp.RestartWindow.Duration)
+		if full || rp.CrashLoopCooldown.Duration != defaults.CrashLoopCooldown.Duration {
+			app.CrashLoopCooldown = rp.CrashLoopCooldown.Duration.String()
+		}
+	}
+	app.BackoffJitter = rp.BackoffJitter
+	if rp.MaxMemory > 0 {
+		app.MaxMemory = protocol.FormatSize(rp.MaxMemory)
+	}
+	if rp.MaxMemoryGrace.Duration > 0 || (full && rp.MaxMemory > 0) {
+		app.MaxMemoryGrace = rp.MaxMemoryGrace.Duration.String()
+	}
 	if full {
 		if p.LogOut != "" {
 			app.LogOut = p.LogOut
@@ -226,6 +271,37 @@ func processToAppConfig(p protocol.ProcessInfo, full bool) config.AppConfig {
 			app.MaxLogSize = protocol.FormatSize(p.MaxLogSize)
 		}
 	}
+	if p.HealthCheck != nil {
+		hc := *p.HealthCheck
+		app.HealthCheck = &hc
+	}
+	if p.Cgroup != nil {
+		cg := *p.Cgroup
+		app.Cgroup = &cg
+	}
+	if p.Limits != nil {
+		l := *p.Limits
+		app.Limits = &l
+	}
+	app.CronRestart = p.CronRestart
+	app.Schedule = p.Schedule
+	if p.Schedule != "" && (full || p.Overlap != protocol.OverlapSkip) {
+		app.Overlap = string(p.Overlap)
+	}
+	app.Watch = p.Watch
+	app.WatchPaths = p.WatchPaths
+	app.IgnoreWatch = p.IgnoreWatch
+	app.WatchDelay = p.WatchDelay
+	app.DependsOn = p.DependsOn
+	app.ReadyMessage = p.ReadyMessage
+	app.Sockets = p.Sockets
+	app.Notify = p.Notify
+	app.WatchdogSec = p.WatchdogSec
+	if p.Hooks != nil {
+		hooks := *p.Hooks
+		app.Hooks = &hooks
+	}
+	app.Instances = p.Instances
 
 	return app
 }
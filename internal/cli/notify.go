@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/7c/gopm/internal/display"
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Webhook notifications",
+	Long: `Webhook notifications are configured in the "notifications" section of
+gopm.config.json. The daemon POSTs a JSON payload with the process info,
+the reason and the last lines of its stderr log when a process is
+errored, restarted, crash-looping or fails its health check.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var (
+	notifyTestEvent   string
+	notifyTestProcess string
+)
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a sample notification to every webhook",
+	Long: `Send a sample notification, marked "test": true, to every configured
+webhook regardless of its event filter, and show how each one answered.
+The notification describes a made-up errored process unless --process
+names a real one. It is sent once, without retries.`,
+	Example: `  # Sample "errored" notification
+  gopm notify test
+
+  # A crash-loop notification describing the api process
+  gopm notify test --event crash-loop --process api`,
+	Args: cobra.NoArgs,
+	Run:  runNotifyTest,
+}
+
+func init() {
+	notifyTestCmd.Flags().StringVar(&notifyTestEvent, "event", "errored", "event to send: errored, restarted, crash-loop or health-failed")
+	notifyTestCmd.Flags().StringVar(&notifyTestProcess, "process", "", "describe this process (name or id) instead of a sample one")
+	notifyCmd.AddCommand(notifyTestCmd)
+}
+
+func runNotifyTest(cmd *cobra.Command, args []string) {
+	c, err := newClient()
+	if err != nil {
+		outputError(err.Error())
+	}
+	defer c.Close()
+
+	resp, err := c.Send(protocol.MethodNotifyTest, protocol.NotifyTestParams{
+		Event:  protocol.NotifyEvent(notifyTestEvent),
+		Target: notifyTestProcess,
+	})
+	if err != nil {
+		outputError(err.Error())
+	}
+	if !resp.Success {
+		outputError(resp.Error)
+	}
+
+	var results []protocol.NotifyTestResult
+	if err := json.Unmarshal(resp.Data, &results); err != nil {
+		outputError(fmt.Sprintf("failed to parse result: %s", err))
+	}
+	failed := false
+	for _, r := range results {
+		if r.Error != "" {
+			failed = true
+		}
+	}
+	if jsonOutput {
+		outputJSON(resp.Data)
+	} else {
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Printf("%s %s: %s: %s\n", display.Red("FAIL"), display.Bold(r.Webhook), r.URL, r.Error)
+			} else {
+				fmt.Printf("%s %s: %s (HTTP %d)\n", display.Green("OK"), display.Bold(r.Webhook), r.URL, r.Status)
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
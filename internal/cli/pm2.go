@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"runtime"
 	"sort"
 	"strings"
 
@@ -29,7 +30,8 @@ Specify one or more PM2 process names to migrate selectively, or omit to migrate
 Use --dry to preview the import without starting or deleting anything.
 
 Verbose output is printed for every step so you can verify the migration.
-Cluster-mode processes are imported as single fork-mode processes.`,
+Cluster-mode processes are imported as an instance group with the same number
+of instances.`,
 	Example: `  # Migrate all PM2 processes
   gopm pm2
 
@@ -71,7 +73,11 @@ type pm2Env struct {
 	RestartDelay int                    `json:"restart_delay"`
 	KillTimeout  int                    `json:"kill_timeout"`
 	ExecMode     string                 `json:"exec_mode"`
-	Instances    int                    `json:"instances"`
+	Instances    interface{}            `json:"instances"` // number, or "max"
+	CronRestart  string                 `json:"cron_restart"`
+	Watch        interface{}            `json:"watch"`        // bool, path, or list of paths
+	IgnoreWatch  interface{}            `json:"ignore_watch"` // pattern or list of patterns
+	WatchDelay   interface{}            `json:"watch_delay"`  // milliseconds
 }
 
 // pm2InternalEnvKeys are env vars injected by PM2 that should not be imported.
@@ -85,7 +91,8 @@ var pm2InternalEnvKeys = map[string]bool{
 	"versioning": true, "restart_time": true, "created_at": true,
 	"watch": true, "filter_env": true, "namespace": true,
 	"kill_retry_time": true, "username": true, "windowsHide": true,
-	"instance_var": true,
+	"instance_var": true, "cron_restart": true, "ignore_watch": true,
+	"watch_delay": true, "NODE_APP_INSTANCE": true,
 }
 
 func runPM2Import(cmd *cobra.Command, args []string) {
@@ -111,6 +118,8 @@ func runPM2Import(cmd *cobra.Command, args []string) {
 		fmt.Println("No PM2 processes found.")
 		return
 	}
+	// PM2 lists each instance of a cluster-mode app separately.
+	allProcs = dedupePM2Procs(allProcs)
 
 	// Filter to requested names if any were provided.
 	procs := allProcs
@@ -142,12 +151,6 @@ func runPM2Import(cmd *cobra.Command, args []string) {
 		params := pm2ToStartParams(p)
 		printPM2Details(params, p)
 
-		// Warn about cluster mode.
-		if p.PM2Env.ExecMode == "cluster_mode" || p.PM2Env.Instances > 1 {
-			fmt.Printf("  %s cluster mode with %d instance(s) — importing as single fork process\n",
-				display.Yellow("!"), p.PM2Env.Instances)
-		}
-
 		// Start in gopm (new connection per request).
 		c, err := newClient()
 		if err != nil {
@@ -167,11 +170,18 @@ func runPM2Import(cmd *cobra.Command, args []string) {
 			continue
 		}
 
-		var info protocol.ProcessInfo
-		if err := json.Unmarshal(resp.Data, &info); err == nil {
-			fmt.Printf("%s (id=%d, PID=%d)\n", display.Green("OK"), info.ID, info.PID)
-		} else {
+		infos, err := protocol.DecodeStarted(resp.Data)
+		switch {
+		case err != nil:
 			fmt.Printf("%s\n", display.Green("OK"))
+		case len(infos) == 1:
+			fmt.Printf("%s (id=%d, PID=%d)\n", display.Green("OK"), infos[0].ID, infos[0].PID)
+		default:
+			pids := make([]string, len(infos))
+			for i, info := range infos {
+				pids[i] = fmt.Sprintf("%d", info.PID)
+			}
+			fmt.Printf("%s (%d instances, PIDs=%s)\n", display.Green("OK"), len(infos), strings.Join(pids, ","))
 		}
 
 		// Remove from PM2.
@@ -206,6 +216,21 @@ func filterPM2Procs(procs []pm2Process, targets []string) []pm2Process {
 	return result
 }
 
+// dedupePM2Procs keeps the first entry of each name. Instances of a
+// cluster-mode app share a name and are imported together as one group.
+func dedupePM2Procs(procs []pm2Process) []pm2Process {
+	seen := make(map[string]bool, len(procs))
+	var result []pm2Process
+	for _, p := range procs {
+		if seen[p.Name] {
+			continue
+		}
+		seen[p.Name] = true
+		result = append(result, p)
+	}
+	return result
+}
+
 // runPM2Dry prints the gopm StartParams JSON for each process without starting or deleting.
 func runPM2Dry(procs []pm2Process) {
 	for i, p := range procs {
@@ -280,6 +305,32 @@ func pm2ToStartParams(p pm2Process) protocol.StartParams {
 		params.KillTimeout = fmt.Sprintf("%dms", env.KillTimeout)
 	}
 
+	// Cluster mode and fork mode with several instances become an instance
+	// group.
+	if n := parsePM2Instances(env.Instances); env.ExecMode == "cluster_mode" || n > 1 {
+		params.Instances = n
+	}
+
+	// PM2 and gopm both accept 5- and 6-field cron expressions.
+	params.CronRestart = env.CronRestart
+
+	// Watch: PM2 takes true (watch cwd) or the paths to watch.
+	switch w := env.Watch.(type) {
+	case bool:
+		params.Watch = w
+	default:
+		if paths := parsePM2StringList(w); len(paths) > 0 {
+			params.Watch = true
+			params.WatchPaths = paths
+		}
+	}
+	if params.Watch {
+		params.IgnoreWatch = parsePM2StringList(env.IgnoreWatch)
+		if ms := parsePM2Millis(env.WatchDelay); ms > 0 {
+			params.WatchDelay = fmt.Sprintf("%dms", ms)
+		}
+	}
+
 	return params
 }
 
@@ -322,6 +373,25 @@ func printPM2Details(params protocol.StartParams, p pm2Process) {
 	if params.KillTimeout != "" {
 		fmt.Printf("  %-14s %s\n", display.Dim("kill_timeout:"), params.KillTimeout)
 	}
+	if params.Instances != 0 {
+		fmt.Printf("  %-14s %s\n", display.Dim("instances:"), params.Instances)
+	}
+	if params.CronRestart != "" {
+		fmt.Printf("  %-14s %s\n", display.Dim("cron_restart:"), params.CronRestart)
+	}
+	if params.Watch {
+		paths := "cwd"
+		if len(params.WatchPaths) > 0 {
+			paths = strings.Join(params.WatchPaths, ", ")
+		}
+		fmt.Printf("  %-14s %s\n", display.Dim("watch:"), paths)
+	}
+	if len(params.IgnoreWatch) > 0 {
+		fmt.Printf("  %-14s %s\n", display.Dim("ignore_watch:"), strings.Join(params.IgnoreWatch, ", "))
+	}
+	if params.WatchDelay != "" {
+		fmt.Printf("  %-14s %s\n", display.Dim("watch_delay:"), params.WatchDelay)
+	}
 }
 
 // parsePM2Args parses the pm2 args field which can be []string or a single string.
@@ -342,6 +412,53 @@ func parsePM2Args(raw json.RawMessage) []string {
 	return nil
 }
 
+// parsePM2StringList parses a PM2 field that can be a single string or a
+// list of strings, such as watch or ignore_watch.
+func parsePM2StringList(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		if val != "" {
+			return []string{val}
+		}
+	case []interface{}:
+		var list []string
+		for _, item := range val {
+			if s, ok := item.(string); ok && s != "" {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// parsePM2Instances converts PM2's instances setting. PM2 runs one instance
+// per CPU for "max" or 0, and that many fewer than the CPU count for a
+// negative number.
+func parsePM2Instances(v interface{}) protocol.Instances {
+	var n int
+	switch val := v.(type) {
+	case float64:
+		n = int(val)
+	case string:
+		if val == "max" {
+			return protocol.InstancesMax
+		}
+		if _, err := fmt.Sscanf(val, "%d", &n); err != nil {
+			return 1
+		}
+	default:
+		return 1
+	}
+	switch {
+	case n == 0:
+		return protocol.InstancesMax
+	case n < 0:
+		return protocol.Instances(max(runtime.NumCPU()+n, 1))
+	}
+	return protocol.Instances(n)
+}
+
 // parsePM2Millis extracts a millisecond value from PM2's min_uptime
 // which can be a number or a string like "1000".
 func parsePM2Millis(v interface{}) int {
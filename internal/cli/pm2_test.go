@@ -3,6 +3,8 @@ package cli
 import (
 	"encoding/json"
 	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
 )
 
 func makePM2Procs() []pm2Process {
@@ -111,6 +113,67 @@ func TestPM2ToStartParams_DryJSON(t *testing.T) {
 	}
 }
 
+func TestPM2ToStartParams_CronRestart(t *testing.T) {
+	p := makePM2Procs()[2]
+	p.PM2Env.CronRestart = "0 */6 * * *"
+	p.PM2Env.Env = map[string]interface{}{"cron_restart": "0 */6 * * *", "PORT": "3000"}
+
+	params := pm2ToStartParams(p)
+	if params.CronRestart != "0 */6 * * *" {
+		t.Errorf("CronRestart = %q, want %q", params.CronRestart, "0 */6 * * *")
+	}
+	if _, ok := params.Env["cron_restart"]; ok {
+		t.Error("cron_restart leaked into env")
+	}
+
+	// Unset in PM2 stays unset.
+	if got := pm2ToStartParams(makePM2Procs()[0]).CronRestart; got != "" {
+		t.Errorf("CronRestart = %q, want empty", got)
+	}
+}
+
+func TestPM2ToStartParams_Watch(t *testing.T) {
+	p := makePM2Procs()[2]
+	p.PM2Env.Watch = []interface{}{"src", "config"}
+	p.PM2Env.IgnoreWatch = "*.log"
+	p.PM2Env.WatchDelay = float64(2500)
+	p.PM2Env.Env = map[string]interface{}{"watch": true, "ignore_watch": "*.log", "PORT": "3000"}
+
+	params := pm2ToStartParams(p)
+	if !params.Watch {
+		t.Error("Watch = false, want true")
+	}
+	if len(params.WatchPaths) != 2 || params.WatchPaths[0] != "src" || params.WatchPaths[1] != "config" {
+		t.Errorf("WatchPaths = %v, want [src config]", params.WatchPaths)
+	}
+	if len(params.IgnoreWatch) != 1 || params.IgnoreWatch[0] != "*.log" {
+		t.Errorf("IgnoreWatch = %v, want [*.log]", params.IgnoreWatch)
+	}
+	if params.WatchDelay != "2500ms" {
+		t.Errorf("WatchDelay = %q, want %q", params.WatchDelay, "2500ms")
+	}
+	for _, k := range []string{"watch", "ignore_watch"} {
+		if _, ok := params.Env[k]; ok {
+			t.Errorf("%s leaked into env", k)
+		}
+	}
+	if err := params.ValidateWatch(); err != nil {
+		t.Errorf("ValidateWatch: %v", err)
+	}
+
+	// watch: true watches cwd.
+	p.PM2Env.Watch = true
+	if params := pm2ToStartParams(p); !params.Watch || len(params.WatchPaths) != 0 {
+		t.Errorf("watch=true: Watch = %v, WatchPaths = %v", params.Watch, params.WatchPaths)
+	}
+
+	// watch: false drops ignore_watch too.
+	p.PM2Env.Watch = false
+	if params := pm2ToStartParams(p); params.Watch || params.IgnoreWatch != nil || params.WatchDelay != "" {
+		t.Errorf("watch=false: got %+v", params)
+	}
+}
+
 func TestPM2Cmd_DryFlag(t *testing.T) {
 	f := pm2Cmd.Flags()
 	dryFlag := f.Lookup("dry")
@@ -147,3 +210,48 @@ func TestFilterPM2Procs_PreservesOrder(t *testing.T) {
 		t.Errorf("expected second proc 'cron', got %q", result[1].Name)
 	}
 }
+
+func TestPM2ToStartParams_Instances(t *testing.T) {
+	tests := []struct {
+		mode      string
+		instances interface{}
+		want      protocol.Instances
+	}{
+		{"fork_mode", nil, 0},
+		{"fork_mode", float64(1), 0},
+		{"fork_mode", float64(3), 3},
+		{"cluster_mode", float64(4), 4},
+		{"cluster_mode", float64(0), protocol.InstancesMax},
+		{"cluster_mode", "max", protocol.InstancesMax},
+		{"cluster_mode", "2", 2},
+		{"cluster_mode", nil, 1},
+	}
+	for _, tt := range tests {
+		p := makePM2Procs()[0]
+		p.PM2Env.ExecMode = tt.mode
+		p.PM2Env.Instances = tt.instances
+		p.PM2Env.Env = map[string]interface{}{"NODE_APP_INSTANCE": "0", "PORT": "3000"}
+		params := pm2ToStartParams(p)
+		if params.Instances != tt.want {
+			t.Errorf("%s instances=%v: Instances = %v, want %v", tt.mode, tt.instances, params.Instances, tt.want)
+		}
+		if _, ok := params.Env["NODE_APP_INSTANCE"]; ok {
+			t.Error("NODE_APP_INSTANCE leaked into env")
+		}
+	}
+}
+
+func TestDedupePM2Procs(t *testing.T) {
+	procs := makePM2Procs()
+	second := procs[0]
+	second.PM2ID = 3
+	procs = append(procs[:1], append([]pm2Process{second}, procs[1:]...)...)
+
+	result := dedupePM2Procs(procs)
+	if len(result) != 3 {
+		t.Fatalf("expected 3 procs, got %d", len(result))
+	}
+	if result[0].Name != "api" || result[0].PM2ID != 0 || result[1].Name != "worker" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
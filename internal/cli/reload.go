@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/7c/gopm/internal/display"
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+var reloadTimeout string
+
+var reloadCmd = &cobra.Command{
+	Use:   "reload <name|id|all|selector>",
+	Short: "Restart a process without downtime",
+	Long: `Replace a process with a new one without dropping connections. The new
+process is started first, and the old one is stopped only once the new one is
+ready:
+
+  - it sends READY=1, if it uses notify
+  - else its health check passes, if it has one
+  - else its ready_message appears on stdout, if set
+  - else it is running, if it was given sockets (--socket)
+  - else it opens a listening socket
+
+The instances of a group, or all processes, are reloaded one at a time. If a
+new process exits or isn't ready within --timeout, it is stopped, the old one
+keeps running, and the reload stops there.
+
+The old and new process run side by side for a moment, so a server must be
+able to bind its port twice (SO_REUSEPORT), unless it was given sockets. A
+process that isn't running is simply restarted.
+
+A process started with --reload-signal reloads itself instead: it is sent
+that signal (e.g. SIGHUP) and keeps running with the same PID.`,
+	Example: `  # Reload after deploying a new binary
+  gopm reload api
+
+  # Reload every instance of a group, one at a time
+  gopm reload web --timeout 1m
+
+  # Reload every process labelled tier=web
+  gopm reload -l tier=web`,
+	Args: targetArgs(1, false),
+	Run:  runReload,
+}
+
+func init() {
+	reloadCmd.Flags().StringVar(&reloadTimeout, "timeout", "", "how long to wait for each new process to become ready (default 30s)")
+	addSelectorFlag(reloadCmd)
+}
+
+func runReload(cmd *cobra.Command, args []string) {
+	c, err := newClient()
+	if err != nil {
+		exitError(fmt.Sprintf("cannot connect to daemon: %v", err))
+	}
+	defer c.Close()
+
+	params := protocol.ReloadParams{Target: targetFrom(args, 1), Timeout: reloadTimeout}
+	resp, err := c.Send(protocol.MethodReload, params)
+	if err != nil {
+		exitError(fmt.Sprintf("failed to reload process: %v", err))
+	}
+	if !resp.Success {
+		exitError(resp.Error)
+	}
+
+	if jsonOutput {
+		fmt.Println(string(resp.Data))
+		return
+	}
+
+	var single protocol.ProcessInfo
+	if err := json.Unmarshal(resp.Data, &single); err == nil {
+		display.RenderDescribe(os.Stdout, single)
+		return
+	}
+
+	var multi []protocol.ProcessInfo
+	if err := json.Unmarshal(resp.Data, &multi); err == nil {
+		display.RenderProcessList(os.Stdout, multi, false)
+		return
+	}
+
+	fmt.Println(string(resp.Data))
+}
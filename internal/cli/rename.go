@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/7c/gopm/internal/display"
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <name|id> <new-name>",
+	Short: "Give a process a new name",
+	Long: `Give a process, or an instance group and all its instances, a new name.
+It keeps running, with its ID, history and metrics. Log files in the default
+place (<name>-out.log and <name>-err.log in the log directory) are moved to
+the new name; log files set with --log-out or --log-err stay where they are.
+Processes that depend on it are changed to name it by its new name.`,
+	Example: `  # Rename a process
+  gopm rename api api-v1
+
+  # Rename an instance group: web-0, web-1 become www-0, www-1
+  gopm rename web www`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := newClient()
+		if err != nil {
+			outputError(err.Error())
+		}
+		defer c.Close()
+
+		resp, err := c.Send(protocol.MethodRename, protocol.RenameParams{Target: args[0], Name: args[1]})
+		if err != nil {
+			outputError(err.Error())
+		}
+		if !resp.Success {
+			outputError(resp.Error)
+		}
+
+		if jsonOutput {
+			outputJSON(resp.Data)
+		} else {
+			fmt.Printf("Process %s renamed to %s\n", display.Bold(args[0]), display.Bold(args[1]))
+		}
+	},
+}
@@ -11,14 +11,18 @@ import (
 )
 
 var restartCmd = &cobra.Command{
-	Use:   "restart <name|id|all>",
+	Use:   "restart <name|id|all|selector>",
 	Short: "Restart a process",
-	Args:  cobra.ExactArgs(1),
+	Args:  targetArgs(1, false),
 	Run:   runRestart,
 }
 
+func init() {
+	addSelectorFlag(restartCmd)
+}
+
 func runRestart(cmd *cobra.Command, args []string) {
-	target := args[0]
+	target := targetFrom(args, 1)
 
 	c, err := newClient()
 	if err != nil {
@@ -124,6 +124,8 @@ func Execute() {
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(stopCmd)
 	rootCmd.AddCommand(restartCmd)
+	rootCmd.AddCommand(reloadCmd)
+	rootCmd.AddCommand(signalCmd)
 	rootCmd.AddCommand(deleteCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(describeCmd)
@@ -140,12 +142,20 @@ func Execute() {
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(setCmd)
+	rootCmd.AddCommand(renameCmd)
 	rootCmd.AddCommand(suspendCmd)
 	rootCmd.AddCommand(unsuspendCmd)
 	rootCmd.AddCommand(pidCmd)
 	rootCmd.AddCommand(pm2Cmd)
 	rootCmd.AddCommand(watchCmd)
 	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(runsCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(eventsCmd)
+	rootCmd.AddCommand(notifyCmd)
+	rootCmd.AddCommand(scaleCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/7c/gopm/internal/display"
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+var runsLimit int
+
+var runsCmd = &cobra.Command{
+	Use:   "runs <name|id> [run]",
+	Short: "Show recent runs of a scheduled job",
+	Long: `List recent executions of a scheduled job (started with --schedule),
+newest first, with status, trigger, duration and exit code.
+
+Pass a run number to show that run's details and the tail of its output.`,
+	Example: `  # List recent runs
+  gopm runs backup
+
+  # Only the last 5
+  gopm runs backup --limit 5
+
+  # Show run #12 with its output
+  gopm runs backup 12`,
+	Args: cobra.RangeArgs(1, 2),
+	Run:  runRuns,
+}
+
+func init() {
+	runsCmd.Flags().IntVarP(&runsLimit, "limit", "n", 20, "number of runs to show (0 = all kept)")
+}
+
+func runRuns(cmd *cobra.Command, args []string) {
+	target := args[0]
+	runID := 0
+	if len(args) == 2 {
+		n, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+		if err != nil || n <= 0 {
+			outputError(fmt.Sprintf("invalid run number %q", args[1]))
+		}
+		runID = n
+	}
+
+	c, err := newClient()
+	if err != nil {
+		outputError(err.Error())
+	}
+	defer c.Close()
+
+	params := protocol.RunsParams{Target: target, Limit: runsLimit}
+	if runID > 0 {
+		params.Limit = 0
+	}
+	resp, err := c.Send(protocol.MethodRuns, params)
+	if err != nil {
+		outputError(err.Error())
+	}
+	if !resp.Success {
+		outputError(resp.Error)
+	}
+
+	var runs []protocol.RunRecord
+	if err := json.Unmarshal(resp.Data, &runs); err != nil {
+		outputError(fmt.Sprintf("failed to parse runs: %s", err))
+	}
+
+	if runID > 0 {
+		for _, r := range runs {
+			if r.ID != runID {
+				continue
+			}
+			if jsonOutput {
+				data, _ := json.Marshal(r)
+				outputJSON(data)
+				return
+			}
+			display.RenderRun(os.Stdout, r)
+			return
+		}
+		outputError(fmt.Sprintf("run #%d not found for %q", runID, target))
+	}
+
+	if jsonOutput {
+		outputJSON(resp.Data)
+		return
+	}
+	if len(runs) == 0 {
+		fmt.Println("No runs yet")
+		return
+	}
+	display.RenderRuns(os.Stdout, runs)
+}
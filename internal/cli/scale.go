@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/7c/gopm/internal/display"
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+var scaleCmd = &cobra.Command{
+	Use:   "scale <name> <instances|max>",
+	Short: "Change the number of instances of an instance group",
+	Long: `Start or stop instances of a group started with --instances (or "instances"
+in an ecosystem file) until it runs the given number. New instances take the
+lowest free instance IDs; when scaling down, the highest IDs are stopped and
+deleted first. "max" runs one instance per CPU.`,
+	Example: `  # Run six instances of api
+  gopm scale api 6
+
+  # One instance per CPU
+  gopm scale api max`,
+	Args: cobra.ExactArgs(2),
+	Run:  runScale,
+}
+
+func runScale(cmd *cobra.Command, args []string) {
+	n, err := protocol.ParseInstances(args[1])
+	if err != nil {
+		exitError(fmt.Sprintf("invalid instances: %v", err))
+	}
+
+	c, err := newClient()
+	if err != nil {
+		exitError(fmt.Sprintf("cannot connect to daemon: %v", err))
+	}
+	defer c.Close()
+
+	resp, err := c.Send(protocol.MethodScale, protocol.ScaleParams{Target: args[0], Instances: n})
+	if err != nil {
+		exitError(fmt.Sprintf("failed to scale: %v", err))
+	}
+	if !resp.Success {
+		exitError(resp.Error)
+	}
+
+	if jsonOutput {
+		fmt.Println(string(resp.Data))
+		return
+	}
+
+	var procs []protocol.ProcessInfo
+	if err := json.Unmarshal(resp.Data, &procs); err != nil {
+		exitError(fmt.Sprintf("failed to parse response: %v", err))
+	}
+	fmt.Printf("Scaled %s to %d instance(s)\n", display.Bold(args[0]), len(procs))
+	display.RenderProcessList(os.Stdout, procs, false)
+}
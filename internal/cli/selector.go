@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+// selectorFlag is the -l/--selector of the command being run.
+var selectorFlag string
+
+// addSelectorFlag adds -l/--selector to a command that takes a target, to
+// pick processes by label or name pattern instead.
+func addSelectorFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&selectorFlag, "selector", "l", "", "pick processes by label or name pattern, e.g. team=payments or 'api-*'")
+}
+
+// targetArgs accepts n positional args, the last one being the target, or
+// n-1 with --selector. If optional is set the target may also be left out.
+func targetArgs(n int, optional bool) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if selectorFlag != "" {
+			if len(args) >= n {
+				return fmt.Errorf("give a target or --selector, not both")
+			}
+			return cobra.ExactArgs(n-1)(cmd, args)
+		}
+		if optional {
+			return cobra.RangeArgs(n-1, n)(cmd, args)
+		}
+		return cobra.ExactArgs(n)(cmd, args)
+	}
+}
+
+// targetFrom returns the target of a command checked by targetArgs(n): the
+// --selector, the nth arg, or "" if it was left out.
+func targetFrom(args []string, n int) string {
+	if selectorFlag != "" {
+		if _, err := protocol.ParseSelector(selectorFlag); err != nil {
+			outputError(err.Error())
+		}
+		return selectorFlag
+	}
+	if len(args) >= n {
+		return args[n-1]
+	}
+	return ""
+}
@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/7c/gopm/internal/display"
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+var (
+	setEnv          []string
+	setUnsetEnv     []string
+	setLabels       []string
+	setUnsetLabels  []string
+	setArgs         string
+	setAutoRestart  string
+	setMaxRestarts  int
+	setMinUptime    string
+	setRestartDelay string
+	setKillTimeout  string
+	setMaxMemory    string
+	setCronRestart  string
+	setSettings     []string
+	setUnset        []string
+	setRestart      bool
+)
+
+var setCmd = &cobra.Command{
+	Use:   "set <name|id|all|selector>",
+	Short: "Change the settings of processes in place",
+	Long: `Change the settings of processes without deleting them, so they keep their
+ID, history and restart count. Changes are checked with the same rules as an
+ecosystem file and saved with the process list.
+
+The restart policy (autorestart, max_restarts, restart_delay, ...) applies
+from the next exit; everything else from the next start. --restart restarts
+the processes right away when something changed.
+
+Any setting can be changed with --set KEY=VALUE, KEY being its name in an
+ecosystem file and VALUE plain text or JSON, and cleared with --unset KEY.
+For an instance group, the change is made to every instance.`,
+	Example: `  # Change an env var and restart to pick it up
+  gopm set api --env LOG_LEVEL=debug --unset-env DEBUG --restart
+
+  # Give up after 5 restarts from the next crash on
+  gopm set api --max-restarts 5
+
+  # Any other setting, by its ecosystem file name
+  gopm set api --set kill_signal=SIGINT --set 'health_check={"url":"http://localhost:8080/health"}'`,
+	Args: targetArgs(1, false),
+	Run:  runSet,
+}
+
+func init() {
+	f := setCmd.Flags()
+	f.StringArrayVar(&setEnv, "env", nil, "set environment variable KEY=VAL (repeatable)")
+	f.StringArrayVar(&setUnsetEnv, "unset-env", nil, "remove environment variable KEY (repeatable)")
+	f.StringArrayVar(&setLabels, "label", nil, "set label key=value (repeatable)")
+	f.StringArrayVar(&setUnsetLabels, "unset-label", nil, "remove label KEY (repeatable)")
+	f.StringVar(&setArgs, "args", "", "replace the arguments, space separated (empty to clear them)")
+	f.StringVar(&setAutoRestart, "autorestart", "", "restart policy: always|on-failure|never")
+	f.IntVar(&setMaxRestarts, "max-restarts", 0, "max restart attempts")
+	f.StringVar(&setMinUptime, "min-uptime", "", "minimum uptime before considered stable (e.g. 5s)")
+	f.StringVar(&setRestartDelay, "restart-delay", "", "delay between restarts (e.g. 1s)")
+	f.StringVar(&setKillTimeout, "kill-timeout", "", "time to wait for graceful stop (e.g. 5s)")
+	f.StringVar(&setMaxMemory, "max-memory", "", "restart when RSS exceeds this size (e.g. 512M)")
+	f.StringVar(&setCronRestart, "cron-restart", "", "restart on a cron schedule (e.g. \"0 3 * * *\" or @daily)")
+	f.StringArrayVar(&setSettings, "set", nil, "set any setting by its ecosystem file name, KEY=VALUE (repeatable)")
+	f.StringArrayVar(&setUnset, "unset", nil, "clear any setting by its ecosystem file name (repeatable)")
+	f.BoolVar(&setRestart, "restart", false, "restart the processes if their settings changed")
+	addSelectorFlag(setCmd)
+}
+
+func runSet(cmd *cobra.Command, args []string) {
+	params := protocol.UpdateParams{
+		Target:      targetFrom(args, 1),
+		Set:         make(map[string]json.RawMessage),
+		Unset:       setUnset,
+		UnsetEnv:    setUnsetEnv,
+		UnsetLabels: setUnsetLabels,
+		Restart:     setRestart,
+	}
+	for _, s := range setSettings {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" {
+			exitError(fmt.Sprintf("invalid --set %q: want KEY=VALUE", s))
+		}
+		params.Set[k] = settingValue(k, v)
+	}
+
+	f := cmd.Flags()
+	if len(setEnv) > 0 {
+		params.Set["env"] = keyValues("env", setEnv)
+	}
+	if len(setLabels) > 0 {
+		params.Set["labels"] = keyValues("label", setLabels)
+	}
+	if f.Changed("args") {
+		if fields := strings.Fields(setArgs); len(fields) > 0 {
+			params.Set["args"], _ = json.Marshal(fields)
+		} else {
+			params.Unset = append(params.Unset, "args")
+		}
+	}
+	if f.Changed("max-restarts") {
+		params.Set["max_restarts"], _ = json.Marshal(setMaxRestarts)
+	}
+	for _, s := range []struct {
+		flag, key, value string
+	}{
+		{"autorestart", "autorestart", setAutoRestart},
+		{"min-uptime", "min_uptime", setMinUptime},
+		{"restart-delay", "restart_delay", setRestartDelay},
+		{"kill-timeout", "kill_timeout", setKillTimeout},
+		{"max-memory", "max_memory", setMaxMemory},
+		{"cron-restart", "cron_restart", setCronRestart},
+	} {
+		if f.Changed(s.flag) {
+			params.Set[s.key], _ = json.Marshal(s.value)
+		}
+	}
+
+	c, err := newClient()
+	if err != nil {
+		exitError(fmt.Sprintf("cannot connect to daemon: %v", err))
+	}
+	defer c.Close()
+
+	resp, err := c.Send(protocol.MethodUpdate, params)
+	if err != nil {
+		exitError(fmt.Sprintf("failed to update: %v", err))
+	}
+	if !resp.Success {
+		exitError(resp.Error)
+	}
+
+	var results []protocol.UpdateResult
+	if err := json.Unmarshal(resp.Data, &results); err != nil {
+		exitError(fmt.Sprintf("failed to parse response: %v", err))
+	}
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+
+	if jsonOutput {
+		fmt.Println(string(resp.Data))
+	} else {
+		for _, r := range results {
+			switch {
+			case r.Error != "":
+				fmt.Printf("%s %s: settings changed, restart failed: %s\n", display.Red("FAIL"), display.Bold(r.Name), r.Error)
+			case len(r.Diff) == 0:
+				fmt.Printf("%s %s: nothing changed\n", display.Dim("OK"), display.Bold(r.Name))
+			case r.Restarted:
+				fmt.Printf("%s %s: settings changed (restarted)\n", display.Green("OK"), display.Bold(r.Name))
+			default:
+				fmt.Printf("%s %s: settings changed\n", display.Green("OK"), display.Bold(r.Name))
+			}
+			for _, d := range r.Diff {
+				fmt.Printf("    %s: %s → %s\n", d.Field, applyValue(d.Old), applyValue(d.New))
+			}
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// keyValues turns KEY=VAL flag values into a JSON object.
+func keyValues(flag string, entries []string) json.RawMessage {
+	m := make(map[string]string, len(entries))
+	for _, e := range entries {
+		k, v, ok := strings.Cut(e, "=")
+		if !ok || k == "" {
+			exitError(fmt.Sprintf("invalid --%s %q: want KEY=VAL", flag, e))
+		}
+		m[k] = v
+	}
+	data, _ := json.Marshal(m)
+	return data
+}
+
+// settingValue turns the VALUE of --set KEY=VALUE into JSON: as it is if
+// it is JSON the setting takes, e.g. 5 or ["-v"], else as a string, so
+// --set cwd=/srv needs no quotes. Values that fit neither are left for the
+// daemon to reject.
+func settingValue(key, value string) json.RawMessage {
+	quoted, _ := json.Marshal(value)
+	candidates := []json.RawMessage{quoted}
+	if json.Valid([]byte(value)) {
+		candidates = []json.RawMessage{json.RawMessage(value), quoted}
+	}
+	for _, v := range candidates {
+		data, _ := json.Marshal(map[string]json.RawMessage{key: v})
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if dec.Decode(&protocol.StartParams{}) == nil {
+			return v
+		}
+	}
+	return candidates[0]
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/7c/gopm/internal/display"
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+var signalGroup bool
+
+var signalCmd = &cobra.Command{
+	Use:   "signal <signal> <name|id|all|selector>",
+	Short: "Send a signal to a process",
+	Long: `Send a signal to running processes, e.g. SIGUSR1 to reopen log files or
+SIGHUP to reload configuration. The signal is a name, with or without the
+SIG prefix and in any case (SIGHUP, HUP, hup), or a number.
+
+By default only the process gopm started gets the signal; --group sends it to
+its whole process group, including any children. Each signal is recorded as
+a [gopm] line in the process's stderr log.`,
+	Example: `  # Ask nginx to reopen its log files
+  gopm signal USR1 nginx
+
+  # Reload config of a shell script and everything it started
+  gopm signal SIGHUP worker --group`,
+	Args: targetArgs(2, false),
+	Run:  runSignal,
+}
+
+func init() {
+	signalCmd.Flags().BoolVar(&signalGroup, "group", false, "signal the whole process group, not just the process")
+	addSelectorFlag(signalCmd)
+}
+
+func runSignal(cmd *cobra.Command, args []string) {
+	if _, err := protocol.ParseSignal(args[0]); err != nil {
+		exitError(err.Error())
+	}
+
+	c, err := newClient()
+	if err != nil {
+		exitError(fmt.Sprintf("cannot connect to daemon: %v", err))
+	}
+	defer c.Close()
+
+	params := protocol.SignalParams{Target: targetFrom(args, 2), Signal: args[0], Group: signalGroup}
+	resp, err := c.Send(protocol.MethodSignal, params)
+	if err != nil {
+		exitError(fmt.Sprintf("failed to send signal: %v", err))
+	}
+	if !resp.Success {
+		exitError(resp.Error)
+	}
+
+	if jsonOutput {
+		fmt.Println(string(resp.Data))
+		return
+	}
+
+	var results []protocol.SignalResult
+	if err := json.Unmarshal(resp.Data, &results); err != nil {
+		exitError(fmt.Sprintf("failed to parse response: %v", err))
+	}
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Printf("%s %s: %s\n", display.Red("FAIL"), display.Bold(r.Name), r.Error)
+			continue
+		}
+		to := "PID"
+		if r.Group {
+			to = "process group"
+		}
+		fmt.Printf("%s sent %s to %s (%s %d)\n", display.Green("OK"), r.Signal, display.Bold(r.Name), to, r.PID)
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/7c/gopm/internal/client"
 	"github.com/7c/gopm/internal/config"
+	"github.com/7c/gopm/internal/cron"
 	"github.com/7c/gopm/internal/display"
 	"github.com/7c/gopm/internal/protocol"
 	"github.com/spf13/cobra"
@@ -38,8 +40,50 @@ to the child process.`,
   # Start with restart policies
   gopm start ./worker --name worker --autorestart always --max-restarts 10
   gopm start ./task --name task --autorestart on-failure --restart-delay 5s --exp-backoff
+  gopm start ./worker --name worker --exp-backoff --backoff-jitter 0.2 --restart-budget 10/15m
   gopm start ./job --name job --autorestart never
 
+  # Restart when memory stays above 512M for 30s
+  gopm start ./api --name api --max-memory 512M --max-memory-grace 30s
+
+  # Restart every night at 03:00
+  gopm start ./api --name api --cron-restart "0 3 * * *"
+
+  # Restart on source changes while developing
+  gopm start ./api --name api --watch --ignore-watch "*.log" --ignore-watch tmp
+  gopm start ./api --name api --watch --watch-path ./bin --watch-delay 2s
+
+  # Start only after db is healthy and migrate has exited with code 0
+  gopm start ./api --name api --depends-on db:healthy --depends-on migrate:completed_successfully
+
+  # Hand the app a listening socket bound by gopm (LISTEN_FDS), kept open across restarts
+  gopm start ./api --name api --socket http=tcp:127.0.0.1:8080
+
+  # An app that speaks the sd_notify protocol; wait until it sends READY=1
+  gopm start ./api --name api --notify --watchdog-sec 30s --wait
+
+  # Run four instances of the same app (see "gopm scale")
+  gopm start ./api --name api --instances 4
+  gopm start node --name web --instances max -- server.js
+
+  # Run a batch script every 15 minutes as a scheduled job
+  gopm start ./sync.sh --name sync --schedule "*/15 * * * *" --overlap skip
+
+  # Cap a noisy worker with cgroup v2 limits
+  gopm start ./worker --name worker --cpu-max 50% --memory-max 1G --pids-max 256
+
+  # Raise the open files limit and keep a batch job out of the way
+  gopm start ./proxy --name proxy --limit-nofile 65536 --oom-score-adj -500
+  gopm start ./backup.sh --name backup --nice 10 --ionice-class idle --umask 077
+
+  # Start with an active health check (restarts after 3 failed checks)
+  gopm start ./api --name api --health-check http://127.0.0.1:8080/health
+  gopm start ./db --name db --health-check tcp:127.0.0.1:5432 --health-interval 10s
+  gopm start ./worker --name worker --health-check "exec:./check.sh" --health-failures 5
+
+  # Run migrations before every start and deregister from the load balancer before stop
+  gopm start ./api --name api --pre-start "./migrate up" --pre-stop "./lb deregister" --hook-timeout 2m
+
   # Start with custom log settings
   gopm start ./app --name app --log-out /var/log/app.log --max-log-size 50M
 
@@ -57,6 +101,7 @@ var (
 	startCwd         string
 	startInterpreter string
 	startEnv         []string
+	startLabels      []string
 	startAutoRestart string
 	startMaxRestarts int
 	startMinUptime   string
@@ -67,6 +112,76 @@ var (
 	startLogOut      string
 	startLogErr      string
 	startMaxLogSize  string
+
+	startMaxMemory      string
+	startMaxMemoryGrace string
+
+	startRestartBudget     string
+	startCrashLoopCooldown string
+	startBackoffJitter     float64
+
+	startRestartOnExit   []int
+	startNoRestartOnExit []int
+
+	startKillSignal   string
+	startStopCommand  string
+	startReloadSignal string
+
+	startUser   string
+	startGroup  string
+	startGroups []string
+
+	startCronRestart string
+	startSchedule    string
+	startOverlap     string
+
+	startWatch       bool
+	startWatchPaths  []string
+	startIgnoreWatch []string
+	startWatchDelay  string
+
+	startDependsOn []string
+
+	startInstances string
+
+	startReadyMessage string
+
+	startSockets []string
+
+	startNotify      bool
+	startWatchdogSec string
+
+	startWait        bool
+	startWaitTimeout string
+
+	startCPUMax     string
+	startMemoryMax  string
+	startMemoryHigh string
+	startPidsMax    int
+	startIOWeight   int
+
+	startLimitNofile  string
+	startLimitNproc   string
+	startLimitCore    string
+	startLimitMemlock string
+	startNice         int
+	startIONiceClass  string
+	startIONiceLevel  int
+	startCPUAffinity  string
+	startOOMScoreAdj  int
+	startUmask        string
+
+	startHealthCheck       string
+	startHealthInterval    string
+	startHealthTimeout     string
+	startHealthFailures    int
+	startHealthStartPeriod string
+
+	startPreStart    string
+	startPostStart   string
+	startPreStop     string
+	startPostExit    string
+	startHookTimeout string
 )
 
 func init() {
@@ -75,16 +190,70 @@ func init() {
 	f.StringVar(&startCwd, "cwd", "", "working directory")
 	f.StringVar(&startInterpreter, "interpreter", "", "interpreter (e.g. node, python3)")
 	f.StringArrayVar(&startEnv, "env", nil, "environment variable KEY=VAL (repeatable)")
+	f.StringArrayVar(&startLabels, "label", nil, "label key=value to select the process by, e.g. team=payments (repeatable)")
 	f.StringVar(&startAutoRestart, "autorestart", "", "restart policy: always|on-failure|never")
 	f.IntVar(&startMaxRestarts, "max-restarts", -1, "max restart attempts (-1 = use default)")
 	f.StringVar(&startMinUptime, "min-uptime", "", "minimum uptime before considered stable (e.g. 5s)")
 	f.StringVar(&startRestartDelay, "restart-delay", "", "delay between restarts (e.g. 1s)")
 	f.BoolVar(&startExpBackoff, "exp-backoff", false, "enable exponential backoff on restarts")
 	f.StringVar(&startMaxDelay, "max-delay", "", "max delay for exponential backoff (e.g. 30s)")
+	f.Float64Var(&startBackoffJitter, "backoff-jitter", 0, "randomize each restart delay by up to ± this fraction of it (0-1)")
+	f.StringVar(&startRestartBudget, "restart-budget", "", "at most this many restarts in a time window, e.g. 10/15m, before a crashloop cool-down")
+	f.StringVar(&startCrashLoopCooldown, "crashloop-cooldown", "", "how long a process over its restart budget waits before one more try (default 5m)")
 	f.StringVar(&startKillTimeout, "kill-timeout", "", "time to wait for graceful stop (e.g. 5s)")
+	f.StringVar(&startKillSignal, "kill-signal", "", "signal that stops the process, e.g. SIGINT or SIGQUIT (default SIGTERM)")
+	f.StringVar(&startStopCommand, "stop-command", "", "shell command run to stop the process, before the kill signal")
+	f.StringVar(&startReloadSignal, "reload-signal", "", "signal that makes the process reload in place, e.g. SIGHUP (used by gopm reload)")
+	f.IntSliceVar(&startRestartOnExit, "restart-on-exit", nil, "only restart on these exit codes (comma-separated)")
+	f.IntSliceVar(&startNoRestartOnExit, "no-restart-on-exit", nil, "never restart on these exit codes (comma-separated)")
+	f.StringVar(&startUser, "user", "", "run as this user, by name or uid (needs a daemon running as root)")
+	f.StringVar(&startGroup, "group", "", "run with this group, by name or gid (default: the user's primary group)")
+	f.StringSliceVar(&startGroups, "groups", nil, "supplementary groups, comma-separated (default: the user's own)")
 	f.StringVar(&startLogOut, "log-out", "", "stdout log file path")
 	f.StringVar(&startLogErr, "log-err", "", "stderr log file path")
 	f.StringVar(&startMaxLogSize, "max-log-size", "", "max log file size before rotation (e.g. 10M)")
+	f.StringVar(&startMaxMemory, "max-memory", "", "restart when RSS exceeds this size (e.g. 512M)")
+	f.StringVar(&startMaxMemoryGrace, "max-memory-grace", "", "how long RSS may stay above --max-memory before restart (e.g. 30s)")
+	f.StringVar(&startCronRestart, "cron-restart", "", "restart on a cron schedule (e.g. \"0 3 * * *\" or @daily)")
+	f.StringVar(&startSchedule, "schedule", "", "run as a scheduled job on this cron expression instead of keeping it alive")
+	f.StringVar(&startOverlap, "overlap", "", "when a scheduled run is due while the last is running: skip|queue|kill (default skip)")
+	f.BoolVar(&startWatch, "watch", false, "restart when files in the working directory (or --watch-path) change")
+	f.StringArrayVar(&startWatchPaths, "watch-path", nil, "file or directory to watch instead of the working directory (repeatable)")
+	f.StringArrayVar(&startIgnoreWatch, "ignore-watch", nil, "glob pattern of paths not to watch, e.g. \"*.log\" (repeatable)")
+	f.StringVar(&startWatchDelay, "watch-delay", "", "how long changes must settle before restarting (default 1s)")
+	f.StringArrayVar(&startDependsOn, "depends-on", nil, "start after this process: NAME[:started|healthy|completed_successfully] (repeatable)")
+	f.StringVar(&startInstances, "instances", "", "run this many instances of the app, or max for one per CPU")
+	f.StringVar(&startReadyMessage, "ready-message", "", "text the process prints on stdout once it is ready (see gopm reload)")
+	f.StringArrayVar(&startSockets, "socket", nil, "listening socket to pass as LISTEN_FDS: [NAME=]tcp:HOST:PORT or [NAME=]unix:/path (repeatable)")
+	f.BoolVar(&startNotify, "notify", false, "the process speaks the sd_notify protocol and is starting until it sends READY=1")
+	f.StringVar(&startWatchdogSec, "watchdog-sec", "", "restart the process if it sends no WATCHDOG=1 for this long (requires --notify)")
+	f.BoolVar(&startWait, "wait", false, "wait until the process is ready and exit non-zero if it isn't")
+	f.StringVar(&startWaitTimeout, "wait-timeout", "", "how long --wait waits for each process (default 30s)")
+	f.StringVar(&startCPUMax, "cpu-max", "", "cgroup CPU limit, e.g. 50% of one CPU or 200% for two")
+	f.StringVar(&startMemoryMax, "memory-max", "", "cgroup hard memory limit (e.g. 1G)")
+	f.StringVar(&startMemoryHigh, "memory-high", "", "cgroup memory throttle threshold (e.g. 768M)")
+	f.IntVar(&startPidsMax, "pids-max", 0, "cgroup limit on number of tasks")
+	f.IntVar(&startIOWeight, "io-weight", 0, "cgroup IO weight, 1-10000 (default 100)")
+	f.StringVar(&startLimitNofile, "limit-nofile", "", "open files limit, e.g. 65536 or soft:hard 1024:65536")
+	f.StringVar(&startLimitNproc, "limit-nproc", "", "limit on processes of the user, e.g. 4096 or unlimited")
+	f.StringVar(&startLimitCore, "limit-core", "", "core dump size limit, e.g. 0 or unlimited")
+	f.StringVar(&startLimitMemlock, "limit-memlock", "", "locked memory limit, e.g. 64M or unlimited")
+	f.IntVar(&startNice, "nice", 0, "CPU scheduling priority, -20 (highest) to 19")
+	f.StringVar(&startIONiceClass, "ionice-class", "", "IO scheduling class: realtime|best-effort|idle")
+	f.IntVar(&startIONiceLevel, "ionice-level", 0, "IO priority within the class, 0 (highest) to 7")
+	f.StringVar(&startCPUAffinity, "cpu-affinity", "", "CPUs the process may run on, e.g. 0-3,6")
+	f.IntVar(&startOOMScoreAdj, "oom-score-adj", 0, "OOM killer preference, -1000 (never) to 1000")
+	f.StringVar(&startUmask, "umask", "", "file creation mask in octal, e.g. 027")
+	f.StringVar(&startHealthCheck, "health-check", "", "health check: http://URL, https://URL, tcp:HOST:PORT, or exec:COMMAND")
+	f.StringVar(&startHealthInterval, "health-interval", "", "time between health checks (default 30s)")
+	f.StringVar(&startHealthTimeout, "health-timeout", "", "timeout for a single health check (default 5s)")
+	f.IntVar(&startHealthFailures, "health-failures", 0, "consecutive failed checks before restart (default 3)")
+	f.StringVar(&startHealthStartPeriod, "health-start-period", "", "grace period after start where failed checks don't count")
+	f.StringVar(&startPreStart, "pre-start", "", "shell command to run before every start; if it fails the process is not started")
+	f.StringVar(&startPostStart, "post-start", "", "shell command to run in the background after every start")
+	f.StringVar(&startPreStop, "pre-stop", "", "shell command to run before the process is sent its kill signal")
+	f.StringVar(&startPostExit, "post-exit", "", "shell command to run in the background after every exit")
+	f.StringVar(&startHookTimeout, "hook-timeout", "", "how long a hook may run before it is killed (default 30s)")
 }
 
 func runStart(cmd *cobra.Command, args []string) {
@@ -99,15 +268,18 @@ func runStart(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	startSingle(target, childArgs)
+	startSingle(cmd, target, childArgs)
 }
 
-// startEcosystem loads an ecosystem JSON file and starts each app.
+// startEcosystem loads an ecosystem JSON file and starts each app, after
+// the apps it depends on.
 func startEcosystem(path string) {
 	eco, err := config.LoadEcosystem(path)
 	if err != nil {
 		exitError(fmt.Sprintf("failed to load ecosystem config: %v", err))
 	}
+	// LoadEcosystem already rejected dependency cycles.
+	apps, _ := eco.StartOrder()
 
 	c, err := newClient()
 	if err != nil {
@@ -115,7 +287,8 @@ func startEcosystem(path string) {
 	}
 	defer c.Close()
 
-	for _, app := range eco.Apps {
+	var started []protocol.ProcessInfo
+	for _, app := range apps {
 		params := app.ToStartParams()
 		resp, err := c.Send(protocol.MethodStart, params)
 		if err != nil {
@@ -125,19 +298,27 @@ func startEcosystem(path string) {
 			exitError(fmt.Sprintf("failed to start %q: %s", app.Name, resp.Error))
 		}
 
+		infos, _ := protocol.DecodeStarted(resp.Data)
+		if startWait {
+			started = append(started, infos...)
+			continue
+		}
 		if jsonOutput {
 			fmt.Println(string(resp.Data))
 		} else {
-			var info protocol.ProcessInfo
-			if err := json.Unmarshal(resp.Data, &info); err == nil {
-				fmt.Printf("Process %s %s (PID: %s)\n", display.Bold(info.Name), display.Green("started"), display.Cyan(fmt.Sprintf("%d", info.PID)))
+			for _, info := range infos {
+				printStarted(info)
 			}
 		}
 	}
+	// All apps are started before waiting, so dependents can come up.
+	if startWait {
+		waitStarted(c, started)
+	}
 }
 
 // startSingle starts a single process from CLI flags.
-func startSingle(command string, childArgs []string) {
+func startSingle(cmd *cobra.Command, command string, childArgs []string) {
 	// Always resolve CWD on the CLI side. The daemon's own cwd is
 	// meaningless to the user; we need the directory the CLI was invoked from.
 	cwd := startCwd
@@ -178,12 +359,170 @@ func startSingle(command string, childArgs []string) {
 		LogOut:       startLogOut,
 		LogErr:       startLogErr,
 		MaxLogSize:   startMaxLogSize,
+
+		MaxMemory:      startMaxMemory,
+		MaxMemoryGrace: startMaxMemoryGrace,
+
+		RestartBudget:     startRestartBudget,
+		CrashLoopCooldown: startCrashLoopCooldown,
+		BackoffJitter:     startBackoffJitter,
+
+		RestartOnExit:   startRestartOnExit,
+		NoRestartOnExit: startNoRestartOnExit,
+
+		KillSignal:   startKillSignal,
+		StopCommand:  startStopCommand,
+		ReloadSignal: startReloadSignal,
+
+		User:   startUser,
+		Group:  startGroup,
+		Groups: startGroups,
+
+		CronRestart: startCronRestart,
+		Schedule:    startSchedule,
+		Overlap:     startOverlap,
+
+		Watch:       startWatch,
+		WatchPaths:  startWatchPaths,
+		IgnoreWatch: startIgnoreWatch,
+		WatchDelay:  startWatchDelay,
+
+		ReadyMessage: startReadyMessage,
+
+		Notify:      startNotify,
+		WatchdogSec: startWatchdogSec,
+	}
+
+	if startMaxMemory != "" {
+		if _, err := protocol.ParseSize(startMaxMemory); err != nil {
+			exitError(fmt.Sprintf("invalid --max-memory: %v", err))
+		}
+	}
+
+	if startCronRestart != "" {
+		if _, err := cron.Parse(startCronRestart); err != nil {
+			exitError(fmt.Sprintf("invalid --cron-restart: %v", err))
+		}
+	}
+
+	if startSchedule != "" {
+		if _, err := cron.Parse(startSchedule); err != nil {
+			exitError(fmt.Sprintf("invalid --schedule: %v", err))
+		}
+		if startCronRestart != "" {
+			exitError("--cron-restart cannot be used with --schedule")
+		}
+	}
+	if startOverlap != "" {
+		if startSchedule == "" {
+			exitError("--overlap requires --schedule")
+		}
+		if !protocol.OverlapPolicy(startOverlap).Valid() {
+			exitError(fmt.Sprintf("invalid --overlap %q: must be skip, queue, or kill", startOverlap))
+		}
+	}
+
+	if err := params.ValidateWatch(); err != nil {
+		exitError(fmt.Sprintf("invalid watch options: %v", err))
+	}
+
+	for _, v := range startDependsOn {
+		name, cond, _ := strings.Cut(v, ":")
+		params.DependsOn = append(params.DependsOn, protocol.Dependency{
+			Name:      name,
+			Condition: protocol.DependencyCondition(cond),
+		})
+	}
+	if err := protocol.ValidateDependsOn(params.Name, params.DependsOn); err != nil {
+		exitError(fmt.Sprintf("invalid --depends-on: %v", err))
+	}
+
+	if err := params.ValidateNotify(); err != nil {
+		exitError(fmt.Sprintf("invalid notify options: %v", err))
+	}
+
+	if err := params.ValidateRestartBudget(); err != nil {
+		exitError(fmt.Sprintf("invalid restart options: %v", err))
+	}
+
+	if err := params.ValidateSignals(); err != nil {
+		exitError(err.Error())
+	}
+
+	for _, v := range startSockets {
+		params.Sockets = append(params.Sockets, protocol.ParseSocket(v))
+	}
+	if err := protocol.ValidateSockets(params.Sockets); err != nil {
+		exitError(fmt.Sprintf("invalid --socket: %v", err))
+	}
+
+	if startInstances != "" {
+		n, err := protocol.ParseInstances(startInstances)
+		if err != nil {
+			exitError(fmt.Sprintf("invalid --instances: %v", err))
+		}
+		params.Instances = n
+		if err := params.ValidateInstances(); err != nil {
+			exitError(fmt.Sprintf("invalid --instances: %v", err))
+		}
 	}
 
 	if startMaxRestarts >= 0 {
 		params.MaxRestarts = &startMaxRestarts
 	}
 
+	if startCPUMax != "" || startMemoryMax != "" || startMemoryHigh != "" || startPidsMax > 0 || startIOWeight > 0 {
+		cg := &protocol.CgroupLimits{
+			CPUMax:     startCPUMax,
+			MemoryMax:  startMemoryMax,
+			MemoryHigh: startMemoryHigh,
+			PidsMax:    startPidsMax,
+			IOWeight:   startIOWeight,
+		}
+		if err := cg.Validate(); err != nil {
+			exitError(fmt.Sprintf("invalid cgroup limits: %v", err))
+		}
+		params.Cgroup = cg
+	}
+
+	if limits := startLimits(cmd); limits != nil {
+		if err := limits.Validate(); err != nil {
+			exitError(fmt.Sprintf("invalid limits: %v", err))
+		}
+		params.Limits = limits
+	}
+
+	if startHealthCheck != "" {
+		hc, err := parseHealthCheckFlag(startHealthCheck)
+		if err != nil {
+			exitError(err.Error())
+		}
+		hc.Interval = startHealthInterval
+		hc.Timeout = startHealthTimeout
+		hc.FailureThreshold = startHealthFailures
+		hc.StartPeriod = startHealthStartPeriod
+		if err := hc.Validate(); err != nil {
+			exitError(fmt.Sprintf("invalid --health-check: %v", err))
+		}
+		params.HealthCheck = hc
+	}
+
+	if startPreStart != "" || startPostStart != "" || startPreStop != "" || startPostExit != "" {
+		hooks := &protocol.Hooks{
+			PreStart:  startPreStart,
+			PostStart: startPostStart,
+			PreStop:   startPreStop,
+			PostExit:  startPostExit,
+			Timeout:   startHookTimeout,
+		}
+		if err := hooks.Validate(); err != nil {
+			exitError(fmt.Sprintf("invalid --hook-timeout: %v", err))
+		}
+		params.Hooks = hooks
+	} else if startHookTimeout != "" {
+		exitError("--hook-timeout requires a hook (--pre-start, --post-start, --pre-stop or --post-exit)")
+	}
+
 	// Parse --env KEY=VAL entries into a map.
 	if len(startEnv) > 0 {
 		envMap := make(map[string]string, len(startEnv))
@@ -197,6 +536,17 @@ func startSingle(command string, childArgs []string) {
 		params.Env = envMap
 	}
 
+	if len(startLabels) > 0 {
+		params.Labels = make(map[string]string, len(startLabels))
+		for _, entry := range startLabels {
+			k, v, err := protocol.ParseLabel(entry)
+			if err != nil {
+				exitError(err.Error())
+			}
+			params.Labels[k] = v
+		}
+	}
+
 	c, err := newClient()
 	if err != nil {
 		exitError(fmt.Sprintf("cannot connect to daemon: %v", err))
@@ -211,12 +561,115 @@ func startSingle(command string, childArgs []string) {
 		exitError(resp.Error)
 	}
 
+	infos, _ := protocol.DecodeStarted(resp.Data)
+	if startWait {
+		waitStarted(c, infos)
+		return
+	}
 	if jsonOutput {
 		fmt.Println(string(resp.Data))
 	} else {
-		var info protocol.ProcessInfo
-		if err := json.Unmarshal(resp.Data, &info); err == nil {
-			fmt.Printf("Process %s %s (PID: %s)\n", display.Bold(info.Name), display.Green("started"), display.Cyan(fmt.Sprintf("%d", info.PID)))
+		for _, info := range infos {
+			printStarted(info)
+		}
+	}
+}
+
+// waitStarted waits for each started process to become ready and prints
+// the result, or exits with an error for the first one that doesn't.
+func waitStarted(c *client.Client, started []protocol.ProcessInfo) {
+	if !jsonOutput {
+		for _, info := range started {
+			printStarted(info)
 		}
 	}
+	var ready []json.RawMessage
+	for _, info := range started {
+		resp, err := c.Send(protocol.MethodWait, protocol.WaitParams{Target: info.Name, Timeout: startWaitTimeout})
+		if err != nil {
+			exitError(fmt.Sprintf("failed to wait for %q: %v", info.Name, err))
+		}
+		if !resp.Success {
+			exitError(resp.Error)
+		}
+		ready = append(ready, resp.Data)
+		if !jsonOutput {
+			fmt.Printf("Process %s %s\n", display.Bold(info.Name), display.Green("ready"))
+		}
+	}
+	if jsonOutput {
+		if len(ready) == 1 {
+			outputJSON(ready[0])
+		} else {
+			data, _ := json.Marshal(ready)
+			outputJSON(data)
+		}
+	}
+}
+
+// printStarted prints the one-line result of starting a process or
+// scheduling a job.
+func printStarted(info protocol.ProcessInfo) {
+	if info.Schedule != "" {
+		next := "never"
+		if info.NextRun != nil {
+			next = info.NextRun.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("Job %s %s (next run: %s)\n", display.Bold(info.Name), display.Cyan("scheduled"), next)
+		return
+	}
+	if info.Status == protocol.StatusWaiting {
+		fmt.Printf("Process %s %s (%s)\n", display.Bold(info.Name), display.Magenta("waiting"), info.StatusReason)
+		return
+	}
+	if info.Status == protocol.StatusErrored {
+		fmt.Printf("Process %s %s (%s)\n", display.Bold(info.Name), display.Red("errored"), info.StatusReason)
+		return
+	}
+	if info.Status == protocol.StatusStarting {
+		fmt.Printf("Process %s %s (PID: %s, waiting for READY=1)\n", display.Bold(info.Name), display.Yellow("starting"), display.Cyan(fmt.Sprintf("%d", info.PID)))
+		return
+	}
+	fmt.Printf("Process %s %s (PID: %s)\n", display.Bold(info.Name), display.Green("started"), display.Cyan(fmt.Sprintf("%d", info.PID)))
+}
+
+// parseHealthCheckFlag parses a --health-check value. HTTP(S) URLs are used
+// as-is; other checks are written as "type:target" (e.g. "tcp:127.0.0.1:5432",
+// "exec:./check.sh").
+func parseHealthCheckFlag(v string) (*protocol.HealthCheck, error) {
+	if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") {
+		return &protocol.HealthCheck{Type: protocol.HealthCheckHTTP, Target: v}, nil
+	}
+	typ, target, ok := strings.Cut(v, ":")
+	if !ok || target == "" {
+		return nil, fmt.Errorf("invalid --health-check value %q: expected URL or type:target", v)
+	}
+	return &protocol.HealthCheck{Type: typ, Target: target}, nil
+}
+
+// startLimits returns the limits set by flags, or nil if none are.
+func startLimits(cmd *cobra.Command) *protocol.Limits {
+	f := cmd.Flags()
+	l := &protocol.Limits{
+		Nofile:      startLimitNofile,
+		Nproc:       startLimitNproc,
+		Core:        startLimitCore,
+		Memlock:     startLimitMemlock,
+		IONiceClass: startIONiceClass,
+		CPUAffinity: startCPUAffinity,
+		Umask:       startUmask,
+	}
+	if f.Changed("nice") {
+		l.Nice = &startNice
+	}
+	if f.Changed("ionice-level") {
+		l.IONiceLevel = &startIONiceLevel
+	}
+	if f.Changed("oom-score-adj") {
+		l.OOMScoreAdj = &startOOMScoreAdj
+	}
+	if *l == (protocol.Limits{}) {
+		return nil
+	}
+	return l
 }
@@ -0,0 +1,35 @@
+package cli
+
+import "testing"
+
+func TestParseHealthCheckFlag(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantType   string
+		wantTarget string
+		err        bool
+	}{
+		{"http://127.0.0.1:8080/health", "http", "http://127.0.0.1:8080/health", false},
+		{"https://example.com/ping", "http", "https://example.com/ping", false},
+		{"tcp:127.0.0.1:5432", "tcp", "127.0.0.1:5432", false},
+		{"exec:curl -sf localhost:3000", "exec", "curl -sf localhost:3000", false},
+		{"tcp:", "", "", true},
+		{"localhost", "", "", true},
+	}
+	for _, tt := range tests {
+		hc, err := parseHealthCheckFlag(tt.input)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseHealthCheckFlag(%q) expected error", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseHealthCheckFlag(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if hc.Type != tt.wantType || hc.Target != tt.wantTarget {
+			t.Errorf("parseHealthCheckFlag(%q) = %s %q, want %s %q", tt.input, hc.Type, hc.Target, tt.wantType, tt.wantTarget)
+		}
+	}
+}
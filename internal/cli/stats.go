@@ -21,7 +21,7 @@ var (
 )
 
 var statsCmd = &cobra.Command{
-	Use:   "stats [all|name|id]",
+	Use:   "stats [all|name|id|selector]",
 	Short: "Show historical metrics charts",
 	Long: `Display terminal charts showing CPU, memory, uptime, and restart
 history for managed processes. Data is collected every 60 seconds
@@ -38,6 +38,9 @@ If only one process is managed, the target can be omitted.`,
   # Show charts for a specific process
   gopm stats my-api
 
+  # Overlay the processes labelled tier=worker
+  gopm stats -l tier=worker
+
   # Show only CPU chart, last 2 hours
   gopm stats --cpu --hours 2
 
@@ -46,7 +49,7 @@ If only one process is managed, the target can be omitted.`,
 
   # JSON output (raw snapshot data)
   gopm stats --json`,
-	Args: cobra.MaximumNArgs(1),
+	Args: targetArgs(1, true),
 	Run:  runStats,
 }
 
@@ -57,13 +60,12 @@ func init() {
 	f.BoolVar(&statsMem, "mem", false, "show only memory chart")
 	f.BoolVar(&statsUptime, "uptime", false, "show only uptime chart")
 	f.BoolVar(&statsAll, "all", false, "show all charts (default)")
+	addSelectorFlag(statsCmd)
 }
 
 func runStats(cmd *cobra.Command, args []string) {
-	target := ""
-	if len(args) > 0 {
-		target = args[0]
-	} else {
+	target := targetFrom(args, 1)
+	if target == "" {
 		c, err := newClient()
 		if err != nil {
 			outputError(fmt.Sprintf("cannot connect to daemon: %v", err))
@@ -155,7 +157,25 @@ func runStats(cmd *cobra.Command, args []string) {
 		renderMetricChart(os.Stdout, result, "Restarts", 60, 8,
 			func(s protocol.MetricsSnapshot) float64 { return float64(s.Restarts) },
 			display.FormatRestartsAxis)
+		if hasMemRestarts(result) {
+			renderMetricChart(os.Stdout, result, "Memory Limit Restarts", 60, 8,
+				func(s protocol.MetricsSnapshot) float64 { return float64(s.MemRestarts) },
+				display.FormatRestartsAxis)
+		}
+	}
+}
+
+// hasMemRestarts reports whether any process was restarted for exceeding
+// max_memory within the returned window.
+func hasMemRestarts(data protocol.StatsResult) bool {
+	for _, snaps := range data {
+		for _, s := range snaps {
+			if s.MemRestarts > 0 {
+				return true
+			}
+		}
 	}
+	return false
 }
 
 // renderMetricChart builds ChartSeries from StatsResult and renders a chart.
@@ -2,6 +2,8 @@ package cli
 
 import (
 	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
 )
 
 func TestStatsCmd_Flags(t *testing.T) {
@@ -52,7 +54,21 @@ func TestStatsCmd_Args(t *testing.T) {
 }
 
 func TestStatsCmd_Use(t *testing.T) {
-	if statsCmd.Use != "stats [all|name|id]" {
+	if statsCmd.Use != "stats [all|name|id|selector]" {
 		t.Errorf("unexpected Use: %q", statsCmd.Use)
 	}
 }
+
+func TestHasMemRestarts(t *testing.T) {
+	data := protocol.StatsResult{
+		"api":    {{Timestamp: 1, Restarts: 2}},
+		"worker": {{Timestamp: 1}},
+	}
+	if hasMemRestarts(data) {
+		t.Error("hasMemRestarts = true, want false")
+	}
+	data["worker"] = append(data["worker"], protocol.MetricsSnapshot{Timestamp: 2, MemRestarts: 1})
+	if !hasMemRestarts(data) {
+		t.Error("hasMemRestarts = false, want true")
+	}
+}
@@ -9,14 +9,18 @@ import (
 )
 
 var stopCmd = &cobra.Command{
-	Use:   "stop <name|id|all>",
+	Use:   "stop <name|id|all|selector>",
 	Short: "Stop a running process",
-	Args:  cobra.ExactArgs(1),
+	Args:  targetArgs(1, false),
 	Run:   runStop,
 }
 
+func init() {
+	addSelectorFlag(stopCmd)
+}
+
 func runStop(cmd *cobra.Command, args []string) {
-	target := args[0]
+	target := targetFrom(args, 1)
 
 	c, err := newClient()
 	if err != nil {
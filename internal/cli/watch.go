@@ -22,7 +22,7 @@ var (
 )
 
 var watchCmd = &cobra.Command{
-	Use:   "watch [name|id|all]",
+	Use:   "watch [name|id|all|selector]",
 	Short: "Live-updating process table",
 	Long: `Display a live-updating process table that refreshes at a configurable interval.
 
@@ -36,6 +36,9 @@ If only one process is managed, the target can be omitted.`,
   # Watch a specific process
   gopm watch api
 
+  # Watch the processes labelled team=payments
+  gopm watch -l team=payments
+
   # Update every 5 seconds
   gopm watch -i 5
 
@@ -47,7 +50,7 @@ If only one process is managed, the target can be omitted.`,
 
   # Auto-quit after 30 seconds
   gopm watch --timeout 30`,
-	Args: cobra.MaximumNArgs(1),
+	Args: targetArgs(1, true),
 	Run:  runWatch,
 }
 
@@ -56,6 +59,7 @@ func init() {
 	f.IntVarP(&watchInterval, "interval", "i", 1, "refresh interval in seconds (min: 1)")
 	f.BoolVarP(&watchPorts, "ports", "p", false, "show listening ports column")
 	f.IntVarP(&watchTimeout, "timeout", "t", 0, "auto-quit after N seconds (0 = no timeout)")
+	addSelectorFlag(watchCmd)
 }
 
 func runWatch(cmd *cobra.Command, args []string) {
@@ -63,10 +67,7 @@ func runWatch(cmd *cobra.Command, args []string) {
 		watchInterval = 1
 	}
 
-	target := ""
-	if len(args) > 0 {
-		target = args[0]
-	}
+	target := targetFrom(args, 1)
 
 	// Determine if we're watching all or a specific process.
 	showAll := target == "" || strings.EqualFold(target, "all")
@@ -202,11 +203,20 @@ func runWatch(cmd *cobra.Command, args []string) {
 	}
 }
 
-// filterProcs filters a process list to match a target by name or ID.
+// filterProcs filters a process list to match a target by name, ID or
+// selector.
 func filterProcs(procs []protocol.ProcessInfo, target string) []protocol.ProcessInfo {
+	var sel *protocol.Selector
+	if protocol.IsSelector(target) {
+		sel, _ = protocol.ParseSelector(target)
+	}
 	var result []protocol.ProcessInfo
 	for _, p := range procs {
-		if p.Name == target {
+		if sel != nil {
+			if sel.Matches(p.Name, p.Labels) {
+				result = append(result, p)
+			}
+		} else if p.Name == target {
 			result = append(result, p)
 		} else if id, err := strconv.Atoi(target); err == nil && p.ID == id {
 			result = append(result, p)
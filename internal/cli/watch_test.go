@@ -137,7 +137,7 @@ func TestWatchCmd_Args(t *testing.T) {
 }
 
 func TestWatchCmd_Use(t *testing.T) {
-	if watchCmd.Use != "watch [name|id|all]" {
+	if watchCmd.Use != "watch [name|id|all|selector]" {
 		t.Errorf("unexpected Use: %q", watchCmd.Use)
 	}
 }
@@ -172,3 +172,18 @@ func TestWatchIntervalClamp(t *testing.T) {
 		t.Errorf("expected interval to stay 10, got %d", watchInterval)
 	}
 }
+
+func TestFilterProcs_BySelector(t *testing.T) {
+	procs := makeProcs()
+	procs[0].Labels = map[string]string{"tier": "web"}
+	procs[3].Labels = map[string]string{"tier": "web"}
+
+	result := filterProcs(procs, "tier=web")
+	if len(result) != 2 || result[0].Name != "api" || result[1].Name != "proxy" {
+		t.Fatalf("tier=web: got %v", result)
+	}
+	result = filterProcs(procs, "*r*")
+	if len(result) != 3 {
+		t.Fatalf("*r*: expected 3 procs, got %d", len(result))
+	}
+}
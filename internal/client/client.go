@@ -3,6 +3,7 @@ package client
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -117,6 +118,35 @@ func (c *Client) Send(method string, params interface{}) (*protocol.Response, er
 	return &resp, nil
 }
 
+// Subscribe sends a "subscribe" request and calls fn with each event the
+// daemon pushes, until fn returns an error or the daemon ends the stream.
+func (c *Client) Subscribe(params protocol.SubscribeParams, fn func(protocol.Event) error) error {
+	resp, err := c.Send(protocol.MethodSubscribe, params)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New(resp.Error)
+	}
+	for c.scanner.Scan() {
+		var r protocol.Response
+		if err := json.Unmarshal(c.scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("unmarshal event: %w", err)
+		}
+		if !r.Success {
+			return errors.New(r.Error)
+		}
+		var ev protocol.Event
+		if err := json.Unmarshal(r.Data, &ev); err != nil {
+			return fmt.Errorf("unmarshal event: %w", err)
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return c.scanner.Err()
+}
+
 // TryConnect attempts to connect to a running daemon without auto-starting one.
 // Returns nil, err if the daemon is not running.
 func TryConnect(configFlag string) (*Client, error) {
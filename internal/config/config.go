@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/7c/gopm/internal/protocol"
 )
 
 // Config is the raw parsed gopm.config.json.
@@ -14,6 +16,8 @@ type Config struct {
 	Logs      json.RawMessage `json:"logs"`
 	MCPServer json.RawMessage `json:"mcpserver"`
 	Telemetry json.RawMessage `json:"telemetry"`
+
+	Notifications json.RawMessage `json:"notifications"`
 }
 
 type LogsConfig struct {
@@ -37,6 +41,31 @@ type TelegrafConfig struct {
 	Measurement string `json:"measurement"`
 }
 
+type NotificationsConfig struct {
+	Webhooks          []WebhookConfig           `json:"webhooks"`
+	Events            []protocol.NotifyEvent    `json:"events"`
+	StderrLines       int                       `json:"stderr_lines"`
+	CrashLoopRestarts int                       `json:"crash_loop_restarts"`
+	Processes         map[string]NotifyOverride `json:"processes"`
+}
+
+// WebhookConfig is a webhook that notifications are POSTed to. Events
+// defaults to notifications.events.
+type WebhookConfig struct {
+	Name    string                 `json:"name"`
+	URL     string                 `json:"url"`
+	Events  []protocol.NotifyEvent `json:"events"`
+	Headers map[string]string      `json:"headers"`
+}
+
+// NotifyOverride changes notifications for one process or instance group:
+// Events replaces the events of every webhook ([] mutes the process) and
+// Webhooks limits delivery to the named webhooks. Nil keeps the default.
+type NotifyOverride struct {
+	Events   []protocol.NotifyEvent `json:"events"`
+	Webhooks []string               `json:"webhooks"`
+}
+
 type LoadResult struct {
 	Config *Config
 	Path   string // file path used, empty if none
@@ -6,6 +6,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/7c/gopm/internal/cron"
 	"github.com/7c/gopm/internal/protocol"
 )
 
@@ -22,6 +23,7 @@ type AppConfig struct {
 	Cwd          string            `json:"cwd,omitempty"`
 	Interpreter  string            `json:"interpreter,omitempty"`
 	Env          map[string]string `json:"env,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
 	AutoRestart  string            `json:"autorestart,omitempty"`
 	MaxRestarts  *int              `json:"max_restarts,omitempty"`
 	MinUptime    string            `json:"min_uptime,omitempty"`
@@ -32,6 +34,53 @@ type AppConfig struct {
 	LogOut       string            `json:"log_out,omitempty"`
 	LogErr       string            `json:"log_err,omitempty"`
 	MaxLogSize   string            `json:"max_log_size,omitempty"`
+
+	HealthCheck *protocol.HealthCheck `json:"health_check,omitempty"`
+
+	MaxMemory      string `json:"max_memory,omitempty"`
+	MaxMemoryGrace string `json:"max_memory_grace,omitempty"`
+
+	RestartBudget     string  `json:"restart_budget,omitempty"`
+	CrashLoopCooldown string  `json:"crashloop_cooldown,omitempty"`
+	BackoffJitter     float64 `json:"backoff_jitter,omitempty"`
+
+	RestartOnExit   []int `json:"restart_on_exit,omitempty"`
+	NoRestartOnExit []int `json:"no_restart_on_exit,omitempty"`
+
+	KillSignal   string `json:"kill_signal,omitempty"`
+	StopCommand  string `json:"stop_command,omitempty"`
+	ReloadSignal string `json:"reload_signal,omitempty"`
+
+	User   string   `json:"user,omitempty"`
+	Group  string   `json:"group,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+
+	Cgroup *protocol.CgroupLimits `json:"cgroup,omitempty"`
+
+	Limits *protocol.Limits `json:"limits,omitempty"`
+
+	CronRestart string `json:"cron_restart,omitempty"`
+
+	Schedule string `json:"schedule,omitempty"`
+	Overlap  string `json:"overlap,omitempty"`
+
+	Watch       bool     `json:"watch,omitempty"`
+	WatchPaths  []string `json:"watch_paths,omitempty"`
+	IgnoreWatch []string `json:"ignore_watch,omitempty"`
+	WatchDelay  string   `json:"watch_delay,omitempty"`
+
+	DependsOn []protocol.Dependency `json:"depends_on,omitempty"`
+
+	ReadyMessage string `json:"ready_message,omitempty"`
+
+	Sockets []protocol.Socket `json:"sockets,omitempty"`
+
+	Notify      bool   `json:"notify,omitempty"`
+	WatchdogSec string `json:"watchdog_sec,omitempty"`
+
+	Hooks *protocol.Hooks `json:"hooks,omitempty"`
+
+	Instances protocol.Instances `json:"instances,omitempty"`
 }
 
 // LoadEcosystem reads and validates an ecosystem JSON file.
@@ -60,50 +109,160 @@ func (c *EcosystemConfig) Validate() error {
 		if app.Name == "" {
 			return fmt.Errorf("app at index %d is missing a name", i)
 		}
-		if app.Command == "" {
-			return fmt.Errorf("app %q is missing a command", app.Name)
-		}
 		if names[app.Name] {
 			return fmt.Errorf("duplicate app name %q", app.Name)
 		}
 		names[app.Name] = true
+		if err := app.Validate(); err != nil {
+			return err
+		}
+	}
+	if _, err := c.StartOrder(); err != nil {
+		return fmt.Errorf("depends_on: %w", err)
+	}
+	return nil
+}
 
-		if app.AutoRestart != "" {
-			switch protocol.AutoRestartMode(app.AutoRestart) {
-			case protocol.RestartAlways, protocol.RestartOnFailure, protocol.RestartNever:
-			default:
-				return fmt.Errorf("app %q: invalid autorestart %q", app.Name, app.AutoRestart)
-			}
+// Validate checks the settings of a single app for errors. Errors name the
+// app.
+func (a *AppConfig) Validate() error {
+	if a.Command == "" {
+		return fmt.Errorf("app %q is missing a command", a.Name)
+	}
+	if a.AutoRestart != "" {
+		switch protocol.AutoRestartMode(a.AutoRestart) {
+		case protocol.RestartAlways, protocol.RestartOnFailure, protocol.RestartNever:
+		default:
+			return fmt.Errorf("app %q: invalid autorestart %q", a.Name, a.AutoRestart)
+		}
+	}
+	if a.MinUptime != "" {
+		if _, err := time.ParseDuration(a.MinUptime); err != nil {
+			return fmt.Errorf("app %q: invalid min_uptime %q: %w", a.Name, a.MinUptime, err)
+		}
+	}
+	if a.RestartDelay != "" {
+		if _, err := time.ParseDuration(a.RestartDelay); err != nil {
+			return fmt.Errorf("app %q: invalid restart_delay %q: %w", a.Name, a.RestartDelay, err)
+		}
+	}
+	if a.MaxDelay != "" {
+		if _, err := time.ParseDuration(a.MaxDelay); err != nil {
+			return fmt.Errorf("app %q: invalid max_delay %q: %w", a.Name, a.MaxDelay, err)
+		}
+	}
+	if a.KillTimeout != "" {
+		if _, err := time.ParseDuration(a.KillTimeout); err != nil {
+			return fmt.Errorf("app %q: invalid kill_timeout %q: %w", a.Name, a.KillTimeout, err)
 		}
-		if app.MinUptime != "" {
-			if _, err := time.ParseDuration(app.MinUptime); err != nil {
-				return fmt.Errorf("app %q: invalid min_uptime %q: %w", app.Name, app.MinUptime, err)
-			}
+	}
+	if a.MaxLogSize != "" {
+		if _, err := protocol.ParseSize(a.MaxLogSize); err != nil {
+			return fmt.Errorf("app %q: invalid max_log_size %q: %w", a.Name, a.MaxLogSize, err)
 		}
-		if app.RestartDelay != "" {
-			if _, err := time.ParseDuration(app.RestartDelay); err != nil {
-				return fmt.Errorf("app %q: invalid restart_delay %q: %w", app.Name, app.RestartDelay, err)
-			}
+	}
+	if a.MaxMemory != "" {
+		if _, err := protocol.ParseSize(a.MaxMemory); err != nil {
+			return fmt.Errorf("app %q: invalid max_memory %q: %w", a.Name, a.MaxMemory, err)
+		}
+	}
+	if a.MaxMemoryGrace != "" {
+		if _, err := time.ParseDuration(a.MaxMemoryGrace); err != nil {
+			return fmt.Errorf("app %q: invalid max_memory_grace %q: %w", a.Name, a.MaxMemoryGrace, err)
 		}
-		if app.MaxDelay != "" {
-			if _, err := time.ParseDuration(app.MaxDelay); err != nil {
-				return fmt.Errorf("app %q: invalid max_delay %q: %w", app.Name, app.MaxDelay, err)
-			}
+	}
+	if a.Cgroup != nil {
+		if err := a.Cgroup.Validate(); err != nil {
+			return fmt.Errorf("app %q: cgroup: %w", a.Name, err)
 		}
-		if app.KillTimeout != "" {
-			if _, err := time.ParseDuration(app.KillTimeout); err != nil {
-				return fmt.Errorf("app %q: invalid kill_timeout %q: %w", app.Name, app.KillTimeout, err)
-			}
+	}
+	if a.Limits != nil {
+		if err := a.Limits.Validate(); err != nil {
+			return fmt.Errorf("app %q: limits: %w", a.Name, err)
 		}
-		if app.MaxLogSize != "" {
-			if _, err := protocol.ParseSize(app.MaxLogSize); err != nil {
-				return fmt.Errorf("app %q: invalid max_log_size %q: %w", app.Name, app.MaxLogSize, err)
-			}
+	}
+	if err := protocol.ValidateLabels(a.Labels); err != nil {
+		return fmt.Errorf("app %q: %w", a.Name, err)
+	}
+	if a.HealthCheck != nil {
+		if err := a.HealthCheck.Validate(); err != nil {
+			return fmt.Errorf("app %q: health_check: %w", a.Name, err)
 		}
 	}
+	if a.Hooks != nil {
+		if err := a.Hooks.Validate(); err != nil {
+			return fmt.Errorf("app %q: hooks: %w", a.Name, err)
+		}
+	}
+	if a.CronRestart != "" {
+		if _, err := cron.Parse(a.CronRestart); err != nil {
+			return fmt.Errorf("app %q: cron_restart: %w", a.Name, err)
+		}
+	}
+	if a.Schedule != "" {
+		if _, err := cron.Parse(a.Schedule); err != nil {
+			return fmt.Errorf("app %q: schedule: %w", a.Name, err)
+		}
+		if a.CronRestart != "" {
+			return fmt.Errorf("app %q: cron_restart cannot be used with schedule", a.Name)
+		}
+	}
+	if a.Overlap != "" {
+		if a.Schedule == "" {
+			return fmt.Errorf("app %q: overlap requires schedule", a.Name)
+		}
+		if !protocol.OverlapPolicy(a.Overlap).Valid() {
+			return fmt.Errorf("app %q: invalid overlap %q: must be skip, queue, or kill", a.Name, a.Overlap)
+		}
+	}
+	sp := a.ToStartParams()
+	if err := sp.ValidateWatch(); err != nil {
+		return fmt.Errorf("app %q: %w", a.Name, err)
+	}
+	if err := sp.ValidateInstances(); err != nil {
+		return fmt.Errorf("app %q: %w", a.Name, err)
+	}
+	if err := sp.ValidateNotify(); err != nil {
+		return fmt.Errorf("app %q: %w", a.Name, err)
+	}
+	if err := sp.ValidateRestartBudget(); err != nil {
+		return fmt.Errorf("app %q: %w", a.Name, err)
+	}
+	if err := sp.ValidateSignals(); err != nil {
+		return fmt.Errorf("app %q: %w", a.Name, err)
+	}
+	if err := protocol.ValidateDependsOn(a.Name, a.DependsOn); err != nil {
+		return fmt.Errorf("app %q: depends_on: %w", a.Name, err)
+	}
+	if err := protocol.ValidateSockets(a.Sockets); err != nil {
+		return fmt.Errorf("app %q: sockets: %w", a.Name, err)
+	}
 	return nil
 }
 
+// StartOrder returns the apps ordered so that each comes after the apps it
+// depends on, keeping file order otherwise. Dependencies on processes not
+// in the file are left to the daemon. It fails on a dependency cycle.
+func (c *EcosystemConfig) StartOrder() ([]AppConfig, error) {
+	byName := make(map[string]AppConfig, len(c.Apps))
+	names := make([]string, len(c.Apps))
+	for i, app := range c.Apps {
+		byName[app.Name] = app
+		names[i] = app.Name
+	}
+	order, err := protocol.DependencyOrder(names, func(name string) []protocol.Dependency {
+		return byName[name].DependsOn
+	})
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]AppConfig, len(order))
+	for i, name := range order {
+		apps[i] = byName[name]
+	}
+	return apps, nil
+}
+
 // ToStartParams converts an AppConfig to a StartParams for the daemon RPC.
 func (a *AppConfig) ToStartParams() protocol.StartParams {
 	return protocol.StartParams{
@@ -113,6 +272,7 @@ func (a *AppConfig) ToStartParams() protocol.StartParams {
 		Cwd:          a.Cwd,
 		Interpreter:  a.Interpreter,
 		Env:          a.Env,
+		Labels:       a.Labels,
 		AutoRestart:  a.AutoRestart,
 		MaxRestarts:  a.MaxRestarts,
 		MinUptime:    a.MinUptime,
@@ -123,5 +283,59 @@ func (a *AppConfig) ToStartParams() protocol.StartParams {
 		LogOut:       a.LogOut,
 		LogErr:       a.LogErr,
 		MaxLogSize:   a.MaxLogSize,
+		HealthCheck:  a.HealthCheck,
+
+		MaxMemory:      a.MaxMemory,
+		MaxMemoryGrace: a.MaxMemoryGrace,
+
+		RestartBudget:     a.RestartBudget,
+		CrashLoopCooldown: a.CrashLoopCooldown,
+		BackoffJitter:     a.BackoffJitter,
+
+		RestartOnExit:   a.RestartOnExit,
+		NoRestartOnExit: a.NoRestartOnExit,
+
+		KillSignal:   a.KillSignal,
+		StopCommand:  a.StopCommand,
+		ReloadSignal: a.ReloadSignal,
+
+		User:   a.User,
+		Group:  a.Group,
+		Groups: a.Groups,
+
+		Cgroup: a.Cgroup,
+		Limits: a.Limits,
+
+		CronRestart: a.CronRestart,
+		Schedule:    a.Schedule,
+		Overlap:     a.Overlap,
+
+		Watch:       a.Watch,
+		WatchPaths:  a.WatchPaths,
+		IgnoreWatch: a.IgnoreWatch,
+		WatchDelay:  a.WatchDelay,
+
+		DependsOn: a.DependsOn,
+
+		ReadyMessage: a.ReadyMessage,
+
+		Sockets: a.Sockets,
+
+		Notify:      a.Notify,
+		WatchdogSec: a.WatchdogSec,
+
+		Hooks: a.Hooks,
+
+		Instances: a.Instances,
 	}
 }
+
+// AppFromStartParams is the reverse of ToStartParams, e.g. to check
+// settings given to the daemon by the rules of an ecosystem file. The two
+// share their JSON keys.
+func AppFromStartParams(sp protocol.StartParams) AppConfig {
+	var app AppConfig
+	data, _ := json.Marshal(sp)
+	json.Unmarshal(data, &app)
+	return app
+}
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
 )
 
 func TestLoadEcosystem(t *testing.T) {
@@ -68,6 +73,196 @@ func TestLoadEcosystemValidation(t *testing.T) {
 	if err == nil {
 		t.Error("expected validation error for empty apps")
 	}
+
+	// Invalid health check type
+	path5 := filepath.Join(dir, "bad5.json")
+	os.WriteFile(path5, []byte(`{"apps":[
+		{"name":"api","command":"/bin/api","health_check":{"type":"grpc","target":":50051"}}
+	]}`), 0644)
+	_, err = LoadEcosystem(path5)
+	if err == nil {
+		t.Error("expected validation error for invalid health_check type")
+	}
+
+	// Invalid cron expression
+	path6 := filepath.Join(dir, "bad6.json")
+	os.WriteFile(path6, []byte(`{"apps":[
+		{"name":"api","command":"/bin/api","cron_restart":"0 25 * * *"}
+	]}`), 0644)
+	_, err = LoadEcosystem(path6)
+	if err == nil {
+		t.Error("expected validation error for invalid cron_restart")
+	}
+
+	// Overlap without schedule, or an unknown policy
+	for i, app := range []string{
+		`{"name":"job","command":"/bin/job","overlap":"queue"}`,
+		`{"name":"job","command":"/bin/job","schedule":"@hourly","overlap":"wait"}`,
+		`{"name":"job","command":"/bin/job","schedule":"@hourly","cron_restart":"@daily"}`,
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("bad-sched%d.json", i))
+		os.WriteFile(path, []byte(`{"apps":[`+app+`]}`), 0644)
+		if _, err := LoadEcosystem(path); err == nil {
+			t.Errorf("expected validation error for %s", app)
+		}
+	}
+
+	// Watch options without watch, or invalid ones
+	for i, app := range []string{
+		`{"name":"api","command":"/bin/api","watch_paths":["src"]}`,
+		`{"name":"api","command":"/bin/api","watch":true,"watch_delay":"soon"}`,
+		`{"name":"api","command":"/bin/api","watch":true,"ignore_watch":["[a-"]}`,
+		`{"name":"job","command":"/bin/job","watch":true,"schedule":"@hourly"}`,
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("bad-watch%d.json", i))
+		os.WriteFile(path, []byte(`{"apps":[`+app+`]}`), 0644)
+		if _, err := LoadEcosystem(path); err == nil {
+			t.Errorf("expected validation error for %s", app)
+		}
+	}
+
+	// Bad instances
+	for i, app := range []string{
+		`{"name":"api","command":"/bin/api","instances":-2}`,
+		`{"name":"api","command":"/bin/api","instances":"all"}`,
+		`{"name":"job","command":"/bin/job","instances":2,"schedule":"@hourly"}`,
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("bad-instances%d.json", i))
+		os.WriteFile(path, []byte(`{"apps":[`+app+`]}`), 0644)
+		if _, err := LoadEcosystem(path); err == nil {
+			t.Errorf("expected validation error for %s", app)
+		}
+	}
+
+	// Bad sockets
+	for i, app := range []string{
+		`{"name":"api","command":"/bin/api","sockets":["tcp:127.0.0.1"]}`,
+		`{"name":"api","command":"/bin/api","sockets":["unix:api.sock"]}`,
+		`{"name":"api","command":"/bin/api","sockets":[":8080","tcp::8080"]}`,
+		`{"name":"api","command":"/bin/api","sockets":[8080]}`,
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("bad-sockets%d.json", i))
+		os.WriteFile(path, []byte(`{"apps":[`+app+`]}`), 0644)
+		if _, err := LoadEcosystem(path); err == nil {
+			t.Errorf("expected validation error for %s", app)
+		}
+	}
+
+	// Bad notify settings
+	for i, app := range []string{
+		`{"name":"api","command":"/bin/api","watchdog_sec":"30s"}`,
+		`{"name":"api","command":"/bin/api","notify":true,"watchdog_sec":"0"}`,
+		`{"name":"api","command":"/bin/api","notify":true,"watchdog_sec":"often"}`,
+		`{"name":"job","command":"/bin/job","notify":true,"schedule":"0 * * * *"}`,
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("bad-notify%d.json", i))
+		os.WriteFile(path, []byte(`{"apps":[`+app+`]}`), 0644)
+		if _, err := LoadEcosystem(path); err == nil {
+			t.Errorf("expected validation error for %s", app)
+		}
+	}
+
+	// Bad signals
+	for i, app := range []string{
+		`{"name":"api","command":"/bin/api","kill_signal":"SIGNOPE"}`,
+		`{"name":"api","command":"/bin/api","reload_signal":"0"}`,
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("bad-signal%d.json", i))
+		os.WriteFile(path, []byte(`{"apps":[`+app+`]}`), 0644)
+		if _, err := LoadEcosystem(path); err == nil {
+			t.Errorf("expected validation error for %s", app)
+		}
+	}
+
+	// Bad limits
+	for i, app := range []string{
+		`{"name":"api","command":"/bin/api","limits":{"nofile":"many"}}`,
+		`{"name":"api","command":"/bin/api","limits":{"nice":-21}}`,
+		`{"name":"api","command":"/bin/api","limits":{"umask":"888"}}`,
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("bad-limits%d.json", i))
+		os.WriteFile(path, []byte(`{"apps":[`+app+`]}`), 0644)
+		if _, err := LoadEcosystem(path); err == nil {
+			t.Errorf("expected validation error for %s", app)
+		}
+	}
+
+	// Bad labels
+	for i, app := range []string{
+		`{"name":"api","command":"/bin/api","labels":{"":"x"}}`,
+		`{"name":"api","command":"/bin/api","labels":{"team":"a b"}}`,
+		`{"name":"api","command":"/bin/api","labels":{"te=am":"x"}}`,
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("bad-labels%d.json", i))
+		os.WriteFile(path, []byte(`{"apps":[`+app+`]}`), 0644)
+		if _, err := LoadEcosystem(path); err == nil {
+			t.Errorf("expected validation error for %s", app)
+		}
+	}
+
+	// Bad depends_on entries and dependency cycles
+	for i, apps := range []string{
+		`{"name":"api","command":"/bin/api","depends_on":["api"]}`,
+		`{"name":"api","command":"/bin/api","depends_on":[{"name":"db","condition":"ready"}]}`,
+		`{"name":"api","command":"/bin/api","depends_on":["db","db"]}`,
+		`{"name":"a","command":"/bin/a","depends_on":["b"]},{"name":"b","command":"/bin/b","depends_on":["a"]}`,
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("bad-deps%d.json", i))
+		os.WriteFile(path, []byte(`{"apps":[`+apps+`]}`), 0644)
+		if _, err := LoadEcosystem(path); err == nil {
+			t.Errorf("expected validation error for %s", apps)
+		}
+	}
+}
+
+func TestEcosystemStartOrder(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "eco.json")
+	os.WriteFile(path, []byte(`{"apps":[
+		{"name":"api","command":"/bin/api","depends_on":[{"name":"db","condition":"healthy"},"migrate"]},
+		{"name":"migrate","command":"/bin/migrate","depends_on":["db"]},
+		{"name":"web","command":"/bin/web","depends_on":["external"]},
+		{"name":"db","command":"/bin/db"}
+	]}`), 0644)
+	eco, err := LoadEcosystem(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps, err := eco.StartOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, app := range apps {
+		names = append(names, app.Name)
+	}
+	if got, want := strings.Join(names, ","), "db,migrate,api,web"; got != want {
+		t.Errorf("StartOrder = %s, want %s", got, want)
+	}
+	if dep := apps[2].ToStartParams().DependsOn[0]; dep.Name != "db" || dep.Condition != protocol.DependsHealthy {
+		t.Errorf("api depends_on[0] = %+v", dep)
+	}
+}
+
+func TestLoadEcosystemHealthCheck(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "eco.json")
+	os.WriteFile(path, []byte(`{"apps":[{
+		"name":"api","command":"/bin/api",
+		"health_check":{"type":"http","target":"http://127.0.0.1:8080/health","interval":"10s","failure_threshold":2}
+	}]}`), 0644)
+
+	eco, err := LoadEcosystem(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hc := eco.Apps[0].ToStartParams().HealthCheck
+	if hc == nil {
+		t.Fatal("HealthCheck = nil")
+	}
+	if hc.Type != "http" || hc.Target != "http://127.0.0.1:8080/health" || hc.Interval != "10s" || hc.FailureThreshold != 2 {
+		t.Errorf("HealthCheck = %+v", *hc)
+	}
 }
 
 func TestAppConfigToStartParams(t *testing.T) {
@@ -79,6 +274,7 @@ func TestAppConfigToStartParams(t *testing.T) {
 		AutoRestart:  "on-failure",
 		MaxRestarts:  intPtr(5),
 		RestartDelay: "2s",
+		CronRestart:  "0 3 * * *",
 	}
 
 	params := app.ToStartParams()
@@ -97,6 +293,32 @@ func TestAppConfigToStartParams(t *testing.T) {
 	if params.RestartDelay != "2s" {
 		t.Errorf("RestartDelay = %q", params.RestartDelay)
 	}
+	if params.CronRestart != "0 3 * * *" {
+		t.Errorf("CronRestart = %q", params.CronRestart)
+	}
+}
+
+func TestAppFromStartParams(t *testing.T) {
+	nice := 5
+	app := AppConfig{
+		Name: "api", Command: "/bin/api", Args: []string{"-v"}, Cwd: "/srv", Interpreter: "node",
+		Env: map[string]string{"A": "1"}, Labels: map[string]string{"team": "x"},
+		AutoRestart: "on-failure", MaxRestarts: intPtr(5), MinUptime: "5s", RestartDelay: "2s",
+		ExpBackoff: true, MaxDelay: "1m", KillTimeout: "10s", LogOut: "/l/out", LogErr: "/l/err",
+		MaxLogSize: "1M", HealthCheck: &protocol.HealthCheck{Type: "tcp", Target: "127.0.0.1:80"},
+		MaxMemory: "1G", MaxMemoryGrace: "30s", RestartBudget: "10/15m", CrashLoopCooldown: "5m",
+		BackoffJitter: 0.2, RestartOnExit: []int{1}, NoRestartOnExit: []int{78},
+		KillSignal: "SIGINT", StopCommand: "drain", ReloadSignal: "SIGHUP",
+		User: "www", Group: "www", Groups: []string{"ssl"},
+		Cgroup: &protocol.CgroupLimits{PidsMax: 10}, Limits: &protocol.Limits{Nice: &nice},
+		CronRestart: "0 3 * * *", Watch: true, WatchPaths: []string{"bin"}, IgnoreWatch: []string{"*.log"},
+		WatchDelay: "1s", DependsOn: []protocol.Dependency{{Name: "db"}}, ReadyMessage: "up",
+		Sockets: []protocol.Socket{{Address: "tcp:127.0.0.1:80"}}, Notify: true, WatchdogSec: "30s",
+		Hooks: &protocol.Hooks{PreStart: "./migrate"}, Instances: 2,
+	}
+	if got := AppFromStartParams(app.ToStartParams()); !reflect.DeepEqual(got, app) {
+		t.Errorf("AppFromStartParams(ToStartParams()) =\n%+v\nwant\n%+v", got, app)
+	}
 }
 
 func intPtr(i int) *int { return &i }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -30,6 +31,23 @@ type Resolved struct {
 	TelegrafEnabled bool
 	TelegrafAddr    *net.UDPAddr
 	TelegrafMeas    string
+
+	Webhooks          []WebhookConfig // with names and events filled in
+	NotifyOverrides   map[string]NotifyOverride
+	NotifyStderrLines int
+	CrashLoopRestarts int
+}
+
+// Notification defaults, used when a key is left out.
+const (
+	DefaultNotifyStderrLines = 20
+	DefaultCrashLoopRestarts = 3
+)
+
+// DefaultNotifyEvents are the events sent when notifications.events is
+// left out: the ones worth paging for.
+var DefaultNotifyEvents = []protocol.NotifyEvent{
+	protocol.NotifyErrored, protocol.NotifyCrashLoop, protocol.NotifyHealthFailed,
 }
 
 // Resolve takes a raw Config (may be nil) and returns the validated runtime config.
@@ -140,9 +158,120 @@ func Resolve(cfg *Config, gopmHome string) (*Resolved, []string, error) {
 		}
 	}
 
+	// --- Notifications (absent/null = disabled) ---
+	if cfg != nil && cfg.Notifications != nil && !isJSONNull(cfg.Notifications) {
+		n := NotificationsConfig{
+			StderrLines:       DefaultNotifyStderrLines,
+			CrashLoopRestarts: DefaultCrashLoopRestarts,
+		}
+		if err := json.Unmarshal(cfg.Notifications, &n); err != nil {
+			return nil, nil, fmt.Errorf("notifications: %w", err)
+		}
+		if err := resolveNotifications(r, n); err != nil {
+			return nil, nil, fmt.Errorf("notifications.%w", err)
+		}
+	}
+
 	return r, warnings, nil
 }
 
+// resolveNotifications validates the notifications section and fills in
+// r. Errors name the offending key.
+func resolveNotifications(r *Resolved, n NotificationsConfig) error {
+	if n.StderrLines < 0 {
+		return fmt.Errorf("stderr_lines must be >= 0 (got: %d)", n.StderrLines)
+	}
+	if n.CrashLoopRestarts < 1 {
+		return fmt.Errorf("crash_loop_restarts must be >= 1 (got: %d)", n.CrashLoopRestarts)
+	}
+	if n.Events == nil {
+		n.Events = DefaultNotifyEvents
+	}
+	if err := validateNotifyEvents(n.Events); err != nil {
+		return fmt.Errorf("events: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i, wh := range n.Webhooks {
+		if wh.Name == "" {
+			wh.Name = fmt.Sprintf("webhook-%d", i+1)
+		}
+		if names[wh.Name] {
+			return fmt.Errorf("webhooks: duplicate name %q", wh.Name)
+		}
+		names[wh.Name] = true
+		u, err := url.Parse(wh.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhooks[%d].url %q - expected an http:// or https:// URL", i, wh.URL)
+		}
+		if wh.Events == nil {
+			wh.Events = n.Events
+		}
+		if err := validateNotifyEvents(wh.Events); err != nil {
+			return fmt.Errorf("webhooks[%d].events: %w", i, err)
+		}
+		r.Webhooks = append(r.Webhooks, wh)
+	}
+	for name, ov := range n.Processes {
+		if err := validateNotifyEvents(ov.Events); err != nil {
+			return fmt.Errorf("processes.%s.events: %w", name, err)
+		}
+		for _, w := range ov.Webhooks {
+			if !names[w] {
+				return fmt.Errorf("processes.%s.webhooks: no webhook named %q", name, w)
+			}
+		}
+	}
+	r.NotifyOverrides = n.Processes
+	r.NotifyStderrLines = n.StderrLines
+	r.CrashLoopRestarts = n.CrashLoopRestarts
+	return nil
+}
+
+func validateNotifyEvents(events []protocol.NotifyEvent) error {
+	for _, e := range events {
+		if !e.Valid() {
+			return fmt.Errorf("unknown event %q (expected errored, restarted, crash-loop or health-failed)", e)
+		}
+	}
+	return nil
+}
+
+// WebhooksFor returns the webhooks to notify of event for a process, after
+// its override (by name, else by instance group) if it has one.
+func (r *Resolved) WebhooksFor(event protocol.NotifyEvent, name, app string) []WebhookConfig {
+	ov, ok := r.NotifyOverrides[name]
+	if !ok && app != "" {
+		ov = r.NotifyOverrides[app]
+	}
+	var hooks []WebhookConfig
+	for _, wh := range r.Webhooks {
+		if ov.Webhooks != nil && !containsString(ov.Webhooks, wh.Name) {
+			continue
+		}
+		events := wh.Events
+		if ov.Events != nil {
+			events = ov.Events
+		}
+		for _, e := range events {
+			if e == event {
+				hooks = append(hooks, wh)
+				break
+			}
+		}
+	}
+	return hooks
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 // resolveBindAddrs resolves an empty device list to localhost (127.0.0.1).
 func resolveBindAddrs(devices []string, port int) []BindAddr {
 	if len(devices) == 0 {
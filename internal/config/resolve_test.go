@@ -0,0 +1,95 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func resolveWithNotifications(t *testing.T, notifications string) (*Resolved, error) {
+	t.Helper()
+	r, _, err := Resolve(&Config{MCPServer: []byte("null"), Notifications: []byte(notifications)}, t.TempDir())
+	return r, err
+}
+
+func webhookNames(hooks []WebhookConfig) string {
+	names := make([]string, len(hooks))
+	for i, wh := range hooks {
+		names[i] = wh.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestResolveNotifications(t *testing.T) {
+	r, err := resolveWithNotifications(t, `{
+		"webhooks": [
+			{"name": "pager", "url": "https://pager.example.com/hook"},
+			{"url": "http://chat.example.com/hook", "events": ["restarted", "errored"]}
+		],
+		"processes": {
+			"batch": {"events": []},
+			"api": {"webhooks": ["pager"]},
+			"worker": {"events": ["restarted"]}
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.NotifyStderrLines != DefaultNotifyStderrLines || r.CrashLoopRestarts != DefaultCrashLoopRestarts {
+		t.Errorf("stderr_lines, crash_loop_restarts = %d, %d, want the defaults", r.NotifyStderrLines, r.CrashLoopRestarts)
+	}
+	if len(r.Webhooks) != 2 || r.Webhooks[1].Name != "webhook-2" {
+		t.Fatalf("webhooks = %+v, want the second named webhook-2", r.Webhooks)
+	}
+	if len(r.Webhooks[0].Events) != len(DefaultNotifyEvents) {
+		t.Errorf("pager events = %v, want the defaults", r.Webhooks[0].Events)
+	}
+
+	tests := []struct {
+		event     protocol.NotifyEvent
+		name, app string
+		want      string
+	}{
+		{protocol.NotifyErrored, "web", "", "pager,webhook-2"},
+		{protocol.NotifyCrashLoop, "web", "", "pager"},
+		{protocol.NotifyRestarted, "web", "", "webhook-2"},
+		{protocol.NotifyErrored, "batch", "", ""},
+		{protocol.NotifyErrored, "api", "", "pager"},
+		{protocol.NotifyErrored, "api-1", "api", "pager"},
+		{protocol.NotifyRestarted, "worker", "", "pager,webhook-2"},
+		{protocol.NotifyErrored, "worker", "", ""},
+	}
+	for _, tt := range tests {
+		if got := webhookNames(r.WebhooksFor(tt.event, tt.name, tt.app)); got != tt.want {
+			t.Errorf("WebhooksFor(%s, %s, %q) = %q, want %q", tt.event, tt.name, tt.app, got, tt.want)
+		}
+	}
+}
+
+func TestResolveNotificationsErrors(t *testing.T) {
+	tests := []struct {
+		config string
+		want   string
+	}{
+		{`{"webhooks": [{"url": "ftp://example.com"}]}`, "webhooks[0].url"},
+		{`{"webhooks": [{"url": "/hook"}]}`, "webhooks[0].url"},
+		{`{"webhooks": [{"name": "a", "url": "http://x"}, {"name": "a", "url": "http://y"}]}`, "duplicate name"},
+		{`{"events": ["crashed"]}`, "unknown event"},
+		{`{"webhooks": [{"url": "http://x", "events": ["oom"]}]}`, "webhooks[0].events"},
+		{`{"processes": {"api": {"webhooks": ["nope"]}}}`, "no webhook named"},
+		{`{"crash_loop_restarts": 0}`, "crash_loop_restarts"},
+		{`{"stderr_lines": -1}`, "stderr_lines"},
+	}
+	for _, tt := range tests {
+		_, err := resolveWithNotifications(t, tt.config)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want it to mention %q", tt.config, err, tt.want)
+		}
+	}
+
+	r, err := resolveWithNotifications(t, `null`)
+	if err != nil || len(r.Webhooks) != 0 {
+		t.Errorf("null notifications = %v, %v; want disabled", r.Webhooks, err)
+	}
+}
@@ -0,0 +1,261 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// handleApply makes the process table match the apps of an ecosystem file:
+// new apps are started, apps whose settings changed are restarted with the
+// new ones, and with prune, processes not in the file are deleted. Apps
+// that didn't change are left alone, running or not.
+func (d *Daemon) handleApply(params json.RawMessage) protocol.Response {
+	var ap protocol.ApplyParams
+	if err := json.Unmarshal(params, &ap); err != nil {
+		return errorResponse("invalid apply params: " + err.Error())
+	}
+	if len(ap.Apps) == 0 {
+		return errorResponse("no apps to apply")
+	}
+
+	// Everything is checked before anything changes.
+	names := make(map[string]bool, len(ap.Apps))
+	for i := range ap.Apps {
+		sp := &ap.Apps[i]
+		if err := validateStart(sp); err != nil {
+			return errorResponse(fmt.Sprintf("app %q: %v", sp.Name, err))
+		}
+		if names[sp.Name] {
+			return errorResponse(fmt.Sprintf("duplicate app name %q", sp.Name))
+		}
+		names[sp.Name] = true
+	}
+	for _, sp := range ap.Apps {
+		for _, dep := range sp.DependsOn {
+			if names[dep.Name] {
+				continue
+			}
+			if ap.Prune {
+				return errorResponse(fmt.Sprintf("app %q: depends_on %q, which is not in the file and would be pruned", sp.Name, dep.Name))
+			}
+			if len(d.dependencyProcs(dep.Name)) == 0 {
+				return errorResponse(fmt.Sprintf("app %q: depends_on: process %q not found", sp.Name, dep.Name))
+			}
+		}
+	}
+
+	var changes []protocol.ApplyChange
+	if ap.Prune {
+		var pruned []*Process
+		seen := make(map[string]bool)
+		for _, p := range d.resolveTarget("all") {
+			app := socketOwner(p.Info()) // the group name for an instance
+			if names[app] {
+				continue
+			}
+			pruned = append(pruned, p)
+			if !seen[app] {
+				seen[app] = true
+				changes = append(changes, protocol.ApplyChange{Name: app, Action: protocol.ApplyDelete})
+			}
+		}
+		if !ap.DryRun {
+			stopInOrder(pruned, d.removeProcess)
+		}
+	}
+
+	changed := ap.Prune && len(changes) > 0
+	for _, sp := range ap.Apps {
+		c := d.planApply(sp)
+		if !ap.DryRun && c.Action != protocol.ApplyUnchanged {
+			if err := d.applyChange(sp, c); err != nil {
+				c.Error = err.Error()
+				slog.Error("apply failed", "name", sp.Name, "action", c.Action, "error", err)
+			} else {
+				slog.Info("app applied", "name", sp.Name, "action", c.Action)
+			}
+			changed = true
+		}
+		changes = append(changes, c)
+	}
+
+	if changed && !ap.DryRun {
+		if err := d.SaveState(); err != nil {
+			slog.Error("auto-save failed after apply", "error", err)
+		}
+	}
+	return successResponse(changes)
+}
+
+// planApply works out what apply does to the app sp describes, comparing
+// the settings it would start with to those of the processes running it.
+func (d *Daemon) planApply(sp protocol.StartParams) protocol.ApplyChange {
+	c := protocol.ApplyChange{Name: sp.Name, Action: protocol.ApplyCreate}
+	group := d.appInstances(sp.Name)
+	d.mu.RLock()
+	single := d.processes[sp.Name]
+	d.mu.RUnlock()
+
+	var live protocol.StartParams
+	switch {
+	case len(group) > 0:
+		live = groupStartParams(group[0].Info())
+	case single != nil:
+		live = infoToStartParams(single.Info())
+	default:
+		return c
+	}
+
+	c.Diff = diffStartParams(live, infoToStartParams(NewProcess(0, sp).info))
+	switch {
+	case len(c.Diff) == 0:
+		c.Action = protocol.ApplyUnchanged
+	case len(c.Diff) == 1 && c.Diff[0].Field == "instances" && len(group) > 0 && sp.Instances != 0:
+		c.Action = protocol.ApplyScale
+	default:
+		c.Action = protocol.ApplyUpdate
+	}
+	return c
+}
+
+// applyChange carries out what planApply decided for the app sp describes.
+func (d *Daemon) applyChange(sp protocol.StartParams, c protocol.ApplyChange) error {
+	group := d.appInstances(sp.Name)
+	switch c.Action {
+	case protocol.ApplyScale:
+		_, err := d.scaleGroup(group, sp.Instances)
+		return err
+
+	case protocol.ApplyUpdate:
+		fields := make([]string, len(c.Diff))
+		for i, f := range c.Diff {
+			fields[i] = f.Field
+		}
+		reason := "settings changed: " + strings.Join(fields, ", ")
+
+		d.mu.RLock()
+		single := d.processes[sp.Name]
+		d.mu.RUnlock()
+		switch {
+		case single != nil && sp.Instances == 0:
+			return d.replaceProcess(single, sp, reason)
+		case len(group) > 0 && sp.Instances != 0:
+			// Instances are replaced one at a time, then the group is
+			// scaled, new instances taking the new settings.
+			for _, p := range group {
+				if err := d.replaceProcess(p, instanceStartParams(sp, p.info.InstanceID), reason); err != nil {
+					return fmt.Errorf("instance %d: %w", p.info.InstanceID, err)
+				}
+			}
+			_, err := d.scaleGroup(d.appInstances(sp.Name), sp.Instances)
+			return err
+		}
+		// A process becomes an instance group or the other way round:
+		// there is nothing to keep, so it is deleted and started anew.
+		if single != nil {
+			group = []*Process{single}
+		}
+		for _, p := range group {
+			d.removeProcess(p)
+		}
+	}
+
+	if sp.Instances != 0 {
+		ids := make([]int, sp.Instances.Count())
+		for i := range ids {
+			ids[i] = i
+		}
+		_, err := d.startInstances(sp, ids)
+		return err
+	}
+	_, err := d.startProcess(sp)
+	return err
+}
+
+// replaceProcess stops p and starts a process from sp in its place, with
+// the same ID, creation time, history and restart counts, for apply to change its settings.
+// If the new process fails to start it is kept, errored.
+func (d *Daemon) replaceProcess(p *Process, sp protocol.StartParams, reason string) error {
+	info := p.Info()
+	p.LogAction("%s, restarting", reason)
+	p.Stop()
+	p.CloseLogWriters()
+
+	np := NewProcess(info.ID, sp)
+	np.cgroups = d.cgroups
+	np.events = d.events
+	np.info.CreatedAt = info.CreatedAt
+	np.info.LastRestartReason = "config changed"
+	np.carryOver(p)
+
+	d.mu.Lock()
+	if d.processes[info.Name] != p {
+		d.mu.Unlock()
+		return fmt.Errorf("process was removed")
+	}
+	d.processes[info.Name] = np
+	d.mu.Unlock()
+	// The old sockets are kept if np has the same ones, else closed.
+	d.releaseSockets(socketOwner(info))
+
+	waiting := len(np.info.DependsOn) > 0
+	err := d.attachSockets(np)
+	if err == nil {
+		if waiting {
+			err = np.beginWaiting()
+		} else {
+			err = d.launch(np)
+		}
+	}
+	if err != nil {
+		np.mu.Lock()
+		np.info.Status = protocol.StatusErrored
+		np.info.StatusReason = err.Error()
+		np.mu.Unlock()
+		return err
+	}
+	np.publishConfig("updated")
+	if waiting {
+		np.LogAction("%s", np.info.StatusReason)
+		go d.startWhenReady(np)
+	}
+	return nil
+}
+
+// diffStartParams returns the settings that differ from live to want, by
+// their JSON keys, which are those of the ecosystem file.
+func diffStartParams(live, want protocol.StartParams) []protocol.FieldChange {
+	a, b := startParamFields(live), startParamFields(want)
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var diff []protocol.FieldChange
+	for _, k := range keys {
+		if !bytes.Equal(a[k], b[k]) {
+			diff = append(diff, protocol.FieldChange{Field: k, Old: a[k], New: b[k]})
+		}
+	}
+	return diff
+}
+
+// startParamFields returns the set fields of sp by JSON key.
+func startParamFields(sp protocol.StartParams) map[string]json.RawMessage {
+	data, _ := json.Marshal(sp)
+	var fields map[string]json.RawMessage
+	json.Unmarshal(data, &fields)
+	return fields
+}
@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestPlanApply(t *testing.T) {
+	single := protocol.StartParams{Command: "/bin/api", Name: "api", Cwd: "/srv", Env: map[string]string{"A": "1"}}
+	group := protocol.StartParams{Command: "/bin/web", Name: "web", Cwd: "/srv", Instances: 2}
+
+	d := &Daemon{processes: map[string]*Process{"api": NewProcess(0, single)}}
+	for _, id := range []int{0, 1} {
+		p := NewProcess(id+1, instanceStartParams(group, id))
+		d.processes[p.info.Name] = p
+	}
+
+	changed := single
+	changed.Env = map[string]string{"A": "2"}
+	changed.MinUptime = "5s" // the default, so not a change
+	scaled := group
+	scaled.Instances = 3
+	regrouped := group
+	regrouped.Instances = 3
+	regrouped.Args = []string{"-v"}
+
+	tests := []struct {
+		name   string
+		sp     protocol.StartParams
+		action protocol.ApplyAction
+		fields []string
+	}{
+		{"same", single, protocol.ApplyUnchanged, nil},
+		{"same group", group, protocol.ApplyUnchanged, nil},
+		{"new", protocol.StartParams{Command: "/bin/new", Name: "new"}, protocol.ApplyCreate, nil},
+		{"changed", changed, protocol.ApplyUpdate, []string{"env"}},
+		{"scaled", scaled, protocol.ApplyScale, []string{"instances"}},
+		{"scaled and changed", regrouped, protocol.ApplyUpdate, []string{"args", "instances"}},
+	}
+	for _, tt := range tests {
+		c := d.planApply(tt.sp)
+		if c.Action != tt.action {
+			t.Errorf("%s: action = %s, want %s", tt.name, c.Action, tt.action)
+		}
+		var fields []string
+		for _, f := range c.Diff {
+			fields = append(fields, f.Field)
+		}
+		if len(fields) != len(tt.fields) {
+			t.Errorf("%s: diff = %v, want %v", tt.name, fields, tt.fields)
+			continue
+		}
+		for i := range fields {
+			if fields[i] != tt.fields[i] {
+				t.Errorf("%s: diff = %v, want %v", tt.name, fields, tt.fields)
+			}
+		}
+	}
+}
+
+func TestDiffStartParams(t *testing.T) {
+	old := protocol.StartParams{Command: "/bin/api", Env: map[string]string{"A": "1"}, KillSignal: "SIGINT"}
+	want := protocol.StartParams{Command: "/bin/api", Env: map[string]string{"A": "1"}, StopCommand: "drain"}
+	diff := diffStartParams(old, want)
+	if len(diff) != 2 {
+		t.Fatalf("diff = %+v, want kill_signal and stop_command", diff)
+	}
+	if diff[0].Field != "kill_signal" || string(diff[0].Old) != `"SIGINT"` || diff[0].New != nil {
+		t.Errorf("diff[0] = %+v", diff[0])
+	}
+	if diff[1].Field != "stop_command" || diff[1].Old != nil || string(diff[1].New) != `"drain"` {
+		t.Errorf("diff[1] = %+v", diff[1])
+	}
+}
@@ -0,0 +1,284 @@
+//go:build linux
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// cgroupMount is where the cgroup v2 unified hierarchy is expected.
+const cgroupMount = "/sys/fs/cgroup"
+
+// cgroupControllers are the controllers gopm enables for managed processes.
+var cgroupControllers = []string{"cpu", "memory", "pids", "io"}
+
+// cgroupManager places each managed process in its own child cgroup under
+// the daemon's cgroup v2 subtree:
+//
+//	<daemon cgroup>/daemon          the gopm daemon itself
+//	<daemon cgroup>/app-<name>      one per managed process
+//	<daemon cgroup>/reload-<name>   the other one, for a reload replacement
+type cgroupManager struct {
+	root        string
+	controllers map[string]bool // enabled in root's cgroup.subtree_control
+}
+
+// newCgroupManager prepares the daemon's cgroup for delegation. It fails if
+// the host is not on cgroup v2 or the daemon's cgroup was not delegated to
+// it (e.g. not running as a systemd service with Delegate=yes), before
+// touching anything in it.
+func newCgroupManager() (*cgroupManager, error) {
+	if _, err := os.Stat(filepath.Join(cgroupMount, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 not mounted at %s", cgroupMount)
+	}
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return nil, err
+	}
+	rel, ok := parseCgroupV2Path(string(data))
+	if !ok {
+		return nil, fmt.Errorf("daemon is not in a cgroup v2 hierarchy")
+	}
+	// A daemon started by a previous daemon (e.g. reboot) may already sit
+	// in our leaf; manage the parent rather than nesting another level.
+	if filepath.Base(rel) == "daemon" {
+		rel = filepath.Dir(rel)
+	}
+	m := &cgroupManager{root: filepath.Join(cgroupMount, rel)}
+	if !cgroupDelegated(m.root) {
+		return nil, fmt.Errorf("cgroup %s is not delegated to the daemon", m.root)
+	}
+
+	// cgroup v2 only lets a cgroup hand controllers to its children if it
+	// has no processes of its own, so move the daemon into a leaf first.
+	leaf := filepath.Join(m.root, "daemon")
+	if err := os.MkdirAll(leaf, 0755); err != nil {
+		return nil, fmt.Errorf("cgroup %s not writable: %w", m.root, err)
+	}
+	if err := writeCgroupFile(leaf, "cgroup.procs", strconv.Itoa(os.Getpid())); err != nil {
+		return nil, fmt.Errorf("cannot move daemon into %s: %w", leaf, err)
+	}
+
+	if err := m.enableControllers(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// cgroupDelegated reports whether the cgroup at path was delegated to the
+// daemon, so it may create child cgroups and move processes there without
+// taking over one systemd manages. A daemon not running as root must own
+// the cgroup's files, as systemd makes them for a delegated user. As root,
+// systemd marks the cgroup of a unit with Delegate=yes with the
+// trusted.delegate xattr (v251+); for older versions the unit is asked.
+func cgroupDelegated(path string) bool {
+	if uid := os.Getuid(); uid != 0 {
+		return cgroupOwnedBy(path, uid)
+	}
+	buf := make([]byte, 8)
+	if n, err := syscall.Getxattr(path, "trusted.delegate", buf); err == nil {
+		return string(buf[:n]) == "1"
+	}
+	unit := filepath.Base(path)
+	if !strings.HasSuffix(unit, ".service") && !strings.HasSuffix(unit, ".scope") {
+		return false
+	}
+	out, err := exec.Command("systemctl", "show", "--property=Delegate", "--value", unit).Output()
+	return err == nil && strings.TrimSpace(string(out)) == "yes"
+}
+
+// cgroupOwnedBy reports whether uid owns the files of the cgroup at path
+// that a delegate writes to.
+func cgroupOwnedBy(path string, uid int) bool {
+	for _, f := range []string{"cgroup.procs", "cgroup.subtree_control"} {
+		fi, err := os.Stat(filepath.Join(path, f))
+		if err != nil {
+			return false
+		}
+		if st, ok := fi.Sys().(*syscall.Stat_t); !ok || st.Uid != uint32(uid) {
+			return false
+		}
+	}
+	return true
+}
+
+// enableControllers turns on every available controller gopm uses in the
+// root's cgroup.subtree_control and records which ones took effect.
+func (m *cgroupManager) enableControllers() error {
+	data, err := os.ReadFile(filepath.Join(m.root, "cgroup.controllers"))
+	if err != nil {
+		return err
+	}
+	available := make(map[string]bool)
+	for _, c := range strings.Fields(string(data)) {
+		available[c] = true
+	}
+
+	m.controllers = make(map[string]bool)
+	for _, c := range cgroupControllers {
+		if !available[c] {
+			continue
+		}
+		if err := writeCgroupFile(m.root, "cgroup.subtree_control", "+"+c); err != nil {
+			continue
+		}
+		m.controllers[c] = true
+	}
+	return nil
+}
+
+// Root returns the daemon's delegated cgroup directory.
+func (m *cgroupManager) Root() string { return m.root }
+
+// Path returns the cgroup directory used for a process name.
+func (m *cgroupManager) Path(name string) string {
+	return filepath.Join(m.root, "app-"+cgroupNameRe.ReplaceAllString(name, "_"))
+}
+
+// ReloadPath returns the cgroup directory for a reload replacement of the
+// process name running in cgroup current: the other one of the name's two,
+// so each process keeps its own limits until the old one exits.
+func (m *cgroupManager) ReloadPath(name, current string) string {
+	if current != m.Path(name) {
+		return m.Path(name)
+	}
+	return filepath.Join(m.root, "reload-"+cgroupNameRe.ReplaceAllString(name, "_"))
+}
+
+var cgroupNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Prepare creates the cgroup at path for a process and writes its limits.
+// Every limit file is written, unset ones as "max"/default, so a reused
+// cgroup never keeps stale values. It returns warnings for limits that
+// could not be applied, and an error if the cgroup itself is unusable.
+func (m *cgroupManager) Prepare(path string, limits *protocol.CgroupLimits) ([]string, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	if limits == nil {
+		limits = &protocol.CgroupLimits{}
+	}
+
+	cpuMax, err := limits.CPUMaxValue()
+	if err != nil {
+		return nil, err
+	}
+	memMax, memHigh := "max", "max"
+	if limits.MemoryMax != "" {
+		n, err := protocol.ParseSize(limits.MemoryMax)
+		if err != nil {
+			return nil, err
+		}
+		memMax = strconv.FormatInt(n, 10)
+	}
+	if limits.MemoryHigh != "" {
+		n, err := protocol.ParseSize(limits.MemoryHigh)
+		if err != nil {
+			return nil, err
+		}
+		memHigh = strconv.FormatInt(n, 10)
+	}
+	pidsMax := "max"
+	if limits.PidsMax > 0 {
+		pidsMax = strconv.Itoa(limits.PidsMax)
+	}
+	ioWeight := 100
+	if limits.IOWeight > 0 {
+		ioWeight = limits.IOWeight
+	}
+
+	var warnings []string
+	for _, f := range []struct {
+		controller, file, value string
+		set                     bool
+	}{
+		{"cpu", "cpu.max", cpuMax, limits.CPUMax != ""},
+		{"memory", "memory.max", memMax, limits.MemoryMax != ""},
+		{"memory", "memory.high", memHigh, limits.MemoryHigh != ""},
+		{"pids", "pids.max", pidsMax, limits.PidsMax > 0},
+		{"io", "io.weight", fmt.Sprintf("default %d", ioWeight), limits.IOWeight > 0},
+	} {
+		if !m.controllers[f.controller] {
+			if f.set {
+				warnings = append(warnings, fmt.Sprintf("%s ignored: %s controller not available", f.file, f.controller))
+			}
+			continue
+		}
+		if err := writeCgroupFile(path, f.file, f.value); err != nil && f.set {
+			warnings = append(warnings, fmt.Sprintf("%s not applied: %v", f.file, err))
+		}
+	}
+	return warnings, nil
+}
+
+// openCgroup opens a cgroup directory for use as SysProcAttr.CgroupFD.
+func openCgroup(path string) (int, error) {
+	return syscall.Open(path, syscall.O_DIRECTORY|syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+}
+
+// setCgroupFD makes the child start directly inside the cgroup, so nothing
+// it forks can run outside it.
+func setCgroupFD(attr *syscall.SysProcAttr, fd int) {
+	attr.UseCgroupFD = true
+	attr.CgroupFD = fd
+}
+
+// cgroupStartUnsupported reports whether a start in a cgroup failed
+// because the kernel can't start a child in one (before 5.7), rather than
+// for a reason that would fail a plain start too.
+func cgroupStartUnsupported(err error) bool {
+	return errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.E2BIG) || errors.Is(err, syscall.EINVAL)
+}
+
+// killCgroup SIGKILLs every process left in a cgroup. It uses cgroup.kill
+// (Linux 5.14+) and falls back to signalling each PID in cgroup.procs.
+func killCgroup(path string) {
+	if path == "" {
+		return
+	}
+	if writeCgroupFile(path, "cgroup.kill", "1") == nil {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(path, "cgroup.procs"))
+	if err != nil {
+		return
+	}
+	for _, f := range strings.Fields(string(data)) {
+		if pid, err := strconv.Atoi(f); err == nil && pid > 0 {
+			syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+}
+
+// removeCgroup deletes a process cgroup. It fails harmlessly if processes
+// are still exiting; Prepare reuses the directory on the next start.
+func removeCgroup(path string) {
+	if path != "" {
+		os.Remove(path)
+	}
+}
+
+// parseCgroupV2Path extracts the unified hierarchy path ("0::<path>") from
+// the contents of /proc/<pid>/cgroup.
+func parseCgroupV2Path(data string) (string, bool) {
+	for _, line := range strings.Split(data, "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+func writeCgroupFile(dir, file, value string) error {
+	return os.WriteFile(filepath.Join(dir, file), []byte(value), 0644)
+}
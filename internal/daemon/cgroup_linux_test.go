@@ -0,0 +1,157 @@
+//go:build linux
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestParseCgroupV2Path(t *testing.T) {
+	v2 := "0::/system.slice/gopm.service\n"
+	if got, ok := parseCgroupV2Path(v2); !ok || got != "/system.slice/gopm.service" {
+		t.Errorf("parseCgroupV2Path(v2) = %q, %v", got, ok)
+	}
+
+	hybrid := "12:pids:/\n4:memory:/user.slice\n0::/user.slice/session-1.scope\n"
+	if got, ok := parseCgroupV2Path(hybrid); !ok || got != "/user.slice/session-1.scope" {
+		t.Errorf("parseCgroupV2Path(hybrid) = %q, %v", got, ok)
+	}
+
+	v1 := "12:pids:/\n4:memory:/user.slice\n"
+	if _, ok := parseCgroupV2Path(v1); ok {
+		t.Error("parseCgroupV2Path(v1) = ok, want not found")
+	}
+}
+
+func readCgroupFile(t *testing.T, dir, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		t.Fatalf("read %s: %v", file, err)
+	}
+	return string(data)
+}
+
+func TestCgroupOwnedBy(t *testing.T) {
+	dir := t.TempDir()
+	if cgroupOwnedBy(dir, os.Getuid()) {
+		t.Error("owned without cgroup files")
+	}
+	for _, f := range []string{"cgroup.procs", "cgroup.subtree_control"} {
+		os.WriteFile(filepath.Join(dir, f), nil, 0644)
+	}
+	if !cgroupOwnedBy(dir, os.Getuid()) {
+		t.Error("not owned by the uid that created it")
+	}
+	if cgroupOwnedBy(dir, os.Getuid()+1) {
+		t.Error("owned by another uid")
+	}
+}
+
+func TestCgroupPrepare(t *testing.T) {
+	m := &cgroupManager{
+		root:        t.TempDir(),
+		controllers: map[string]bool{"cpu": true, "memory": true, "pids": true},
+	}
+
+	path := m.Path("my api:0")
+	if want := filepath.Join(m.root, "app-my_api_0"); path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	warnings, err := m.Prepare(path, &protocol.CgroupLimits{
+		CPUMax:    "50%",
+		MemoryMax: "512M",
+		PidsMax:   64,
+		IOWeight:  500,
+	})
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+
+	for file, want := range map[string]string{
+		"cpu.max":     "50000 100000",
+		"memory.max":  "536870912",
+		"memory.high": "max",
+		"pids.max":    "64",
+	} {
+		if got := readCgroupFile(t, path, file); got != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+
+	// io controller is not enabled: the explicit io_weight must be reported.
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "io.weight") {
+		t.Errorf("warnings = %v, want one io.weight warning", warnings)
+	}
+	if _, err := os.Stat(filepath.Join(path, "io.weight")); !os.IsNotExist(err) {
+		t.Error("io.weight written without the io controller")
+	}
+}
+
+func TestCgroupPrepareResetsLimits(t *testing.T) {
+	m := &cgroupManager{
+		root:        t.TempDir(),
+		controllers: map[string]bool{"cpu": true, "memory": true, "pids": true, "io": true},
+	}
+
+	path := m.Path("worker")
+	if _, err := m.Prepare(path, &protocol.CgroupLimits{CPUMax: "25%", PidsMax: 10}); err != nil {
+		t.Fatal(err)
+	}
+	warnings, err := m.Prepare(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %v, want none", warnings)
+	}
+	for file, want := range map[string]string{
+		"cpu.max":   "max",
+		"pids.max":  "max",
+		"io.weight": "default 100",
+	} {
+		if got := readCgroupFile(t, path, file); got != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestCgroupReloadPath(t *testing.T) {
+	m := &cgroupManager{root: "/sys/fs/cgroup/gopm"}
+	app, reload := m.Path("api"), filepath.Join(m.root, "reload-api")
+	for _, tt := range []struct{ current, want string }{
+		{app, reload},
+		{reload, app},
+		{"", app}, // started without a cgroup
+	} {
+		if got := m.ReloadPath("api", tt.current); got != tt.want {
+			t.Errorf("ReloadPath(api, %q) = %q, want %q", tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestCgroupStartUnsupported(t *testing.T) {
+	for _, errno := range []syscall.Errno{syscall.ENOSYS, syscall.E2BIG, syscall.EINVAL} {
+		if err := (&os.PathError{Op: "fork/exec", Path: "/bin/api", Err: errno}); !cgroupStartUnsupported(err) {
+			t.Errorf("%v: not taken for an old kernel", err)
+		}
+	}
+
+	// A command that can't start at all fails as it would without a cgroup.
+	cmd := exec.Command("/nonexistent/api")
+	err := cmd.Start()
+	if err == nil || cgroupStartUnsupported(err) {
+		t.Errorf("missing command: err = %v, taken for an old kernel", err)
+	}
+	if err := fmt.Errorf("start: %w", &os.PathError{Op: "fork/exec", Err: syscall.EACCES}); cgroupStartUnsupported(err) {
+		t.Errorf("%v: taken for an old kernel", err)
+	}
+}
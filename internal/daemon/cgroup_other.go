@@ -0,0 +1,39 @@
+//go:build !linux
+
+package daemon
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// cgroupManager is a stub on platforms without cgroups.
+type cgroupManager struct{}
+
+func newCgroupManager() (*cgroupManager, error) {
+	return nil, fmt.Errorf("cgroups are only supported on Linux")
+}
+
+func (m *cgroupManager) Root() string { return "" }
+
+func (m *cgroupManager) Path(name string) string { return "" }
+
+func (m *cgroupManager) ReloadPath(name, current string) string { return "" }
+
+func (m *cgroupManager) Prepare(path string, limits *protocol.CgroupLimits) ([]string, error) {
+	return nil, fmt.Errorf("cgroups are only supported on Linux")
+}
+
+func openCgroup(path string) (int, error) {
+	return -1, fmt.Errorf("cgroups are only supported on Linux")
+}
+
+func setCgroupFD(attr *syscall.SysProcAttr, fd int) {}
+
+func cgroupStartUnsupported(err error) bool { return false }
+
+func killCgroup(path string) {}
+
+func removeCgroup(path string) {}
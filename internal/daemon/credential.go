@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// runAs is the resolved user and groups a process runs as.
+type runAs struct {
+	cred *syscall.Credential
+	info protocol.Credential
+	home string // empty if the user has no home directory
+}
+
+// env returns HOME, USER and LOGNAME for the user.
+func (r *runAs) env() []string {
+	env := []string{"USER=" + r.info.User, "LOGNAME=" + r.info.User}
+	if r.home != "" {
+		env = append(env, "HOME="+r.home)
+	}
+	return env
+}
+
+// resolveCredential looks up the user, group and supplementary groups a
+// process is configured to run as. It returns nil if none are set. An
+// unset group is the user's primary group, and unset groups are the
+// user's own. Running as another user needs a daemon running as root.
+func resolveCredential(userName, group string, groups []string) (*runAs, error) {
+	if userName == "" && group == "" && len(groups) == 0 {
+		return nil, nil
+	}
+	if uid := os.Geteuid(); uid != 0 {
+		return nil, fmt.Errorf("user and group need the daemon to run as root (it runs as uid %d)", uid)
+	}
+
+	var u *user.User
+	var err error
+	if userName != "" {
+		u, err = lookupUser(userName)
+	} else {
+		u, err = user.Current()
+	}
+	if err != nil {
+		return nil, err
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return nil, fmt.Errorf("user %q has non-numeric uid %q", u.Username, u.Uid)
+	}
+
+	ra := &runAs{info: protocol.Credential{User: u.Username, UID: uid}}
+	if userName != "" {
+		ra.home = u.HomeDir
+	}
+
+	if group == "" {
+		group = u.Gid
+	}
+	ra.info.Group, ra.info.GID, err = lookupGroup(group)
+	if err != nil {
+		return nil, err
+	}
+
+	if groups == nil && userName != "" {
+		// Like a login: the user's own supplementary groups.
+		groups, _ = u.GroupIds()
+	}
+	for _, g := range groups {
+		_, gid, err := lookupGroup(g)
+		if err != nil {
+			return nil, err
+		}
+		if gid != ra.info.GID {
+			ra.info.Groups = append(ra.info.Groups, gid)
+		}
+	}
+
+	ra.cred = &syscall.Credential{Uid: uint32(uid), Gid: uint32(ra.info.GID)}
+	for _, gid := range ra.info.Groups {
+		ra.cred.Groups = append(ra.cred.Groups, uint32(gid))
+	}
+	return ra, nil
+}
+
+// currentCredential returns the user and groups of the daemon itself.
+func currentCredential() *protocol.Credential {
+	c := &protocol.Credential{UID: os.Getuid(), GID: os.Getgid()}
+	c.User = strconv.Itoa(c.UID)
+	if u, err := user.LookupId(c.User); err == nil {
+		c.User = u.Username
+	}
+	c.Group = strconv.Itoa(c.GID)
+	if g, err := user.LookupGroupId(c.Group); err == nil {
+		c.Group = g.Name
+	}
+	return c
+}
+
+// lookupUser finds a user by name or uid.
+func lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u, nil
+	}
+	if _, nerr := strconv.Atoi(name); nerr == nil {
+		if u, err := user.LookupId(name); err == nil {
+			return u, nil
+		}
+		// A uid without a passwd entry, as containers often use.
+		return &user.User{Uid: name, Gid: name, Username: name}, nil
+	}
+	return nil, fmt.Errorf("unknown user %q", name)
+}
+
+// lookupGroup finds a group by name or gid, returning its name and gid.
+func lookupGroup(name string) (string, int, error) {
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		if gid, nerr := strconv.Atoi(name); nerr == nil {
+			if g, err := user.LookupGroupId(name); err == nil {
+				return g.Name, gid, nil
+			}
+			return name, gid, nil
+		}
+		return "", 0, fmt.Errorf("unknown group %q", name)
+	}
+	gid, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return "", 0, fmt.Errorf("group %q has non-numeric gid %q", name, g.Gid)
+	}
+	return g.Name, gid, nil
+}
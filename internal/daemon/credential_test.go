@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestResolveCredentialUnset(t *testing.T) {
+	ra, err := resolveCredential("", "", nil)
+	if err != nil || ra != nil {
+		t.Fatalf("resolveCredential() = %v, %v; want nil, nil", ra, err)
+	}
+}
+
+func TestResolveCredentialNotRoot(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("running as root")
+	}
+	_, err := resolveCredential("nobody", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "run as root") {
+		t.Fatalf("err = %v, want run as root error", err)
+	}
+}
+
+func TestResolveCredential(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("needs root")
+	}
+
+	ra, err := resolveCredential("65534", "0", []string{"0", "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ra.info.UID != 65534 || ra.info.GID != 0 || ra.info.Group != "root" {
+		t.Errorf("info = %+v, want uid 65534, group root (0)", ra.info)
+	}
+	// The primary group is not repeated in the supplementary groups.
+	if !reflect.DeepEqual(ra.info.Groups, []int{1}) {
+		t.Errorf("groups = %v, want [1]", ra.info.Groups)
+	}
+	if ra.cred.Uid != 65534 || ra.cred.Gid != 0 || !reflect.DeepEqual(ra.cred.Groups, []uint32{1}) {
+		t.Errorf("cred = %+v", ra.cred)
+	}
+
+	// A group alone keeps the daemon's user.
+	ra, err = resolveCredential("", "1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ra.info.UID != 0 || ra.info.GID != 1 || len(ra.info.Groups) != 0 {
+		t.Errorf("info = %+v, want uid 0, gid 1, no groups", ra.info)
+	}
+	for _, kv := range ra.env() {
+		if strings.HasPrefix(kv, "HOME=") {
+			t.Errorf("env() = %v, want no HOME without a user", ra.env())
+		}
+	}
+
+	for _, bad := range [][]string{{"no-such-user-xyz", ""}, {"", "no-such-group-xyz"}} {
+		if _, err := resolveCredential(bad[0], bad[1], nil); err == nil || !strings.Contains(err.Error(), "unknown") {
+			t.Errorf("resolveCredential(%q, %q) err = %v, want unknown error", bad[0], bad[1], err)
+		}
+	}
+}
+
+func TestCredentialString(t *testing.T) {
+	c := &protocol.Credential{User: "www", UID: 33, Group: "www", GID: 33}
+	if got, want := c.String(), "www (33), group www (33)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	c.Groups = []int{4, 27}
+	if got, want := c.String(), "www (33), group www (33), groups 4,27"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
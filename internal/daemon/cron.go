@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"log/slog"
+	"time"
+)
+
+// cronInterval is how often cron_restart and job schedules are checked.
+const cronInterval = time.Second
+
+// cronLoop restarts processes whose cron_restart time has come and starts
+// scheduled jobs that are due.
+func (d *Daemon) cronLoop() {
+	ticker := time.NewTicker(cronInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			d.checkCronRestarts(now)
+			d.checkScheduledRuns(now)
+		case <-d.stopCh:
+			return
+		}
+	}
+}
+
+func (d *Daemon) checkCronRestarts(now time.Time) {
+	d.mu.RLock()
+	var due []*Process
+	for _, p := range d.processes {
+		if p.cronDue(now) {
+			due = append(due, p)
+		}
+	}
+	d.mu.RUnlock()
+
+	for _, p := range due {
+		go d.cronRestart(p)
+	}
+}
+
+// cronRestart restarts a process on its cron_restart schedule through the
+// same path as "gopm restart".
+func (d *Daemon) cronRestart(p *Process) {
+	p.mu.Lock()
+	name := p.info.Name
+	expr := p.info.CronRestart
+	p.mu.Unlock()
+
+	p.LogAction("cron restart (%s)", expr)
+	slog.Info("cron restart", "name", name, "schedule", expr)
+	if err := d.restartProcess(p, "cron restart ("+expr+")"); err != nil {
+		slog.Error("cron restart failed", "name", name, "error", err)
+		return
+	}
+	if err := d.SaveState(); err != nil {
+		slog.Error("auto-save failed after cron restart", "error", err)
+	}
+}
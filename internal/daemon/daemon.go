@@ -3,12 +3,14 @@ package daemon
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,9 +18,11 @@ import (
 	"time"
 
 	"github.com/7c/gopm/internal/config"
+	"github.com/7c/gopm/internal/cron"
 	"github.com/7c/gopm/internal/mcphttp"
 	"github.com/7c/gopm/internal/protocol"
 	"github.com/7c/gopm/internal/telemetry"
+	"github.com/7c/gopm/internal/webhook"
 )
 
 // Version is set at build time.
@@ -36,7 +40,11 @@ type Daemon struct {
 
 	mcpServer    *mcphttp.Server
 	telegraf     *telemetry.TelegrafEmitter
+	webhooks     *webhook.Notifier // nil when no webhooks are configured
+	cgroups      *cgroupManager // nil when cgroup v2 delegation is unavailable
 	snapshots map[string]*snapshotRing // per-process metrics history
+	sockets   map[string]*socketSet    // held listening sockets, by app
+	events    *eventBus                // subscribe connections
 
 	resolved     *config.Resolved
 	configPath   string
@@ -93,6 +101,8 @@ func Run(version string, configFlag string, debug bool) {
 	d := &Daemon{
 		processes:    make(map[string]*Process),
 		snapshots:    make(map[string]*snapshotRing),
+		sockets:      make(map[string]*socketSet),
+		events:       newEventBus(),
 		startTime:    time.Now(),
 		stopCh:       make(chan struct{}),
 		home:         home,
@@ -117,6 +127,20 @@ func Run(version string, configFlag string, debug bool) {
 
 	slog.Info("daemon started", "pid", os.Getpid(), "socket", sockPath, "version", Version)
 
+	// Start webhook notifications if any webhooks are configured, before
+	// any process can start and exit.
+	if len(resolved.Webhooks) > 0 {
+		d.webhooks = webhook.New()
+	}
+
+	// Give each process its own cgroup if we have a writable cgroup v2 subtree.
+	if cg, err := newCgroupManager(); err != nil {
+		slog.Warn("cgroup v2 not available, per-process resource limits disabled", "reason", err)
+	} else {
+		d.cgroups = cg
+		slog.Info("cgroup v2 resource limits enabled", "cgroup", cg.Root())
+	}
+
 	// Auto-load saved process list from dump.json
 	if resurrected, err := d.ResurrectProcesses(); err != nil {
 		slog.Error("failed to resurrect processes on startup", "error", err)
@@ -164,8 +188,15 @@ func Run(version string, configFlag string, debug bool) {
 	// Start listener scanning
 	go d.scanListeners()
 
+	// Start cron_restart scheduler
+	go d.cronLoop()
+
 	// Accept connections
 	d.acceptLoop()
+
+	// The listener is closed on shutdown; give subscribers their last
+	// events before returning.
+	d.events.close(time.Second)
 }
 
 // printBanner logs the startup banner with resolved configuration.
@@ -189,6 +220,15 @@ func (d *Daemon) printBanner(r *config.Resolved, configPath, configSource string
 		telegrafLine = fmt.Sprintf("enabled, UDP: %s, measurement: %s", r.TelegrafAddr.String(), r.TelegrafMeas)
 	}
 
+	notifyLine := "disabled"
+	if len(r.Webhooks) > 0 {
+		var names []string
+		for _, wh := range r.Webhooks {
+			names = append(names, wh.Name)
+		}
+		notifyLine = "webhooks: " + strings.Join(names, ", ")
+	}
+
 	slog.Info("GoPM starting",
 		"version", Version,
 		"pid", os.Getpid(),
@@ -199,6 +239,7 @@ func (d *Daemon) printBanner(r *config.Resolved, configPath, configSource string
 		"log_max_files", r.LogMaxFiles,
 		"mcp", mcpLine,
 		"telegraf", telegrafLine,
+		"notifications", notifyLine,
 	)
 }
 
@@ -274,6 +315,10 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 		}
 
 		slog.Debug("request received", "method", req.Method)
+		if req.Method == protocol.MethodSubscribe {
+			d.serveSubscribe(conn, scanner, req.Params)
+			return
+		}
 		resp := d.handleRequest(req)
 		data, _ := json.Marshal(resp)
 		slog.Debug("response sent", "method", req.Method, "success", resp.Success, "bytes", len(data))
@@ -294,7 +339,7 @@ func (d *Daemon) handleRequest(req protocol.Request) protocol.Response {
 	case protocol.MethodDelete:
 		return d.handleDelete(req.Params)
 	case protocol.MethodList:
-		return d.handleList()
+		return d.handleList(req.Params)
 	case protocol.MethodDescribe:
 		return d.handleDescribe(req.Params)
 	case protocol.MethodIsRunning:
@@ -311,8 +356,30 @@ func (d *Daemon) handleRequest(req protocol.Request) protocol.Response {
 		return d.handleKill()
 	case protocol.MethodReboot:
 		return d.handleReboot()
+	case protocol.MethodRuns:
+		return d.handleRuns(req.Params)
 	case protocol.MethodStats:
 		return d.handleStats(req.Params)
+	case protocol.MethodScale:
+		return d.handleScale(req.Params)
+	case protocol.MethodReload:
+		return d.handleReload(req.Params)
+	case protocol.MethodWait:
+		return d.handleWait(req.Params)
+	case protocol.MethodHistory:
+		return d.handleHistory(req.Params)
+	case protocol.MethodNotifyTest:
+		return d.handleNotifyTest(req.Params)
+	case protocol.MethodSignal:
+		return d.handleSignal(req.Params)
+	case protocol.MethodApply:
+		return d.handleApply(req.Params)
+	case protocol.MethodUpdate:
+		return d.handleUpdate(req.Params)
+	case protocol.MethodRename:
+		return d.handleRename(req.Params)
+	case protocol.MethodSubscribe:
+		return errorResponse("subscribe needs a streaming connection")
 	default:
 		return errorResponse(fmt.Sprintf("unknown method: %s", req.Method))
 	}
@@ -335,8 +402,17 @@ func (d *Daemon) handleStart(params json.RawMessage) protocol.Response {
 	if err := json.Unmarshal(params, &sp); err != nil {
 		return errorResponse("invalid start params: " + err.Error())
 	}
-	if sp.Command == "" {
-		return errorResponse("command is required")
+	if err := validateStart(&sp); err != nil {
+		return errorResponse(err.Error())
+	}
+	for _, dep := range sp.DependsOn {
+		if len(d.dependencyProcs(dep.Name)) == 0 {
+			return errorResponse(fmt.Sprintf("depends_on: process %q not found", dep.Name))
+		}
+	}
+
+	if sp.Instances != 0 {
+		return d.startGroup(sp)
 	}
 
 	proc, err := d.startProcess(sp)
@@ -349,7 +425,125 @@ func (d *Daemon) handleStart(params json.RawMessage) protocol.Response {
 	return successResponse(proc.Info())
 }
 
+// validateStart checks start params the way the daemon starts them,
+// defaulting the name to the command's base name. It doesn't check that
+// dependencies exist.
+func validateStart(sp *protocol.StartParams) error {
+	if sp.Command == "" {
+		return errors.New("command is required")
+	}
+	if sp.HealthCheck != nil {
+		if err := sp.HealthCheck.Validate(); err != nil {
+			return fmt.Errorf("invalid health_check: %w", err)
+		}
+	}
+	if sp.Cgroup != nil {
+		if err := sp.Cgroup.Validate(); err != nil {
+			return fmt.Errorf("invalid cgroup: %w", err)
+		}
+	}
+	if sp.Limits != nil {
+		if err := sp.Limits.Validate(); err != nil {
+			return fmt.Errorf("invalid limits: %w", err)
+		}
+	}
+	if err := protocol.ValidateLabels(sp.Labels); err != nil {
+		return err
+	}
+	if sp.Hooks != nil {
+		if err := sp.Hooks.Validate(); err != nil {
+			return fmt.Errorf("invalid hooks: %w", err)
+		}
+	}
+	if sp.CronRestart != "" {
+		if _, err := cron.Parse(sp.CronRestart); err != nil {
+			return fmt.Errorf("invalid cron_restart: %w", err)
+		}
+	}
+	if sp.Schedule != "" {
+		if _, err := cron.Parse(sp.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+		if sp.CronRestart != "" {
+			return errors.New("cron_restart cannot be used with schedule")
+		}
+	}
+	if sp.Overlap != "" {
+		if sp.Schedule == "" {
+			return errors.New("overlap requires schedule")
+		}
+		if !protocol.OverlapPolicy(sp.Overlap).Valid() {
+			return fmt.Errorf("invalid overlap %q: must be skip, queue, or kill", sp.Overlap)
+		}
+	}
+	if err := sp.ValidateWatch(); err != nil {
+		return err
+	}
+	if err := sp.ValidateInstances(); err != nil {
+		return err
+	}
+	if err := protocol.ValidateSockets(sp.Sockets); err != nil {
+		return fmt.Errorf("invalid sockets: %w", err)
+	}
+	if err := sp.ValidateNotify(); err != nil {
+		return err
+	}
+	if err := sp.ValidateRestartBudget(); err != nil {
+		return err
+	}
+	if err := sp.ValidateSignals(); err != nil {
+		return err
+	}
+	if _, err := resolveCredential(sp.User, sp.Group, sp.Groups); err != nil {
+		return err
+	}
+	if sp.Name == "" {
+		sp.Name = filepath.Base(sp.Command)
+	}
+	if len(sp.DependsOn) > 0 {
+		if err := protocol.ValidateDependsOn(sp.Name, sp.DependsOn); err != nil {
+			return fmt.Errorf("invalid depends_on: %v", err)
+		}
+	}
+	return nil
+}
+
+// startGroup starts all instances of a new instance group.
+func (d *Daemon) startGroup(sp protocol.StartParams) protocol.Response {
+	d.mu.RLock()
+	taken := d.nameTakenLocked(sp.Name)
+	d.mu.RUnlock()
+	if taken {
+		return errorResponse(fmt.Sprintf("process %q already exists", sp.Name))
+	}
+
+	ids := make([]int, sp.Instances.Count())
+	for i := range ids {
+		ids[i] = i
+	}
+	procs, err := d.startInstances(sp, ids)
+	if err != nil {
+		return errorResponse(err.Error())
+	}
+	if err := d.SaveState(); err != nil {
+		slog.Error("auto-save failed after start", "error", err)
+	}
+
+	infos := make([]protocol.ProcessInfo, len(procs))
+	for i, p := range procs {
+		infos[i] = p.Info()
+	}
+	return successResponse(infos)
+}
+
 func (d *Daemon) startProcess(params protocol.StartParams) (*Process, error) {
+	return d.startProcessID(params, -1)
+}
+
+// startProcessID is startProcess for a process restored with the ID it
+// had, which it keeps unless another process has it by now. A negative id
+// gives it the next one.
+func (d *Daemon) startProcessID(params protocol.StartParams, id int) (*Process, error) {
 	d.mu.Lock()
 
 	name := params.Name
@@ -358,31 +552,76 @@ func (d *Daemon) startProcess(params protocol.StartParams) (*Process, error) {
 	}
 
 	// Check for duplicate name
-	if _, exists := d.processes[name]; exists {
+	if d.nameTakenLocked(name) {
 		d.mu.Unlock()
 		return nil, fmt.Errorf("process %q already exists", name)
 	}
 
-	id := d.nextID
-	d.nextID++
+	id = d.claimIDLocked(id)
 	d.mu.Unlock()
 
 	proc := NewProcess(id, params)
+	proc.cgroups = d.cgroups
+	proc.events = d.events
+	if err := d.attachSockets(proc); err != nil {
+		return nil, err
+	}
 
-	if err := proc.Start(); err != nil {
+	// A process with depends_on is registered as waiting and launched by
+	// startWhenReady once its dependencies are up.
+	waiting := len(proc.info.DependsOn) > 0
+	var err error
+	if waiting {
+		err = proc.beginWaiting()
+	} else {
+		err = d.launch(proc)
+	}
+	if err != nil {
+		d.releaseSockets(socketOwner(proc.info))
 		return nil, err
 	}
 
 	d.mu.Lock()
 	d.processes[proc.info.Name] = proc
 	d.mu.Unlock()
+	proc.publishConfig("added")
 
-	proc.LogAction("process started (PID %d)", proc.info.PID)
+	if waiting {
+		proc.LogAction("%s", proc.info.StatusReason)
+		slog.Info("process waiting for dependencies", "name", proc.info.Name, "id", id)
+		go d.startWhenReady(proc)
+	}
+	return proc, nil
+}
 
-	go d.monitor(proc)
+// launch starts p under a new monitor or, for a scheduled job, arms it so
+// it waits for its first run.
+func (d *Daemon) launch(p *Process) error {
+	if p.info.Schedule != "" {
+		if err := p.Arm(); err != nil {
+			return err
+		}
+		p.LogAction("job scheduled (%s)", p.info.Schedule)
+		slog.Info("job scheduled", "name", p.info.Name, "schedule", p.info.Schedule, "id", p.info.ID)
+		return nil
+	}
 
-	slog.Info("process started", "name", proc.info.Name, "pid", proc.info.PID, "id", id)
-	return proc, nil
+	if err := p.Start(); err != nil {
+		var herr *hookError
+		if !errors.As(err, &herr) {
+			return err
+		}
+		// The process is kept, errored, so a restart can try again.
+		slog.Warn("pre_start hook failed, not starting", "name", p.info.Name, "error", err)
+		d.notifyWebhooks(p, protocol.NotifyErrored, err.Error())
+		return nil
+	}
+	p.LogAction("process started (PID %d)", p.info.PID)
+
+	go d.monitor(p)
+
+	slog.Info("process started", "name", p.info.Name, "pid", p.info.PID, "id", p.info.ID)
+	return nil
 }
 
 func (d *Daemon) handleStop(params json.RawMessage) protocol.Response {
@@ -393,14 +632,15 @@ func (d *Daemon) handleStop(params json.RawMessage) protocol.Response {
 
 	procs := d.resolveTarget(target)
 	if len(procs) == 0 {
-		return errorResponse(fmt.Sprintf("process %q not found", target))
+		return targetNotFound(target)
 	}
 
-	for _, p := range procs {
+	// Dependents stop before the processes they depend on.
+	stopInOrder(procs, func(p *Process) {
 		if err := p.Stop(); err != nil {
 			slog.Error("failed to stop process", "name", p.info.Name, "error", err)
 		}
-	}
+	})
 
 	if err := d.SaveState(); err != nil {
 		slog.Error("auto-save failed after stop", "error", err)
@@ -416,23 +656,15 @@ func (d *Daemon) handleRestart(params json.RawMessage) protocol.Response {
 
 	procs := d.resolveTarget(target)
 	if len(procs) == 0 {
-		return errorResponse(fmt.Sprintf("process %q not found", target))
+		return targetNotFound(target)
 	}
 
 	var results []protocol.ProcessInfo
 	for _, p := range procs {
-		p.Stop()
-
-		p.mu.Lock()
-		p.info.Restarts = 0
-		p.mu.Unlock()
-
-		p.CloseLogWriters()
-		if err := p.Start(); err != nil {
+		if err := d.restartProcess(p, ""); err != nil {
 			slog.Error("failed to restart process", "name", p.info.Name, "error", err)
 			continue
 		}
-		go d.monitor(p)
 		results = append(results, p.Info())
 	}
 
@@ -445,6 +677,45 @@ func (d *Daemon) handleRestart(params json.RawMessage) protocol.Response {
 	return successResponse(results)
 }
 
+// restartProcess stops p, resets its restart counter, and starts it again
+// under a new monitor. A non-empty reason is recorded as LastRestartReason.
+func (d *Daemon) restartProcess(p *Process, reason string) error {
+	if err := d.attachSockets(p); err != nil {
+		return err
+	}
+
+	// A scheduled job is re-armed and run once right away.
+	if p.info.Schedule != "" {
+		p.Stop()
+		if err := p.Arm(); err != nil {
+			return err
+		}
+		d.startRun(p, "manual")
+		return nil
+	}
+
+	p.Stop()
+
+	p.mu.Lock()
+	p.info.Restarts = 0
+	p.restartTimes = nil
+	if reason != "" {
+		p.info.LastRestartReason = reason
+	}
+	p.mu.Unlock()
+
+	p.CloseLogWriters()
+	if err := p.Start(); err != nil {
+		return err
+	}
+	if reason == "" {
+		reason = "restarted by user"
+	}
+	p.publishRestart(reason)
+	go d.monitor(p)
+	return nil
+}
+
 func (d *Daemon) handleDelete(params json.RawMessage) protocol.Response {
 	target, err := parseTarget(params)
 	if err != nil {
@@ -453,17 +724,11 @@ func (d *Daemon) handleDelete(params json.RawMessage) protocol.Response {
 
 	procs := d.resolveTarget(target)
 	if len(procs) == 0 {
-		return errorResponse(fmt.Sprintf("process %q not found", target))
+		return targetNotFound(target)
 	}
 
 	for _, p := range procs {
-		p.Stop()
-		p.CloseLogWriters()
-		d.mu.Lock()
-		delete(d.processes, p.info.Name)
-		delete(d.snapshots, p.info.Name)
-		d.mu.Unlock()
-		slog.Info("process deleted", "name", p.info.Name)
+		d.removeProcess(p)
 	}
 
 	if err := d.SaveState(); err != nil {
@@ -472,12 +737,29 @@ func (d *Daemon) handleDelete(params json.RawMessage) protocol.Response {
 	return successResponse(map[string]bool{"success": true})
 }
 
-func (d *Daemon) handleList() protocol.Response {
+func (d *Daemon) handleList(params json.RawMessage) protocol.Response {
+	var lp protocol.ListParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &lp); err != nil {
+			return errorResponse("invalid list params: " + err.Error())
+		}
+	}
+	var sel *protocol.Selector
+	if lp.Selector != "" {
+		var err error
+		if sel, err = protocol.ParseSelector(lp.Selector); err != nil {
+			return errorResponse(err.Error())
+		}
+	}
+
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
 	var infos []protocol.ProcessInfo
 	for _, p := range d.processes {
+		if sel != nil && !sel.Matches(p.info.Name, p.info.Labels) {
+			continue
+		}
 		infos = append(infos, p.Info())
 	}
 
@@ -503,12 +785,48 @@ func (d *Daemon) handleDescribe(params json.RawMessage) protocol.Response {
 		return errorResponse(err.Error())
 	}
 
+	// An instance group or a selector is described by all the processes
+	// it covers.
+	procs := d.appInstances(target)
+	if len(procs) == 0 && protocol.IsSelector(target) {
+		if matched := d.resolveTarget(target); len(matched) > 1 {
+			procs = matched
+		}
+	}
+	if len(procs) > 0 {
+		infos := make([]protocol.ProcessInfo, len(procs))
+		for i, p := range procs {
+			infos[i] = p.Info()
+			infos[i].EffectiveLimits = effectiveLimits(infos[i])
+		}
+		return successResponse(infos)
+	}
+
 	proc := d.findProcess(target)
 	if proc == nil {
-		return errorResponse(fmt.Sprintf("process %q not found", target))
+		return targetNotFound(target)
 	}
 
-	return successResponse(proc.Info())
+	info := proc.Info()
+	info.EffectiveLimits = effectiveLimits(info)
+	return successResponse(info)
+}
+
+func (d *Daemon) handleRuns(params json.RawMessage) protocol.Response {
+	var rp protocol.RunsParams
+	if err := json.Unmarshal(params, &rp); err != nil {
+		return errorResponse("invalid runs params: " + err.Error())
+	}
+
+	proc := d.findProcess(rp.Target)
+	if proc == nil {
+		return targetNotFound(rp.Target)
+	}
+	if proc.info.Schedule == "" {
+		return errorResponse(fmt.Sprintf("process %q is not a scheduled job", rp.Target))
+	}
+
+	return successResponse(proc.Runs(rp.Limit))
 }
 
 func (d *Daemon) handleIsRunning(params json.RawMessage) protocol.Response {
@@ -529,13 +847,14 @@ func (d *Daemon) handleIsRunning(params json.RawMessage) protocol.Response {
 
 	info := proc.Info()
 	result := protocol.IsRunningResult{
-		Name:     info.Name,
-		Running:  info.Status == protocol.StatusOnline,
-		Status:   info.Status,
-		PID:      info.PID,
-		Uptime:   protocol.FormatDuration(time.Since(info.Uptime)),
-		ExitCode: info.ExitCode,
-		Restarts: info.Restarts,
+		Name:      info.Name,
+		Running:   info.Status.Up(),
+		Status:    info.Status,
+		PID:       info.PID,
+		Uptime:    protocol.FormatDuration(time.Since(info.Uptime)),
+		ExitCode:  info.ExitCode,
+		Restarts:  info.Restarts,
+		RestartAt: info.RestartAt,
 	}
 	return successResponse(result)
 }
@@ -551,12 +870,12 @@ func (d *Daemon) handleLogs(params json.RawMessage) protocol.Response {
 		lines = 20
 	}
 
-	// Support "all" target by aggregating logs from every process.
-	if lp.Target == "all" {
-		d.mu.RLock()
+	// Support "all", instance group and selector targets by aggregating
+	// logs from every process they cover.
+	if lp.Target == "all" || len(d.appInstances(lp.Target)) > 0 || (protocol.IsSelector(lp.Target) && len(d.resolveTarget(lp.Target)) > 1) {
 		var parts []string
 		logPaths := make(map[string]string) // name → log file path
-		for _, p := range d.processes {
+		for _, p := range d.resolveTarget(lp.Target) {
 			info := p.Info()
 			logPath := info.LogOut
 			if lp.ErrOnly {
@@ -572,7 +891,6 @@ func (d *Daemon) handleLogs(params json.RawMessage) protocol.Response {
 				parts = append(parts, header+"\n"+content)
 			}
 		}
-		d.mu.RUnlock()
 		combined := strings.Join(parts, "\n\n")
 		return successResponse(map[string]interface{}{
 			"content":   combined,
@@ -583,7 +901,7 @@ func (d *Daemon) handleLogs(params json.RawMessage) protocol.Response {
 
 	proc := d.findProcess(lp.Target)
 	if proc == nil {
-		return errorResponse(fmt.Sprintf("process %q not found", lp.Target))
+		return targetNotFound(lp.Target)
 	}
 
 	info := proc.Info()
@@ -611,7 +929,7 @@ func (d *Daemon) handleFlush(params json.RawMessage) protocol.Response {
 
 	procs := d.resolveTarget(target)
 	if len(procs) == 0 {
-		return errorResponse(fmt.Sprintf("process %q not found", target))
+		return targetNotFound(target)
 	}
 
 	for _, p := range procs {
@@ -678,6 +996,7 @@ func (d *Daemon) handleReboot() protocol.Response {
 // The caller must have already saved state with online processes.
 func (d *Daemon) rebootShutdown() {
 	slog.Info("daemon rebooting (save-and-exit)")
+	d.publishDaemon("rebooting")
 
 	// Stop MCP HTTP server
 	if d.mcpServer != nil {
@@ -694,22 +1013,23 @@ func (d *Daemon) rebootShutdown() {
 	close(d.stopCh)
 	d.listener.Close()
 
-	// Stop all processes in parallel
+	// Stop all processes, dependents first, in parallel where possible
 	d.mu.RLock()
-	var wg sync.WaitGroup
+	procs := make([]*Process, 0, len(d.processes))
 	for _, p := range d.processes {
-		wg.Add(1)
-		go func(proc *Process) {
-			defer wg.Done()
-			proc.Stop()
-			proc.CloseLogWriters()
-		}(p)
+		procs = append(procs, p)
 	}
 	d.mu.RUnlock()
-	wg.Wait()
+	stopInOrder(procs, func(proc *Process) {
+		proc.Stop()
+		proc.CloseLogWriters()
+	})
+	d.closeSockets()
 
 	// Do NOT save state again — dump.json already has online statuses.
 
+	d.events.close(time.Second)
+
 	// Cleanup
 	os.Remove(protocol.SocketPath())
 	os.Remove(protocol.PIDFilePath())
@@ -720,6 +1040,7 @@ func (d *Daemon) rebootShutdown() {
 
 func (d *Daemon) shutdown() {
 	slog.Info("daemon shutting down")
+	d.publishDaemon("shutting down")
 
 	// Stop MCP HTTP server
 	if d.mcpServer != nil {
@@ -736,23 +1057,24 @@ func (d *Daemon) shutdown() {
 	close(d.stopCh)
 	d.listener.Close()
 
-	// Stop all processes in parallel
+	// Stop all processes, dependents first, in parallel where possible
 	d.mu.RLock()
-	var wg sync.WaitGroup
+	procs := make([]*Process, 0, len(d.processes))
 	for _, p := range d.processes {
-		wg.Add(1)
-		go func(proc *Process) {
-			defer wg.Done()
-			proc.Stop()
-			proc.CloseLogWriters()
-		}(p)
+		procs = append(procs, p)
 	}
 	d.mu.RUnlock()
-	wg.Wait()
+	stopInOrder(procs, func(proc *Process) {
+		proc.Stop()
+		proc.CloseLogWriters()
+	})
+	d.closeSockets()
 
 	// Save state
 	d.SaveState()
 
+	d.events.close(time.Second)
+
 	// Cleanup
 	os.Remove(protocol.SocketPath())
 	os.Remove(protocol.PIDFilePath())
@@ -761,11 +1083,16 @@ func (d *Daemon) shutdown() {
 	os.Exit(0)
 }
 
-// resolveTarget finds processes matching a target string (name, id, or "all").
+// resolveTarget finds processes matching a target string (name, instance
+// group, id, or "all").
 func (d *Daemon) resolveTarget(target string) []*Process {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
+	return d.resolveTargetLocked(target)
+}
 
+// resolveTargetLocked is resolveTarget with d.mu held (read or write).
+func (d *Daemon) resolveTargetLocked(target string) []*Process {
 	if target == "all" {
 		procs := make([]*Process, 0, len(d.processes))
 		for _, p := range d.processes {
@@ -779,6 +1106,11 @@ func (d *Daemon) resolveTarget(target string) []*Process {
 		return []*Process{p}
 	}
 
+	// Try by instance group
+	if procs := d.appInstancesLocked(target); len(procs) > 0 {
+		return procs
+	}
+
 	// Try by ID
 	id, err := strconv.Atoi(target)
 	if err == nil {
@@ -789,9 +1121,42 @@ func (d *Daemon) resolveTarget(target string) []*Process {
 		}
 	}
 
+	// Try as a selector, e.g. "api-*" or "team=payments"
+	if protocol.IsSelector(target) {
+		return d.selectLocked(target)
+	}
+
 	return nil
 }
 
+// selectLocked returns the processes a selector picks, by ID, or none if it
+// does not parse. Must be called with d.mu held (read or write).
+func (d *Daemon) selectLocked(selector string) []*Process {
+	sel, err := protocol.ParseSelector(selector)
+	if err != nil {
+		return nil
+	}
+	var procs []*Process
+	for _, p := range d.processes {
+		if sel.Matches(p.info.Name, p.info.Labels) {
+			procs = append(procs, p)
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].info.ID < procs[j].info.ID })
+	return procs
+}
+
+// targetNotFound is the error for a target that resolves to no process.
+func targetNotFound(target string) protocol.Response {
+	if protocol.IsSelector(target) {
+		if _, err := protocol.ParseSelector(target); err != nil {
+			return errorResponse(err.Error())
+		}
+		return errorResponse(fmt.Sprintf("no process matches %q", target))
+	}
+	return errorResponse(fmt.Sprintf("process %q not found", target))
+}
+
 // findProcess finds a single process by name or ID.
 func (d *Daemon) findProcess(target string) *Process {
 	procs := d.resolveTarget(target)
@@ -0,0 +1,308 @@
+package daemon
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// dependencyPollInterval is how often a waiting process rechecks its
+// depends_on.
+const dependencyPollInterval = 250 * time.Millisecond
+
+// expandDependencies replaces dependencies on an instance group with one
+// on each of its instances. groups maps app names to instance names.
+func expandDependencies(deps []protocol.Dependency, groups map[string][]string) []protocol.Dependency {
+	var out []protocol.Dependency
+	for _, dep := range deps {
+		members, ok := groups[dep.Name]
+		if !ok {
+			out = append(out, dep)
+			continue
+		}
+		for _, m := range members {
+			out = append(out, protocol.Dependency{Name: m, Condition: dep.Condition})
+		}
+	}
+	return out
+}
+
+// sortByDependencies orders saved processes so each comes after the ones it
+// depends on. On a cycle (only possible in a hand-edited dump) the saved
+// order is kept; the processes then wait on each other until stopped.
+func sortByDependencies(infos []protocol.ProcessInfo) []protocol.ProcessInfo {
+	byName := make(map[string]protocol.ProcessInfo, len(infos))
+	groups := make(map[string][]string)
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		byName[info.Name] = info
+		names = append(names, info.Name)
+		if info.App != "" {
+			groups[info.App] = append(groups[info.App], info.Name)
+		}
+	}
+	order, err := protocol.DependencyOrder(names, func(name string) []protocol.Dependency {
+		return expandDependencies(byName[name].DependsOn, groups)
+	})
+	if err != nil || len(order) != len(infos) {
+		if err != nil {
+			slog.Warn("ignoring depends_on order of saved processes", "error", err)
+		}
+		return infos
+	}
+	sorted := make([]protocol.ProcessInfo, 0, len(infos))
+	for _, name := range order {
+		sorted = append(sorted, byName[name])
+	}
+	return sorted
+}
+
+// beginWaiting puts a process with depends_on in the waiting state. The log
+// writers are opened so actions taken while waiting are logged.
+func (p *Process) beginWaiting() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.openLogWriters(); err != nil {
+		return err
+	}
+	deps := make([]string, len(p.info.DependsOn))
+	for i, dep := range p.info.DependsOn {
+		deps[i] = dep.String()
+	}
+	p.setStatus(protocol.StatusWaiting)
+	p.info.StatusReason = "waiting for " + strings.Join(deps, ", ")
+	p.info.PID = 0
+	p.waitCh = make(chan struct{})
+	return nil
+}
+
+// startWhenReady launches a waiting process once all of its dependencies
+// meet their conditions. It gives up, marking the process errored, if a
+// dependency fails in a way it won't recover from, and stops waiting if the
+// process is stopped or the daemon shuts down.
+func (d *Daemon) startWhenReady(p *Process) {
+	p.mu.Lock()
+	waitCh := p.waitCh
+	p.mu.Unlock()
+	if waitCh == nil {
+		return
+	}
+
+	ticker := time.NewTicker(dependencyPollInterval)
+	defer ticker.Stop()
+	for {
+		// Read each time, as a rename of a dependency changes it.
+		p.mu.Lock()
+		deps := p.info.DependsOn
+		p.mu.Unlock()
+		pending, err := d.checkDependencies(deps)
+		if err != nil {
+			p.mu.Lock()
+			if p.waitCh != waitCh {
+				p.mu.Unlock()
+				return
+			}
+			p.waitCh = nil
+			p.setStatus(protocol.StatusErrored)
+			p.info.StatusReason = err.Error()
+			p.mu.Unlock()
+			p.LogAction("not starting: %v", err)
+			d.notifyWebhooks(p, protocol.NotifyErrored, err.Error())
+			slog.Warn("dependency failed, not starting", "name", p.info.Name, "error", err)
+			p.CloseLogWriters()
+			d.autoSave("dependency failed")
+			return
+		}
+		if len(pending) == 0 {
+			break
+		}
+		p.mu.Lock()
+		if p.waitCh == waitCh {
+			p.info.StatusReason = "waiting for " + strings.Join(pending, ", ")
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-ticker.C:
+		case <-waitCh:
+			return
+		case <-d.stopCh:
+			return
+		}
+	}
+
+	// Claim the process; Stop may have cancelled the wait in the meantime.
+	p.mu.Lock()
+	if p.waitCh != waitCh {
+		p.mu.Unlock()
+		return
+	}
+	p.waitCh = nil
+	p.mu.Unlock()
+
+	p.LogAction("dependencies ready")
+	p.CloseLogWriters()
+	if err := d.launch(p); err != nil {
+		p.MarkExited(-1, protocol.StatusErrored)
+		p.SetReason(fmt.Sprintf("failed to start: %s", err))
+		slog.Error("failed to start process after dependencies", "name", p.info.Name, "error", err)
+	}
+	d.autoSave("dependencies ready")
+}
+
+// dependencyProcs returns the process a dependency names or, for an
+// instance group, all of its instances.
+func (d *Daemon) dependencyProcs(name string) []*Process {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if p, ok := d.processes[name]; ok {
+		return []*Process{p}
+	}
+	return d.appInstancesLocked(name)
+}
+
+// checkDependencies returns the dependencies that are not met yet, or an
+// error if one can no longer be met. A dependency on an instance group is
+// met once every instance meets it.
+func (d *Daemon) checkDependencies(deps []protocol.Dependency) ([]string, error) {
+	var pending []string
+	for _, dep := range deps {
+		procs := d.dependencyProcs(dep.Name)
+		if len(procs) == 0 {
+			return nil, fmt.Errorf("dependency %q not found", dep.Name)
+		}
+		for _, dp := range procs {
+			met, err := dp.dependencyState(dep.Condition)
+			if err != nil {
+				return nil, fmt.Errorf("dependency %q %v", dp.info.Name, err)
+			}
+			if !met {
+				pending = append(pending, dep.String())
+				break
+			}
+		}
+	}
+	return pending, nil
+}
+
+// dependencyState reports whether p meets cond for a process that depends
+// on it. An error means it won't without intervention: it errored or, for
+// completed_successfully, exited non-zero and won't be restarted. A stopped
+// process is otherwise treated as pending, since it may be between restarts.
+func (p *Process) dependencyState(cond protocol.DependencyCondition) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.info.Status == protocol.StatusErrored {
+		return false, fmt.Errorf("errored")
+	}
+
+	switch cond {
+	case protocol.DependsHealthy:
+		if p.info.Status != protocol.StatusOnline {
+			return false, nil
+		}
+		return p.info.HealthCheck == nil || p.info.Health == protocol.HealthHealthy, nil
+
+	case protocol.DependsCompleted:
+		if p.info.Schedule != "" {
+			n := len(p.runs)
+			return n > 0 && p.runs[n-1].Status == protocol.RunSuccess, nil
+		}
+		if p.info.Status != protocol.StatusStopped {
+			return false, nil
+		}
+		if p.info.ExitCode == 0 && p.info.StatusReason != "stopped by user" {
+			return true, nil
+		}
+		if p.info.ExitCode != 0 && p.info.RestartPolicy.AutoRestart == protocol.RestartNever {
+			return false, fmt.Errorf("exited with code %d", p.info.ExitCode)
+		}
+		return false, nil
+
+	default:
+		return p.info.Status == protocol.StatusOnline || p.info.Status == protocol.StatusScheduled, nil
+	}
+}
+
+// stopInOrder stops procs so that dependents stop before the processes they
+// depend on. Processes at the same depth stop in parallel.
+func stopInOrder(procs []*Process, stop func(*Process)) {
+	for _, wave := range stopWaves(procs) {
+		var wg sync.WaitGroup
+		for _, p := range wave {
+			wg.Add(1)
+			go func(proc *Process) {
+				defer wg.Done()
+				stop(proc)
+			}(p)
+		}
+		wg.Wait()
+	}
+}
+
+// stopWaves groups procs by dependency depth, deepest first: a process with
+// no dependencies among procs is at depth 0 and any other one level deeper
+// than its deepest dependency. Each wave is sorted by name.
+func stopWaves(procs []*Process) [][]*Process {
+	byName := make(map[string]*Process, len(procs))
+	deps := make(map[string][]protocol.Dependency, len(procs))
+	groups := make(map[string][]string)
+	for _, p := range procs {
+		p.mu.Lock()
+		byName[p.info.Name] = p
+		deps[p.info.Name] = p.info.DependsOn
+		if p.info.App != "" {
+			groups[p.info.App] = append(groups[p.info.App], p.info.Name)
+		}
+		p.mu.Unlock()
+	}
+	for name, list := range deps {
+		deps[name] = expandDependencies(list, groups)
+	}
+
+	depth := make(map[string]int, len(procs))
+	visiting := make(map[string]bool)
+	var level func(name string) int
+	level = func(name string) int {
+		if l, ok := depth[name]; ok {
+			return l
+		}
+		if visiting[name] {
+			return 0 // cycle; validation keeps these out
+		}
+		visiting[name] = true
+		l := 0
+		for _, dep := range deps[name] {
+			if _, ok := byName[dep.Name]; ok {
+				if dl := level(dep.Name) + 1; dl > l {
+					l = dl
+				}
+			}
+		}
+		visiting[name] = false
+		depth[name] = l
+		return l
+	}
+
+	maxLevel := -1
+	for name := range byName {
+		if l := level(name); l > maxLevel {
+			maxLevel = l
+		}
+	}
+	waves := make([][]*Process, maxLevel+1)
+	for name, p := range byName {
+		i := maxLevel - depth[name]
+		waves[i] = append(waves[i], p)
+	}
+	for _, wave := range waves {
+		sort.Slice(wave, func(i, j int) bool { return wave[i].info.Name < wave[j].info.Name })
+	}
+	return waves
+}
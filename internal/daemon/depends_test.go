@@ -0,0 +1,112 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestDependencyState(t *testing.T) {
+	hc := &protocol.HealthCheck{Type: "tcp", Target: ":80"}
+	tests := []struct {
+		name   string
+		params protocol.StartParams
+		set    func(p *Process)
+		cond   protocol.DependencyCondition
+		met    bool
+		err    bool
+	}{
+		{"started online", protocol.StartParams{}, func(p *Process) { p.info.Status = protocol.StatusOnline }, protocol.DependsStarted, true, false},
+		{"started waiting", protocol.StartParams{}, func(p *Process) { p.info.Status = protocol.StatusWaiting }, "", false, false},
+		{"started job armed", protocol.StartParams{Schedule: "@hourly"}, func(p *Process) { p.info.Status = protocol.StatusScheduled }, "", true, false},
+		{"started errored", protocol.StartParams{}, func(p *Process) { p.info.Status = protocol.StatusErrored }, "", false, true},
+		{"healthy without check", protocol.StartParams{}, func(p *Process) { p.info.Status = protocol.StatusOnline }, protocol.DependsHealthy, true, false},
+		{"healthy starting", protocol.StartParams{HealthCheck: hc}, func(p *Process) {
+			p.info.Status = protocol.StatusOnline
+			p.info.Health = protocol.HealthStarting
+		}, protocol.DependsHealthy, false, false},
+		{"healthy passing", protocol.StartParams{HealthCheck: hc}, func(p *Process) {
+			p.info.Status = protocol.StatusOnline
+			p.info.Health = protocol.HealthHealthy
+		}, protocol.DependsHealthy, true, false},
+		{"completed running", protocol.StartParams{}, func(p *Process) { p.info.Status = protocol.StatusOnline }, protocol.DependsCompleted, false, false},
+		{"completed exit 0", protocol.StartParams{AutoRestart: "never"}, func(p *Process) {
+			p.info.Status = protocol.StatusStopped
+			p.info.StatusReason = "autorestart disabled"
+		}, protocol.DependsCompleted, true, false},
+		{"completed stopped by user", protocol.StartParams{}, func(p *Process) {
+			p.info.Status = protocol.StatusStopped
+			p.info.StatusReason = "stopped by user"
+		}, protocol.DependsCompleted, false, false},
+		{"completed exit 1", protocol.StartParams{AutoRestart: "never"}, func(p *Process) {
+			p.info.Status = protocol.StatusStopped
+			p.info.ExitCode = 1
+		}, protocol.DependsCompleted, false, true},
+		{"completed exit 1 restarting", protocol.StartParams{}, func(p *Process) {
+			p.info.Status = protocol.StatusStopped
+			p.info.ExitCode = 1
+		}, protocol.DependsCompleted, false, false},
+		{"completed job run", protocol.StartParams{Schedule: "@hourly"}, func(p *Process) {
+			p.info.Status = protocol.StatusScheduled
+			p.addRun(protocol.RunRecord{Status: protocol.RunSuccess})
+		}, protocol.DependsCompleted, true, false},
+		{"completed job not run", protocol.StartParams{Schedule: "@hourly"}, func(p *Process) {
+			p.info.Status = protocol.StatusScheduled
+		}, protocol.DependsCompleted, false, false},
+	}
+	for _, tt := range tests {
+		tt.params.Command = "/bin/true"
+		p := NewProcess(0, tt.params)
+		tt.set(p)
+		met, err := p.dependencyState(tt.cond)
+		if met != tt.met || (err != nil) != tt.err {
+			t.Errorf("%s: dependencyState = %v, %v; want %v, error %v", tt.name, met, err, tt.met, tt.err)
+		}
+	}
+}
+
+func TestStopWaves(t *testing.T) {
+	proc := func(name string, deps ...string) *Process {
+		sp := protocol.StartParams{Command: "/bin/true", Name: name}
+		for _, d := range deps {
+			sp.DependsOn = append(sp.DependsOn, protocol.Dependency{Name: d})
+		}
+		return NewProcess(0, sp)
+	}
+	procs := []*Process{
+		proc("web", "api"),
+		proc("api", "db", "migrate"),
+		proc("migrate", "db"),
+		proc("db"),
+		proc("cache"),
+		proc("worker", "db", "deleted"),
+	}
+
+	var got [][]string
+	for _, wave := range stopWaves(procs) {
+		var names []string
+		for _, p := range wave {
+			names = append(names, p.info.Name)
+		}
+		got = append(got, names)
+	}
+	want := [][]string{{"web"}, {"api"}, {"migrate", "worker"}, {"cache", "db"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stopWaves = %v, want %v", got, want)
+	}
+}
+
+func TestExpandDependencies(t *testing.T) {
+	deps := []protocol.Dependency{{Name: "db"}, {Name: "api", Condition: protocol.DependsHealthy}}
+	groups := map[string][]string{"api": {"api-0", "api-1"}}
+	got := expandDependencies(deps, groups)
+	want := []protocol.Dependency{
+		{Name: "db"},
+		{Name: "api-0", Condition: protocol.DependsHealthy},
+		{Name: "api-1", Condition: protocol.DependsHealthy},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandDependencies = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,232 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// eventBuffer is how many events a subscriber may fall behind before it is
+// dropped.
+const eventBuffer = 256
+
+// eventWriteTimeout bounds how long one event write to a subscriber may
+// block.
+const eventWriteTimeout = 10 * time.Second
+
+// subscriber is one subscribe connection.
+type subscriber struct {
+	ch       chan protocol.Event
+	target   string // process name or instance group, empty for all
+	overflow bool   // dropped for falling behind; set before ch is closed
+}
+
+func (s *subscriber) wants(ev protocol.Event) bool {
+	return s.target == "" || ev.Name == "" || ev.Name == s.target || ev.App == s.target
+}
+
+// eventBus fans events out to subscribers without blocking the publisher.
+// A nil bus discards events.
+type eventBus struct {
+	mu     sync.Mutex
+	subs   map[*subscriber]bool
+	closed bool
+	wg     sync.WaitGroup // running subscribe connections
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[*subscriber]bool)}
+}
+
+// publish sends ev to every subscriber that wants it. A subscriber whose
+// buffer is full is dropped.
+func (b *eventBus) publish(ev protocol.Event) {
+	if b == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if !s.wants(ev) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			s.overflow = true
+			delete(b.subs, s)
+			close(s.ch)
+		}
+	}
+}
+
+// subscribe registers a subscriber for target, or returns nil once the
+// bus is closed.
+func (b *eventBus) subscribe(target string) *subscriber {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	s := &subscriber{ch: make(chan protocol.Event, eventBuffer), target: target}
+	b.subs[s] = true
+	b.wg.Add(1)
+	return s
+}
+
+// unsubscribe removes s, if it is still registered.
+func (b *eventBus) unsubscribe(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[s] {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+	b.wg.Done()
+}
+
+// close ends every subscription once its pending events are sent, waiting
+// up to timeout for that.
+func (b *eventBus) close(timeout time.Duration) {
+	b.mu.Lock()
+	b.closed = true
+	for s := range b.subs {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// publish sends a process event, filling in its name and group. Must be
+// called with p.mu held.
+func (p *Process) publish(ev protocol.Event) {
+	ev.Name = p.info.Name
+	ev.App = p.info.App
+	p.events.publish(ev)
+}
+
+// setStatus changes the status, publishing a status event if it changed.
+// Must be called with p.mu held.
+func (p *Process) setStatus(status protocol.Status) {
+	prev := p.info.Status
+	p.info.Status = status
+	if status != prev {
+		p.publish(protocol.Event{
+			ProcessEvent: protocol.ProcessEvent{Type: protocol.EventStatus, PID: p.info.PID},
+			Status:       status,
+			PrevStatus:   prev,
+		})
+	}
+}
+
+// serveSubscribe answers a "subscribe" request on conn: a success response,
+// then one response per event until the client disconnects or the daemon
+// shuts down. scanner reads the rest of the connection.
+func (d *Daemon) serveSubscribe(conn net.Conn, scanner *bufio.Scanner, params json.RawMessage) {
+	var sp protocol.SubscribeParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &sp); err != nil {
+			writeResponse(conn, errorResponse("invalid subscribe params: "+err.Error()))
+			return
+		}
+	}
+	target := sp.Target
+	if target == "all" {
+		target = ""
+	}
+	// An id stands for the process that has it now.
+	if _, err := strconv.Atoi(target); err == nil {
+		p := d.findProcess(target)
+		if p == nil {
+			writeResponse(conn, targetNotFound(target))
+			return
+		}
+		target = p.Info().Name
+	}
+
+	sub := d.events.subscribe(target)
+	if sub == nil {
+		writeResponse(conn, errorResponse("daemon is shutting down"))
+		return
+	}
+	defer d.events.unsubscribe(sub)
+	if writeResponse(conn, successResponse(nil)) != nil {
+		return
+	}
+	slog.Debug("client subscribed", "target", target)
+
+	// The client sends nothing more; the read ends when it goes away.
+	gone := make(chan struct{})
+	go func() {
+		for scanner.Scan() {
+		}
+		close(gone)
+	}()
+
+	for {
+		select {
+		case ev, ok := <-sub.ch:
+			if !ok {
+				if sub.overflow {
+					writeResponse(conn, errorResponse("event stream dropped: client too slow"))
+				}
+				return
+			}
+			if writeResponse(conn, successResponse(ev)) != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// writeResponse writes resp as one line to a streaming connection.
+func writeResponse(conn net.Conn, resp protocol.Response) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+	_, err = fmt.Fprintf(conn, "%s\n", data)
+	return err
+}
+
+// publishConfig publishes a config event, e.g. "added" or "deleted".
+func (p *Process) publishConfig(reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.publish(protocol.Event{ProcessEvent: protocol.ProcessEvent{Type: protocol.EventConfig, Reason: reason}})
+}
+
+// publishRestart publishes a restart event for the run just started.
+func (p *Process) publishRestart(reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.publish(protocol.Event{ProcessEvent: protocol.ProcessEvent{Type: protocol.EventRestart, PID: p.info.PID, Reason: reason}})
+}
+
+// publishDaemon publishes a daemon lifecycle event.
+func (d *Daemon) publishDaemon(reason string) {
+	d.events.publish(protocol.Event{ProcessEvent: protocol.ProcessEvent{Type: protocol.EventDaemon, Reason: reason}})
+}
@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestEventBusFilter(t *testing.T) {
+	b := newEventBus()
+	all := b.subscribe("")
+	api := b.subscribe("api")
+
+	b.publish(protocol.Event{ProcessEvent: protocol.ProcessEvent{Type: protocol.EventStart}, Name: "api"})
+	b.publish(protocol.Event{ProcessEvent: protocol.ProcessEvent{Type: protocol.EventStart}, Name: "worker-1", App: "worker"})
+	b.publish(protocol.Event{ProcessEvent: protocol.ProcessEvent{Type: protocol.EventStart}, Name: "api-0", App: "api"})
+	b.publish(protocol.Event{ProcessEvent: protocol.ProcessEvent{Type: protocol.EventDaemon, Reason: "shutting down"}})
+
+	if n := len(all.ch); n != 4 {
+		t.Errorf("unfiltered subscriber got %d events, want 4", n)
+	}
+	var names []string
+	for len(api.ch) > 0 {
+		ev := <-api.ch
+		if ev.Time.IsZero() {
+			t.Error("event published without a time")
+		}
+		names = append(names, ev.Name)
+	}
+	if len(names) != 3 || names[0] != "api" || names[1] != "api-0" || names[2] != "" {
+		t.Errorf("filtered subscriber got events of %q, want api, api-0 and the daemon", names)
+	}
+
+	b.unsubscribe(all)
+	b.unsubscribe(api)
+	b.close(time.Second)
+	if b.subscribe("") != nil {
+		t.Error("subscribe succeeded on a closed bus")
+	}
+}
+
+func TestEventBusOverflow(t *testing.T) {
+	b := newEventBus()
+	s := b.subscribe("")
+	for i := 0; i <= eventBuffer; i++ {
+		b.publish(protocol.Event{ProcessEvent: protocol.ProcessEvent{Type: protocol.EventStatus}, Name: "api"})
+	}
+	n := 0
+	for range s.ch {
+		n++
+	}
+	if n != eventBuffer || !s.overflow {
+		t.Errorf("got %d events, overflow %v; want %d and a dropped subscriber", n, s.overflow, eventBuffer)
+	}
+	b.unsubscribe(s)
+}
+
+func TestSetStatusPublishes(t *testing.T) {
+	b := newEventBus()
+	s := b.subscribe("")
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/sleep", Name: "api"})
+	p.events = b
+
+	p.mu.Lock()
+	p.setStatus(protocol.StatusWaiting)
+	p.setStatus(protocol.StatusWaiting)
+	p.mu.Unlock()
+
+	if n := len(s.ch); n != 1 {
+		t.Fatalf("got %d events, want 1 for a single change", n)
+	}
+	ev := <-s.ch
+	if ev.Type != protocol.EventStatus || ev.Name != "api" || ev.Status != protocol.StatusWaiting || ev.PrevStatus != protocol.StatusStopped {
+		t.Errorf("event = %+v, want api stopped -> waiting", ev)
+	}
+}
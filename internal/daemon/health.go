@@ -0,0 +1,154 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// runHealthChecks probes a running process every interval until exitCh is
+// closed. After threshold consecutive failures the process is killed and
+// handed to the restart logic via ForceRestart. Failures during the start
+// period are reported but not counted.
+func (d *Daemon) runHealthChecks(p *Process, hc protocol.HealthCheck, exitCh chan struct{}) {
+	interval, timeout, startPeriod, threshold := hc.Timings()
+
+	p.mu.Lock()
+	name := p.info.Name
+	cwd := p.info.Cwd
+	env := p.info.Env
+	started := p.info.Uptime
+	p.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failures := 0
+	state := protocol.HealthStarting
+	for {
+		select {
+		case <-ticker.C:
+		case <-exitCh:
+			return
+		case <-d.stopCh:
+			return
+		}
+
+		err := probe(hc, cwd, env, timeout)
+		if err == nil {
+			if state != protocol.HealthHealthy {
+				p.LogAction("health check passed (%s %s)", hc.Type, hc.Target)
+				slog.Info("health check passed", "name", name, "type", hc.Type)
+			}
+			failures = 0
+			state = protocol.HealthHealthy
+			p.setHealth(state, 0, "")
+			continue
+		}
+
+		msg := err.Error()
+		if time.Since(started) < startPeriod {
+			p.setHealth(state, 0, msg)
+			slog.Debug("health check failed during start period", "name", name, "error", msg)
+			continue
+		}
+
+		failures++
+		slog.Warn("health check failed", "name", name, "failures", failures, "threshold", threshold, "error", msg)
+		if failures < threshold {
+			p.setHealth(state, failures, msg)
+			continue
+		}
+
+		p.setHealth(protocol.HealthUnhealthy, failures, msg)
+		reason := fmt.Sprintf("health check failed %d times: %s", failures, msg)
+		p.LogAction("%s, restarting", reason)
+		d.notifyWebhooks(p, protocol.NotifyHealthFailed, reason)
+		p.ForceRestart(reason)
+		return
+	}
+}
+
+// probe runs a single health check and returns nil if the process is healthy.
+func probe(hc protocol.HealthCheck, cwd string, env map[string]string, timeout time.Duration) error {
+	switch hc.Type {
+	case protocol.HealthCheckHTTP:
+		return probeHTTP(hc.Target, timeout)
+	case protocol.HealthCheckTCP:
+		return probeTCP(hc.Target, timeout)
+	case protocol.HealthCheckExec:
+		return probeExec(hc.Target, cwd, env, timeout)
+	default:
+		return fmt.Errorf("unknown health check type %q", hc.Type)
+	}
+}
+
+// probeHTTP issues a GET and treats any 2xx or 3xx status as healthy.
+func probeHTTP(url string, timeout time.Duration) error {
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// probeTCP succeeds if a connection to addr can be opened.
+func probeTCP(addr string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	return nil
+}
+
+// probeExec runs command via sh -c in the process's cwd and environment.
+// A zero exit status is healthy.
+func probeExec(command, cwd string, env map[string]string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	// Kill the whole group on timeout so grandchildren don't keep the
+	// output pipe open.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+	cmd.Dir = cwd
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		if s := strings.TrimSpace(string(out)); s != "" {
+			if len(s) > 200 {
+				s = s[:200]
+			}
+			return fmt.Errorf("%v: %s", err, s)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestProbeHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	hc := protocol.HealthCheck{Type: "http", Target: srv.URL + "/health"}
+	if err := probe(hc, "", nil, time.Second); err != nil {
+		t.Errorf("healthy endpoint: unexpected error: %v", err)
+	}
+	hc.Target = srv.URL + "/down"
+	if err := probe(hc, "", nil, time.Second); err == nil {
+		t.Error("503 endpoint: expected error")
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+
+	hc := protocol.HealthCheck{Type: "tcp", Target: addr}
+	if err := probe(hc, "", nil, time.Second); err != nil {
+		t.Errorf("open port: unexpected error: %v", err)
+	}
+	ln.Close()
+	if err := probe(hc, "", nil, time.Second); err == nil {
+		t.Error("closed port: expected error")
+	}
+}
+
+func TestProbeExec(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"GOPM_TEST_HEALTH": "ok"}
+
+	hc := protocol.HealthCheck{Type: "exec", Target: `test "$GOPM_TEST_HEALTH" = ok && test "$PWD" = "` + dir + `"`}
+	if err := probe(hc, dir, env, time.Second); err != nil {
+		t.Errorf("passing command: unexpected error: %v", err)
+	}
+
+	hc.Target = "echo broken; exit 1"
+	err := probe(hc, dir, env, time.Second)
+	if err == nil {
+		t.Fatal("failing command: expected error")
+	}
+	if got := err.Error(); got != "exit status 1: broken" {
+		t.Errorf("error = %q, want %q", got, "exit status 1: broken")
+	}
+
+	hc.Target = "sleep 5"
+	if err := probe(hc, dir, env, 100*time.Millisecond); err == nil {
+		t.Error("slow command: expected timeout error")
+	}
+}
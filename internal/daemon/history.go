@@ -0,0 +1,191 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// maxHistory is how many events are kept per process.
+const maxHistory = 100
+
+// exitStatus describes how a process exited.
+type exitStatus struct {
+	code     int            // exit code, -1 if killed by a signal
+	signal   syscall.Signal // terminating signal, 0 if it exited
+	coreDump bool
+}
+
+// exitStatusOf reads the exit status from the error of cmd.Wait.
+func exitStatusOf(err error) exitStatus {
+	if err == nil {
+		return exitStatus{}
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return exitStatus{code: -1}
+	}
+	st := exitStatus{code: exitErr.ExitCode()}
+	if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		st.signal = ws.Signal()
+		st.coreDump = ws.CoreDump()
+	}
+	return st
+}
+
+func (st exitStatus) String() string {
+	if st.signal == 0 {
+		return fmt.Sprintf("exited with code %d", st.code)
+	}
+	s := "killed by " + protocol.SignalName(st.signal)
+	if st.coreDump {
+		s += " (core dumped)"
+	}
+	return s
+}
+
+// addEvent appends an event to the history, dropping the oldest beyond
+// maxHistory. Must be called with p.mu held.
+func (p *Process) addEvent(ev protocol.ProcessEvent) {
+	p.history = append(p.history, ev)
+	if len(p.history) > maxHistory {
+		p.history = append(p.history[:0], p.history[len(p.history)-maxHistory:]...)
+	}
+}
+
+// recordExit adds an exit event for the run that just ended. It must be
+// called before MarkExited, while the PID and uptime are still set.
+func (p *Process) recordExit(st exitStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	uptime := protocol.Duration{Duration: time.Since(p.info.Uptime).Round(time.Millisecond)}
+	ev := protocol.ProcessEvent{
+		Time:     time.Now(),
+		Type:     protocol.EventExit,
+		PID:      p.info.PID,
+		ExitCode: st.code,
+		CoreDump: st.coreDump,
+		Uptime:   &uptime,
+	}
+	if st.signal != 0 {
+		ev.Signal = protocol.SignalName(st.signal)
+	}
+	p.addEvent(ev)
+}
+
+// decide records what the daemon did about the last exit, and why, and
+// publishes the exit.
+func (p *Process) decide(decision protocol.RestartDecision, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := len(p.history)
+	if n == 0 || p.history[n-1].Type != protocol.EventExit {
+		return
+	}
+	p.history[n-1].Decision = decision
+	p.history[n-1].Reason = reason
+	p.publish(protocol.Event{ProcessEvent: p.history[n-1]})
+}
+
+// History returns up to limit events, newest first (all if limit <= 0).
+func (p *Process) History(limit int) []protocol.ProcessEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := make([]protocol.ProcessEvent, 0, len(p.history))
+	for i := len(p.history) - 1; i >= 0; i-- {
+		if limit > 0 && len(events) == limit {
+			break
+		}
+		events = append(events, p.history[i])
+	}
+	return events
+}
+
+// restoreHistory puts saved events, oldest first, before the ones
+// recorded since the process was restored.
+func (p *Process) restoreHistory(saved []protocol.ProcessEvent) {
+	if len(saved) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := append(append([]protocol.ProcessEvent{}, saved...), p.history...)
+	p.history = nil
+	for _, ev := range events {
+		p.addEvent(ev)
+	}
+}
+
+// carryOver gives p, which replaces old (reload, apply), old's history and
+// restart counts, so its restart budget and statistics go on.
+func (p *Process) carryOver(old *Process) {
+	old.mu.Lock()
+	history := append([]protocol.ProcessEvent(nil), old.history...)
+	restartTimes := append([]time.Time(nil), old.restartTimes...)
+	restarts, memRestarts := old.info.Restarts, old.info.MemoryRestarts
+	old.mu.Unlock()
+	p.restoreHistory(history)
+	p.mu.Lock()
+	p.restartTimes = restartTimes
+	p.info.Restarts = restarts
+	p.info.MemoryRestarts = memRestarts
+	p.mu.Unlock()
+}
+
+func (d *Daemon) handleHistory(params json.RawMessage) protocol.Response {
+	var hp protocol.HistoryParams
+	if err := json.Unmarshal(params, &hp); err != nil {
+		return errorResponse("invalid history params: " + err.Error())
+	}
+
+	proc := d.findProcess(hp.Target)
+	if proc == nil {
+		return targetNotFound(hp.Target)
+	}
+	return successResponse(proc.History(hp.Limit))
+}
+
+// saveHistory writes the history of every process to history.json, next
+// to dump.json. Must be called with d.mu held.
+func (d *Daemon) saveHistory() error {
+	history := make(map[string][]protocol.ProcessEvent, len(d.processes))
+	for name, p := range d.processes {
+		p.mu.Lock()
+		if len(p.history) > 0 {
+			history[name] = append([]protocol.ProcessEvent(nil), p.history...)
+		}
+		p.mu.Unlock()
+	}
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal history: %w", err)
+	}
+	if err := os.WriteFile(protocol.HistoryFilePath(), data, 0644); err != nil {
+		return fmt.Errorf("write history file: %w", err)
+	}
+	return nil
+}
+
+// loadHistory reads history.json, keyed by process name. A missing or
+// unreadable file gives no history.
+func loadHistory() map[string][]protocol.ProcessEvent {
+	data, err := os.ReadFile(protocol.HistoryFilePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("cannot read history file", "error", err)
+		}
+		return nil
+	}
+	var history map[string][]protocol.ProcessEvent
+	if err := json.Unmarshal(data, &history); err != nil {
+		slog.Warn("invalid history file", "error", err)
+		return nil
+	}
+	return history
+}
@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestExitStatusOf(t *testing.T) {
+	tests := []struct {
+		script string
+		want   exitStatus
+		str    string
+	}{
+		{"exit 0", exitStatus{}, "exited with code 0"},
+		{"exit 3", exitStatus{code: 3}, "exited with code 3"},
+		{"kill -KILL $$", exitStatus{code: -1, signal: syscall.SIGKILL}, "killed by SIGKILL"},
+		{"kill -TERM $$", exitStatus{code: -1, signal: syscall.SIGTERM}, "killed by SIGTERM"},
+	}
+	for _, tt := range tests {
+		st := exitStatusOf(exec.Command("/bin/sh", "-c", tt.script).Run())
+		if st != tt.want {
+			t.Errorf("%q: exit status = %+v, want %+v", tt.script, st, tt.want)
+		}
+		if st.String() != tt.str {
+			t.Errorf("%q: String() = %q, want %q", tt.script, st.String(), tt.str)
+		}
+	}
+	if s := (exitStatus{code: -1, signal: syscall.SIGSEGV, coreDump: true}).String(); s != "killed by SIGSEGV (core dumped)" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestHistoryCapAndOrder(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/sleep"})
+	for i := 1; i <= maxHistory+5; i++ {
+		p.addEvent(protocol.ProcessEvent{Type: protocol.EventStart, PID: i})
+	}
+	events := p.History(0)
+	if len(events) != maxHistory {
+		t.Fatalf("len(History) = %d, want %d", len(events), maxHistory)
+	}
+	if events[0].PID != maxHistory+5 || events[len(events)-1].PID != 6 {
+		t.Errorf("History PIDs = %d..%d, want %d..6 (newest first)", events[0].PID, events[len(events)-1].PID, maxHistory+5)
+	}
+	if got := p.History(2); len(got) != 2 || got[0].PID != maxHistory+5 {
+		t.Errorf("History(2) = %+v", got)
+	}
+}
+
+func TestDecideAndRestoreHistory(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/sleep"})
+	p.decide(protocol.DecisionRestart, "no exit yet")
+	p.addEvent(protocol.ProcessEvent{Type: protocol.EventStart, PID: 10})
+	p.recordExit(exitStatus{code: -1, signal: syscall.SIGKILL})
+	p.decide(protocol.DecisionRestart, "killed by SIGKILL")
+
+	events := p.History(0)
+	if len(events) != 2 {
+		t.Fatalf("History = %+v, want a start and an exit", events)
+	}
+	exit := events[0]
+	if exit.Type != protocol.EventExit || exit.Signal != "SIGKILL" || exit.ExitCode != -1 || exit.Decision != protocol.DecisionRestart || exit.Uptime == nil {
+		t.Errorf("exit event = %+v", exit)
+	}
+
+	restored := NewProcess(0, protocol.StartParams{Command: "/bin/sleep"})
+	restored.addEvent(protocol.ProcessEvent{Type: protocol.EventStart, PID: 20})
+	restored.restoreHistory(p.history)
+	got := restored.History(0)
+	if len(got) != 3 || got[0].PID != 20 || got[1].Signal != "SIGKILL" {
+		t.Errorf("restored History = %+v, want saved events before the new start", got)
+	}
+}
+
+func TestCarryOver(t *testing.T) {
+	old := NewProcess(0, protocol.StartParams{Command: "/bin/sleep"})
+	old.addEvent(protocol.ProcessEvent{Type: protocol.EventStart, PID: 10})
+	old.restartTimes = []time.Time{time.Now()}
+	old.info.Restarts, old.info.MemoryRestarts = 3, 1
+
+	np := NewProcess(0, protocol.StartParams{Command: "/bin/sleep"})
+	np.carryOver(old)
+	info := np.Info()
+	if info.Restarts != 3 || info.MemoryRestarts != 1 || len(np.restartTimes) != 1 {
+		t.Errorf("restarts = %d, memory restarts = %d, restart times = %v", info.Restarts, info.MemoryRestarts, np.restartTimes)
+	}
+	if h := np.History(0); len(h) != 1 || h[0].PID != 10 {
+		t.Errorf("History = %+v, want the old start", h)
+	}
+}
@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/7c/gopm/internal/logwriter"
+)
+
+// hookError is a failed pre_start hook, which blocks the start.
+type hookError struct {
+	hook string
+	err  error
+}
+
+func (e *hookError) Error() string { return fmt.Sprintf("%s hook failed: %v", e.hook, e.err) }
+func (e *hookError) Unwrap() error { return e.err }
+
+// hookRun is one run of a lifecycle hook or the stop_command, prepared from
+// the process info at the point it belongs to.
+type hookRun struct {
+	name       string
+	label      string // what a failure is reported as, e.g. "pre_start hook"
+	prefix     string // written before each line of output
+	process    string
+	command    string
+	timeout    time.Duration
+	cwd        string
+	env        []string
+	logErr     string
+	maxLogSize int64
+	cred       *syscall.Credential // nil runs it as the daemon's user
+	err        error               // resolving the user failed; run fails with it
+}
+
+// hook prepares the named hook, or returns nil if it is not set. The hook
+// gets the process env plus GOPM_* vars describing the process, and extra
+// ("KEY=value"). Must be called with p.mu held.
+func (p *Process) hook(name string, extra ...string) *hookRun {
+	command := p.info.Hooks.Command(name)
+	if command == "" {
+		return nil
+	}
+	h := p.shellRun(name, command, p.info.Hooks.TimeoutDuration(), append([]string{"GOPM_HOOK=" + name}, extra...))
+	h.label = name + " hook"
+	h.prefix = "[gopm hook] "
+	return h
+}
+
+// stopCommand prepares the stop_command, or returns nil if it is not set.
+// It may run for up to kill_timeout. Must be called with p.mu held.
+func (p *Process) stopCommand() *hookRun {
+	if p.info.StopCommand == "" {
+		return nil
+	}
+	timeout := p.info.RestartPolicy.KillTimeout.Duration
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	h := p.shellRun("stop_command", p.info.StopCommand, timeout, nil)
+	h.label = "stop_command"
+	h.prefix = "[gopm stop] "
+	return h
+}
+
+// shellRun prepares command to run with sh -c in the process's cwd and env,
+// as its user, plus GOPM_* vars describing the process and extra. Must be
+// called with p.mu held.
+func (p *Process) shellRun(name, command string, timeout time.Duration, extra []string) *hookRun {
+	ra, err := resolveCredential(p.info.User, p.info.Group, p.info.Groups)
+	env := os.Environ()
+	if ra != nil {
+		env = append(env, ra.env()...)
+	}
+	for k, v := range p.info.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	env = append(env,
+		"GOPM_NAME="+p.info.Name,
+		"GOPM_ID="+strconv.Itoa(p.info.ID),
+		"GOPM_RESTARTS="+strconv.Itoa(p.info.Restarts),
+	)
+	if p.info.PID > 0 {
+		env = append(env, "GOPM_PID="+strconv.Itoa(p.info.PID))
+	}
+	if p.info.App != "" {
+		env = append(env, "GOPM_APP="+p.info.App, "GOPM_INSTANCE_ID="+strconv.Itoa(p.info.InstanceID))
+	}
+	h := &hookRun{
+		name:       name,
+		process:    p.info.Name,
+		command:    command,
+		timeout:    timeout,
+		cwd:        p.info.Cwd,
+		env:        append(env, extra...),
+		logErr:     p.info.LogErr,
+		maxLogSize: p.info.MaxLogSize,
+		err:        err,
+	}
+	if ra != nil {
+		h.cred = ra.cred
+	}
+	return h
+}
+
+// run runs the hook with sh -c and waits for it, killing it after its
+// timeout. Output goes to the process's stderr log, each line prefixed
+// with h.prefix; a failure is logged there too. A nil hook does nothing.
+func (h *hookRun) run() error {
+	if h == nil {
+		return nil
+	}
+	// The hook has its own writer: the process's may be closed (before a
+	// start) or reopened (on a restart) while the hook runs.
+	os.MkdirAll(filepath.Dir(h.logErr), 0755)
+	var log io.Writer = io.Discard
+	if rot, err := logwriter.New(h.logErr, h.maxLogSize, 3); err == nil {
+		defer rot.Close()
+		log = logwriter.NewTimestampWriter(rot)
+	}
+	out := &prefixWriter{w: log, prefix: []byte(h.prefix)}
+	if h.err != nil {
+		fmt.Fprintf(log, "[gopm] %s failed: %v\n", h.label, h.err)
+		return h.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.command)
+	// Kill the whole group on timeout so grandchildren don't keep the
+	// output pipe open.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Credential: h.cred}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+	cmd.WaitDelay = time.Second
+	cmd.Dir = h.cwd
+	cmd.Env = h.env
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	start := time.Now()
+	err := cmd.Run()
+	out.flush()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", h.timeout)
+	}
+	if err != nil {
+		fmt.Fprintf(log, "[gopm] %s failed: %v\n", h.label, err)
+		slog.Warn("hook failed", "name", h.process, "hook", h.name, "error", err)
+		return err
+	}
+	slog.Debug("hook finished", "name", h.process, "hook", h.name, "duration", time.Since(start))
+	return nil
+}
+
+// prefixWriter writes each line to w with prefix. A partial last line is
+// held until the next newline or flush.
+type prefixWriter struct {
+	w      io.Writer
+	prefix []byte
+	buf    []byte
+}
+
+func (pw *prefixWriter) Write(b []byte) (int, error) {
+	pw.buf = append(pw.buf, b...)
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		pw.w.Write(append(append([]byte{}, pw.prefix...), pw.buf[:i+1]...))
+		pw.buf = pw.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+func (pw *prefixWriter) flush() {
+	if len(pw.buf) > 0 {
+		pw.Write([]byte("\n"))
+	}
+}
@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestPreStartHookFailureBlocksStart(t *testing.T) {
+	dir := t.TempDir()
+	p := NewProcess(3, protocol.StartParams{
+		Command: "/bin/sleep",
+		Args:    []string{"60"},
+		Name:    "api",
+		Cwd:     dir,
+		Env:     map[string]string{"STAGE": "test"},
+		LogOut:  filepath.Join(dir, "out.log"),
+		LogErr:  filepath.Join(dir, "err.log"),
+		Hooks: &protocol.Hooks{
+			PreStart: `echo "$GOPM_HOOK $GOPM_NAME $GOPM_ID $STAGE"; echo partial >&2; printf tail; exit 3`,
+		},
+	})
+
+	err := p.Start()
+	var herr *hookError
+	if !errors.As(err, &herr) || herr.hook != protocol.HookPreStart {
+		t.Fatalf("Start() = %v, want a pre_start hookError", err)
+	}
+	info := p.Info()
+	if info.Status != protocol.StatusErrored || info.PID != 0 || !strings.Contains(info.StatusReason, "exit status 3") {
+		t.Errorf("status = %s (pid %d, %q), want errored by the hook", info.Status, info.PID, info.StatusReason)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "err.log"))
+	log := string(data)
+	for _, want := range []string{
+		"[gopm hook] pre_start api 3 test\n",
+		"[gopm hook] partial\n",
+		"[gopm hook] tail\n",
+		"[gopm] pre_start hook failed: exit status 3\n",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("stderr log missing %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	dir := t.TempDir()
+	p := NewProcess(0, protocol.StartParams{
+		Command: "/bin/sleep",
+		Cwd:     dir,
+		LogErr:  filepath.Join(dir, "err.log"),
+		Hooks:   &protocol.Hooks{PreStop: "sleep 10 & sleep 10", Timeout: "100ms"},
+	})
+	p.mu.Lock()
+	h := p.hook(protocol.HookPreStop)
+	p.mu.Unlock()
+	if err := h.run(); err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("run() = %v, want a timeout", err)
+	}
+
+	p.mu.Lock()
+	h = p.hook(protocol.HookPostExit)
+	p.mu.Unlock()
+	if h != nil || h.run() != nil {
+		t.Errorf("unset hook = %+v, want nil and a no-op run", h)
+	}
+}
@@ -0,0 +1,209 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// appInstances returns the instances of the group named app, ordered by
+// instance ID.
+func (d *Daemon) appInstances(app string) []*Process {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.appInstancesLocked(app)
+}
+
+// appInstancesLocked is appInstances for callers holding d.mu.
+func (d *Daemon) appInstancesLocked(app string) []*Process {
+	var procs []*Process
+	for _, p := range d.processes {
+		p.mu.Lock()
+		in := p.info.App == app
+		p.mu.Unlock()
+		if in {
+			procs = append(procs, p)
+		}
+	}
+	sortInstances(procs)
+	return procs
+}
+
+// sortInstances orders instances of a group by instance ID.
+func sortInstances(procs []*Process) {
+	ids := make(map[*Process]int, len(procs))
+	for _, p := range procs {
+		p.mu.Lock()
+		ids[p] = p.info.InstanceID
+		p.mu.Unlock()
+	}
+	sort.Slice(procs, func(i, j int) bool { return ids[procs[i]] < ids[procs[j]] })
+}
+
+// nameTakenLocked reports whether name is used by a process or an instance
+// group. Must be called with d.mu held.
+func (d *Daemon) nameTakenLocked(name string) bool {
+	if _, ok := d.processes[name]; ok {
+		return true
+	}
+	for _, p := range d.processes {
+		p.mu.Lock()
+		app := p.info.App
+		p.mu.Unlock()
+		if app == name {
+			return true
+		}
+	}
+	return false
+}
+
+// startInstances starts the instances with the given IDs of the group that
+// sp describes (sp.Name is the app name). Each gets its own name and log
+// files. If one fails to start, those started so far are removed again.
+func (d *Daemon) startInstances(sp protocol.StartParams, ids []int) ([]*Process, error) {
+	var started []*Process
+	for _, id := range ids {
+		p, err := d.startProcess(instanceStartParams(sp, id))
+		if err != nil {
+			for _, p := range started {
+				d.removeProcess(p)
+			}
+			return nil, fmt.Errorf("instance %d: %w", id, err)
+		}
+		started = append(started, p)
+	}
+	return started, nil
+}
+
+// instanceStartParams returns the StartParams of instance id of the group
+// that sp describes.
+func instanceStartParams(sp protocol.StartParams, id int) protocol.StartParams {
+	app := sp.Name
+	logOut := sp.LogOut
+	if logOut == "" {
+		logOut = filepath.Join(protocol.LogDir(), fmt.Sprintf("%s-out.log", app))
+	}
+	logErr := sp.LogErr
+	if logErr == "" {
+		logErr = filepath.Join(protocol.LogDir(), fmt.Sprintf("%s-err.log", app))
+	}
+
+	ip := sp
+	ip.Name = protocol.InstanceName(app, id)
+	ip.App = app
+	ip.InstanceID = id
+	ip.LogOut = protocol.InstanceLogPath(logOut, id)
+	ip.LogErr = protocol.InstanceLogPath(logErr, id)
+	return ip
+}
+
+// groupStartParams returns the StartParams that started the group an
+// instance belongs to, for starting more instances like it.
+func groupStartParams(info protocol.ProcessInfo) protocol.StartParams {
+	sp := infoToStartParams(info)
+	sp.Name = info.App
+	sp.App = ""
+	sp.InstanceID = 0
+	sp.LogOut = protocol.GroupLogPath(info.LogOut, info.InstanceID)
+	sp.LogErr = protocol.GroupLogPath(info.LogErr, info.InstanceID)
+	return sp
+}
+
+// removeProcess stops p and forgets it, closing its sockets if no other
+// instance of its group uses them.
+func (d *Daemon) removeProcess(p *Process) {
+	p.Stop()
+	p.CloseLogWriters()
+	d.mu.Lock()
+	p.mu.Lock()
+	name, owner := p.info.Name, socketOwner(p.info)
+	p.mu.Unlock()
+	delete(d.processes, name)
+	delete(d.snapshots, name)
+	d.mu.Unlock()
+	p.publishConfig("deleted")
+	d.releaseSockets(owner)
+	slog.Info("process deleted", "name", name)
+}
+
+func (d *Daemon) handleScale(params json.RawMessage) protocol.Response {
+	var sp protocol.ScaleParams
+	if err := json.Unmarshal(params, &sp); err != nil {
+		return errorResponse("invalid scale params: " + err.Error())
+	}
+	if sp.Target == "" {
+		return errorResponse("target is required")
+	}
+	if sp.Instances == 0 || (sp.Instances < 0 && sp.Instances != protocol.InstancesMax) {
+		return errorResponse("instances must be a positive number or max")
+	}
+
+	procs := d.appInstances(sp.Target)
+	if len(procs) == 0 {
+		if d.findProcess(sp.Target) != nil {
+			return errorResponse(fmt.Sprintf("process %q is not an instance group (start it with instances)", sp.Target))
+		}
+		return targetNotFound(sp.Target)
+	}
+
+	procs, err := d.scaleGroup(procs, sp.Instances)
+	if err != nil {
+		return errorResponse(err.Error())
+	}
+	infos := make([]protocol.ProcessInfo, len(procs))
+	for i, p := range procs {
+		infos[i] = p.Info()
+	}
+	slog.Info("instance group scaled", "app", sp.Target, "instances", len(procs))
+
+	if err := d.SaveState(); err != nil {
+		slog.Error("auto-save failed after scale", "error", err)
+	}
+	return successResponse(infos)
+}
+
+// scaleGroup starts or removes instances of the group procs, ordered by
+// instance ID, so it has n. It returns the instances it has then.
+func (d *Daemon) scaleGroup(procs []*Process, n protocol.Instances) ([]*Process, error) {
+	want := n.Count()
+	if want > len(procs) {
+		// New instances take the lowest free IDs.
+		used := make(map[int]bool, len(procs))
+		for _, p := range procs {
+			p.mu.Lock()
+			used[p.info.InstanceID] = true
+			p.mu.Unlock()
+		}
+		var ids []int
+		for id := 0; len(procs)+len(ids) < want; id++ {
+			if !used[id] {
+				ids = append(ids, id)
+			}
+		}
+		gp := groupStartParams(procs[0].Info())
+		gp.Instances = n
+		started, err := d.startInstances(gp, ids)
+		if err != nil {
+			return nil, err
+		}
+		procs = append(procs, started...)
+		sortInstances(procs)
+	} else if want < len(procs) {
+		// The highest instance IDs go first.
+		for _, p := range procs[want:] {
+			d.removeProcess(p)
+		}
+		procs = procs[:want]
+	}
+
+	for _, p := range procs {
+		p.mu.Lock()
+		p.info.Instances = n
+		p.mu.Unlock()
+	}
+	return procs, nil
+}
@@ -0,0 +1,29 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestGroupStartParams(t *testing.T) {
+	p := NewProcess(3, protocol.StartParams{
+		Command:    "/bin/api",
+		Name:       "api-2",
+		App:        "api",
+		InstanceID: 2,
+		Instances:  4,
+		LogOut:     "/logs/api-out-2.log",
+		LogErr:     "/logs/api-err-2.log",
+	})
+	sp := groupStartParams(p.Info())
+	if sp.Name != "api" || sp.App != "" || sp.InstanceID != 0 || sp.Instances != 4 {
+		t.Errorf("groupStartParams = %+v", sp)
+	}
+	if sp.LogOut != "/logs/api-out.log" || sp.LogErr != "/logs/api-err.log" {
+		t.Errorf("log paths = %q, %q", sp.LogOut, sp.LogErr)
+	}
+	if sp.Command != "/bin/api" {
+		t.Errorf("Command = %q", sp.Command)
+	}
+}
@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// limitsScript holds a process with limits in a shell until the daemon has
+// applied them to its PID (see limitsGate), then execs the command so the
+// PID, and the limits, are kept. The fd it waits on is closed first.
+const limitsScript = `read _ <&%[1]d; exec %[1]d<&-; %[2]sexec "$0" "$@"`
+
+// limitsGate holds a starting process until its limits are applied. Most
+// limits can only be set from outside the process once it has a PID, so it
+// waits in a shell for the gate to close before running the command.
+type limitsGate struct {
+	r, w   *os.File
+	limits *protocol.Limits
+}
+
+// holdForLimits rewrites cmd to wait for the returned gate before running,
+// if the process has limits. It returns nil, leaving cmd alone, if there is
+// nothing to apply or cmd cannot be started anyway. Must be called with
+// p.mu held.
+func (p *Process) holdForLimits(cmd *exec.Cmd) *limitsGate {
+	l := p.info.Limits
+	if l == nil || len(l.Values()) == 0 || cmd.Err != nil {
+		return nil
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		fmt.Fprintf(p.stderr, "[gopm] limits not applied: %v\n", err)
+		return nil
+	}
+
+	umask := ""
+	if l.Umask != "" {
+		if m, err := protocol.ParseUmask(l.Umask); err == nil {
+			umask = fmt.Sprintf("umask %04o; ", m)
+		}
+	}
+	fd := 3 + len(cmd.ExtraFiles)
+	cmd.ExtraFiles = append(cmd.ExtraFiles, r)
+	cmd.Args = append([]string{"/bin/sh", "-c", fmt.Sprintf(limitsScript, fd, umask), cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+	return &limitsGate{r: r, w: w, limits: l}
+}
+
+// release applies the limits to the started process, logging the ones
+// that could not be applied to log, and lets it run.
+func (g *limitsGate) release(pid int, log io.Writer) {
+	if g == nil {
+		return
+	}
+	g.r.Close()
+	for _, w := range applyLimits(pid, g.limits) {
+		fmt.Fprintf(log, "[gopm] limits: %s\n", w)
+	}
+	g.w.Close()
+}
+
+// close abandons the gate, e.g. when the process failed to start.
+func (g *limitsGate) close() {
+	if g == nil {
+		return
+	}
+	g.r.Close()
+	g.w.Close()
+}
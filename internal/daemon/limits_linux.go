@@ -0,0 +1,118 @@
+//go:build linux
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"github.com/7c/gopm/internal/procinspect"
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// Resource numbers for prlimit(2), as on amd64 and arm64. syscall only
+// defines some of them.
+const (
+	rlimitCore    = 4
+	rlimitNproc   = 6
+	rlimitNofile  = 7
+	rlimitMemlock = 8
+)
+
+// ioprioWhoProcess is IOPRIO_WHO_PROCESS for ioprio_set(2).
+const ioprioWhoProcess = 1
+
+// applyLimits applies l to the process pid, except umask, which
+// limitsScript sets from inside. It returns a warning for each setting that
+// could not be applied.
+func applyLimits(pid int, l *protocol.Limits) []string {
+	var warnings []string
+	warn := func(name string, err error) {
+		warnings = append(warnings, fmt.Sprintf("%s not applied: %v", name, err))
+	}
+
+	for _, r := range []struct {
+		name     string
+		value    string
+		resource int
+		size     bool
+	}{
+		{"nofile", l.Nofile, rlimitNofile, false},
+		{"nproc", l.Nproc, rlimitNproc, false},
+		{"core", l.Core, rlimitCore, true},
+		{"memlock", l.Memlock, rlimitMemlock, true},
+	} {
+		if r.value == "" {
+			continue
+		}
+		soft, hard, err := protocol.ParseRlimit(r.value, r.size)
+		if err == nil {
+			err = prlimit(pid, r.resource, soft, hard)
+		}
+		if err != nil {
+			warn(r.name, err)
+		}
+	}
+
+	if l.Nice != nil {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, *l.Nice); err != nil {
+			warn("nice", err)
+		}
+	}
+	if class, level, ok := l.IONice(); ok {
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(pid), uintptr(class<<13|level)); errno != 0 {
+			warn("ionice", errno)
+		}
+	}
+	if l.CPUAffinity != "" {
+		cpus, err := protocol.ParseCPUList(l.CPUAffinity)
+		if err == nil {
+			err = setAffinity(pid, cpus)
+		}
+		if err != nil {
+			warn("cpu_affinity", err)
+		}
+	}
+	if l.OOMScoreAdj != nil {
+		path := fmt.Sprintf("/proc/%d/oom_score_adj", pid)
+		if err := os.WriteFile(path, []byte(strconv.Itoa(*l.OOMScoreAdj)), 0); err != nil {
+			warn("oom_score_adj", err)
+		}
+	}
+	return warnings
+}
+
+// prlimit sets a resource limit of another process.
+func prlimit(pid, resource int, soft, hard uint64) error {
+	lim := struct{ cur, max uint64 }{soft, hard}
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource), uintptr(unsafe.Pointer(&lim)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// setAffinity restricts a process to the given CPUs.
+func setAffinity(pid int, cpus []int) error {
+	var mask [protocol.MaxCPUs / 64]uint64
+	for _, c := range cpus {
+		mask[c/64] |= 1 << (c % 64)
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(pid), unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// effectiveLimits reads back the settings of a running process that has
+// limits configured, for describe. It returns nil otherwise.
+func effectiveLimits(info protocol.ProcessInfo) map[string]string {
+	if info.Limits == nil || !info.Status.Running() || info.PID <= 0 {
+		return nil
+	}
+	return procinspect.ReadLimits(info.PID)
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package daemon
+
+import "github.com/7c/gopm/internal/protocol"
+
+// applyLimits only supports umask, which limitsScript sets, off Linux.
+func applyLimits(pid int, l *protocol.Limits) []string {
+	for name := range l.Values() {
+		if name != "umask" {
+			return []string{"only umask is supported off Linux"}
+		}
+	}
+	return nil
+}
+
+func effectiveLimits(info protocol.ProcessInfo) map[string]string { return nil }
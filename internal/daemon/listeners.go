@@ -3,8 +3,6 @@ package daemon
 import (
 	"log/slog"
 	"time"
-
-	"github.com/7c/gopm/internal/protocol"
 )
 
 const listenersInterval = 60 * time.Second
@@ -37,7 +35,7 @@ func (d *Daemon) doListenerScan() {
 
 	for _, p := range procs {
 		p.mu.Lock()
-		if p.info.Status != protocol.StatusOnline || p.info.PID == 0 {
+		if !p.info.Status.Running() || p.info.PID == 0 {
 			p.mu.Unlock()
 			continue
 		}
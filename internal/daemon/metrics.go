@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"fmt"
 	"log/slog"
 	"syscall"
 	"time"
@@ -32,7 +33,7 @@ func (d *Daemon) sampleMetrics() {
 
 			for _, p := range procs {
 				p.mu.Lock()
-				if p.info.Status != protocol.StatusOnline || p.info.PID == 0 {
+				if !p.info.Status.Running() || p.info.PID == 0 {
 					p.mu.Unlock()
 					continue
 				}
@@ -68,7 +69,24 @@ func (d *Daemon) sampleMetrics() {
 				}
 				p.lastTicks = cpuTicks
 				p.lastSample = now
+
+				// Memory limit: restart once RSS has stayed above
+				// max_memory for the grace period.
+				memExceeded := false
+				limit := p.info.RestartPolicy.MaxMemory
+				if limit > 0 && rss > uint64(limit) {
+					if p.memOverSince.IsZero() {
+						p.memOverSince = now
+					}
+					memExceeded = now.Sub(p.memOverSince) >= p.info.RestartPolicy.MaxMemoryGrace.Duration
+				} else {
+					p.memOverSince = time.Time{}
+				}
 				p.mu.Unlock()
+
+				if memExceeded {
+					go d.restartForMemory(p, rss, limit)
+				}
 			}
 
 			// Emit telegraf metrics
@@ -94,3 +112,33 @@ func (d *Daemon) sampleMetrics() {
 		}
 	}
 }
+
+// restartForMemory kills a process whose RSS exceeded max_memory so the
+// supervisor restarts it, and counts the restart once it is on its way.
+func (d *Daemon) restartForMemory(p *Process, rss uint64, limit int64) {
+	p.mu.Lock()
+	if p.stopping || p.restartReason != "" {
+		// Already on its way down (e.g. a previous sample triggered this).
+		p.mu.Unlock()
+		return
+	}
+	name := p.info.Name
+	p.mu.Unlock()
+
+	reason := memoryRestartReason(rss, limit)
+	p.LogAction("%s, restarting", reason)
+	slog.Warn("memory limit exceeded, restarting", "name", name, "rss", rss, "max_memory", limit)
+	if !p.ForceRestart(reason) {
+		return
+	}
+	p.mu.Lock()
+	p.info.MemoryRestarts++
+	p.mu.Unlock()
+}
+
+// memoryRestartReason is the restart reason for a process whose RSS
+// exceeded limit, in whole MB, e.g. "memory limit exceeded: 812 MB > 512 MB".
+func memoryRestartReason(rss uint64, limit int64) string {
+	const mb = 1024 * 1024
+	return fmt.Sprintf("memory limit exceeded: %d MB > %d MB", rss/mb, uint64(limit)/mb)
+}
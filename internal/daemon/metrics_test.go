@@ -0,0 +1,23 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestMemoryRestartReason(t *testing.T) {
+	got := memoryRestartReason(812*1024*1024+300*1024, 512*1024*1024)
+	if want := "memory limit exceeded: 812 MB > 512 MB"; got != want {
+		t.Errorf("reason = %q, want %q", got, want)
+	}
+}
+
+func TestRestartForMemoryCountsOnlyKills(t *testing.T) {
+	// A process that already exited can't be restarted for its memory.
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/sleep", Name: "api"})
+	(&Daemon{}).restartForMemory(p, 2048, 1024)
+	if n := p.Info().MemoryRestarts; n != 0 {
+		t.Errorf("MemoryRestarts = %d, want 0", n)
+	}
+}
@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// notifySeq numbers notify sockets so each run of a process gets its own.
+var notifySeq atomic.Int64
+
+// openNotify creates the NOTIFY_SOCKET for a run of p. Must be called with
+// p.mu held.
+func (p *Process) openNotify() error {
+	dir := filepath.Join(protocol.GopmHome(), "notify")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("create notify socket: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%d-%d.sock", os.Getpid(), notifySeq.Add(1)))
+	os.Remove(path)
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("create notify socket: %w", err)
+	}
+	if ra := p.runAs; ra != nil {
+		// The process runs as another user, who must reach the socket.
+		os.Chmod(dir, 0711)
+		os.Chown(path, ra.info.UID, ra.info.GID)
+	}
+	p.notify = conn
+	p.notifyPath = path
+	return nil
+}
+
+// closeNotify closes the notify socket of the last run, if any. Must be
+// called with p.mu held.
+func (p *Process) closeNotify() {
+	if p.notify == nil {
+		return
+	}
+	p.notify.Close()
+	os.Remove(p.notifyPath)
+	p.notify = nil
+	p.notifyPath = ""
+}
+
+// notifyEnv returns the environment for a process that speaks the notify
+// protocol. Must be called with p.mu held.
+func (p *Process) notifyEnv() []string {
+	env := []string{"NOTIFY_SOCKET=" + p.notifyPath}
+	if wd, err := protocol.ParseWatchdogSec(p.info.WatchdogSec); err == nil && wd > 0 {
+		env = append(env, fmt.Sprintf("WATCHDOG_USEC=%d", wd.Microseconds()))
+	}
+	return env
+}
+
+// runNotify reads the notify messages of one run of p until its socket is
+// closed. READY=1 makes the process online, STATUS= is shown in describe,
+// and with watchdog_sec a missing WATCHDOG=1 ping (or WATCHDOG=trigger)
+// has the process restarted via ForceRestart.
+func (d *Daemon) runNotify(p *Process, conn *net.UnixConn) {
+	p.mu.Lock()
+	name := p.info.Name
+	watchdog, _ := protocol.ParseWatchdogSec(p.info.WatchdogSec)
+	p.mu.Unlock()
+
+	buf := make([]byte, 4096)
+	lastPing := time.Now()
+	for {
+		if watchdog > 0 {
+			conn.SetReadDeadline(lastPing.Add(watchdog))
+		}
+		n, _, err := conn.ReadFromUnix(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				reason := fmt.Sprintf("watchdog timeout (no WATCHDOG=1 within %s)", watchdog)
+				if p.ForceRestart(reason) {
+					p.LogAction("%s, restarting", reason)
+					slog.Warn("watchdog timeout", "name", name, "watchdog", watchdog)
+				}
+			}
+			return
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			key, value, _ := strings.Cut(line, "=")
+			switch key {
+			case "READY":
+				if value == "1" && p.markReady() {
+					p.LogAction("process ready (READY=1)")
+					slog.Info("process ready", "name", name)
+					d.autoSave("process ready")
+				}
+			case "STATUS":
+				p.mu.Lock()
+				p.info.NotifyStatus = value
+				p.mu.Unlock()
+			case "WATCHDOG":
+				switch value {
+				case "1":
+					lastPing = time.Now()
+				case "trigger":
+					reason := "watchdog triggered by the process"
+					if p.ForceRestart(reason) {
+						p.LogAction("%s, restarting", reason)
+					}
+				}
+			}
+		}
+	}
+}
+
+// markReady moves a starting process to online, reporting whether it was
+// starting.
+func (p *Process) markReady() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.info.Status != protocol.StatusStarting {
+		return false
+	}
+	p.setStatus(protocol.StatusOnline)
+	return true
+}
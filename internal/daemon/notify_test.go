@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func sendNotify(t *testing.T, path, msg string) {
+	t.Helper()
+	c, err := net.Dial("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunNotify(t *testing.T) {
+	t.Setenv("GOPM_HOME", t.TempDir())
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/sleep", Name: "api", Notify: true})
+	p.mu.Lock()
+	if err := p.openNotify(); err != nil {
+		p.mu.Unlock()
+		t.Fatal(err)
+	}
+	p.info.Status = protocol.StatusStarting
+	path := p.notifyPath
+	conn := p.notify
+	env := p.notifyEnv()
+	p.mu.Unlock()
+
+	if want := []string{"NOTIFY_SOCKET=" + path}; !reflect.DeepEqual(env, want) {
+		t.Errorf("notifyEnv() = %v, want %v", env, want)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		(&Daemon{}).runNotify(p, conn)
+		close(done)
+	}()
+
+	sendNotify(t, path, "STATUS=loading cache")
+	waitFor(t, "STATUS=", func() bool { return p.Info().NotifyStatus == "loading cache" })
+	if s := p.Info().Status; s != protocol.StatusStarting {
+		t.Errorf("status before READY=1 = %s, want starting", s)
+	}
+
+	sendNotify(t, path, "READY=1\nSTATUS=serving")
+	waitFor(t, "READY=1", func() bool { return p.Info().Status == protocol.StatusOnline })
+	waitFor(t, "STATUS=", func() bool { return p.Info().NotifyStatus == "serving" })
+
+	p.mu.Lock()
+	p.closeNotify()
+	p.mu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("runNotify did not return after the socket was closed")
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("notify socket not removed on close: %v", err)
+	}
+}
+
+func TestNotifyEnvWatchdog(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/sleep", Notify: true, WatchdogSec: "30"})
+	p.notifyPath = "/tmp/n.sock"
+	env := p.notifyEnv()
+	want := []string{"NOTIFY_SOCKET=/tmp/n.sock", "WATCHDOG_USEC=30000000"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("notifyEnv() = %v, want %v", env, want)
+	}
+}
@@ -2,13 +2,17 @@ package daemon
 
 import (
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/7c/gopm/internal/cron"
 	"github.com/7c/gopm/internal/logwriter"
 	"github.com/7c/gopm/internal/protocol"
 )
@@ -23,9 +27,60 @@ type Process struct {
 	stdout   *logwriter.TimestampWriter
 	stderr   *logwriter.TimestampWriter
 
+	// restartReason is set when the daemon kills the process to force a
+	// restart (e.g. failed health check). monitor consumes it.
+	restartReason string
+
+	// waitCh is closed to cancel waiting for depends_on (status waiting).
+	waitCh chan struct{}
+	// restartCh is closed to cancel a pending restart (status
+	// waiting_restart, crashloop or launching).
+	restartCh chan struct{}
+
+	// restartTimes are the restarts within the restart budget window,
+	// oldest first (see spendRestart).
+	restartTimes []time.Time
+
+	// candidate is set on a reload replacement until it takes over; monitor
+	// does not restart it if it exits before then.
+	candidate bool
+	// ready watches stdout for the ready_message, if one is set.
+	ready *readyWriter
+	// runAs is the user the current run was started as; nil for the
+	// daemon's own.
+	runAs *runAs
+	// sockets are the listening sockets the daemon holds for the process,
+	// passed to it on every start.
+	sockets []*os.File
+	// notify is the NOTIFY_SOCKET of the current run, if info.Notify.
+	notify     *net.UnixConn
+	notifyPath string
+
+	// cgroups is the daemon's cgroup manager, nil when cgroup v2
+	// delegation is unavailable.
+	cgroups *cgroupManager
+	// cgroupDir is the cgroup to start in instead of the one of the
+	// process's name, for a reload replacement.
+	cgroupDir string
+	// events is the daemon's event bus, nil in tests.
+	events *eventBus
+
+	// history is the start and exit events, oldest first, capped at
+	// maxHistory.
+	history []protocol.ProcessEvent
+
+	// Scheduled job state (info.Schedule set).
+	runs      []protocol.RunRecord // oldest first, capped at maxRuns
+	nextRunID int
+	runQueued bool        // a run is waiting for the current one to exit
+	runOutput *tailBuffer // captures the current run's output
+
 	// Metrics tracking
 	lastTicks  uint64
 	lastSample time.Time
+	// memOverSince is when RSS first exceeded max_memory in the current
+	// streak; zero while under the limit.
+	memOverSince time.Time
 }
 
 // NewProcess creates a new Process from StartParams.
@@ -61,17 +116,58 @@ func NewProcess(id int, params protocol.StartParams) *Process {
 			policy.KillTimeout = protocol.Duration{Duration: d}
 		}
 	}
+	if params.KillSignal != "" {
+		if sig, err := protocol.ParseSignal(params.KillSignal); err == nil {
+			policy.KillSignal = int(sig)
+		}
+	}
+	policy.RestartOnExit = params.RestartOnExit
+	policy.NoRestartOnExit = params.NoRestartOnExit
+	if params.MaxMemory != "" {
+		if s, err := protocol.ParseSize(params.MaxMemory); err == nil {
+			policy.MaxMemory = s
+		}
+	}
+	if params.MaxMemoryGrace != "" {
+		if d, err := time.ParseDuration(params.MaxMemoryGrace); err == nil {
+			policy.MaxMemoryGrace = protocol.Duration{Duration: d}
+		}
+	}
+	if params.RestartBudget != "" {
+		if n, window, err := protocol.ParseRestartBudget(params.RestartBudget); err == nil {
+			policy.RestartBudget = n
+			policy.RestartWindow = protocol.Duration{Duration: window}
+		}
+	}
+	if params.CrashLoopCooldown != "" {
+		if d, err := time.ParseDuration(params.CrashLoopCooldown); err == nil {
+			policy.CrashLoopCooldown = protocol.Duration{Duration: d}
+		}
+	}
+	policy.BackoffJitter = params.BackoffJitter
 
 	name := params.Name
 	if name == "" {
 		name = filepath.Base(params.Command)
 	}
 
+	overlap := protocol.OverlapPolicy(params.Overlap)
+	if params.Schedule != "" && overlap == "" {
+		overlap = protocol.OverlapSkip
+	}
+
 	cwd := params.Cwd
 	if cwd == "" {
 		cwd, _ = os.Getwd()
 	}
 
+	var reloadSignal int
+	if params.ReloadSignal != "" {
+		if sig, err := protocol.ParseSignal(params.ReloadSignal); err == nil {
+			reloadSignal = int(sig)
+		}
+	}
+
 	var maxLogSize int64 = 1048576 // 1MB
 	if params.MaxLogSize != "" {
 		if s, err := protocol.ParseSize(params.MaxLogSize); err == nil {
@@ -97,12 +193,37 @@ func NewProcess(id int, params protocol.StartParams) *Process {
 			Cwd:           cwd,
 			Env:           params.Env,
 			Interpreter:   params.Interpreter,
+			Labels:        params.Labels,
 			Status:        protocol.StatusStopped,
 			RestartPolicy: policy,
 			CreatedAt:     time.Now(),
 			LogOut:        logOut,
 			LogErr:        logErr,
 			MaxLogSize:    maxLogSize,
+			HealthCheck:   params.HealthCheck,
+			Cgroup:        params.Cgroup,
+			Limits:        params.Limits,
+			CronRestart:   params.CronRestart,
+			Schedule:      params.Schedule,
+			Overlap:       overlap,
+			Watch:         params.Watch,
+			WatchPaths:    params.WatchPaths,
+			IgnoreWatch:   params.IgnoreWatch,
+			WatchDelay:    params.WatchDelay,
+			DependsOn:     params.DependsOn,
+			ReadyMessage:  params.ReadyMessage,
+			Sockets:       params.Sockets,
+			Notify:        params.Notify,
+			WatchdogSec:   params.WatchdogSec,
+			Hooks:         params.Hooks,
+			StopCommand:   params.StopCommand,
+			ReloadSignal:  reloadSignal,
+			User:          params.User,
+			Group:         params.Group,
+			Groups:        params.Groups,
+			App:           params.App,
+			InstanceID:    params.InstanceID,
+			Instances:     params.Instances,
 		},
 	}
 }
@@ -121,87 +242,358 @@ func (p *Process) Info() protocol.ProcessInfo {
 	if info.Listeners == nil {
 		info.Listeners = []string{}
 	}
+	if n := len(p.runs); n > 0 {
+		last := p.runs[n-1]
+		last.Output = ""
+		info.LastRun = &last
+	}
 	return info
 }
 
-// Start launches the process.
+// Start runs the pre_start hook, if any, and launches the process. If the
+// hook fails the process is not started but marked errored, and a
+// *hookError is returned.
 func (p *Process) Start() error {
+	p.mu.Lock()
+	pre := p.hook(protocol.HookPreStart)
+	p.mu.Unlock()
+	if err := pre.run(); err != nil {
+		herr := &hookError{hook: protocol.HookPreStart, err: err}
+		p.mu.Lock()
+		p.info.PID = 0
+		p.setStatus(protocol.StatusErrored)
+		p.info.StatusReason = herr.Error()
+		p.mu.Unlock()
+		return herr
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.start(); err != nil {
+		return err
+	}
+	go p.hook(protocol.HookPostStart).run()
+	return nil
+}
+
+// startPending launches the process for the pending restart cancel belongs
+// to, unless Stop cancelled it, after running the pre_start hook. It reports
+// whether the process was started or its hook failed.
+func (p *Process) startPending(cancel chan struct{}) (bool, error) {
+	p.mu.Lock()
+	if p.restartCh != cancel {
+		p.mu.Unlock()
+		return false, nil
+	}
+	pre := p.hook(protocol.HookPreStart)
+	p.mu.Unlock()
+	hookErr := pre.run()
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if p.restartCh != cancel {
+		return false, nil
+	}
+	p.restartCh = nil
+	if hookErr != nil {
+		return true, &hookError{hook: protocol.HookPreStart, err: hookErr}
+	}
+	if err := p.start(); err != nil {
+		return true, err
+	}
+	go p.hook(protocol.HookPostStart).run()
+	return true, nil
+}
+
+// start launches the process. Must be called with p.mu held.
+func (p *Process) start() error {
+	ra, err := resolveCredential(p.info.User, p.info.Group, p.info.Groups)
+	if err != nil {
+		return fmt.Errorf("start process: %w", err)
+	}
+	p.runAs = ra
+	if err := p.openLogWriters(); err != nil {
+		return err
+	}
+
+	// Place the process in its own cgroup when the daemon manages a
+	// cgroup v2 subtree. Any failure falls back to starting without one.
+	cgroupFD := -1
+	p.info.CgroupPath = ""
+	if p.cgroups != nil {
+		path := p.cgroupDir
+		if path == "" {
+			path = p.cgroups.Path(p.info.Name)
+		}
+		warnings, err := p.cgroups.Prepare(path, p.info.Cgroup)
+		for _, w := range warnings {
+			fmt.Fprintf(p.stderr, "[gopm] cgroup: %s\n", w)
+		}
+		if err == nil {
+			cgroupFD, err = openCgroup(path)
+		}
+		if err != nil {
+			fmt.Fprintf(p.stderr, "[gopm] cgroup setup failed, starting without resource limits: %v\n", err)
+		} else {
+			p.info.CgroupPath = path
+		}
+	} else if p.info.Cgroup != nil {
+		fmt.Fprintf(p.stderr, "[gopm] cgroup limits ignored: cgroup v2 delegation not available\n")
+	}
+
+	p.ready = nil
+	if p.info.ReadyMessage != "" {
+		p.ready = newReadyWriter(p.info.ReadyMessage)
+	}
+
+	p.closeNotify()
+	if p.info.Notify {
+		if err := p.openNotify(); err != nil {
+			if cgroupFD >= 0 {
+				syscall.Close(cgroupFD)
+			}
+			p.stdout.Underlying().Close()
+			p.stderr.Underlying().Close()
+			return err
+		}
+	}
+
+	cmd := p.buildCmd()
+	gate := p.holdForLimits(cmd)
+	if cgroupFD >= 0 {
+		setCgroupFD(cmd.SysProcAttr, cgroupFD)
+	}
+	err = cmd.Start()
+	if cgroupFD >= 0 {
+		syscall.Close(cgroupFD)
+		if cgroupStartUnsupported(err) {
+			// Kernels before 5.7 can't start a child directly in a cgroup.
+			fmt.Fprintf(p.stderr, "[gopm] cannot start in cgroup, starting without resource limits: %v\n", err)
+			p.info.CgroupPath = ""
+			gate.close()
+			cmd = p.buildCmd()
+			gate = p.holdForLimits(cmd)
+			err = cmd.Start()
+		}
+	}
+	if err != nil {
+		gate.close()
+		p.closeNotify()
+		p.stdout.Underlying().Close()
+		p.stderr.Underlying().Close()
+		return fmt.Errorf("start process: %w", err)
+	}
+
+	p.cmd = cmd
+	p.exitCh = make(chan struct{})
+	p.stopping = false
+	p.info.PID = cmd.Process.Pid
+	gate.release(p.info.PID, p.stderr)
+	p.info.RunAs = currentCredential()
+	if ra != nil {
+		p.info.RunAs = &ra.info
+	}
+	p.info.StatusReason = ""
+	p.info.NotifyStatus = ""
+	p.info.Uptime = time.Now()
+	start := protocol.ProcessEvent{Time: p.info.Uptime, Type: protocol.EventStart, PID: p.info.PID}
+	p.addEvent(start)
+	p.publish(protocol.Event{ProcessEvent: start})
+	if p.info.Notify {
+		// Online once it sends READY=1 (see runNotify).
+		p.setStatus(protocol.StatusStarting)
+	} else {
+		p.setStatus(protocol.StatusOnline)
+	}
+	p.info.Health = ""
+	p.info.HealthFailures = 0
+	if p.info.HealthCheck != nil {
+		p.info.Health = protocol.HealthStarting
+	}
+	p.lastSample = time.Now()
+	p.lastTicks = 0
+	p.memOverSince = time.Time{}
+	p.info.NextCronRestart = nil
+	if p.info.CronRestart != "" {
+		// handleStart validates the expression; a bad one here (e.g. from
+		// a hand-edited dump) just disables the schedule.
+		if sched, err := cron.Parse(p.info.CronRestart); err == nil {
+			if next := sched.Next(time.Now()); !next.IsZero() {
+				p.info.NextCronRestart = &next
+			}
+		}
+	}
 
+	return nil
+}
+
+// logFiles is the number of rotated files kept of each log.
+const logFiles = 3
+
+// openLogWriters opens the stdout/stderr logs with timestamps. Must be called
+// with p.mu held.
+func (p *Process) openLogWriters() error {
 	// Ensure log directory exists
 	os.MkdirAll(filepath.Dir(p.info.LogOut), 0755)
 
-	// Set up log writers with timestamps
-	var err error
-	outRot, err := logwriter.New(p.info.LogOut, p.info.MaxLogSize, 3)
+	outRot, err := logwriter.New(p.info.LogOut, p.info.MaxLogSize, logFiles)
 	if err != nil {
 		return fmt.Errorf("open stdout log: %w", err)
 	}
-	errRot, err := logwriter.New(p.info.LogErr, p.info.MaxLogSize, 3)
+	errRot, err := logwriter.New(p.info.LogErr, p.info.MaxLogSize, logFiles)
 	if err != nil {
 		outRot.Close()
 		return fmt.Errorf("open stderr log: %w", err)
 	}
 	p.stdout = logwriter.NewTimestampWriter(outRot)
 	p.stderr = logwriter.NewTimestampWriter(errRot)
+	if ra := p.runAs; ra != nil {
+		// The logs belong to the user the process runs as.
+		for _, w := range []*logwriter.RotatingWriter{outRot, errRot} {
+			if err := w.Chown(ra.info.UID, ra.info.GID); err != nil {
+				fmt.Fprintf(p.stderr, "[gopm] cannot give log file to %s: %v\n", ra.info.User, err)
+			}
+		}
+	}
+	return nil
+}
 
-	// Build command
-	var cmd *exec.Cmd
+// buildCmd builds the exec.Cmd for the process. Must be called with p.mu held
+// and log writers open.
+func (p *Process) buildCmd() *exec.Cmd {
+	name, args := p.info.Command, p.info.Args
 	if p.info.Interpreter != "" {
-		args := append([]string{p.info.Command}, p.info.Args...)
-		cmd = exec.Command(p.info.Interpreter, args...)
-	} else {
-		cmd = exec.Command(p.info.Command, p.info.Args...)
+		name, args = p.info.Interpreter, append([]string{p.info.Command}, p.info.Args...)
+	}
+	cmd := exec.Command(name, args...)
+	if len(p.sockets) > 0 && cmd.Err == nil {
+		// LISTEN_PID must be the child's own PID, so a shell sets it and
+		// execs the command in its place.
+		cmd = exec.Command("/bin/sh", append([]string{"-c", listenPIDScript, cmd.Path}, args...)...)
+		cmd.ExtraFiles = p.sockets
 	}
 
 	cmd.Dir = p.info.Cwd
 	cmd.Stdout = p.stdout
 	cmd.Stderr = p.stderr
+	if p.runOutput != nil {
+		cmd.Stdout = io.MultiWriter(p.stdout, p.runOutput)
+		cmd.Stderr = io.MultiWriter(p.stderr, p.runOutput)
+	}
+	if p.ready != nil {
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, p.ready)
+	}
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if p.runAs != nil {
+		cmd.SysProcAttr.Credential = p.runAs.cred
+	}
 
 	// Build environment
-	if len(p.info.Env) > 0 {
+	if len(p.info.Env) > 0 || p.info.App != "" || len(p.sockets) > 0 || p.notify != nil || p.runAs != nil {
 		env := os.Environ()
+		if p.runAs != nil {
+			env = append(env, p.runAs.env()...)
+		}
 		for k, v := range p.info.Env {
 			env = append(env, fmt.Sprintf("%s=%s", k, v))
 		}
+		// Instances of a group learn which one they are, under gopm's
+		// name and the one Node.js cluster-aware code reads.
+		if p.info.App != "" {
+			id := strconv.Itoa(p.info.InstanceID)
+			env = append(env, "GOPM_INSTANCE_ID="+id, "NODE_APP_INSTANCE="+id)
+		}
+		if len(p.sockets) > 0 {
+			env = append(env, socketEnv(p.info)...)
+		}
+		if p.notify != nil {
+			env = append(env, p.notifyEnv()...)
+		}
 		cmd.Env = env
 	}
+	return cmd
+}
 
-	if err := cmd.Start(); err != nil {
-		p.stdout.Underlying().Close()
-		p.stderr.Underlying().Close()
-		return fmt.Errorf("start process: %w", err)
+// Stop sends SIGTERM then SIGKILL after timeout. A scheduled job is also
+// disarmed, so no further runs start, a process waiting for its
+// dependencies stops waiting, and a pending restart is cancelled.
+func (p *Process) Stop() error {
+	p.mu.Lock()
+	p.info.NextRun = nil
+	p.runQueued = false
+	if p.waitCh != nil {
+		close(p.waitCh)
+		p.waitCh = nil
 	}
+	if p.restartCh != nil {
+		close(p.restartCh)
+		p.restartCh = nil
+		p.info.RestartAt = nil
+	}
+	switch p.info.Status {
+	case protocol.StatusScheduled, protocol.StatusWaiting, protocol.StatusWaitingRestart, protocol.StatusCrashLoop, protocol.StatusLaunching:
+		p.setStatus(protocol.StatusStopped)
+		p.info.StatusReason = "stopped by user"
+		p.mu.Unlock()
+		return nil
+	}
+	if !p.info.Status.Running() || p.cmd == nil {
+		p.mu.Unlock()
+		return nil
+	}
+	p.stopping = true
+	p.setStatus(protocol.StatusStopping)
+	pid := p.info.PID
+	exitCh := p.exitCh
+	killTimeout := p.info.RestartPolicy.KillTimeout.Duration
+	killSignal := p.info.RestartPolicy.KillSignal
+	cgroupPath := p.info.CgroupPath
+	pre := p.hook(protocol.HookPreStop)
+	stop := p.stopCommand()
+	p.mu.Unlock()
 
-	p.cmd = cmd
-	p.exitCh = make(chan struct{})
-	p.stopping = false
-	p.info.PID = cmd.Process.Pid
-	p.info.Status = protocol.StatusOnline
-	p.info.StatusReason = ""
-	p.info.Uptime = time.Now()
-	p.lastSample = time.Now()
-	p.lastTicks = 0
-
+	pre.run()
+	stop.run()
+	terminate(pid, exitCh, killSignal, killTimeout, cgroupPath)
 	return nil
 }
 
-// Stop sends SIGTERM then SIGKILL after timeout.
-func (p *Process) Stop() error {
+// ForceRestart kills the running process without marking it as stopped by
+// the user, so monitor hands the exit to the restart logic. The reason is
+// recorded and bypasses the autorestart and exit code filters. It is a no-op
+// if the process is not running or is already being stopped or restarted,
+// and reports whether the restart was initiated.
+func (p *Process) ForceRestart(reason string) bool {
 	p.mu.Lock()
-	if p.info.Status != protocol.StatusOnline || p.cmd == nil {
+	if !p.info.Status.Running() || p.cmd == nil || p.stopping || p.restartReason != "" {
 		p.mu.Unlock()
-		return nil
+		return false
 	}
-	p.stopping = true
+	p.restartReason = reason
 	pid := p.info.PID
 	exitCh := p.exitCh
 	killTimeout := p.info.RestartPolicy.KillTimeout.Duration
 	killSignal := p.info.RestartPolicy.KillSignal
+	cgroupPath := p.info.CgroupPath
+	stop := p.stopCommand()
 	p.mu.Unlock()
 
+	stop.run()
+	terminate(pid, exitCh, killSignal, killTimeout, cgroupPath)
+	return true
+}
+
+// terminate sends killSignal to the process group and escalates to SIGKILL
+// if exitCh is not closed within killTimeout. The SIGKILL also covers the
+// process's cgroup, if any, to catch children that left the process group.
+// Nothing is sent if the process has already exited, e.g. by its
+// stop_command.
+func terminate(pid int, exitCh chan struct{}, killSignal int, killTimeout time.Duration, cgroupPath string) {
+	select {
+	case <-exitCh:
+		return
+	default:
+	}
 	if killTimeout == 0 {
 		killTimeout = 5 * time.Second
 	}
@@ -214,25 +606,17 @@ func (p *Process) Stop() error {
 
 	select {
 	case <-exitCh:
-		return nil
 	case <-time.After(killTimeout):
 		// Escalate to SIGKILL
 		syscall.Kill(-pid, syscall.SIGKILL)
+		killCgroup(cgroupPath)
 		<-exitCh
-		return nil
 	}
 }
 
-// Wait blocks until the process exits. Returns the exit code.
-func (p *Process) Wait() int {
-	err := p.cmd.Wait()
-	exitCode := 0
-	if exitErr, ok := err.(*exec.ExitError); ok {
-		exitCode = exitErr.ExitCode()
-	} else if err != nil {
-		exitCode = -1
-	}
-	return exitCode
+// Wait blocks until the process exits and returns how it exited.
+func (p *Process) Wait() exitStatus {
+	return exitStatusOf(p.cmd.Wait())
 }
 
 // MarkExited updates process state after exit.
@@ -243,7 +627,9 @@ func (p *Process) MarkExited(exitCode int, status protocol.Status) {
 	p.info.PID = 0
 	p.info.CPU = 0
 	p.info.Memory = 0
-	p.info.Status = status
+	p.setStatus(status)
+	p.info.Health = ""
+	p.info.NextCronRestart = nil
 }
 
 // SetReason sets the status reason (why a process stopped/errored).
@@ -253,6 +639,38 @@ func (p *Process) SetReason(reason string) {
 	p.info.StatusReason = reason
 }
 
+// cronDue reports whether the process is online and its scheduled cron
+// restart time has passed. It clears the schedule so a restart fires once;
+// the next Start computes the following one.
+func (p *Process) cronDue(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	next := p.info.NextCronRestart
+	if next == nil || !p.info.Status.Running() || p.stopping || p.restartReason != "" {
+		return false
+	}
+	if now.Before(*next) {
+		return false
+	}
+	p.info.NextCronRestart = nil
+	return true
+}
+
+// setHealth records the outcome of a health check run.
+func (p *Process) setHealth(state protocol.HealthState, failures int, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if state != p.info.Health {
+		p.publish(protocol.Event{
+			ProcessEvent: protocol.ProcessEvent{Type: protocol.EventHealth, PID: p.info.PID, Reason: message},
+			Health:       state,
+		})
+	}
+	p.info.Health = state
+	p.info.HealthFailures = failures
+	p.info.HealthMessage = message
+}
+
 // LogAction writes a daemon action message to the process's stderr log.
 // Messages are prefixed with [gopm] and get a timestamp from TimestampWriter.
 func (p *Process) LogAction(format string, args ...interface{}) {
@@ -0,0 +1,181 @@
+package daemon
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestNewProcessMaxMemory(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{
+		Command:        "/bin/sleep",
+		MaxMemory:      "512M",
+		MaxMemoryGrace: "30s",
+	})
+	rp := p.info.RestartPolicy
+	if rp.MaxMemory != 512*1024*1024 {
+		t.Errorf("MaxMemory = %d, want %d", rp.MaxMemory, 512*1024*1024)
+	}
+	if rp.MaxMemoryGrace.Duration != 30*time.Second {
+		t.Errorf("MaxMemoryGrace = %v, want 30s", rp.MaxMemoryGrace.Duration)
+	}
+
+	// Unset max_memory must not pick up ParseSize's 1M default.
+	p = NewProcess(0, protocol.StartParams{Command: "/bin/sleep"})
+	if p.info.RestartPolicy.MaxMemory != 0 {
+		t.Errorf("MaxMemory = %d, want 0", p.info.RestartPolicy.MaxMemory)
+	}
+}
+
+func TestInfoToStartParamsMaxMemory(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{
+		Command:        "/bin/sleep",
+		MaxMemory:      "1G",
+		MaxMemoryGrace: "1m",
+	})
+	params := infoToStartParams(p.Info())
+	restored := NewProcess(0, params)
+	if restored.info.RestartPolicy.MaxMemory != p.info.RestartPolicy.MaxMemory {
+		t.Errorf("MaxMemory = %d, want %d", restored.info.RestartPolicy.MaxMemory, p.info.RestartPolicy.MaxMemory)
+	}
+	if restored.info.RestartPolicy.MaxMemoryGrace != p.info.RestartPolicy.MaxMemoryGrace {
+		t.Errorf("MaxMemoryGrace = %v, want %v", restored.info.RestartPolicy.MaxMemoryGrace, p.info.RestartPolicy.MaxMemoryGrace)
+	}
+}
+
+func TestInfoToStartParamsStopBehaviour(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{
+		Command:         "/bin/sleep",
+		KillSignal:      "quit",
+		StopCommand:     "kill -USR2 $GOPM_PID",
+		ReloadSignal:    "SIGHUP",
+		RestartOnExit:   []int{1, 2},
+		NoRestartOnExit: []int{0, 78},
+	})
+	rp := p.info.RestartPolicy
+	if rp.KillSignal != int(syscall.SIGQUIT) || p.info.ReloadSignal != int(syscall.SIGHUP) {
+		t.Fatalf("kill signal %d, reload signal %d, want SIGQUIT and SIGHUP", rp.KillSignal, p.info.ReloadSignal)
+	}
+
+	restored := NewProcess(0, infoToStartParams(p.Info()))
+	if !reflect.DeepEqual(restored.info.RestartPolicy, rp) {
+		t.Errorf("RestartPolicy = %+v, want %+v", restored.info.RestartPolicy, rp)
+	}
+	if restored.info.StopCommand != p.info.StopCommand || restored.info.ReloadSignal != p.info.ReloadSignal {
+		t.Errorf("stop command %q, reload signal %d, want %q, %d", restored.info.StopCommand, restored.info.ReloadSignal, p.info.StopCommand, p.info.ReloadSignal)
+	}
+}
+
+func TestProcessCronDue(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{
+		Command:     "/bin/sleep",
+		CronRestart: "0 3 * * *",
+	})
+	now := time.Now()
+	next := now.Add(time.Minute)
+	p.info.Status = protocol.StatusOnline
+	p.info.NextCronRestart = &next
+
+	if p.cronDue(now) {
+		t.Error("cronDue before scheduled time = true")
+	}
+	if !p.cronDue(next) {
+		t.Error("cronDue at scheduled time = false")
+	}
+	if p.cronDue(next.Add(time.Second)) {
+		t.Error("cronDue fired twice for the same schedule")
+	}
+
+	// Stopped processes are not restarted by cron.
+	p.info.NextCronRestart = &next
+	p.info.Status = protocol.StatusStopped
+	if p.cronDue(next) {
+		t.Error("cronDue for stopped process = true")
+	}
+}
+
+func TestInfoToStartParamsCronRestart(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{
+		Command:     "/bin/sleep",
+		CronRestart: "@daily",
+	})
+	if got := infoToStartParams(p.Info()).CronRestart; got != "@daily" {
+		t.Errorf("CronRestart = %q, want %q", got, "@daily")
+	}
+}
+
+func TestStopCancelsPendingRestart(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/sleep"})
+	restartAt := time.Now().Add(time.Minute)
+	cancel := make(chan struct{})
+	p.info.Status = protocol.StatusWaitingRestart
+	p.info.RestartAt = &restartAt
+	p.restartCh = cancel
+
+	p.Stop()
+	select {
+	case <-cancel:
+	default:
+		t.Error("Stop did not cancel the pending restart")
+	}
+	if p.info.Status != protocol.StatusStopped || p.info.RestartAt != nil {
+		t.Errorf("after Stop: status %s, restart at %v", p.info.Status, p.info.RestartAt)
+	}
+	if started, err := p.startPending(cancel); started || err != nil {
+		t.Errorf("startPending after Stop = %v, %v, want not started", started, err)
+	}
+}
+
+func TestResurrectable(t *testing.T) {
+	for status, want := range map[protocol.Status]bool{
+		protocol.StatusOnline:         true,
+		protocol.StatusStarting:       true,
+		protocol.StatusLaunching:      true,
+		protocol.StatusWaitingRestart: true,
+		protocol.StatusWaiting:        true,
+		protocol.StatusScheduled:      true,
+		protocol.StatusCrashLoop:      true,
+		protocol.StatusStopping:       false,
+		protocol.StatusStopped:        false,
+		protocol.StatusErrored:        false,
+	} {
+		if got := resurrectable(status); got != want {
+			t.Errorf("resurrectable(%s) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestSpendRestart(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/sleep"})
+	policy := protocol.RestartPolicy{RestartBudget: 3, RestartWindow: protocol.Duration{Duration: time.Minute}}
+	now := time.Now()
+
+	for i, want := range []bool{false, false, false, true, true} {
+		n, over := p.spendRestart(policy, now.Add(time.Duration(i)*time.Second))
+		if n != i || over != want {
+			t.Errorf("restart %d: spendRestart = %d, %v, want %d, %v", i+1, n, over, i, want)
+		}
+	}
+	// Past the window the old restarts no longer count.
+	if n, over := p.spendRestart(policy, now.Add(2*time.Minute)); n != 0 || over {
+		t.Errorf("after the window: spendRestart = %d, %v, want 0, false", n, over)
+	}
+
+	if _, over := p.spendRestart(protocol.RestartPolicy{}, now); over {
+		t.Error("spendRestart without a budget reported it spent")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	if got := jitter(time.Second, 0); got != time.Second {
+		t.Errorf("jitter(1s, 0) = %v, want 1s", got)
+	}
+	for i := 0; i < 100; i++ {
+		if got := jitter(time.Second, 0.25); got < 750*time.Millisecond || got > 1250*time.Millisecond {
+			t.Fatalf("jitter(1s, 0.25) = %v, want within 750ms..1.25s", got)
+		}
+	}
+}
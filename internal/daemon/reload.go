@@ -0,0 +1,265 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"syscall"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// reloadPollInterval is how often a reload checks whether the replacement
+// process is ready.
+const reloadPollInterval = 250 * time.Millisecond
+
+// readyWriter watches a process's stdout for its ready_message and closes
+// ch once the message has been written. Output is passed on unchanged.
+type readyWriter struct {
+	msg  []byte
+	ch   chan struct{}
+	tail []byte // end of the output so far, to match across writes
+	seen bool
+}
+
+func newReadyWriter(msg string) *readyWriter {
+	return &readyWriter{msg: []byte(msg), ch: make(chan struct{})}
+}
+
+func (w *readyWriter) Write(b []byte) (int, error) {
+	if w.seen {
+		return len(b), nil
+	}
+	buf := append(w.tail, b...)
+	if bytes.Contains(buf, w.msg) {
+		w.seen = true
+		w.tail = nil
+		close(w.ch)
+		return len(b), nil
+	}
+	if keep := len(w.msg) - 1; len(buf) > keep {
+		buf = buf[len(buf)-keep:]
+	}
+	w.tail = append([]byte(nil), buf...)
+	return len(b), nil
+}
+
+func (d *Daemon) handleReload(params json.RawMessage) protocol.Response {
+	var rp protocol.ReloadParams
+	if err := json.Unmarshal(params, &rp); err != nil {
+		return errorResponse("invalid reload params: " + err.Error())
+	}
+	if rp.Target == "" {
+		return errorResponse("target is required")
+	}
+	timeout := protocol.DefaultReloadTimeout
+	if rp.Timeout != "" {
+		t, err := time.ParseDuration(rp.Timeout)
+		if err != nil || t <= 0 {
+			return errorResponse(fmt.Sprintf("invalid timeout %q", rp.Timeout))
+		}
+		timeout = t
+	}
+
+	procs := d.resolveTarget(rp.Target)
+	if len(procs) == 0 {
+		return targetNotFound(rp.Target)
+	}
+
+	// One at a time, so the others keep serving while each is replaced.
+	var results []protocol.ProcessInfo
+	var reloadErr error
+	for _, p := range procs {
+		np, err := d.reloadProcess(p, timeout)
+		if err != nil {
+			reloadErr = fmt.Errorf("reload %s: %w", p.info.Name, err)
+			break
+		}
+		results = append(results, np.Info())
+	}
+
+	if err := d.SaveState(); err != nil {
+		slog.Error("auto-save failed after reload", "error", err)
+	}
+	if reloadErr != nil {
+		if len(results) > 0 {
+			return errorResponse(fmt.Sprintf("%v (%d of %d reloaded)", reloadErr, len(results), len(procs)))
+		}
+		return errorResponse(reloadErr.Error())
+	}
+	if len(results) == 1 {
+		return successResponse(results[0])
+	}
+	return successResponse(results)
+}
+
+// reloadProcess replaces p without downtime: it starts a new process with
+// the same settings, waits for it to become ready, then takes p's place and
+// p is stopped with its kill signal. If the new process isn't ready within
+// timeout it is stopped and p keeps running. A process that isn't running
+// is simply restarted, and one with a reload_signal is sent that signal to
+// reload itself in place. It returns the process now registered under p's
+// name.
+func (d *Daemon) reloadProcess(p *Process, timeout time.Duration) (*Process, error) {
+	info := p.Info()
+	if info.Schedule != "" {
+		return nil, fmt.Errorf("scheduled jobs can't be reloaded; use restart")
+	}
+	if !info.Status.Running() {
+		if err := d.restartProcess(p, "reload"); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	if info.ReloadSignal != 0 {
+		if _, err := p.Signal(syscall.Signal(info.ReloadSignal), false); err != nil {
+			return nil, err
+		}
+		slog.Info("process reloaded by signal", "name", info.Name, "pid", info.PID, "signal", protocol.SignalName(syscall.Signal(info.ReloadSignal)))
+		return p, nil
+	}
+
+	np := NewProcess(info.ID, infoToStartParams(info))
+	np.cgroups = d.cgroups
+	np.events = d.events
+	np.candidate = true
+	np.info.CreatedAt = info.CreatedAt
+	np.info.LastRestartReason = "reload"
+	// The replacement runs in a cgroup of its own, so both processes keep
+	// their limits and each is reaped when it exits.
+	if d.cgroups != nil {
+		np.cgroupDir = d.cgroups.ReloadPath(info.Name, info.CgroupPath)
+	}
+	// The replacement gets the same sockets, already bound for p.
+	if err := d.attachSockets(np); err != nil {
+		return nil, err
+	}
+
+	p.LogAction("reloading: starting replacement")
+	slog.Info("reloading process", "name", info.Name, "pid", info.PID)
+	if err := np.Start(); err != nil {
+		p.LogAction("reload failed: %v", err)
+		return nil, err
+	}
+	go d.monitor(np)
+
+	if err := d.waitReady(np, timeout); err != nil {
+		np.Stop()
+		np.CloseLogWriters()
+		p.LogAction("reload aborted, keeping PID %d: %v", info.PID, err)
+		slog.Warn("reload aborted", "name", info.Name, "error", err)
+		return nil, err
+	}
+
+	d.mu.Lock()
+	if d.processes[info.Name] != p {
+		d.mu.Unlock()
+		np.Stop()
+		np.CloseLogWriters()
+		return nil, fmt.Errorf("process was removed during reload")
+	}
+	np.carryOver(p)
+	np.mu.Lock()
+	np.candidate = false
+	np.mu.Unlock()
+	d.processes[info.Name] = np
+	d.mu.Unlock()
+
+	np.publishRestart("reload")
+	np.LogAction("process started (PID %d), replacing PID %d", np.info.PID, info.PID)
+
+	p.Stop()
+	p.CloseLogWriters()
+	slog.Info("process reloaded", "name", info.Name, "old_pid", info.PID, "pid", np.info.PID)
+	return np, nil
+}
+
+// waitReady waits for a reload replacement to become ready: it sends
+// READY=1 if it uses notify, else its health check passes once it listens
+// itself (or holds the daemon's sockets) if it has one, else its
+// ready_message appears on stdout if set,
+// else it opens a listening socket. A process given sockets by the daemon
+// is ready once it runs, as connections queue on the shared sockets until
+// it accepts them. It fails if the process exits first or isn't ready
+// within timeout.
+func (d *Daemon) waitReady(p *Process, timeout time.Duration) error {
+	p.mu.Lock()
+	hc := p.info.HealthCheck
+	cwd := p.info.Cwd
+	env := p.info.Env
+	ready := p.ready
+	inherited := len(p.sockets) > 0
+	notify := p.info.Notify
+	p.mu.Unlock()
+
+	var want string
+	switch {
+	case notify:
+		want = "READY=1"
+	case hc != nil:
+		want = "health check to pass"
+	case ready != nil:
+		want = fmt.Sprintf("ready message %q", p.info.ReadyMessage)
+	case inherited:
+		want = "it to start"
+	default:
+		want = "a listening socket"
+	}
+
+	ticker := time.NewTicker(reloadPollInterval)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return fmt.Errorf("not ready after %s (waiting for %s)", timeout, want)
+		case <-d.stopCh:
+			return fmt.Errorf("daemon is shutting down")
+		}
+
+		p.mu.Lock()
+		status := p.info.Status
+		reason := p.info.StatusReason
+		pid := p.info.PID
+		p.mu.Unlock()
+		if !status.Running() {
+			return fmt.Errorf("replacement %s", reason)
+		}
+
+		switch {
+		case notify:
+			if status == protocol.StatusOnline {
+				return nil
+			}
+		case hc != nil:
+			// p still serves the health check target: until the
+			// replacement listens itself, a passing probe reached p.
+			if !inherited && len(scanProcessListeners(pid)) == 0 {
+				continue
+			}
+			_, probeTimeout, _, _ := hc.Timings()
+			if probe(*hc, cwd, env, probeTimeout) == nil {
+				p.setHealth(protocol.HealthHealthy, 0, "")
+				return nil
+			}
+		case ready != nil:
+			select {
+			case <-ready.ch:
+				return nil
+			default:
+			}
+		case inherited:
+			return nil
+		default:
+			if listeners := scanProcessListeners(pid); len(listeners) > 0 {
+				p.mu.Lock()
+				p.info.Listeners = listeners
+				p.mu.Unlock()
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestReadyWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		ready  bool
+	}{
+		{"one write", []string{"starting\nlistening on :8080\n"}, true},
+		{"split across writes", []string{"start", "ing\nlist", "ening", " on :8080\n"}, true},
+		{"byte by byte", []string{"l", "i", "s", "t", "e", "n", "i", "n", "g"}, true},
+		{"not written", []string{"starting\n", "listen failed\n"}, false},
+	}
+	for _, tt := range tests {
+		w := newReadyWriter("listening")
+		for _, s := range tt.writes {
+			if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+				t.Fatalf("%s: Write = %d, %v", tt.name, n, err)
+			}
+		}
+		ready := false
+		select {
+		case <-w.ch:
+			ready = true
+		default:
+		}
+		if ready != tt.ready {
+			t.Errorf("%s: ready = %v, want %v", tt.name, ready, tt.ready)
+		}
+		// More output after the message is passed on without closing ch twice.
+		w.Write([]byte("listening again\n"))
+	}
+}
+
+func TestWaitReadyHealthCheckNeedsOwnListener(t *testing.T) {
+	dir := t.TempDir()
+	// The old instance (this test) serves the health check URL; the
+	// replacement never binds anything.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	p := NewProcess(0, protocol.StartParams{
+		Command:     "/bin/sleep",
+		Args:        []string{"30"},
+		Name:        "api",
+		LogOut:      filepath.Join(dir, "out.log"),
+		LogErr:      filepath.Join(dir, "err.log"),
+		HealthCheck: &protocol.HealthCheck{Type: protocol.HealthCheckHTTP, Target: srv.URL},
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Kill(p.Info().PID, syscall.SIGKILL)
+
+	d := &Daemon{stopCh: make(chan struct{})}
+	if err := d.waitReady(p, time.Second); err == nil {
+		t.Error("waitReady passed on the old instance's health check")
+	}
+}
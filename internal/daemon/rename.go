@@ -0,0 +1,198 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/7c/gopm/internal/logwriter"
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// handleRename gives a process, or an instance group and its instances, a
+// new name. It keeps running with its ID, history and metrics; log files
+// in the default place move along, and depends_on of other processes
+// follow the new name.
+func (d *Daemon) handleRename(params json.RawMessage) protocol.Response {
+	var rp protocol.RenameParams
+	if err := json.Unmarshal(params, &rp); err != nil {
+		return errorResponse("invalid rename params: " + err.Error())
+	}
+	if rp.Target == "" {
+		return errorResponse("target is required")
+	}
+	if err := checkName(rp.Name); err != nil {
+		return errorResponse(err.Error())
+	}
+
+	d.mu.Lock()
+	procs, old, err := d.renameLocked(rp.Target, rp.Name)
+	d.mu.Unlock()
+	if err != nil {
+		return errorResponse(err.Error())
+	}
+
+	infos := make([]protocol.ProcessInfo, len(procs))
+	for i, p := range procs {
+		p.LogAction("renamed from %s to %s", old, rp.Name)
+		p.publishConfig("renamed from " + old)
+		infos[i] = p.Info()
+	}
+	slog.Info("process renamed", "from", old, "to", rp.Name)
+
+	if err := d.SaveState(); err != nil {
+		slog.Error("auto-save failed after rename", "error", err)
+	}
+	return successResponse(infos)
+}
+
+// renameLocked re-keys the processes target names, with their metrics and
+// sockets, under name, returning them and their old name (the app name for
+// a group). Must be called with d.mu held.
+func (d *Daemon) renameLocked(target, name string) ([]*Process, string, error) {
+	if target == "all" || protocol.IsSelector(target) {
+		return nil, "", errors.New("rename takes a process name or ID, or an instance group")
+	}
+	procs := d.resolveTargetLocked(target)
+	if len(procs) == 0 {
+		return nil, "", fmt.Errorf("process %q not found", target)
+	}
+	old := procs[0].info.Name
+	if app := procs[0].info.App; app != "" {
+		if target != app {
+			return nil, "", fmt.Errorf("%s is an instance of %s: rename the group instead", old, app)
+		}
+		old = app
+	}
+	if name == old {
+		return nil, "", fmt.Errorf("process is already named %q", name)
+	}
+	if d.nameTakenLocked(name) {
+		return nil, "", fmt.Errorf("process %q already exists", name)
+	}
+
+	// The new name of each process by its old one; for a group, also of
+	// the app, for depends_on naming it.
+	names := map[string]string{old: name}
+	for _, p := range procs {
+		if p.info.App == "" {
+			continue
+		}
+		n := protocol.InstanceName(name, p.info.InstanceID)
+		if d.nameTakenLocked(n) {
+			return nil, "", fmt.Errorf("process %q already exists", n)
+		}
+		names[p.info.Name] = n
+	}
+
+	for _, p := range procs {
+		delete(d.processes, p.info.Name)
+	}
+	for _, p := range procs {
+		from, to := p.info.Name, names[p.info.Name]
+		d.processes[to] = p
+		if ring, ok := d.snapshots[from]; ok {
+			delete(d.snapshots, from)
+			d.snapshots[to] = ring
+		}
+		p.rename(to, name)
+	}
+	if set, ok := d.sockets[old]; ok {
+		delete(d.sockets, old)
+		d.sockets[name] = set
+	}
+	for _, p := range d.processes {
+		p.renameDependencies(names)
+	}
+	return procs, old, nil
+}
+
+// checkName fails for a name that would be taken for something else when
+// used as a target, or that can't be part of a log file name.
+func checkName(name string) error {
+	if name == "" {
+		return errors.New("new name is required")
+	}
+	if name == "all" {
+		return errors.New(`"all" is not a valid name: it targets every process`)
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return fmt.Errorf("%q is not a valid name: it would be taken for an ID", name)
+	}
+	if protocol.IsSelector(name) || strings.ContainsAny(name, "/,") {
+		return fmt.Errorf("%q is not a valid name: it can't contain =, *, ?, [, / or ,", name)
+	}
+	return nil
+}
+
+// rename gives p a new name, and app for an instance, moving its log files
+// if they are the default ones of its old name.
+func (p *Process) rename(name, app string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	oldOut, oldErr := defaultLogs(p.info)
+	p.info.Name = name
+	if p.info.App != "" {
+		p.info.App = app
+	}
+	newOut, newErr := defaultLogs(p.info)
+
+	if p.info.LogOut == oldOut {
+		p.info.LogOut = moveLog(p.stdout, oldOut, newOut)
+	}
+	if p.info.LogErr == oldErr {
+		p.info.LogErr = moveLog(p.stderr, oldErr, newErr)
+	}
+}
+
+// renameDependencies makes the depends_on of p name processes by their
+// new names.
+func (p *Process) renameDependencies(names map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var deps []protocol.Dependency
+	for i, dep := range p.info.DependsOn {
+		n, ok := names[dep.Name]
+		if !ok {
+			continue
+		}
+		if deps == nil {
+			deps = append([]protocol.Dependency(nil), p.info.DependsOn...)
+		}
+		deps[i].Name = n
+	}
+	if deps != nil {
+		p.info.DependsOn = deps
+	}
+}
+
+// defaultLogs returns the log files the daemon gives a process named as
+// info is when none are configured.
+func defaultLogs(info protocol.ProcessInfo) (logOut, logErr string) {
+	if info.App != "" {
+		sp := instanceStartParams(protocol.StartParams{Name: info.App}, info.InstanceID)
+		return sp.LogOut, sp.LogErr
+	}
+	return filepath.Join(protocol.LogDir(), fmt.Sprintf("%s-out.log", info.Name)),
+		filepath.Join(protocol.LogDir(), fmt.Sprintf("%s-err.log", info.Name))
+}
+
+// moveLog moves a log file and its rotated files from oldPath to newPath,
+// through w if it writes there, and returns where the log is now.
+func moveLog(w *logwriter.TimestampWriter, oldPath, newPath string) string {
+	var err error
+	if w != nil && w.Underlying().Path() == oldPath {
+		err = w.Underlying().Rename(newPath)
+	} else {
+		err = logwriter.Rename(oldPath, newPath, logFiles)
+	}
+	if err != nil {
+		slog.Warn("cannot move log file", "from", oldPath, "to", newPath, "error", err)
+		return oldPath
+	}
+	return newPath
+}
@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestRenameLocked(t *testing.T) {
+	t.Setenv("GOPM_HOME", t.TempDir())
+	os.MkdirAll(protocol.LogDir(), 0755)
+
+	api := NewProcess(0, protocol.StartParams{Command: "/bin/api", Name: "api"})
+	custom := NewProcess(1, protocol.StartParams{Command: "/bin/db", Name: "db", LogOut: filepath.Join(t.TempDir(), "db.log")})
+	worker := NewProcess(2, protocol.StartParams{Command: "/bin/worker", Name: "worker",
+		DependsOn: []protocol.Dependency{{Name: "api"}, {Name: "web"}, {Name: "db"}}})
+	d := &Daemon{
+		processes: map[string]*Process{"api": api, "db": custom, "worker": worker},
+		snapshots: map[string]*snapshotRing{"api": {}},
+		sockets:   map[string]*socketSet{},
+	}
+	for _, id := range []int{0, 1} {
+		p := NewProcess(id+3, instanceStartParams(protocol.StartParams{Command: "/bin/web", Name: "web"}, id))
+		d.processes[p.info.Name] = p
+	}
+	os.WriteFile(api.info.LogOut, []byte("out\n"), 0644)
+	os.WriteFile(api.info.LogOut+".1", []byte("older\n"), 0644)
+
+	if _, _, err := d.renameLocked("0", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if d.processes["v2"] != api || d.processes["api"] != nil || d.snapshots["v2"] == nil {
+		t.Errorf("api not re-keyed: processes %v, snapshots %v", d.processes, d.snapshots)
+	}
+	wantOut := filepath.Join(protocol.LogDir(), "v2-out.log")
+	if api.info.LogOut != wantOut {
+		t.Errorf("LogOut = %s, want %s", api.info.LogOut, wantOut)
+	}
+	for _, path := range []string{wantOut, wantOut + ".1"} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("log not moved: %v", err)
+		}
+	}
+
+	logOut := custom.info.LogOut
+	if _, _, err := d.renameLocked("db", "store"); err != nil {
+		t.Fatal(err)
+	}
+	if custom.info.LogOut != logOut {
+		t.Errorf("configured log moved to %s", custom.info.LogOut)
+	}
+
+	if _, old, err := d.renameLocked("web", "www"); err != nil || old != "web" {
+		t.Fatalf("rename group: %q, %v", old, err)
+	}
+	for _, name := range []string{"www-0", "www-1"} {
+		if p := d.processes[name]; p == nil || p.info.Name != name || p.info.App != "www" {
+			t.Errorf("%s not renamed", name)
+		}
+	}
+
+	var deps []string
+	for _, dep := range worker.info.DependsOn {
+		deps = append(deps, dep.Name)
+	}
+	if len(deps) != 3 || deps[0] != "v2" || deps[1] != "www" || deps[2] != "store" {
+		t.Errorf("depends_on = %v, want [v2 www store]", deps)
+	}
+
+	for _, tt := range []struct{ target, name string }{
+		{"www-0", "x"},    // an instance
+		{"worker", "v2"},  // taken
+		{"worker", "www"}, // taken by a group
+		{"worker", "worker"},
+		{"missing", "x"},
+		{"all", "x"},
+	} {
+		if _, _, err := d.renameLocked(tt.target, tt.name); err == nil {
+			t.Errorf("rename %s to %s should fail", tt.target, tt.name)
+		}
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	for _, name := range []string{"api", "api-v2", "web.1", "my_app"} {
+		if err := checkName(name); err != nil {
+			t.Errorf("checkName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "all", "12", "api-*", "team=x", "a/b", "a,b"} {
+		if err := checkName(name); err == nil {
+			t.Errorf("checkName(%q) should fail", name)
+		}
+	}
+}
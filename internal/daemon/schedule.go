@@ -0,0 +1,282 @@
+package daemon
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/7c/gopm/internal/cron"
+	"github.com/7c/gopm/internal/protocol"
+)
+
+const (
+	// maxRuns is how many run records are kept per scheduled job.
+	maxRuns = 50
+	// runOutputLimit is how much of each run's output is kept, from the end.
+	runOutputLimit = 8 * 1024
+)
+
+// Arm puts a scheduled job in the scheduled state and computes its next
+// run. The log writers are reopened so actions between runs are logged.
+func (p *Process) Arm() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sched, err := cron.Parse(p.info.Schedule)
+	if err != nil {
+		return err
+	}
+	p.CloseLogWriters()
+	if err := p.openLogWriters(); err != nil {
+		return err
+	}
+	p.setStatus(protocol.StatusScheduled)
+	p.info.StatusReason = ""
+	p.info.PID = 0
+	p.runQueued = false
+	p.info.NextRun = nil
+	if next := sched.Next(time.Now()); !next.IsZero() {
+		p.info.NextRun = &next
+	}
+	return nil
+}
+
+// runDue reports whether a scheduled job's next run time has passed, and
+// advances NextRun to the following one.
+func (p *Process) runDue(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	next := p.info.NextRun
+	if next == nil || now.Before(*next) {
+		return false
+	}
+	p.info.NextRun = nil
+	if sched, err := cron.Parse(p.info.Schedule); err == nil {
+		if n := sched.Next(now); !n.IsZero() {
+			p.info.NextRun = &n
+		}
+	}
+	return true
+}
+
+// overlapAction is what triggerRun does with a due run.
+type overlapAction int
+
+const (
+	runStart overlapAction = iota
+	runSkip
+	runQueue
+	runKill
+	runIgnore
+)
+
+// claimRun decides what to do with a run that is due now, based on the
+// job's state and overlap policy, and describes why when the previous run
+// is in the way. For queue and kill it marks the run as queued so
+// handleRunExit starts it.
+func (p *Process) claimRun() (overlapAction, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.info.Status {
+	case protocol.StatusScheduled:
+		return runStart, ""
+	case protocol.StatusOnline:
+	default:
+		return runIgnore, ""
+	}
+
+	busy := "previous run still running"
+	if n := len(p.runs); n > 0 {
+		busy = fmt.Sprintf("run #%d still running", p.runs[n-1].ID)
+	}
+	switch p.info.Overlap {
+	case protocol.OverlapQueue:
+		if p.runQueued {
+			return runSkip, "a run is already queued"
+		}
+		p.runQueued = true
+		return runQueue, busy
+	case protocol.OverlapKill:
+		p.runQueued = true
+		return runKill, busy
+	default:
+		return runSkip, busy
+	}
+}
+
+// triggerRun starts a run of a scheduled job, or applies its overlap policy
+// if the previous run is still going.
+func (d *Daemon) triggerRun(p *Process, trigger string) {
+	action, msg := p.claimRun()
+	switch action {
+	case runStart:
+		d.startRun(p, trigger)
+	case runQueue:
+		p.LogAction("%s, next run queued", msg)
+	case runKill:
+		p.LogAction("%s, killing it for the next run", msg)
+		p.ForceRestart("killed by overlapping run")
+	case runSkip:
+		p.addRun(protocol.RunRecord{
+			Status:    protocol.RunSkipped,
+			Trigger:   trigger,
+			StartedAt: time.Now(),
+			Reason:    msg,
+		})
+		p.LogAction("run skipped: %s", msg)
+	}
+}
+
+// startRun launches one run of a scheduled job under a new monitor.
+func (d *Daemon) startRun(p *Process, trigger string) {
+	p.mu.Lock()
+	p.runOutput = &tailBuffer{limit: runOutputLimit}
+	p.mu.Unlock()
+
+	p.CloseLogWriters()
+	err := p.Start()
+
+	rec := protocol.RunRecord{Trigger: trigger, StartedAt: time.Now()}
+	if err != nil {
+		p.mu.Lock()
+		p.runOutput = nil
+		p.mu.Unlock()
+		rec.Status = protocol.RunFailed
+		rec.ExitCode = -1
+		rec.Reason = err.Error()
+		rec = p.addRun(rec)
+		if armErr := p.Arm(); armErr != nil {
+			slog.Error("failed to re-arm scheduled job", "name", p.info.Name, "error", armErr)
+		}
+		p.LogAction("run #%d failed to start: %v", rec.ID, err)
+		slog.Error("scheduled run failed to start", "name", p.info.Name, "run", rec.ID, "error", err)
+		return
+	}
+
+	info := p.Info()
+	rec.Status = protocol.RunRunning
+	rec.PID = info.PID
+	rec.StartedAt = info.Uptime
+	rec = p.addRun(rec)
+	p.LogAction("run #%d started (PID %d, %s)", rec.ID, rec.PID, trigger)
+	slog.Info("scheduled run started", "name", info.Name, "run", rec.ID, "pid", rec.PID, "trigger", trigger)
+	go d.monitor(p)
+}
+
+// handleRunExit records the result of a finished run and returns the job to
+// the scheduled state. A non-empty reason means the daemon killed the run.
+func (d *Daemon) handleRunExit(p *Process, exitCode int, reason string) {
+	defer d.autoSave("run finished")
+
+	rec := p.finishRun(exitCode, reason)
+	p.MarkExited(exitCode, protocol.StatusScheduled)
+	p.LogAction("run #%d %s (exit code %d, %s)", rec.ID, rec.Status, exitCode, rec.Duration)
+	slog.Info("scheduled run finished", "name", p.info.Name, "run", rec.ID, "status", rec.Status, "exit_code", exitCode)
+
+	p.mu.Lock()
+	queued := p.runQueued
+	p.runQueued = false
+	p.mu.Unlock()
+	if queued {
+		d.startRun(p, "queued")
+	}
+}
+
+// addRun appends a run record, assigning its ID, and drops the oldest
+// records beyond maxRuns. It returns the stored record.
+func (p *Process) addRun(rec protocol.RunRecord) protocol.RunRecord {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextRunID++
+	rec.ID = p.nextRunID
+	p.runs = append(p.runs, rec)
+	if len(p.runs) > maxRuns {
+		p.runs = append(p.runs[:0], p.runs[len(p.runs)-maxRuns:]...)
+	}
+	return rec
+}
+
+// finishRun completes the running record with the exit result and the
+// captured output.
+func (p *Process) finishRun(exitCode int, reason string) protocol.RunRecord {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := p.runOutput
+	p.runOutput = nil
+	n := len(p.runs)
+	if n == 0 || p.runs[n-1].Status != protocol.RunRunning {
+		return protocol.RunRecord{}
+	}
+	r := &p.runs[n-1]
+	r.Duration = protocol.Duration{Duration: time.Since(r.StartedAt).Round(time.Millisecond)}
+	r.ExitCode = exitCode
+	r.Reason = reason
+	switch {
+	case reason != "":
+		r.Status = protocol.RunKilled
+	case exitCode == 0:
+		r.Status = protocol.RunSuccess
+	default:
+		r.Status = protocol.RunFailed
+	}
+	if out != nil {
+		r.Output = out.String()
+	}
+	return *r
+}
+
+// Runs returns up to limit run records, newest first (all if limit <= 0).
+func (p *Process) Runs(limit int) []protocol.RunRecord {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	runs := make([]protocol.RunRecord, 0, len(p.runs))
+	for i := len(p.runs) - 1; i >= 0; i-- {
+		if limit > 0 && len(runs) == limit {
+			break
+		}
+		runs = append(runs, p.runs[i])
+	}
+	return runs
+}
+
+// checkScheduledRuns starts every scheduled job whose next run is due.
+func (d *Daemon) checkScheduledRuns(now time.Time) {
+	d.mu.RLock()
+	var due []*Process
+	for _, p := range d.processes {
+		if p.info.Schedule != "" && p.runDue(now) {
+			due = append(due, p)
+		}
+	}
+	d.mu.RUnlock()
+
+	for _, p := range due {
+		go d.triggerRun(p, "schedule")
+	}
+}
+
+// tailBuffer is an io.Writer that keeps the last limit bytes written to it.
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	buf   []byte
+}
+
+func (b *tailBuffer) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, data...)
+	if len(b.buf) > b.limit {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-b.limit:]...)
+	}
+	return len(data), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
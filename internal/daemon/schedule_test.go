@@ -0,0 +1,139 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{limit: 8}
+	b.Write([]byte("hello "))
+	b.Write([]byte("world"))
+	if got := b.String(); got != "lo world" {
+		t.Errorf("String() = %q, want %q", got, "lo world")
+	}
+}
+
+func TestNewProcessScheduleDefaults(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/true", Schedule: "@hourly"})
+	if p.info.Overlap != protocol.OverlapSkip {
+		t.Errorf("Overlap = %q, want %q", p.info.Overlap, protocol.OverlapSkip)
+	}
+	params := infoToStartParams(p.Info())
+	if params.Schedule != "@hourly" || params.Overlap != "skip" {
+		t.Errorf("infoToStartParams = schedule %q overlap %q", params.Schedule, params.Overlap)
+	}
+}
+
+func TestRunDue(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/true", Schedule: "*/10 * * * *"})
+	now := time.Date(2025, 1, 15, 10, 20, 0, 0, time.UTC)
+	p.info.NextRun = &now
+
+	if p.runDue(now.Add(-time.Second)) {
+		t.Error("runDue before next run = true")
+	}
+	if !p.runDue(now) {
+		t.Fatal("runDue at next run = false")
+	}
+	want := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	if p.info.NextRun == nil || !p.info.NextRun.Equal(want) {
+		t.Errorf("NextRun = %v, want %v", p.info.NextRun, want)
+	}
+	if p.runDue(now) {
+		t.Error("runDue fired twice for the same time")
+	}
+}
+
+func TestClaimRun(t *testing.T) {
+	tests := []struct {
+		overlap protocol.OverlapPolicy
+		status  protocol.Status
+		queued  bool
+		want    overlapAction
+	}{
+		{protocol.OverlapSkip, protocol.StatusScheduled, false, runStart},
+		{protocol.OverlapSkip, protocol.StatusOnline, false, runSkip},
+		{protocol.OverlapQueue, protocol.StatusOnline, false, runQueue},
+		{protocol.OverlapQueue, protocol.StatusOnline, true, runSkip},
+		{protocol.OverlapKill, protocol.StatusOnline, false, runKill},
+		{protocol.OverlapSkip, protocol.StatusStopped, false, runIgnore},
+	}
+	for _, tt := range tests {
+		p := NewProcess(0, protocol.StartParams{Command: "/bin/true", Schedule: "@hourly", Overlap: string(tt.overlap)})
+		p.info.Status = tt.status
+		p.runQueued = tt.queued
+		got, _ := p.claimRun()
+		if got != tt.want {
+			t.Errorf("claimRun(%s, %s, queued=%v) = %d, want %d", tt.overlap, tt.status, tt.queued, got, tt.want)
+		}
+		if (tt.want == runQueue || tt.want == runKill) && !p.runQueued {
+			t.Errorf("claimRun(%s) did not queue the run", tt.overlap)
+		}
+	}
+}
+
+func TestFinishRun(t *testing.T) {
+	tests := []struct {
+		exitCode int
+		reason   string
+		want     protocol.RunStatus
+	}{
+		{0, "", protocol.RunSuccess},
+		{3, "", protocol.RunFailed},
+		{-1, "killed by overlapping run", protocol.RunKilled},
+	}
+	for _, tt := range tests {
+		p := NewProcess(0, protocol.StartParams{Command: "/bin/true", Schedule: "@hourly"})
+		p.runOutput = &tailBuffer{limit: runOutputLimit}
+		p.runOutput.Write([]byte("done\n"))
+		p.addRun(protocol.RunRecord{Status: protocol.RunRunning, StartedAt: time.Now()})
+
+		r := p.finishRun(tt.exitCode, tt.reason)
+		if r.Status != tt.want || r.ExitCode != tt.exitCode || r.Reason != tt.reason {
+			t.Errorf("finishRun(%d, %q) = %+v", tt.exitCode, tt.reason, r)
+		}
+		if r.Output != "done\n" {
+			t.Errorf("Output = %q", r.Output)
+		}
+		if info := p.Info(); info.LastRun == nil || info.LastRun.Output != "" {
+			t.Errorf("Info().LastRun = %+v, want record without output", info.LastRun)
+		}
+	}
+}
+
+func TestRunsHistory(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/true", Schedule: "@hourly"})
+	for i := 0; i < maxRuns+5; i++ {
+		p.addRun(protocol.RunRecord{Status: protocol.RunSkipped})
+	}
+	runs := p.Runs(0)
+	if len(runs) != maxRuns {
+		t.Fatalf("len(Runs) = %d, want %d", len(runs), maxRuns)
+	}
+	if runs[0].ID != maxRuns+5 || runs[len(runs)-1].ID != 6 {
+		t.Errorf("Runs IDs = %d..%d, want %d..6 (newest first)", runs[0].ID, runs[len(runs)-1].ID, maxRuns+5)
+	}
+	if got := p.Runs(3); len(got) != 3 || got[0].ID != maxRuns+5 {
+		t.Errorf("Runs(3) = %d records starting at #%d", len(got), got[0].ID)
+	}
+}
+
+func TestStopDisarmsScheduledJob(t *testing.T) {
+	p := NewProcess(0, protocol.StartParams{Command: "/bin/true", Schedule: "@hourly"})
+	next := time.Now().Add(time.Hour)
+	p.info.Status = protocol.StatusScheduled
+	p.info.NextRun = &next
+	p.runQueued = true
+
+	p.Stop()
+	if p.info.Status != protocol.StatusStopped || p.info.NextRun != nil || p.runQueued {
+		t.Errorf("after Stop: status %s, next run %v, queued %v", p.info.Status, p.info.NextRun, p.runQueued)
+	}
+	if !strings.Contains(p.info.StatusReason, "user") {
+		t.Errorf("StatusReason = %q", p.info.StatusReason)
+	}
+}
@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"syscall"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// Signal sends sig to the running process, or to its whole process group if
+// group is set, and records it in the process's stderr log. It returns the
+// PID signalled.
+func (p *Process) Signal(sig syscall.Signal, group bool) (int, error) {
+	p.mu.Lock()
+	pid := p.info.PID
+	running := p.info.Status.Running() && p.cmd != nil && pid > 0
+	p.mu.Unlock()
+	if !running {
+		return 0, errors.New("not running")
+	}
+
+	target := pid
+	if group {
+		target = -pid
+	}
+	if err := syscall.Kill(target, sig); err != nil {
+		return pid, err
+	}
+	if group {
+		p.LogAction("sent %s to process group (PID %d)", protocol.SignalName(sig), pid)
+	} else {
+		p.LogAction("sent %s (PID %d)", protocol.SignalName(sig), pid)
+	}
+	return pid, nil
+}
+
+func (d *Daemon) handleSignal(params json.RawMessage) protocol.Response {
+	var sp protocol.SignalParams
+	if err := json.Unmarshal(params, &sp); err != nil {
+		return errorResponse("invalid signal params: " + err.Error())
+	}
+	if sp.Target == "" {
+		return errorResponse("target is required")
+	}
+	sig, err := protocol.ParseSignal(sp.Signal)
+	if err != nil {
+		return errorResponse(err.Error())
+	}
+
+	procs := d.resolveTarget(sp.Target)
+	if len(procs) == 0 {
+		return targetNotFound(sp.Target)
+	}
+
+	results := make([]protocol.SignalResult, 0, len(procs))
+	var failed []string
+	for _, p := range procs {
+		info := p.Info()
+		r := protocol.SignalResult{ID: info.ID, Name: info.Name, Signal: protocol.SignalName(sig), Group: sp.Group}
+		pid, err := p.Signal(sig, sp.Group)
+		r.PID = pid
+		if err != nil {
+			r.Error = err.Error()
+			failed = append(failed, fmt.Sprintf("%s: %v", info.Name, err))
+		} else {
+			slog.Info("signal sent", "name", info.Name, "signal", r.Signal, "pid", pid, "group", sp.Group)
+		}
+		results = append(results, r)
+	}
+	if len(failed) == len(procs) {
+		return errorResponse(fmt.Sprintf("failed to send %s: %s", protocol.SignalName(sig), strings.Join(failed, "; ")))
+	}
+	return successResponse(results)
+}
@@ -2,7 +2,6 @@ package daemon
 
 import (
 	"encoding/json"
-	"strconv"
 	"time"
 
 	"github.com/7c/gopm/internal/protocol"
@@ -71,13 +70,14 @@ func (d *Daemon) captureSnapshots() {
 	for name, p := range d.processes {
 		p.mu.Lock()
 		snap := protocol.MetricsSnapshot{
-			Timestamp: now,
-			CPU:       p.info.CPU,
-			Memory:    p.info.Memory,
-			Restarts:  p.info.Restarts,
-			Status:    p.info.Status,
+			Timestamp:   now,
+			CPU:         p.info.CPU,
+			Memory:      p.info.Memory,
+			Restarts:    p.info.Restarts,
+			MemRestarts: p.info.MemoryRestarts,
+			Status:      p.info.Status,
 		}
-		if p.info.Status == protocol.StatusOnline && !p.info.Uptime.IsZero() {
+		if p.info.Status.Running() && !p.info.Uptime.IsZero() {
 			snap.UptimeSec = now - p.info.Uptime.Unix()
 		}
 		p.mu.Unlock()
@@ -119,8 +119,8 @@ func (d *Daemon) handleStats(params json.RawMessage) protocol.Response {
 			}
 		}
 	} else {
-		// Resolve by name first, then by ID. Inline to avoid calling
-		// resolveTarget() which acquires its own d.mu.RLock().
+		// d.mu is already held, so resolve without resolveTarget(),
+		// which acquires its own d.mu.RLock().
 		names := d.resolveSnapshotNames(sp.Target)
 		for _, name := range names {
 			if ring, ok := d.snapshots[name]; ok {
@@ -137,17 +137,10 @@ func (d *Daemon) handleStats(params json.RawMessage) protocol.Response {
 // resolveSnapshotNames returns process names matching the target.
 // Must be called with d.mu held (read or write).
 func (d *Daemon) resolveSnapshotNames(target string) []string {
-	// By name
-	if _, ok := d.processes[target]; ok {
-		return []string{target}
+	procs := d.resolveTargetLocked(target)
+	names := make([]string, len(procs))
+	for i, p := range procs {
+		names[i] = p.info.Name
 	}
-	// By ID
-	if id, err := strconv.Atoi(target); err == nil {
-		for _, p := range d.processes {
-			if p.info.ID == id {
-				return []string{p.info.Name}
-			}
-		}
-	}
-	return nil
+	return names
 }
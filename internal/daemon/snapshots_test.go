@@ -225,3 +225,23 @@ func TestHandleStatsHoursClamp(t *testing.T) {
 		t.Fatalf("hours=99: expected success, got: %s", resp.Error)
 	}
 }
+
+func TestCaptureSnapshotsMemRestarts(t *testing.T) {
+	d := &Daemon{
+		processes: make(map[string]*Process),
+		snapshots: make(map[string]*snapshotRing),
+	}
+	d.processes["api"] = &Process{
+		info: protocol.ProcessInfo{ID: 0, Name: "api", Restarts: 1, MemoryRestarts: 3},
+	}
+
+	d.captureSnapshots()
+
+	snaps := d.snapshots["api"].slice(0)
+	if len(snaps) != 1 {
+		t.Fatalf("expected 1 snapshot, got %d", len(snaps))
+	}
+	if snaps[0].MemRestarts != 3 {
+		t.Errorf("MemRestarts = %d, want 3", snaps[0].MemRestarts)
+	}
+}
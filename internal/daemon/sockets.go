@@ -0,0 +1,214 @@
+package daemon
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"syscall"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// listenPIDScript runs a process with sockets through a shell that sets
+// LISTEN_PID to its own PID, then execs the command so the PID is kept.
+const listenPIDScript = `LISTEN_PID=$$; export LISTEN_PID; exec "$0" "$@"`
+
+// socketSet is the listening sockets the daemon holds for one app. They are
+// bound once and passed to every process of the app, on every start, so
+// connections queue in the kernel while a process restarts.
+type socketSet struct {
+	specs []protocol.Socket
+	files []*os.File // fd 3 onwards in the child
+	addrs []string   // "proto@addr", as in ProcessInfo.Listeners
+	paths []string   // unix socket files to remove on close
+}
+
+// bindSockets binds specs, closing the ones bound so far if one fails.
+func bindSockets(specs []protocol.Socket) (*socketSet, error) {
+	set := &socketSet{specs: specs}
+	for _, s := range specs {
+		f, addr, err := bindSocket(s)
+		if err != nil {
+			set.close()
+			return nil, fmt.Errorf("socket %s: %w", s.Address, err)
+		}
+		set.files = append(set.files, f)
+		set.addrs = append(set.addrs, addr)
+		if strings.HasPrefix(addr, "unix@") {
+			set.paths = append(set.paths, strings.TrimPrefix(addr, "unix@"))
+		}
+	}
+	return set, nil
+}
+
+// bindSocket listens on s and returns the listening socket as a file the
+// child can inherit, in blocking mode as systemd passes them.
+func bindSocket(s protocol.Socket) (*os.File, string, error) {
+	network, addr, err := s.Listen()
+	if err != nil {
+		return nil, "", err
+	}
+	if network == "unix" {
+		if err := removeStaleSocket(addr); err != nil {
+			return nil, "", err
+		}
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, "", err
+	}
+	var f *os.File
+	var bound string
+	switch l := ln.(type) {
+	case *net.TCPListener:
+		f, err = l.File()
+		bound = listenerAddr(l.Addr().(*net.TCPAddr))
+	case *net.UnixListener:
+		// The file keeps the socket open; the path must outlive ln.
+		l.SetUnlinkOnClose(false)
+		f, err = l.File()
+		bound = "unix@" + addr
+	}
+	ln.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := syscall.SetNonblock(int(f.Fd()), false); err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	return f, bound, nil
+}
+
+// listenerAddr formats a TCP address the way scanProcessListeners reports
+// it, so the two can be matched up.
+func listenerAddr(a *net.TCPAddr) string {
+	if a.IP.To4() != nil {
+		return fmt.Sprintf("tcp@%s:%d", a.IP, a.Port)
+	}
+	return fmt.Sprintf("tcp6@%s:%d", a.IP, a.Port)
+}
+
+// removeStaleSocket removes a unix socket file nothing is listening on,
+// so it can be bound again. A path that is in use or isn't a socket is an
+// error.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return fmt.Errorf("%s is already in use", path)
+	}
+	return os.Remove(path)
+}
+
+func (s *socketSet) close() {
+	for _, f := range s.files {
+		f.Close()
+	}
+	for _, path := range s.paths {
+		os.Remove(path)
+	}
+}
+
+// socketOwner is the key of the socket set a process uses: its instance
+// group, so all instances share the sockets, or else its name.
+func socketOwner(info protocol.ProcessInfo) string {
+	if info.App != "" {
+		return info.App
+	}
+	return info.Name
+}
+
+// attachSockets gives p the listening sockets of its app, binding them if
+// this is the first process of the app to need them. It does nothing for a
+// process without sockets or one that already has them.
+func (d *Daemon) attachSockets(p *Process) error {
+	p.mu.Lock()
+	specs := p.info.Sockets
+	owner := socketOwner(p.info)
+	attached := p.sockets != nil
+	p.mu.Unlock()
+	if len(specs) == 0 || attached {
+		return nil
+	}
+
+	d.mu.Lock()
+	set := d.sockets[owner]
+	if set == nil || !reflect.DeepEqual(set.specs, specs) {
+		if set != nil {
+			set.close()
+		}
+		var err error
+		set, err = bindSockets(specs)
+		if err != nil {
+			delete(d.sockets, owner)
+			d.mu.Unlock()
+			return err
+		}
+		d.sockets[owner] = set
+		slog.Info("sockets bound", "app", owner, "sockets", strings.Join(set.addrs, ", "))
+	}
+	d.mu.Unlock()
+
+	p.mu.Lock()
+	p.sockets = set.files
+	p.info.SocketListeners = set.addrs
+	p.mu.Unlock()
+	return nil
+}
+
+// releaseSockets closes the sockets of owner once no process uses them.
+func (d *Daemon) releaseSockets(owner string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	set := d.sockets[owner]
+	if set == nil {
+		return
+	}
+	for _, p := range d.processes {
+		if socketOwner(p.info) == owner && len(p.info.Sockets) > 0 {
+			return
+		}
+	}
+	set.close()
+	delete(d.sockets, owner)
+	slog.Info("sockets closed", "app", owner)
+}
+
+// closeSockets closes every socket the daemon holds, on shutdown.
+func (d *Daemon) closeSockets() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for owner, set := range d.sockets {
+		set.close()
+		delete(d.sockets, owner)
+	}
+}
+
+// socketEnv returns the LISTEN_FDS and LISTEN_FDNAMES variables for a
+// process with sockets. Unnamed sockets are named after the app.
+func socketEnv(info protocol.ProcessInfo) []string {
+	names := make([]string, len(info.Sockets))
+	for i, s := range info.Sockets {
+		names[i] = s.Name
+		if names[i] == "" {
+			names[i] = socketOwner(info)
+		}
+	}
+	return []string{
+		fmt.Sprintf("LISTEN_FDS=%d", len(info.Sockets)),
+		"LISTEN_FDNAMES=" + strings.Join(names, ":"),
+	}
+}
@@ -0,0 +1,90 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestBindSockets(t *testing.T) {
+	port := freePort(t)
+	path := filepath.Join(t.TempDir(), "api.sock")
+	specs := []protocol.Socket{
+		{Address: fmt.Sprintf("tcp:127.0.0.1:%d", port)},
+		{Name: "admin", Address: "unix:" + path},
+	}
+	set, err := bindSockets(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{fmt.Sprintf("tcp@127.0.0.1:%d", port), "unix@" + path}
+	if !reflect.DeepEqual(set.addrs, want) {
+		t.Errorf("addrs = %v, want %v", set.addrs, want)
+	}
+
+	// Nothing accepts, but the kernel queues the connection.
+	c, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatalf("dial held tcp socket: %v", err)
+	}
+	c.Close()
+
+	if _, err := bindSockets(specs[1:]); err == nil {
+		t.Error("expected error binding a unix socket in use")
+	}
+
+	set.close()
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("unix socket file not removed on close: %v", err)
+	}
+}
+
+func TestBindSocketsStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	set, err := bindSockets([]protocol.Socket{{Address: "unix:" + path}})
+	if err != nil {
+		t.Fatalf("rebinding stale socket: %v", err)
+	}
+	set.close()
+
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bindSockets([]protocol.Socket{{Address: "unix:" + path}}); err == nil {
+		t.Error("expected error for a path that is not a socket")
+	}
+}
+
+func TestSocketEnv(t *testing.T) {
+	info := protocol.ProcessInfo{
+		Name:    "api-1",
+		App:     "api",
+		Sockets: []protocol.Socket{{Address: ":8080"}, {Name: "admin", Address: "unix:/run/api.sock"}},
+	}
+	want := []string{"LISTEN_FDS=2", "LISTEN_FDNAMES=api:admin"}
+	if got := socketEnv(info); !reflect.DeepEqual(got, want) {
+		t.Errorf("socketEnv = %v, want %v", got, want)
+	}
+}
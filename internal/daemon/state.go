@@ -1,10 +1,13 @@
 package daemon
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
+	"sort"
+	"syscall"
 
 	"github.com/7c/gopm/internal/protocol"
 )
@@ -16,17 +19,25 @@ func (d *Daemon) autoSave(reason string) {
 	}
 }
 
+// dump is the content of dump.json. The next ID is kept so that IDs are
+// not handed out again after a restart, even those of deleted processes.
+type dump struct {
+	NextID    int                    `json:"next_id"`
+	Processes []protocol.ProcessInfo `json:"processes"`
+}
+
 // SaveState persists the current process table to dump.json.
 func (d *Daemon) SaveState() error {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
-	var infos []protocol.ProcessInfo
+	infos := make([]protocol.ProcessInfo, 0, len(d.processes))
 	for _, p := range d.processes {
 		infos = append(infos, p.Info())
 	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
 
-	data, err := json.MarshalIndent(infos, "", "  ")
+	data, err := json.MarshalIndent(dump{NextID: d.nextID, Processes: infos}, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal state: %w", err)
 	}
@@ -35,43 +46,101 @@ func (d *Daemon) SaveState() error {
 	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("write dump file: %w", err)
 	}
+	if err := d.saveHistory(); err != nil {
+		return err
+	}
 
 	slog.Info("state saved", "path", path, "count", len(infos))
 	return nil
 }
 
-// LoadState reads the dump.json and returns process infos.
-func LoadState() ([]protocol.ProcessInfo, error) {
+// LoadState reads the dump.json and returns process infos and the next ID
+// to hand out. A dump written before the next ID was kept, a plain list of
+// process infos, gives the ID after the highest one.
+func LoadState() ([]protocol.ProcessInfo, int, error) {
 	path := protocol.DumpFilePath()
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("read dump file: %w", err)
+	}
+
+	var st dump
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &st.Processes)
+	} else {
+		err = json.Unmarshal(data, &st)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid dump file: %w", err)
+	}
+
+	for _, info := range st.Processes {
+		if info.ID >= st.NextID {
+			st.NextID = info.ID + 1
 		}
-		return nil, fmt.Errorf("read dump file: %w", err)
 	}
+	return st.Processes, st.NextID, nil
+}
 
-	var infos []protocol.ProcessInfo
-	if err := json.Unmarshal(data, &infos); err != nil {
-		return nil, fmt.Errorf("invalid dump file: %w", err)
+// resurrectable reports whether a saved process was meant to be up, so
+// resurrect starts it: running and not being stopped, waiting to start or
+// restart, or a scheduled job.
+func resurrectable(s protocol.Status) bool {
+	switch s {
+	case protocol.StatusStopping:
+		return false
+	case protocol.StatusScheduled, protocol.StatusWaiting, protocol.StatusWaitingRestart, protocol.StatusCrashLoop, protocol.StatusLaunching:
+		return true
 	}
+	return s.Running()
+}
 
-	return infos, nil
+// claimIDLocked returns id for a process restored with it, or the next ID
+// if id is negative or another process has it. Must be called with d.mu
+// held.
+func (d *Daemon) claimIDLocked(id int) int {
+	if id >= 0 {
+		for _, p := range d.processes {
+			if p.info.ID == id {
+				id = -1
+				break
+			}
+		}
+	}
+	if id < 0 {
+		id = d.nextID
+	}
+	if id >= d.nextID {
+		d.nextID = id + 1
+	}
+	return id
 }
 
-// ResurrectProcesses restores all processes from dump.json.
-// Online processes are started; stopped/errored are registered without starting.
+// ResurrectProcesses restores all processes from dump.json, with their
+// IDs and their history from history.json, dependencies first. Online
+// processes are started (or wait for their depends_on) and scheduled jobs
+// re-armed; stopped/errored are registered without starting.
 func (d *Daemon) ResurrectProcesses() ([]protocol.ProcessInfo, error) {
-	infos, err := LoadState()
+	infos, nextID, err := LoadState()
 	if err != nil {
 		return nil, err
 	}
+	d.mu.Lock()
+	if nextID > d.nextID {
+		d.nextID = nextID
+	}
+	d.mu.Unlock()
+	infos = sortByDependencies(infos)
+	history := loadHistory()
 
 	var resurrected []protocol.ProcessInfo
 	for _, info := range infos {
-		if info.Status == protocol.StatusOnline {
+		if resurrectable(info.Status) {
 			params := infoToStartParams(info)
-			proc, err := d.startProcess(params)
+			proc, err := d.startProcessID(params, info.ID)
 			if err != nil {
 				slog.Error("failed to resurrect process", "name", info.Name, "error", err)
 				// Register as errored so the process remains visible and
@@ -81,15 +150,16 @@ func (d *Daemon) ResurrectProcesses() ([]protocol.ProcessInfo, error) {
 					d.mu.Unlock()
 					continue
 				}
-				id := d.nextID
-				d.nextID++
+				id := d.claimIDLocked(info.ID)
 				d.mu.Unlock()
 
-				proc := &Process{info: info}
+				proc := &Process{info: info, cgroups: d.cgroups, events: d.events}
+				proc.restoreHistory(history[info.Name])
 				proc.info.ID = id
 				proc.info.PID = 0
 				proc.info.Status = protocol.StatusErrored
 				proc.info.StatusReason = err.Error()
+				proc.info.SocketListeners = nil
 				proc.info.CPU = 0
 				proc.info.Memory = 0
 
@@ -101,6 +171,7 @@ func (d *Daemon) ResurrectProcesses() ([]protocol.ProcessInfo, error) {
 				resurrected = append(resurrected, proc.Info())
 				continue
 			}
+			proc.restoreHistory(history[info.Name])
 			resurrected = append(resurrected, proc.Info())
 		} else {
 			// Register stopped/errored process without starting it
@@ -109,21 +180,35 @@ func (d *Daemon) ResurrectProcesses() ([]protocol.ProcessInfo, error) {
 				d.mu.Unlock()
 				continue
 			}
-			id := d.nextID
-			d.nextID++
+			id := d.claimIDLocked(info.ID)
 			d.mu.Unlock()
 
 			proc := &Process{
-				info: info,
+				info:    info,
+				cgroups: d.cgroups,
+				events:  d.events,
 			}
+			proc.restoreHistory(history[info.Name])
 			proc.info.ID = id
 			proc.info.PID = 0
+			// One saved while being stopped has no process left to
+			// wait for: it is stopped.
+			if proc.info.Status.Running() {
+				proc.info.Status = protocol.StatusStopped
+			}
+
+			// Its sockets are held while it is stopped, as they would be
+			// had it been stopped in this daemon.
+			proc.info.SocketListeners = nil
+			if err := d.attachSockets(proc); err != nil {
+				slog.Warn("cannot bind sockets of saved process", "name", info.Name, "error", err)
+			}
 
 			d.mu.Lock()
 			d.processes[info.Name] = proc
 			d.mu.Unlock()
 
-			slog.Info("registered saved process", "name", info.Name, "status", info.Status)
+			slog.Info("registered saved process", "name", info.Name, "status", proc.info.Status)
 			resurrected = append(resurrected, proc.Info())
 		}
 	}
@@ -140,9 +225,38 @@ func infoToStartParams(info protocol.ProcessInfo) protocol.StartParams {
 		Cwd:         info.Cwd,
 		Env:         info.Env,
 		Interpreter: info.Interpreter,
+		Labels:      info.Labels,
 		AutoRestart: string(info.RestartPolicy.AutoRestart),
 		LogOut:      info.LogOut,
 		LogErr:      info.LogErr,
+		HealthCheck: info.HealthCheck,
+		Cgroup:      info.Cgroup,
+		Limits:      info.Limits,
+		CronRestart: info.CronRestart,
+		Schedule:    info.Schedule,
+		Overlap:     string(info.Overlap),
+
+		Watch:       info.Watch,
+		WatchPaths:  info.WatchPaths,
+		IgnoreWatch: info.IgnoreWatch,
+		WatchDelay:  info.WatchDelay,
+
+		DependsOn: info.DependsOn,
+
+		ReadyMessage: info.ReadyMessage,
+		Sockets:      info.Sockets,
+		Notify:       info.Notify,
+		WatchdogSec:  info.WatchdogSec,
+		Hooks:        info.Hooks,
+		StopCommand:  info.StopCommand,
+
+		User:   info.User,
+		Group:  info.Group,
+		Groups: info.Groups,
+
+		Instances:  info.Instances,
+		App:        info.App,
+		InstanceID: info.InstanceID,
 	}
 
 	maxRestarts := info.RestartPolicy.MaxRestarts
@@ -161,9 +275,30 @@ func infoToStartParams(info protocol.ProcessInfo) protocol.StartParams {
 	if info.RestartPolicy.KillTimeout.Duration > 0 {
 		params.KillTimeout = info.RestartPolicy.KillTimeout.String()
 	}
+	if info.RestartPolicy.KillSignal > 0 {
+		params.KillSignal = protocol.SignalName(syscall.Signal(info.RestartPolicy.KillSignal))
+	}
+	if info.ReloadSignal > 0 {
+		params.ReloadSignal = protocol.SignalName(syscall.Signal(info.ReloadSignal))
+	}
+	params.RestartOnExit = info.RestartPolicy.RestartOnExit
+	params.NoRestartOnExit = info.RestartPolicy.NoRestartOnExit
 	if info.MaxLogSize > 0 {
 		params.MaxLogSize = fmt.Sprintf("%d", info.MaxLogSize)
 	}
+	if info.RestartPolicy.MaxMemory > 0 {
+		params.MaxMemory = fmt.Sprintf("%d", info.RestartPolicy.MaxMemory)
+	}
+	if info.RestartPolicy.MaxMemoryGrace.Duration > 0 {
+		params.MaxMemoryGrace = info.RestartPolicy.MaxMemoryGrace.String()
+	}
+	if rp := info.RestartPolicy; rp.RestartBudget > 0 {
+		params.RestartBudget = protocol.FormatRestartBudget(rp.RestartBudget, rp.RestartWindow.Duration)
+		if rp.CrashLoopCooldown.Duration > 0 {
+			params.CrashLoopCooldown = rp.CrashLoopCooldown.String()
+		}
+	}
+	params.BackoffJitter = info.RestartPolicy.BackoffJitter
 
 	return params
 }
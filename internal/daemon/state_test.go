@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"os"
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestLoadState(t *testing.T) {
+	t.Setenv("GOPM_HOME", t.TempDir())
+
+	os.WriteFile(protocol.DumpFilePath(), []byte(`{"next_id": 9, "processes": [{"id": 4, "name": "api"}]}`), 0644)
+	infos, nextID, err := LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].ID != 4 || nextID != 9 {
+		t.Errorf("LoadState = %+v, %d", infos, nextID)
+	}
+
+	// A dump from before the next ID was kept is a plain list.
+	os.WriteFile(protocol.DumpFilePath(), []byte(`[{"id": 2, "name": "api"}, {"id": 5, "name": "web"}]`), 0644)
+	infos, nextID, err = LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || nextID != 6 {
+		t.Errorf("LoadState of a list = %+v, %d, want 2 processes and next ID 6", infos, nextID)
+	}
+}
+
+func TestClaimIDLocked(t *testing.T) {
+	d := &Daemon{processes: map[string]*Process{"api": {info: protocol.ProcessInfo{ID: 3}}}, nextID: 4}
+	if id := d.claimIDLocked(1); id != 1 || d.nextID != 4 {
+		t.Errorf("free ID: got %d, next %d", id, d.nextID)
+	}
+	if id := d.claimIDLocked(3); id != 4 || d.nextID != 5 {
+		t.Errorf("taken ID: got %d, next %d", id, d.nextID)
+	}
+	if id := d.claimIDLocked(7); id != 7 || d.nextID != 8 {
+		t.Errorf("ID past next: got %d, next %d", id, d.nextID)
+	}
+	if id := d.claimIDLocked(-1); id != 8 || d.nextID != 9 {
+		t.Errorf("no ID: got %d, next %d", id, d.nextID)
+	}
+}
+
+func TestResurrectStoppingAsStopped(t *testing.T) {
+	t.Setenv("GOPM_HOME", t.TempDir())
+
+	os.WriteFile(protocol.DumpFilePath(), []byte(`{"next_id": 2, "processes": [{"id": 1, "name": "api", "status": "stopping", "pid": 42}]}`), 0644)
+	d := &Daemon{processes: make(map[string]*Process)}
+	infos, err := d.ResurrectProcesses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Status != protocol.StatusStopped || infos[0].PID != 0 {
+		t.Errorf("resurrected %+v, want api stopped without a PID", infos)
+	}
+}
@@ -3,6 +3,8 @@ package daemon
 import (
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"strconv"
 	"time"
 
 	"github.com/7c/gopm/internal/protocol"
@@ -10,43 +12,114 @@ import (
 
 // monitor watches a running process and handles restarts on exit.
 func (d *Daemon) monitor(p *Process) {
-	exitCode := p.Wait()
+	p.mu.Lock()
+	exitCh := p.exitCh
+	hc := p.info.HealthCheck
+	watch := p.info.Watch
+	notify := p.notify
+	p.mu.Unlock()
+	if notify != nil {
+		go d.runNotify(p, notify)
+	}
+	if hc != nil {
+		go d.runHealthChecks(p, *hc, exitCh)
+	}
+	if watch {
+		go d.runWatcher(p, exitCh)
+	}
+
+	st := p.Wait()
+	exitCode := st.code
+	p.recordExit(st)
 
 	p.mu.Lock()
 	wasStopping := p.stopping
 	p.stopping = false
+	restartReason := p.restartReason
+	p.restartReason = ""
+	cgroupPath := p.info.CgroupPath
+	scheduled := p.info.Schedule != ""
+	p.closeNotify()
+	postExit := p.hook(protocol.HookPostExit, "GOPM_EXIT_CODE="+strconv.Itoa(exitCode))
 	p.mu.Unlock()
 
-	// Close the exitCh to signal anyone waiting
-	close(p.exitCh)
+	// Reap anything left in the process's cgroup (children that escaped
+	// the process group) so they don't outlive it or leak into a restart.
+	killCgroup(cgroupPath)
+	removeCgroup(cgroupPath)
+
+	// post_exit runs in the background so it never delays a restart.
+	go postExit.run()
 
 	if wasStopping {
+		if scheduled {
+			p.finishRun(exitCode, "stopped by user")
+		}
+		p.decide(protocol.DecisionNoRestart, "stopped by user")
 		p.MarkExited(exitCode, protocol.StatusStopped)
 		p.SetReason("stopped by user")
+		// Signal Stop only after the state is final, so a restart that
+		// starts the process right away is not overwritten.
+		close(exitCh)
 		p.LogAction("process stopped (exit code %d)", exitCode)
 		slog.Info("process stopped", "name", p.info.Name, "exit_code", exitCode)
 		d.autoSave("process stopped")
 		return
 	}
 
-	p.LogAction("process exited with code %d", exitCode)
-	slog.Info("process exited", "name", p.info.Name, "exit_code", exitCode)
-	d.handleProcessExit(p, exitCode)
+	// Close the exitCh to signal anyone waiting
+	close(exitCh)
+
+	// A reload replacement that exits before taking over is not restarted;
+	// the reload gives up and keeps the old process.
+	p.mu.Lock()
+	candidate := p.candidate
+	p.mu.Unlock()
+	if candidate {
+		reason := st.String() + " before it was ready"
+		p.decide(protocol.DecisionNoRestart, "reload replacement "+reason)
+		p.MarkExited(exitCode, protocol.StatusErrored)
+		p.SetReason(reason)
+		return
+	}
+
+	if scheduled {
+		p.decide(protocol.DecisionNoRestart, "scheduled job, waiting for its next run")
+		d.handleRunExit(p, exitCode, restartReason)
+		return
+	}
+
+	if restartReason != "" {
+		p.LogAction("process killed for restart (%s, %s)", restartReason, st)
+		slog.Info("process killed for restart", "name", p.info.Name, "reason", restartReason, "exit_code", exitCode)
+	} else {
+		p.LogAction("process %s", st)
+		slog.Info("process exited", "name", p.info.Name, "exit_code", exitCode, "signal", st.signal)
+	}
+	d.handleProcessExit(p, st, restartReason)
 }
 
 // handleProcessExit implements the restart logic from the spec.
-func (d *Daemon) handleProcessExit(p *Process, exitCode int) {
+// A non-empty restartReason means the daemon killed the process on purpose
+// (see Process.ForceRestart): the autorestart mode and exit code filters are
+// skipped, but max_restarts and the restart delay still apply. The decision
+// is recorded in the process history.
+func (d *Daemon) handleProcessExit(p *Process, st exitStatus, restartReason string) {
 	defer d.autoSave("process exit")
 
+	exitCode := st.code
 	p.mu.Lock()
 	policy := p.info.RestartPolicy
 	uptime := p.info.Uptime
 	restarts := p.info.Restarts
 	p.mu.Unlock()
 
+	forced := restartReason != ""
+
 	// Check restart policy
-	if policy.AutoRestart == protocol.RestartNever {
+	if !forced && policy.AutoRestart == protocol.RestartNever {
 		reason := "autorestart disabled"
+		p.decide(protocol.DecisionNoRestart, reason)
 		p.MarkExited(exitCode, protocol.StatusStopped)
 		p.SetReason(reason)
 		p.LogAction("%s, not restarting", reason)
@@ -54,8 +127,9 @@ func (d *Daemon) handleProcessExit(p *Process, exitCode int) {
 		return
 	}
 
-	if policy.AutoRestart == protocol.RestartOnFailure && exitCode == 0 {
+	if !forced && policy.AutoRestart == protocol.RestartOnFailure && exitCode == 0 {
 		reason := "clean exit (autorestart=on-failure)"
+		p.decide(protocol.DecisionNoRestart, reason)
 		p.MarkExited(exitCode, protocol.StatusStopped)
 		p.SetReason(reason)
 		p.LogAction("%s, not restarting", reason)
@@ -64,8 +138,9 @@ func (d *Daemon) handleProcessExit(p *Process, exitCode int) {
 	}
 
 	// Check exit code filters
-	if len(policy.NoRestartOnExit) > 0 && containsInt(policy.NoRestartOnExit, exitCode) {
+	if !forced && len(policy.NoRestartOnExit) > 0 && containsInt(policy.NoRestartOnExit, exitCode) {
 		reason := fmt.Sprintf("exit code %d excluded from restart", exitCode)
+		p.decide(protocol.DecisionNoRestart, reason)
 		p.MarkExited(exitCode, protocol.StatusStopped)
 		p.SetReason(reason)
 		p.LogAction("%s", reason)
@@ -74,10 +149,12 @@ func (d *Daemon) handleProcessExit(p *Process, exitCode int) {
 		return
 	}
 
-	if len(policy.RestartOnExit) > 0 && !containsInt(policy.RestartOnExit, exitCode) {
+	if !forced && len(policy.RestartOnExit) > 0 && !containsInt(policy.RestartOnExit, exitCode) {
 		reason := fmt.Sprintf("exit code %d not in restart list", exitCode)
+		p.decide(protocol.DecisionGiveUp, reason)
 		p.MarkExited(exitCode, protocol.StatusErrored)
 		p.SetReason(reason)
+		d.notifyWebhooks(p, protocol.NotifyErrored, reason)
 		p.LogAction("%s", reason)
 		slog.Info("exit code not in restart_on_exit, marking errored",
 			"name", p.info.Name, "exit_code", exitCode)
@@ -97,9 +174,14 @@ func (d *Daemon) handleProcessExit(p *Process, exitCode int) {
 
 	// Check max restarts
 	if policy.MaxRestarts > 0 && restarts >= policy.MaxRestarts {
-		reason := fmt.Sprintf("max restarts reached (exit code %d)", exitCode)
+		reason := fmt.Sprintf("max restarts reached (%s)", st)
+		if forced {
+			reason = fmt.Sprintf("max restarts reached (%s)", restartReason)
+		}
+		p.decide(protocol.DecisionGiveUp, reason)
 		p.MarkExited(exitCode, protocol.StatusErrored)
 		p.SetReason(reason)
+		d.notifyWebhooks(p, protocol.NotifyErrored, reason)
 		p.LogAction("%s — giving up after %d restarts", reason, restarts)
 		slog.Info("max restarts reached, marking errored",
 			"name", p.info.Name, "restarts", restarts, "max", policy.MaxRestarts)
@@ -114,40 +196,138 @@ func (d *Daemon) handleProcessExit(p *Process, exitCode int) {
 			delay = policy.MaxDelay.Duration
 		}
 	}
+	delay = jitter(delay, policy.BackoffJitter)
 
-	maxLabel := "unlimited"
-	if policy.MaxRestarts > 0 {
-		maxLabel = fmt.Sprintf("%d", policy.MaxRestarts)
+	lastReason := restartReason
+	if !forced {
+		lastReason = st.String()
 	}
-	p.LogAction("restarting (attempt %d/%s, delay %s)", restarts+1, maxLabel, delay)
-	slog.Info("restarting process",
-		"name", p.info.Name, "delay", delay, "restart_count", restarts+1)
 
-	// Mark as stopped temporarily during delay
-	p.MarkExited(exitCode, protocol.StatusStopped)
+	// Check the restart budget, which counts restarts however long each
+	// run lasted. Over budget, the process cools down in crashloop and is
+	// then tried once more.
+	status := protocol.StatusWaitingRestart
+	statusReason := restartReason
+	decision := protocol.DecisionRestart
+	decisionReason := fmt.Sprintf("%s, restarting in %s", lastReason, delay)
+	crashLoopReason := ""
+	if n, over := p.spendRestart(policy, time.Now()); over {
+		delay = policy.CrashLoopCooldown.Duration
+		if delay <= 0 {
+			delay = protocol.DefaultCrashLoopCooldown
+		}
+		status = protocol.StatusCrashLoop
+		decision = protocol.DecisionCrashLoop
+		budget := fmt.Sprintf("%d restarts in %s", n, policy.RestartWindow)
+		statusReason = budget
+		decisionReason = fmt.Sprintf("%s, %s, retrying in %s", lastReason, budget, delay)
+		p.LogAction("crash loop: %s (budget %d), retrying in %s", budget, policy.RestartBudget, delay)
+		slog.Warn("restart budget exceeded, cooling down",
+			"name", p.info.Name, "restarts", n, "window", policy.RestartWindow, "cooldown", delay)
+		crashLoopReason = fmt.Sprintf("%s exceeds the restart budget of %d, retrying in %s, last: %s", budget, policy.RestartBudget, delay, lastReason)
+	} else {
+		maxLabel := "unlimited"
+		if policy.MaxRestarts > 0 {
+			maxLabel = fmt.Sprintf("%d", policy.MaxRestarts)
+		}
+		p.LogAction("restarting (attempt %d/%s, delay %s)", restarts+1, maxLabel, delay)
+		slog.Info("restarting process",
+			"name", p.info.Name, "delay", delay, "restart_count", restarts+1)
+	}
+
+	// Wait out the delay as waiting_restart (or the cool-down as
+	// crashloop); Stop cancels the restart.
+	p.decide(decision, decisionReason)
+	p.MarkExited(exitCode, status)
+	restartAt := time.Now().Add(delay)
+	cancel := make(chan struct{})
+	p.mu.Lock()
+	p.info.StatusReason = statusReason
+	p.info.LastRestartReason = lastReason
+	p.info.RestartAt = &restartAt
+	p.restartCh = cancel
+	p.mu.Unlock()
+	d.autoSave("process waiting to restart")
+	if crashLoopReason != "" {
+		d.notifyWebhooks(p, protocol.NotifyCrashLoop, crashLoopReason)
+	}
 
-	time.Sleep(delay)
+	timer := time.NewTimer(delay)
+	select {
+	case <-timer.C:
+	case <-cancel:
+		timer.Stop()
+		slog.Info("pending restart cancelled", "name", p.info.Name)
+		return
+	case <-d.stopCh:
+		timer.Stop()
+		return
+	}
 
 	// Increment restart counter and restart
 	p.mu.Lock()
+	if p.restartCh != cancel {
+		p.mu.Unlock()
+		return
+	}
+	p.setStatus(protocol.StatusLaunching)
+	p.info.RestartAt = nil
 	p.info.Restarts++
 	p.mu.Unlock()
 
 	p.CloseLogWriters()
-	if err := p.Start(); err != nil {
+	started, err := p.startPending(cancel)
+	if !started {
+		return
+	}
+	if err != nil {
 		reason := fmt.Sprintf("failed to restart: %s", err)
 		p.MarkExited(exitCode, protocol.StatusErrored)
 		p.SetReason(reason)
+		d.notifyWebhooks(p, protocol.NotifyErrored, reason)
 		slog.Error("failed to restart process", "name", p.info.Name, "error", err)
 		return
 	}
 
+	p.publishRestart(lastReason)
+	d.notifyWebhooks(p, protocol.NotifyRestarted, lastReason)
+	if restarts+1 == d.crashLoopRestarts() {
+		d.notifyWebhooks(p, protocol.NotifyCrashLoop,
+			fmt.Sprintf("restarted %d times without staying up for min_uptime (%s), last: %s", restarts+1, policy.MinUptime, lastReason))
+	}
 	p.LogAction("process started (PID %d)", p.info.PID)
 
 	// Monitor the new process instance
 	go d.monitor(p)
 }
 
+// spendRestart records a restart against p's restart budget, if it has one,
+// and reports how many restarts the window held before it and whether that
+// was the whole budget already.
+func (p *Process) spendRestart(policy protocol.RestartPolicy, now time.Time) (int, bool) {
+	if policy.RestartBudget <= 0 {
+		return 0, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cutoff := now.Add(-policy.RestartWindow.Duration)
+	i := 0
+	for i < len(p.restartTimes) && !p.restartTimes[i].After(cutoff) {
+		i++
+	}
+	p.restartTimes = append(p.restartTimes[i:], now)
+	n := len(p.restartTimes) - 1
+	return n, n >= policy.RestartBudget
+}
+
+// jitter randomizes d by up to ± frac of it.
+func jitter(d time.Duration, frac float64) time.Duration {
+	if frac <= 0 || d <= 0 {
+		return d
+	}
+	return d + time.Duration((rand.Float64()*2-1)*frac*float64(d))
+}
+
 func containsInt(slice []int, val int) bool {
 	for _, s := range slice {
 		if s == val {
@@ -0,0 +1,231 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+
+	"github.com/7c/gopm/internal/config"
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// fixedSettings can't be changed by update, with what to use instead.
+var fixedSettings = map[string]string{
+	"name":      "use rename to change the name",
+	"instances": "use scale to change the number of instances",
+}
+
+// handleUpdate changes the settings of processes in place, keeping their
+// ID, history and state. The restart policy applies from the next exit and
+// everything else from the next start, unless a restart is asked for.
+func (d *Daemon) handleUpdate(params json.RawMessage) protocol.Response {
+	var up protocol.UpdateParams
+	if err := json.Unmarshal(params, &up); err != nil {
+		return errorResponse("invalid update params: " + err.Error())
+	}
+	if up.Target == "" {
+		return errorResponse("target is required")
+	}
+	if len(up.Set) == 0 && len(up.Unset) == 0 && len(up.UnsetEnv) == 0 && len(up.UnsetLabels) == 0 {
+		return errorResponse("no settings to change")
+	}
+
+	procs := d.resolveTarget(up.Target)
+	if len(procs) == 0 {
+		return targetNotFound(up.Target)
+	}
+
+	// Every process's new settings are checked before any is changed.
+	type update struct {
+		p    *Process
+		want protocol.ProcessInfo
+		diff []protocol.FieldChange
+	}
+	updates := make([]update, 0, len(procs))
+	for _, p := range procs {
+		info := p.Info()
+		sp, err := updatedStartParams(info, up)
+		if err != nil {
+			return errorResponse(fmt.Sprintf("%s: %v", info.Name, err))
+		}
+		app := config.AppFromStartParams(sp)
+		if err := app.Validate(); err != nil {
+			return errorResponse(err.Error())
+		}
+		if err := validateStart(&sp); err != nil {
+			return errorResponse(fmt.Sprintf("%s: %v", info.Name, err))
+		}
+		for _, dep := range sp.DependsOn {
+			if len(d.dependencyProcs(dep.Name)) == 0 {
+				return errorResponse(fmt.Sprintf("%s: depends_on: process %q not found", info.Name, dep.Name))
+			}
+		}
+		want := NewProcess(info.ID, sp).info
+		diff := diffStartParams(infoToStartParams(info), infoToStartParams(want))
+		updates = append(updates, update{p, want, diff})
+	}
+
+	results := make([]protocol.UpdateResult, 0, len(updates))
+	changed := false
+	for _, u := range updates {
+		r := protocol.UpdateResult{ID: u.want.ID, Name: u.want.Name, Diff: u.diff}
+		if len(u.diff) > 0 {
+			changed = true
+			fields := make([]string, len(u.diff))
+			for i, f := range u.diff {
+				fields[i] = f.Field
+			}
+			// d.mu too, as list and selectors read labels under it alone.
+			d.mu.Lock()
+			u.p.setConfig(u.want)
+			d.mu.Unlock()
+			d.releaseSockets(socketOwner(u.want)) // if it no longer has any
+			u.p.LogAction("settings changed: %s", strings.Join(fields, ", "))
+			u.p.publishConfig("updated")
+			slog.Info("process settings changed", "name", u.want.Name, "settings", strings.Join(fields, ","))
+
+			if up.Restart {
+				if err := d.restartProcess(u.p, "config changed"); err != nil {
+					r.Error = err.Error()
+				} else {
+					r.Restarted = true
+				}
+			}
+		}
+		results = append(results, r)
+	}
+
+	if changed {
+		if err := d.SaveState(); err != nil {
+			slog.Error("auto-save failed after update", "error", err)
+		}
+	}
+	return successResponse(results)
+}
+
+// updatedStartParams returns the settings of the process info describes
+// with the changes of up made to them. For an instance, the changes are
+// made to its group's settings, so paths stay per instance.
+func updatedStartParams(info protocol.ProcessInfo, up protocol.UpdateParams) (protocol.StartParams, error) {
+	base := infoToStartParams(info)
+	if info.App != "" {
+		base = groupStartParams(info)
+	}
+	fields := startParamFields(base)
+
+	for _, k := range up.Unset {
+		if err := checkSetting(k); err != nil {
+			return protocol.StartParams{}, err
+		}
+		delete(fields, k)
+	}
+	for k, v := range up.Set {
+		if err := checkSetting(k); err != nil {
+			return protocol.StartParams{}, err
+		}
+		if k != "env" && k != "labels" {
+			fields[k] = v
+			continue
+		}
+		var add map[string]string
+		if err := json.Unmarshal(v, &add); err != nil {
+			return protocol.StartParams{}, fmt.Errorf("invalid %s: %v", k, err)
+		}
+		fields[k] = mergeMap(fields[k], add, nil)
+	}
+	if len(up.UnsetEnv) > 0 {
+		fields["env"] = mergeMap(fields["env"], nil, up.UnsetEnv)
+	}
+	if len(up.UnsetLabels) > 0 {
+		fields["labels"] = mergeMap(fields["labels"], nil, up.UnsetLabels)
+	}
+
+	data, _ := json.Marshal(fields)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var sp protocol.StartParams
+	if err := dec.Decode(&sp); err != nil {
+		return protocol.StartParams{}, fmt.Errorf("invalid settings: %v", err)
+	}
+	if info.App != "" {
+		sp = instanceStartParams(sp, info.InstanceID)
+	}
+	return sp, nil
+}
+
+// checkSetting fails if key is not a setting update can change.
+func checkSetting(key string) error {
+	if why, ok := fixedSettings[key]; ok {
+		return fmt.Errorf("cannot change %s: %s", key, why)
+	}
+	dec := json.NewDecoder(strings.NewReader(fmt.Sprintf("{%q: null}", key)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&protocol.StartParams{}); err != nil {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	return nil
+}
+
+// mergeMap returns the JSON string map raw with the entries of add set and
+// the keys of del removed.
+func mergeMap(raw json.RawMessage, add map[string]string, del []string) json.RawMessage {
+	m := make(map[string]string)
+	if len(raw) > 0 {
+		json.Unmarshal(raw, &m)
+	}
+	for k, v := range add {
+		m[k] = v
+	}
+	for _, k := range del {
+		delete(m, k)
+	}
+	data, _ := json.Marshal(m)
+	return data
+}
+
+// setConfig replaces the settings of p with those of c, keeping its state.
+// If its sockets change, the ones it holds are let go, to be bound anew
+// when it next starts. Must be called with the daemon's mu held.
+func (p *Process) setConfig(c protocol.ProcessInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !reflect.DeepEqual(p.info.Sockets, c.Sockets) {
+		p.sockets = nil
+		p.info.SocketListeners = nil
+	}
+
+	p.info.Command = c.Command
+	p.info.Args = c.Args
+	p.info.Cwd = c.Cwd
+	p.info.Env = c.Env
+	p.info.Interpreter = c.Interpreter
+	p.info.Labels = c.Labels
+	p.info.RestartPolicy = c.RestartPolicy
+	p.info.LogOut = c.LogOut
+	p.info.LogErr = c.LogErr
+	p.info.MaxLogSize = c.MaxLogSize
+	p.info.HealthCheck = c.HealthCheck
+	p.info.Cgroup = c.Cgroup
+	p.info.Limits = c.Limits
+	p.info.CronRestart = c.CronRestart
+	p.info.Schedule = c.Schedule
+	p.info.Overlap = c.Overlap
+	p.info.Watch = c.Watch
+	p.info.WatchPaths = c.WatchPaths
+	p.info.IgnoreWatch = c.IgnoreWatch
+	p.info.WatchDelay = c.WatchDelay
+	p.info.DependsOn = c.DependsOn
+	p.info.ReadyMessage = c.ReadyMessage
+	p.info.Sockets = c.Sockets
+	p.info.Notify = c.Notify
+	p.info.WatchdogSec = c.WatchdogSec
+	p.info.Hooks = c.Hooks
+	p.info.StopCommand = c.StopCommand
+	p.info.ReloadSignal = c.ReloadSignal
+	p.info.User = c.User
+	p.info.Group = c.Group
+	p.info.Groups = c.Groups
+}
@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestUpdatedStartParams(t *testing.T) {
+	single := NewProcess(0, protocol.StartParams{
+		Command: "/bin/api", Name: "api", Args: []string{"-v"},
+		Env: map[string]string{"A": "1", "B": "2"},
+	}).Info()
+	web := NewProcess(1, instanceStartParams(protocol.StartParams{Command: "/bin/web", Name: "web", Instances: 2}, 1)).Info()
+
+	sp, err := updatedStartParams(single, protocol.UpdateParams{
+		Set:      map[string]json.RawMessage{"env": json.RawMessage(`{"C":"3","A":"9"}`), "max_restarts": json.RawMessage(`5`)},
+		Unset:    []string{"args"},
+		UnsetEnv: []string{"B"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sp.Env) != 2 || sp.Env["A"] != "9" || sp.Env["C"] != "3" {
+		t.Errorf("env = %v, want A=9 C=3", sp.Env)
+	}
+	if sp.MaxRestarts == nil || *sp.MaxRestarts != 5 {
+		t.Errorf("max_restarts = %v, want 5", sp.MaxRestarts)
+	}
+	if len(sp.Args) != 0 {
+		t.Errorf("args = %v, want none", sp.Args)
+	}
+
+	// An instance is changed through its group's settings.
+	sp, err = updatedStartParams(web, protocol.UpdateParams{Set: map[string]json.RawMessage{"cwd": json.RawMessage(`"/srv"`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.Name != "web-1" || sp.Cwd != "/srv" || sp.LogOut != web.LogOut {
+		t.Errorf("instance params = %+v", sp)
+	}
+
+	for _, tt := range []struct {
+		up   protocol.UpdateParams
+		want string
+	}{
+		{protocol.UpdateParams{Set: map[string]json.RawMessage{"name": json.RawMessage(`"x"`)}}, "cannot change name"},
+		{protocol.UpdateParams{Unset: []string{"instances"}}, "cannot change instances"},
+		{protocol.UpdateParams{Set: map[string]json.RawMessage{"restart_dealy": json.RawMessage(`"1s"`)}}, `unknown setting "restart_dealy"`},
+		{protocol.UpdateParams{Set: map[string]json.RawMessage{"max_restarts": json.RawMessage(`"five"`)}}, "invalid settings"},
+	} {
+		_, err := updatedStartParams(single, tt.up)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.up, err, tt.want)
+		}
+	}
+}
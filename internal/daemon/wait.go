@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// waitPollInterval is how often "wait" checks whether a process is ready.
+const waitPollInterval = 100 * time.Millisecond
+
+func (d *Daemon) handleWait(params json.RawMessage) protocol.Response {
+	var wp protocol.WaitParams
+	if err := json.Unmarshal(params, &wp); err != nil {
+		return errorResponse("invalid wait params: " + err.Error())
+	}
+	if wp.Target == "" {
+		return errorResponse("target is required")
+	}
+	timeout := protocol.DefaultWaitTimeout
+	if wp.Timeout != "" {
+		t, err := time.ParseDuration(wp.Timeout)
+		if err != nil || t <= 0 {
+			return errorResponse(fmt.Sprintf("invalid timeout %q", wp.Timeout))
+		}
+		timeout = t
+	}
+
+	procs := d.resolveTarget(wp.Target)
+	if len(procs) == 0 {
+		return targetNotFound(wp.Target)
+	}
+
+	deadline := time.After(timeout)
+	infos := make([]protocol.ProcessInfo, 0, len(procs))
+	for _, p := range procs {
+		if err := d.waitStarted(p, deadline, timeout); err != nil {
+			return errorResponse(fmt.Sprintf("%s: %v", p.info.Name, err))
+		}
+		infos = append(infos, p.Info())
+	}
+	if len(infos) == 1 {
+		return successResponse(infos[0])
+	}
+	return successResponse(infos)
+}
+
+// waitStarted waits until p is ready: online, which for a notify process
+// means it sent READY=1, and passing its health check if it has one. A
+// scheduled job is ready once armed and a process waiting for depends_on
+// is waited for. It fails if the process exits (even to be restarted),
+// stops or errors first, or when deadline fires.
+func (d *Daemon) waitStarted(p *Process, deadline <-chan time.Time, timeout time.Duration) error {
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	var runExit chan struct{} // closed when the run being waited for exits
+	for {
+		p.mu.Lock()
+		status := p.info.Status
+		reason := p.info.StatusReason
+		exitCode := p.info.ExitCode
+		health := p.info.Health
+		hasCheck := p.info.HealthCheck != nil
+		exitCh := p.exitCh
+		p.mu.Unlock()
+
+		if status.Running() {
+			if runExit == nil {
+				runExit = exitCh
+			} else if exitCh != runExit {
+				return fmt.Errorf("restarted before it was ready")
+			}
+		}
+
+		var want string
+		switch status {
+		case protocol.StatusScheduled:
+			return nil
+		case protocol.StatusOnline:
+			if !hasCheck || health == protocol.HealthHealthy {
+				return nil
+			}
+			want = "health check to pass"
+		case protocol.StatusStarting:
+			want = "READY=1"
+		case protocol.StatusWaiting:
+			want = "dependencies"
+		case protocol.StatusLaunching:
+			want = "it to start"
+		case protocol.StatusErrored:
+			return fmt.Errorf("errored: %s", reason)
+		case protocol.StatusWaitingRestart, protocol.StatusCrashLoop:
+			return fmt.Errorf("exited with code %d before it was ready", exitCode)
+		case protocol.StatusStopping:
+			return fmt.Errorf("stopping")
+		case protocol.StatusStopped:
+			if runExit != nil {
+				return fmt.Errorf("exited with code %d before it was ready", exitCode)
+			}
+			return fmt.Errorf("stopped")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-runExit:
+			return fmt.Errorf("exited before it was ready")
+		case <-deadline:
+			return fmt.Errorf("not ready after %s (waiting for %s)", timeout, want)
+		case <-d.stopCh:
+			return fmt.Errorf("daemon is shutting down")
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package daemon
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// defaultIgnoreWatch is never watched, in addition to ignore_watch.
+var defaultIgnoreWatch = []string{".git", "node_modules"}
+
+// runWatcher restarts a process when files under its watch paths change,
+// until exitCh is closed. Changes are debounced by watch_delay, so a build
+// that writes many files causes a single restart.
+func (d *Daemon) runWatcher(p *Process, exitCh chan struct{}) {
+	p.mu.Lock()
+	name := p.info.Name
+	cwd := p.info.Cwd
+	roots := watchRoots(cwd, p.info.WatchPaths)
+	m := newWatchMatcher(cwd, p.info.IgnoreWatch, p.info.LogOut, p.info.LogErr)
+	delay := protocol.DefaultWatchDelay
+	if dd, err := time.ParseDuration(p.info.WatchDelay); err == nil && dd >= 0 {
+		delay = dd
+	}
+	p.mu.Unlock()
+
+	w, warnings, err := newFileWatcher(roots, m.ignored)
+	for _, warn := range warnings {
+		p.LogAction("watch: %s", warn)
+		slog.Warn("watch warning", "name", name, "warning", warn)
+	}
+	if err != nil {
+		p.LogAction("watch disabled: %v", err)
+		slog.Warn("watch disabled", "name", name, "error", err)
+		return
+	}
+	defer w.Close()
+
+	var (
+		timer   *time.Timer
+		timerC  <-chan time.Time
+		changed = map[string]bool{}
+	)
+	for {
+		select {
+		case path, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			changed[path] = true
+			if timer == nil {
+				timer = time.NewTimer(delay)
+				defer timer.Stop()
+			} else {
+				timer.Reset(delay)
+			}
+			timerC = timer.C
+			continue
+		case <-timerC:
+		case <-exitCh:
+			return
+		case <-d.stopCh:
+			return
+		}
+
+		desc := describeChanges(cwd, changed)
+		if !p.watchDue() {
+			return
+		}
+		p.LogAction("file change detected: %s", desc)
+		slog.Info("file change detected, restarting", "name", name, "changes", desc)
+		// restartProcess stops this run, which closes exitCh, so this
+		// watcher is done once it returns; the new run gets its own.
+		if err := d.restartProcess(p, "file change detected: "+desc); err != nil {
+			slog.Error("watch restart failed", "name", name, "error", err)
+			return
+		}
+		if err := d.SaveState(); err != nil {
+			slog.Error("auto-save failed after watch restart", "error", err)
+		}
+		return
+	}
+}
+
+// watchDue reports whether a watched process can be restarted for a file
+// change: it is running and not already being stopped or restarted.
+func (p *Process) watchDue() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.info.Status.Running() && !p.stopping && p.restartReason == ""
+}
+
+// describeChanges summarizes changed paths for the log, e.g.
+// "src/app.go (+2 more)". Paths under cwd are shown relative to it.
+func describeChanges(cwd string, changed map[string]bool) string {
+	paths := make([]string, 0, len(changed))
+	for path := range changed {
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if len(paths) == 0 {
+		return ""
+	}
+	if len(paths) == 1 {
+		return paths[0]
+	}
+	return fmt.Sprintf("%s (+%d more)", paths[0], len(paths)-1)
+}
+
+// watchRoots resolves watch_paths against cwd, defaulting to cwd itself.
+func watchRoots(cwd string, paths []string) []string {
+	if len(paths) == 0 {
+		return []string{cwd}
+	}
+	roots := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cwd, path)
+		}
+		roots = append(roots, filepath.Clean(path))
+	}
+	return roots
+}
+
+// watchMatcher decides which paths the watcher skips.
+type watchMatcher struct {
+	cwd      string
+	patterns []string
+	logs     []string
+}
+
+func newWatchMatcher(cwd string, ignore []string, logs ...string) *watchMatcher {
+	m := &watchMatcher{cwd: cwd, logs: logs}
+	for _, pat := range append(append([]string{}, defaultIgnoreWatch...), ignore...) {
+		m.patterns = append(m.patterns, filepath.Clean(pat))
+	}
+	return m
+}
+
+// ignored reports whether path should not trigger a restart. The process's
+// own log files (and their rotations) are always ignored, or every log
+// line would restart it. A pattern is matched, like .gitignore, against
+// each path element and each leading part of the path relative to cwd, so
+// "dist" or "*.log" match at any depth and "build/tmp" excludes everything
+// under it. Absolute patterns are matched against the absolute path.
+func (m *watchMatcher) ignored(path string) bool {
+	for _, lf := range m.logs {
+		if path == lf || strings.HasPrefix(path, lf+".") {
+			return true
+		}
+	}
+
+	rel, err := filepath.Rel(m.cwd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = strings.TrimPrefix(path, string(filepath.Separator))
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+
+	for _, pat := range m.patterns {
+		if filepath.IsAbs(pat) {
+			if path == pat || strings.HasPrefix(path, pat+string(filepath.Separator)) {
+				return true
+			}
+			if ok, _ := filepath.Match(pat, path); ok {
+				return true
+			}
+			continue
+		}
+		for i := range parts {
+			if ok, _ := filepath.Match(pat, parts[i]); ok {
+				return true
+			}
+			if ok, _ := filepath.Match(pat, filepath.Join(parts[:i+1]...)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+//go:build linux
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Events that count as a change. IN_MODIFY is left out on purpose: a file
+// still being written reports IN_CLOSE_WRITE once it is done, and a file
+// the process keeps open for appending should not restart it.
+const (
+	watchDirMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB |
+		syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ONLYDIR
+	watchFileMask = syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB |
+		syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+)
+
+// fileWatcher reports changed paths under a set of roots using inotify.
+// Directories are watched recursively, including ones created later.
+type fileWatcher struct {
+	Events chan string // changed paths; closed when the watcher stops
+
+	file    *os.File
+	fd      int
+	roots   []string
+	ignored func(string) bool
+	paths   map[int32]string // watch descriptor -> path; owned by readLoop
+	done    chan struct{}
+}
+
+// newFileWatcher starts watching roots, skipping paths for which ignored
+// returns true. Roots that can't be watched are reported as warnings; it is
+// an error if none can.
+func newFileWatcher(roots []string, ignored func(string) bool) (*fileWatcher, []string, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, nil, fmt.Errorf("inotify: %w", err)
+	}
+	// A non-blocking fd makes the File use the runtime poller, so Close
+	// unblocks a pending Read.
+	w := &fileWatcher{
+		Events:  make(chan string, 64),
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		roots:   roots,
+		ignored: ignored,
+		paths:   make(map[int32]string),
+		done:    make(chan struct{}),
+	}
+
+	var warnings []string
+	for _, root := range roots {
+		if err := w.addTree(root); err != nil {
+			if errors.Is(err, syscall.ENOSPC) {
+				w.file.Close()
+				return nil, warnings, fmt.Errorf("inotify watch limit reached (raise fs.inotify.max_user_watches or narrow watch_paths)")
+			}
+			warnings = append(warnings, err.Error())
+		}
+	}
+	if len(w.paths) == 0 {
+		w.file.Close()
+		return nil, warnings, fmt.Errorf("nothing to watch")
+	}
+
+	go w.readLoop()
+	return w, warnings, nil
+}
+
+// addTree watches root and, if it is a directory, every directory below it
+// that is not ignored. Unreadable subdirectories are skipped.
+func (w *fileWatcher) addTree(root string) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return w.add(root, watchFileMask)
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && w.ignored(path) {
+			return filepath.SkipDir
+		}
+		if err := w.add(path, watchDirMask); err != nil {
+			if path == root || errors.Is(err, syscall.ENOSPC) {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+func (w *fileWatcher) add(path string, mask uint32) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, path, mask)
+	if err != nil {
+		return &fs.PathError{Op: "watch", Path: path, Err: err}
+	}
+	w.paths[int32(wd)] = path
+	return nil
+}
+
+// readLoop decodes inotify events and sends changed paths until Close.
+func (w *fileWatcher) readLoop() {
+	defer close(w.Events)
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameStart := off + syscall.SizeofInotifyEvent
+			off = nameStart + int(ev.Len)
+			if off > n {
+				break
+			}
+			name := strings.TrimRight(string(buf[nameStart:off]), "\x00")
+			if !w.handle(ev.Wd, ev.Mask, name) {
+				return
+			}
+		}
+	}
+}
+
+// handle processes one event. It returns false once the watcher is closed.
+func (w *fileWatcher) handle(wd int32, mask uint32, name string) bool {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		// Events were dropped; report a change so nothing is missed.
+		return w.send(w.roots[0])
+	}
+	dir, ok := w.paths[wd]
+	if !ok {
+		return true
+	}
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(w.paths, wd)
+		return true
+	}
+
+	path := dir
+	if name != "" {
+		path = filepath.Join(dir, name)
+	}
+	if w.ignored(path) {
+		return true
+	}
+	if mask&syscall.IN_ISDIR != 0 && mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+		// Files may land in a new directory before its watch is added;
+		// the directory event itself still counts as a change.
+		w.addTree(path)
+	}
+	return w.send(path)
+}
+
+func (w *fileWatcher) send(path string) bool {
+	select {
+	case w.Events <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// Close stops the watcher and releases its inotify instance.
+func (w *fileWatcher) Close() {
+	close(w.done)
+	w.file.Close()
+}
@@ -0,0 +1,69 @@
+//go:build linux
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// waitWatchEvent reads events from w until one for want arrives, failing
+// if it times out or an event for a path outside allowed comes first.
+func waitWatchEvent(t *testing.T, w *fileWatcher, want string, allowed ...string) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case path := <-w.Events:
+			if path == want {
+				return
+			}
+			if !slices.Contains(allowed, path) {
+				t.Fatalf("unexpected event for %q while waiting for %q", path, want)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for event for %q", want)
+		}
+	}
+}
+
+func TestFileWatcher(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	os.Mkdir(filepath.Join(dir, "tmp"), 0755)
+
+	m := newWatchMatcher(dir, []string{"tmp", "*.swp"})
+	w, warnings, err := newFileWatcher([]string{dir, filepath.Join(dir, "missing")}, m.ignored)
+	if err != nil {
+		t.Fatalf("newFileWatcher: %v", err)
+	}
+	defer w.Close()
+	if len(warnings) != 1 {
+		t.Errorf("warnings = %v, want one for the missing root", warnings)
+	}
+
+	// Ignored paths produce no events; the first event is the real change.
+	main := filepath.Join(dir, "src", "main.go")
+	os.WriteFile(filepath.Join(dir, "tmp", "cache"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", ".main.go.swp"), []byte("x"), 0644)
+	os.WriteFile(main, []byte("x"), 0644)
+	waitWatchEvent(t, w, main)
+
+	// New directories are watched too.
+	sub := filepath.Join(dir, "src", "pkg")
+	os.Mkdir(sub, 0755)
+	waitWatchEvent(t, w, sub, main)
+	util := filepath.Join(sub, "util.go")
+	os.WriteFile(util, []byte("x"), 0644)
+	waitWatchEvent(t, w, util, main, sub)
+}
+
+func TestFileWatcherNothingToWatch(t *testing.T) {
+	m := newWatchMatcher("/", nil)
+	if _, _, err := newFileWatcher([]string{filepath.Join(t.TempDir(), "missing")}, m.ignored); err == nil {
+		t.Error("expected error when no root can be watched")
+	}
+}
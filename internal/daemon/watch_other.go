@@ -0,0 +1,16 @@
+//go:build !linux
+
+package daemon
+
+import "fmt"
+
+// fileWatcher is a stub on platforms without inotify.
+type fileWatcher struct {
+	Events chan string
+}
+
+func newFileWatcher(roots []string, ignored func(string) bool) (*fileWatcher, []string, error) {
+	return nil, nil, fmt.Errorf("file watching is only supported on Linux")
+}
+
+func (w *fileWatcher) Close() {}
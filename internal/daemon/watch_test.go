@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWatchRoots(t *testing.T) {
+	if got := watchRoots("/srv/app", nil); !reflect.DeepEqual(got, []string{"/srv/app"}) {
+		t.Errorf("default roots = %v", got)
+	}
+	got := watchRoots("/srv/app", []string{"src", "./bin/", "/etc/app.conf"})
+	want := []string{"/srv/app/src", "/srv/app/bin", "/etc/app.conf"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("roots = %v, want %v", got, want)
+	}
+}
+
+func TestWatchMatcherIgnored(t *testing.T) {
+	m := newWatchMatcher("/srv/app", []string{"*.log", "build/tmp", "./dist", "/var/cache/app"},
+		"/srv/app/logs/api-out.log", "/srv/app/logs/api-err.log")
+
+	tests := []struct {
+		path    string
+		ignored bool
+	}{
+		{"/srv/app/main.go", false},
+		{"/srv/app/src/handler.go", false},
+		{"/srv/app/.git", true},
+		{"/srv/app/.git/index", true},
+		{"/srv/app/web/node_modules/x/index.js", true},
+		{"/srv/app/debug.log", true},
+		{"/srv/app/src/sub/trace.log", true},
+		{"/srv/app/build/tmp/a.o", true},
+		{"/srv/app/build/out", false},
+		{"/srv/app/dist", true},
+		{"/srv/app/src/dist/x.js", true},
+		{"/var/cache/app/blob", true},
+		{"/var/cache/application", false},
+		{"/srv/app/logs/api-out.log", true},
+		{"/srv/app/logs/api-err.log.2", true},
+		{"/srv/app/logs/other.txt", false},
+		{"/etc/app.conf", false},
+	}
+	for _, tt := range tests {
+		if got := m.ignored(tt.path); got != tt.ignored {
+			t.Errorf("ignored(%q) = %v, want %v", tt.path, got, tt.ignored)
+		}
+	}
+}
+
+func TestDescribeChanges(t *testing.T) {
+	if got := describeChanges("/srv/app", map[string]bool{"/srv/app/src/a.go": true}); got != "src/a.go" {
+		t.Errorf("single change = %q", got)
+	}
+	changed := map[string]bool{"/srv/app/b.go": true, "/srv/app/a.go": true, "/etc/app.conf": true}
+	if got := describeChanges("/srv/app", changed); got != "/etc/app.conf (+2 more)" {
+		t.Errorf("multiple changes = %q", got)
+	}
+}
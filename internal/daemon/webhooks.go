@@ -0,0 +1,123 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/7c/gopm/internal/config"
+	"github.com/7c/gopm/internal/protocol"
+	"github.com/7c/gopm/internal/webhook"
+)
+
+// notifyWebhooks sends event for p to the webhooks that want it, with the
+// process info as of now and the tail of its stderr log. Delivery happens
+// in the background.
+func (d *Daemon) notifyWebhooks(p *Process, event protocol.NotifyEvent, reason string) {
+	if d.webhooks == nil {
+		return
+	}
+	info := p.Info()
+	hooks := d.resolved.WebhooksFor(event, info.Name, info.App)
+	if len(hooks) == 0 {
+		return
+	}
+	now := time.Now()
+	go func() {
+		payload := webhook.Payload{
+			Event:   event,
+			Time:    now,
+			Host:    hostname(),
+			Reason:  reason,
+			Process: webhook.ProcessOf(info),
+			Stderr:  stderrTail(info.LogErr, d.resolved.NotifyStderrLines),
+		}
+		for _, wh := range hooks {
+			d.webhooks.Enqueue(wh.Name, wh.URL, wh.Headers, payload)
+		}
+	}()
+}
+
+// crashLoopRestarts is how many restarts in a row, each before min_uptime,
+// make a crash loop.
+func (d *Daemon) crashLoopRestarts() int {
+	if d.resolved == nil || d.resolved.CrashLoopRestarts == 0 {
+		return config.DefaultCrashLoopRestarts
+	}
+	return d.resolved.CrashLoopRestarts
+}
+
+// handleNotifyTest sends a sample notification to every webhook, whatever
+// its events, and reports how each one answered.
+func (d *Daemon) handleNotifyTest(params json.RawMessage) protocol.Response {
+	var np protocol.NotifyTestParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &np); err != nil {
+			return errorResponse("invalid notify_test params: " + err.Error())
+		}
+	}
+	if np.Event == "" {
+		np.Event = protocol.NotifyErrored
+	}
+	if !np.Event.Valid() {
+		return errorResponse(fmt.Sprintf("unknown event %q (expected errored, restarted, crash-loop or health-failed)", np.Event))
+	}
+	if len(d.resolved.Webhooks) == 0 {
+		return errorResponse("no webhooks configured (notifications.webhooks in gopm.config.json)")
+	}
+
+	payload := webhook.Payload{
+		Event:  np.Event,
+		Time:   time.Now(),
+		Host:   hostname(),
+		Reason: "test notification (gopm notify test)",
+		Test:   true,
+		Process: webhook.Process{
+			Name:         "example",
+			Status:       protocol.StatusErrored,
+			StatusReason: "max restarts reached (exited with code 1)",
+			ExitCode:     1,
+			Restarts:     15,
+		},
+		Stderr: []string{"example: connection refused"},
+	}
+	if np.Target != "" {
+		p := d.findProcess(np.Target)
+		if p == nil {
+			return targetNotFound(np.Target)
+		}
+		info := p.Info()
+		payload.Process = webhook.ProcessOf(info)
+		payload.Stderr = stderrTail(info.LogErr, d.resolved.NotifyStderrLines)
+	}
+
+	results := make([]protocol.NotifyTestResult, len(d.resolved.Webhooks))
+	for i, wh := range d.resolved.Webhooks {
+		results[i] = protocol.NotifyTestResult{Webhook: wh.Name, URL: wh.URL}
+		status, err := webhook.Post(wh.URL, wh.Headers, payload)
+		results[i].Status = status
+		if err != nil {
+			results[i].Error = err.Error()
+		}
+	}
+	return successResponse(results)
+}
+
+// stderrTail returns the last n lines of a stderr log.
+func stderrTail(path string, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	tail, err := tailFile(path, n)
+	if err != nil || tail == "" {
+		return nil
+	}
+	return strings.Split(tail, "\n")
+}
+
+func hostname() string {
+	h, _ := os.Hostname()
+	return h
+}
@@ -1,6 +1,9 @@
 package display
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // ANSI color codes for terminal output.
 // Using raw ANSI to avoid pulling lipgloss into every CLI command.
@@ -49,13 +52,52 @@ func StatusColor(status string) string {
 		return green + status + reset
 	case "stopped":
 		return yellow + status + reset
-	case "errored":
+	case "errored", "crashloop":
 		return red + status + reset
+	case "scheduled":
+		return cyan + status + reset
+	case "waiting":
+		return magenta + status + reset
+	case "starting", "launching", "stopping":
+		return yellow + status + reset
+	case "waiting_restart":
+		return magenta + status + reset
 	default:
 		return status
 	}
 }
 
+// RunStatusColor returns a scheduled run's status colored by outcome.
+func RunStatusColor(status string) string {
+	switch status {
+	case "success":
+		return green + status + reset
+	case "running":
+		return cyan + status + reset
+	case "skipped":
+		return yellow + status + reset
+	case "failed", "killed":
+		return red + status + reset
+	default:
+		return status
+	}
+}
+
+// HealthColor returns the health state colored by result. Any suffix after
+// the state (e.g. failure counts) is colored the same.
+func HealthColor(health string) string {
+	switch {
+	case strings.HasPrefix(health, "healthy"):
+		return green + health + reset
+	case strings.HasPrefix(health, "starting"):
+		return yellow + health + reset
+	case strings.HasPrefix(health, "unhealthy"):
+		return red + health + reset
+	default:
+		return health
+	}
+}
+
 // colorLen returns the number of non-ANSI visible characters.
 // Used for padding calculations when strings contain color codes.
 func visibleLen(s string) int {
@@ -3,7 +3,10 @@ package display
 import (
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/7c/gopm/internal/protocol"
@@ -107,14 +110,26 @@ func (t *Table) coloredRow(w io.Writer, rawCols, colorCols []string) {
 }
 
 // RenderProcessList renders the process list table with colored status.
-// If showPorts is true, a Ports column is included.
+// If showPorts is true, a Ports column is included. A Health column is added
+// when any process has a health check configured. The instances of a group
+// are listed together, indented under a summary row for their app.
 func RenderProcessList(w io.Writer, procs []protocol.ProcessInfo, showPorts bool) {
+	showHealth := false
+	for _, p := range procs {
+		if p.HealthCheck != nil {
+			showHealth = true
+			break
+		}
+	}
 	headers := []string{"ID", "Name", "Status", "PID", "CPU", "Memory", "Restart", "Uptime"}
+	if showHealth {
+		headers = append(headers, "Health")
+	}
 	if showPorts {
 		headers = append(headers, "Ports")
 	}
 	tbl := NewTable(headers...)
-	for _, p := range procs {
+	addProc := func(p protocol.ProcessInfo, indent string) {
 		pid := Dim("-")
 		cpu := Dim("-")
 		mem := Dim("-")
@@ -125,7 +140,7 @@ func RenderProcessList(w io.Writer, procs []protocol.ProcessInfo, showPorts bool
 		rawUptime := "-"
 		rawPorts := "-"
 		ports := Dim("-")
-		if p.Status == protocol.StatusOnline && p.PID > 0 {
+		if p.Status.Running() && p.PID > 0 {
 			rawPid = fmt.Sprintf("%d", p.PID)
 			rawCpu = fmt.Sprintf("%.1f%%", p.CPU)
 			rawMem = protocol.FormatBytes(p.Memory)
@@ -141,16 +156,29 @@ func RenderProcessList(w io.Writer, procs []protocol.ProcessInfo, showPorts bool
 				ports = formatListenerAddrsColored(p.Listeners)
 			}
 		}
+		// Sockets held by the daemon listen whatever the process state.
+		if showPorts && len(p.SocketListeners) > 0 {
+			var own []string
+			if p.Status.Running() {
+				own = p.Listeners
+			}
+			rawPorts, ports = formatPorts(p.SocketListeners, own)
+		}
 		rawStatus := string(p.Status)
 		colorStatus := StatusColor(rawStatus)
-		if p.StatusReason != "" && p.Status == protocol.StatusErrored {
+		if p.StatusReason != "" && (p.Status == protocol.StatusErrored || p.Status == protocol.StatusCrashLoop) {
 			rawStatus += " (" + p.StatusReason + ")"
 			colorStatus += Dim(" ("+p.StatusReason+")")
 		}
+		if p.RestartAt != nil {
+			in := " (in " + protocol.FormatDuration(time.Until(*p.RestartAt)) + ")"
+			rawStatus += in
+			colorStatus += Dim(in)
+		}
 
 		raw := []string{
 			fmt.Sprintf("%d", p.ID),
-			p.Name,
+			indent + p.Name,
 			rawStatus,
 			rawPid,
 			rawCpu,
@@ -160,7 +188,7 @@ func RenderProcessList(w io.Writer, procs []protocol.ProcessInfo, showPorts bool
 		}
 		colored := []string{
 			Dim(fmt.Sprintf("%d", p.ID)),
-			Bold(p.Name),
+			indent + Bold(p.Name),
 			colorStatus,
 			pid,
 			cpu,
@@ -168,15 +196,85 @@ func RenderProcessList(w io.Writer, procs []protocol.ProcessInfo, showPorts bool
 			fmt.Sprintf("%d", p.Restarts),
 			uptime,
 		}
+		if showHealth {
+			rawHealth := "-"
+			health := Dim("-")
+			if p.Status.Running() && p.Health != "" {
+				rawHealth = string(p.Health)
+				health = HealthColor(rawHealth)
+			}
+			raw = append(raw, rawHealth)
+			colored = append(colored, health)
+		}
 		if showPorts {
 			raw = append(raw, rawPorts)
 			colored = append(colored, ports)
 		}
 		tbl.AddColoredRow(raw, colored)
 	}
+
+	groups := make(map[string][]protocol.ProcessInfo)
+	for _, p := range procs {
+		if p.App != "" {
+			groups[p.App] = append(groups[p.App], p)
+		}
+	}
+	listed := make(map[string]bool)
+	for _, p := range procs {
+		if p.App == "" {
+			addProc(p, "")
+			continue
+		}
+		if listed[p.App] {
+			continue
+		}
+		listed[p.App] = true
+		members := groups[p.App]
+		sort.Slice(members, func(i, j int) bool { return members[i].InstanceID < members[j].InstanceID })
+		raw, colored := groupRow(p.App, members, len(headers))
+		tbl.AddColoredRow(raw, colored)
+		for _, m := range members {
+			addProc(m, "  ")
+		}
+	}
 	tbl.Render(w)
 }
 
+// groupRow returns the summary row of an instance group for the process
+// list: how many instances are online and their summed CPU, memory and
+// restarts.
+func groupRow(app string, members []protocol.ProcessInfo, cols int) (raw, colored []string) {
+	online := 0
+	var cpu float64
+	var mem uint64
+	restarts := 0
+	for _, m := range members {
+		if m.Status == protocol.StatusOnline && m.PID > 0 {
+			online++
+			cpu += m.CPU
+			mem += m.Memory
+		}
+		restarts += m.Restarts
+	}
+	rawStatus := fmt.Sprintf("%d/%d online", online, len(members))
+	colorStatus := Yellow(rawStatus)
+	switch online {
+	case len(members):
+		colorStatus = Green(rawStatus)
+	case 0:
+		colorStatus = Red(rawStatus)
+	}
+	rawCpu := fmt.Sprintf("%.1f%%", cpu)
+	rawMem := protocol.FormatBytes(mem)
+	raw = []string{"-", app, rawStatus, "-", rawCpu, rawMem, fmt.Sprintf("%d", restarts), "-"}
+	colored = []string{Dim("-"), Bold(app), colorStatus, Dim("-"), rawCpu, rawMem, fmt.Sprintf("%d", restarts), Dim("-")}
+	for len(raw) < cols {
+		raw = append(raw, "-")
+		colored = append(colored, Dim("-"))
+	}
+	return raw, colored
+}
+
 // parseListener splits "proto@ip:port" into proto and addr.
 func parseListener(l string) (proto, addr string) {
 	if idx := strings.Index(l, "@"); idx >= 0 {
@@ -233,6 +331,44 @@ func formatListenerAddrsColored(listeners []string) string {
 	return strings.Join(parts, ", ")
 }
 
+// formatPorts returns the ports column for a process with sockets held by
+// the daemon: those first, marked with "*" and in cyan (red if non-local),
+// then the ones the process opened itself.
+func formatPorts(sockets, listeners []string) (raw, colored string) {
+	held := make(map[string]bool, len(sockets))
+	var rawParts, parts []string
+	for _, l := range sockets {
+		held[l] = true
+		rawParts = append(rawParts, l+"*")
+		_, addr := parseListener(l)
+		if strings.HasPrefix(l, "unix@") || isLocalAddr(addr) {
+			parts = append(parts, Cyan(l+"*"))
+		} else {
+			parts = append(parts, Red(l+"*"))
+		}
+	}
+	var own []string
+	for _, l := range listeners {
+		if !held[l] {
+			own = append(own, l)
+		}
+	}
+	if len(own) > 0 {
+		rawParts = append(rawParts, formatListenerAddrs(own))
+		parts = append(parts, formatListenerAddrsColored(own))
+	}
+	return strings.Join(rawParts, ", "), strings.Join(parts, ", ")
+}
+
+// joinInts formats a list of exit codes as "1, 2, 3".
+func joinInts(codes []int) string {
+	parts := make([]string, len(codes))
+	for i, c := range codes {
+		parts[i] = strconv.Itoa(c)
+	}
+	return strings.Join(parts, ", ")
+}
+
 // RenderDescribe renders the describe output as a key-value table with colored status.
 func RenderDescribe(w io.Writer, p protocol.ProcessInfo) {
 	tbl := NewTable("Key", "Value")
@@ -245,11 +381,24 @@ func RenderDescribe(w io.Writer, p protocol.ProcessInfo) {
 
 	addKVc("Name", p.Name, Bold(p.Name))
 	addKV("ID", fmt.Sprintf("%d", p.ID))
+	if p.App != "" {
+		addKV("Instance", fmt.Sprintf("%d of %s (%s)", p.InstanceID, p.App, p.Instances))
+	}
+	if len(p.Labels) > 0 {
+		addKV("Labels", protocol.FormatLabels(p.Labels))
+	}
 	addKVc("Status", string(p.Status), StatusColor(string(p.Status)))
 	if p.StatusReason != "" {
 		addKVc("Status Reason", p.StatusReason, Yellow(p.StatusReason))
 	}
-	if p.Status == protocol.StatusOnline && p.PID > 0 {
+	if p.NotifyStatus != "" {
+		addKV("App Status", p.NotifyStatus)
+	}
+	if p.RestartAt != nil {
+		next := p.RestartAt.Local()
+		addKV("Restarting At", fmt.Sprintf("%s (in %s)", next.Format("2006-01-02 15:04:05 MST"), protocol.FormatDuration(time.Until(next))))
+	}
+	if p.Status.Running() && p.PID > 0 {
 		addKV("PID", fmt.Sprintf("%d", p.PID))
 	} else {
 		addKVc("PID", "-", Dim("-"))
@@ -261,30 +410,111 @@ func RenderDescribe(w io.Writer, p protocol.ProcessInfo) {
 		addKVc("Args", "-", Dim("-"))
 	}
 	addKV("CWD", p.Cwd)
+	switch {
+	case p.RunAs != nil:
+		addKV("User", p.RunAs.String())
+	case p.User != "" || p.Group != "":
+		// Not started since it was configured.
+		addKV("User", strings.TrimPrefix(strings.Join([]string{p.User, p.Group}, ":"), ":"))
+	}
 	if p.Interpreter != "" {
 		addKV("Interpreter", p.Interpreter)
 	} else {
 		addKVc("Interpreter", "-", Dim("-"))
 	}
-	if p.Status == protocol.StatusOnline && !p.Uptime.IsZero() {
+	if p.Status.Running() && !p.Uptime.IsZero() {
 		addKV("Uptime", protocol.FormatDuration(time.Since(p.Uptime)))
 	} else {
 		addKVc("Uptime", "-", Dim("-"))
 	}
 	addKV("Created At", p.CreatedAt.Format("2006-01-02 15:04:05 MST"))
 	addKV("Restarts", fmt.Sprintf("%d", p.Restarts))
-	if p.Status != protocol.StatusOnline {
+	if p.LastRestartReason != "" {
+		addKV("Last Restart", p.LastRestartReason)
+	}
+	if !p.Status.Running() {
 		addKV("Last Exit Code", fmt.Sprintf("%d", p.ExitCode))
 	} else {
 		addKVc("Last Exit Code", "-", Dim("-"))
 	}
-	if p.Status == protocol.StatusOnline && p.PID > 0 {
+	if p.Status.Running() && p.PID > 0 {
 		addKV("CPU", fmt.Sprintf("%.1f%%", p.CPU))
 		addKV("Memory", protocol.FormatBytes(p.Memory))
 	} else {
 		addKVc("CPU", "-", Dim("-"))
 		addKVc("Memory", "-", Dim("-"))
 	}
+	if p.CgroupPath != "" {
+		addKV("Cgroup", p.CgroupPath)
+	}
+	if cg := p.Cgroup; cg != nil {
+		var limits []string
+		if cg.CPUMax != "" {
+			limits = append(limits, "cpu "+cg.CPUMax)
+		}
+		if cg.MemoryMax != "" {
+			limits = append(limits, "memory.max "+cg.MemoryMax)
+		}
+		if cg.MemoryHigh != "" {
+			limits = append(limits, "memory.high "+cg.MemoryHigh)
+		}
+		if cg.PidsMax > 0 {
+			limits = append(limits, fmt.Sprintf("pids %d", cg.PidsMax))
+		}
+		if cg.IOWeight > 0 {
+			limits = append(limits, fmt.Sprintf("io.weight %d", cg.IOWeight))
+		}
+		if len(limits) > 0 {
+			v := strings.Join(limits, ", ")
+			if p.Status.Running() && p.CgroupPath == "" {
+				addKVc("Cgroup Limits", v+" (not applied)", Yellow(v+" (not applied)"))
+			} else {
+				addKV("Cgroup Limits", v)
+			}
+		}
+	}
+	if p.Limits != nil {
+		// Configured limits, with the values read back from the process
+		// when it runs; a mismatch means the setting did not take.
+		values := p.Limits.Values()
+		for _, name := range protocol.LimitNames {
+			v, ok := values[name]
+			if !ok {
+				continue
+			}
+			eff, running := p.EffectiveLimits[name]
+			switch {
+			case !running:
+				addKV("Limit "+name, v)
+			case eff == v:
+				addKV("Limit "+name, v+" (effective "+eff+")")
+			default:
+				s := v + " (effective " + eff + ")"
+				addKVc("Limit "+name, s, Yellow(s))
+			}
+		}
+	}
+	if hc := p.HealthCheck; hc != nil {
+		interval, timeout, startPeriod, threshold := hc.Timings()
+		check := fmt.Sprintf("%s %s (every %s, timeout %s, %d failures", hc.Type, hc.Target, interval, timeout, threshold)
+		if startPeriod > 0 {
+			check += fmt.Sprintf(", start period %s", startPeriod)
+		}
+		check += ")"
+		addKV("Health Check", check)
+		if p.Status.Running() && p.Health != "" {
+			health := string(p.Health)
+			if p.HealthFailures > 0 {
+				health += fmt.Sprintf(" (%d/%d failed)", p.HealthFailures, threshold)
+			}
+			addKVc("Health", health, HealthColor(health))
+		} else {
+			addKVc("Health", "-", Dim("-"))
+		}
+		if p.HealthMessage != "" {
+			addKVc("Last Health Error", p.HealthMessage, Yellow(p.HealthMessage))
+		}
+	}
 	addKV("Auto Restart", string(p.RestartPolicy.AutoRestart))
 	if p.RestartPolicy.MaxRestarts > 0 {
 		addKV("Max Restarts", fmt.Sprintf("%d", p.RestartPolicy.MaxRestarts))
@@ -294,8 +524,116 @@ func RenderDescribe(w io.Writer, p protocol.ProcessInfo) {
 	addKV("Min Uptime", p.RestartPolicy.MinUptime.String())
 	addKV("Restart Delay", p.RestartPolicy.RestartDelay.String())
 	addKV("Exp Backoff", fmt.Sprintf("%v", p.RestartPolicy.ExpBackoff))
-	addKV("Kill Signal", fmt.Sprintf("signal %d", p.RestartPolicy.KillSignal))
+	if rp := p.RestartPolicy; rp.BackoffJitter > 0 {
+		addKV("Backoff Jitter", fmt.Sprintf("±%g%%", rp.BackoffJitter*100))
+	}
+	if rp := p.RestartPolicy; rp.RestartBudget > 0 {
+		addKV("Restart Budget", fmt.Sprintf("%d in %s (cool-down %s)", rp.RestartBudget, rp.RestartWindow, rp.CrashLoopCooldown))
+	}
+	if rp := p.RestartPolicy; len(rp.RestartOnExit) > 0 {
+		addKV("Restart On Exit", joinInts(rp.RestartOnExit))
+	}
+	if rp := p.RestartPolicy; len(rp.NoRestartOnExit) > 0 {
+		addKV("No Restart On Exit", joinInts(rp.NoRestartOnExit))
+	}
+	addKV("Kill Signal", protocol.SignalName(syscall.Signal(p.RestartPolicy.KillSignal)))
 	addKV("Kill Timeout", p.RestartPolicy.KillTimeout.String())
+	if p.StopCommand != "" {
+		addKV("Stop Command", p.StopCommand)
+	}
+	if p.ReloadSignal > 0 {
+		addKV("Reload Signal", protocol.SignalName(syscall.Signal(p.ReloadSignal)))
+	}
+	if p.RestartPolicy.MaxMemory > 0 {
+		limit := protocol.FormatBytes(uint64(p.RestartPolicy.MaxMemory))
+		if p.RestartPolicy.MaxMemoryGrace.Duration > 0 {
+			limit += fmt.Sprintf(" (grace %s)", p.RestartPolicy.MaxMemoryGrace.Duration)
+		}
+		addKV("Max Memory", limit)
+		addKV("Memory Restarts", fmt.Sprintf("%d", p.MemoryRestarts))
+	}
+	if p.Schedule != "" {
+		addKV("Schedule", p.Schedule)
+		addKV("Overlap", string(p.Overlap))
+		if p.NextRun != nil {
+			next := p.NextRun.Local()
+			addKV("Next Run", fmt.Sprintf("%s (in %s)", next.Format("2006-01-02 15:04:05 MST"), protocol.FormatDuration(time.Until(next))))
+		} else {
+			addKVc("Next Run", "-", Dim("-"))
+		}
+		if r := p.LastRun; r != nil {
+			detail := ""
+			switch r.Status {
+			case protocol.RunSuccess, protocol.RunFailed, protocol.RunKilled:
+				detail = fmt.Sprintf(" (exit code %d, %s)", r.ExitCode, r.Duration)
+			}
+			detail += " at " + r.StartedAt.Local().Format("2006-01-02 15:04:05")
+			prefix := fmt.Sprintf("#%d ", r.ID)
+			addKVc("Last Run", prefix+string(r.Status)+detail, prefix+RunStatusColor(string(r.Status))+detail)
+		}
+	}
+	if p.CronRestart != "" {
+		addKV("Cron Restart", p.CronRestart)
+		if p.NextCronRestart != nil {
+			next := p.NextCronRestart.Local()
+			addKV("Next Restart", fmt.Sprintf("%s (in %s)", next.Format("2006-01-02 15:04:05 MST"), protocol.FormatDuration(time.Until(next))))
+		} else {
+			addKVc("Next Restart", "-", Dim("-"))
+		}
+	}
+	if p.Watch {
+		paths := "cwd"
+		if len(p.WatchPaths) > 0 {
+			paths = strings.Join(p.WatchPaths, ", ")
+		}
+		delay := p.WatchDelay
+		if delay == "" {
+			delay = protocol.DefaultWatchDelay.String()
+		}
+		addKV("Watch", fmt.Sprintf("%s (delay %s)", paths, delay))
+		if len(p.IgnoreWatch) > 0 {
+			addKV("Ignore Watch", strings.Join(p.IgnoreWatch, ", "))
+		}
+	}
+	if len(p.DependsOn) > 0 {
+		deps := make([]string, len(p.DependsOn))
+		for i, dep := range p.DependsOn {
+			deps[i] = dep.String()
+		}
+		addKV("Depends On", strings.Join(deps, ", "))
+	}
+	if p.ReadyMessage != "" {
+		addKV("Ready Message", p.ReadyMessage)
+	}
+	for i, sock := range p.Sockets {
+		k := ""
+		if i == 0 {
+			k = "Sockets"
+		}
+		v := fmt.Sprintf("fd %d %s", 3+i, sock)
+		if i < len(p.SocketListeners) {
+			addKV(k, v+" (listening on "+p.SocketListeners[i]+")")
+		} else {
+			addKVc(k, v+" (not bound)", v+Yellow(" (not bound)"))
+		}
+	}
+	if p.Notify {
+		notify := "on"
+		if p.WatchdogSec != "" {
+			notify += ", watchdog " + p.WatchdogSec
+		}
+		addKV("Notify", notify)
+	}
+	if h := p.Hooks; h != nil {
+		k := "Hooks"
+		for _, name := range []string{protocol.HookPreStart, protocol.HookPostStart, protocol.HookPreStop, protocol.HookPostExit} {
+			if cmd := h.Command(name); cmd != "" {
+				addKV(k, name+": "+cmd)
+				k = ""
+			}
+		}
+		addKV("Hook Timeout", h.TimeoutDuration().String())
+	}
 	addKV("Stdout Log", p.LogOut)
 	addKV("Stderr Log", p.LogErr)
 	if len(p.Env) > 0 {
@@ -313,3 +651,173 @@ func RenderDescribe(w io.Writer, p protocol.ProcessInfo) {
 	}
 	tbl.Render(w)
 }
+
+// RenderRuns renders the run history of a scheduled job, newest first.
+func RenderRuns(w io.Writer, runs []protocol.RunRecord) {
+	tbl := NewTable("Run", "Status", "Trigger", "Started", "Duration", "Exit", "Reason")
+	for _, r := range runs {
+		status := string(r.Status)
+		duration, exit := "-", "-"
+		if r.Status != protocol.RunSkipped && r.Status != protocol.RunRunning {
+			duration = r.Duration.String()
+			exit = fmt.Sprintf("%d", r.ExitCode)
+		}
+		if r.Status == protocol.RunRunning {
+			duration = protocol.FormatDuration(time.Since(r.StartedAt))
+		}
+		raw := []string{
+			fmt.Sprintf("#%d", r.ID),
+			status,
+			r.Trigger,
+			r.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			duration,
+			exit,
+			r.Reason,
+		}
+		colored := make([]string, len(raw))
+		copy(colored, raw)
+		colored[1] = RunStatusColor(status)
+		if exit == "-" {
+			colored[5] = Dim(exit)
+		}
+		if duration == "-" {
+			colored[4] = Dim(duration)
+		}
+		tbl.AddColoredRow(raw, colored)
+	}
+	tbl.Render(w)
+}
+
+// RenderHistory renders a process's start and exit events.
+func RenderHistory(w io.Writer, events []protocol.ProcessEvent) {
+	tbl := NewTable("Time", "Event", "PID", "Exit", "Uptime", "Decision", "Reason")
+	for _, ev := range events {
+		pid, exit, uptime, decision := "-", "-", "-", "-"
+		if ev.PID > 0 {
+			pid = fmt.Sprintf("%d", ev.PID)
+		}
+		if ev.Type == protocol.EventExit {
+			exit = fmt.Sprintf("%d", ev.ExitCode)
+			if ev.Signal != "" {
+				exit = ev.Signal
+			}
+			if ev.CoreDump {
+				exit += " (core dumped)"
+			}
+		}
+		if ev.Uptime != nil {
+			uptime = ev.Uptime.String()
+		}
+		if ev.Decision != "" {
+			decision = string(ev.Decision)
+		}
+		raw := []string{
+			ev.Time.Local().Format("2006-01-02 15:04:05"),
+			string(ev.Type),
+			pid,
+			exit,
+			uptime,
+			decision,
+			ev.Reason,
+		}
+		colored := make([]string, len(raw))
+		copy(colored, raw)
+		if ev.Type == protocol.EventStart {
+			colored[1] = Green(raw[1])
+		}
+		switch {
+		case exit == "-":
+			colored[3] = Dim(exit)
+		case exit != "0":
+			colored[3] = Red(exit)
+		}
+		for _, i := range []int{2, 4} {
+			if raw[i] == "-" {
+				colored[i] = Dim(raw[i])
+			}
+		}
+		switch ev.Decision {
+		case protocol.DecisionRestart:
+			colored[5] = Yellow(decision)
+		case protocol.DecisionGiveUp:
+			colored[5] = Red(decision)
+		case "":
+			colored[5] = Dim(decision)
+		}
+		tbl.AddColoredRow(raw, colored)
+	}
+	tbl.Render(w)
+}
+
+// FormatEvent formats a subscribe event as one line for gopm events.
+func FormatEvent(ev protocol.Event) string {
+	name := ev.Name
+	if name == "" {
+		name = "daemon"
+	}
+	var detail string
+	switch ev.Type {
+	case protocol.EventStart:
+		detail = fmt.Sprintf("PID %d", ev.PID)
+	case protocol.EventExit:
+		detail = Red(fmt.Sprintf("exit code %d", ev.ExitCode))
+		if ev.ExitCode == 0 {
+			detail = "exit code 0"
+		}
+		if ev.Signal != "" {
+			detail = Red(ev.Signal)
+		}
+		if ev.CoreDump {
+			detail += " (core dumped)"
+		}
+		if ev.Uptime != nil {
+			detail += " after " + ev.Uptime.String()
+		}
+		if ev.Decision != "" {
+			detail += fmt.Sprintf(", %s: %s", ev.Decision, ev.Reason)
+		}
+	case protocol.EventRestart:
+		detail = fmt.Sprintf("PID %d (%s)", ev.PID, ev.Reason)
+	case protocol.EventStatus:
+		detail = fmt.Sprintf("%s -> %s", StatusColor(string(ev.PrevStatus)), StatusColor(string(ev.Status)))
+	case protocol.EventHealth:
+		detail = HealthColor(string(ev.Health))
+		if ev.Reason != "" {
+			detail += ": " + ev.Reason
+		}
+	default:
+		detail = ev.Reason
+	}
+	return fmt.Sprintf("%s  %s  %s  %s", Dim(ev.Time.Local().Format("2006-01-02 15:04:05")), Bold(name), string(ev.Type), detail)
+}
+
+// RenderRun renders a single run with its captured output.
+func RenderRun(w io.Writer, r protocol.RunRecord) {
+	tbl := NewTable("Key", "Value")
+	addKV := func(k, v string) {
+		tbl.AddColoredRow([]string{k, v}, []string{Cyan(k), v})
+	}
+	addKV("Run", fmt.Sprintf("#%d", r.ID))
+	tbl.AddColoredRow([]string{"Status", string(r.Status)}, []string{Cyan("Status"), RunStatusColor(string(r.Status))})
+	addKV("Trigger", r.Trigger)
+	addKV("Started", r.StartedAt.Local().Format("2006-01-02 15:04:05 MST"))
+	if r.PID > 0 {
+		addKV("PID", fmt.Sprintf("%d", r.PID))
+	}
+	if r.Status != protocol.RunSkipped && r.Status != protocol.RunRunning {
+		addKV("Duration", r.Duration.String())
+		addKV("Exit Code", fmt.Sprintf("%d", r.ExitCode))
+	}
+	if r.Reason != "" {
+		addKV("Reason", r.Reason)
+	}
+	tbl.Render(w)
+
+	if r.Output != "" {
+		fmt.Fprintln(w, Dim("--- output ---"))
+		fmt.Fprint(w, r.Output)
+		if !strings.HasSuffix(r.Output, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+}
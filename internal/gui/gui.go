@@ -330,7 +330,7 @@ func (m model) renderTable() string {
 		cpu := "-"
 		mem := "-"
 		uptime := "-"
-		if p.Status == protocol.StatusOnline && p.PID > 0 {
+		if p.Status.Running() && p.PID > 0 {
 			pid = fmt.Sprintf("%d", p.PID)
 			cpu = fmt.Sprintf("%.1f%%", p.CPU)
 			mem = protocol.FormatBytes(p.Memory)
@@ -434,8 +434,16 @@ func colorStatus(s protocol.Status, text string) string {
 		return statusOnline.Render(text)
 	case protocol.StatusStopped:
 		return statusStopped.Render(text)
-	case protocol.StatusErrored:
+	case protocol.StatusErrored, protocol.StatusCrashLoop:
 		return statusErrored.Render(text)
+	case protocol.StatusScheduled:
+		return statusScheduled.Render(text)
+	case protocol.StatusWaiting:
+		return statusWaiting.Render(text)
+	case protocol.StatusStarting, protocol.StatusLaunching, protocol.StatusStopping:
+		return statusStarting.Render(text)
+	case protocol.StatusWaitingRestart:
+		return statusWaiting.Render(text)
 	default:
 		return text
 	}
@@ -501,7 +509,10 @@ func (m model) renderDetail() string {
 	kvLine("Name", p.Name)
 	kvLine("ID", fmt.Sprintf("%d", p.ID))
 	kvLine("Status", string(p.Status))
-	if p.Status == protocol.StatusOnline && p.PID > 0 {
+	if p.RestartAt != nil {
+		kvLine("Restarting In", protocol.FormatDuration(time.Until(*p.RestartAt)))
+	}
+	if p.Status.Running() && p.PID > 0 {
 		kvLine("PID", fmt.Sprintf("%d", p.PID))
 	} else {
 		kvLine("PID", "-")
@@ -514,17 +525,17 @@ func (m model) renderDetail() string {
 	if p.Interpreter != "" {
 		kvLine("Interpreter", p.Interpreter)
 	}
-	if p.Status == protocol.StatusOnline && !p.Uptime.IsZero() {
+	if p.Status.Running() && !p.Uptime.IsZero() {
 		kvLine("Uptime", protocol.FormatDuration(time.Since(p.Uptime)))
 	} else {
 		kvLine("Uptime", "-")
 	}
 	kvLine("Created", p.CreatedAt.Format("2006-01-02 15:04:05"))
 	kvLine("Restarts", fmt.Sprintf("%d", p.Restarts))
-	if p.Status != protocol.StatusOnline {
+	if !p.Status.Running() {
 		kvLine("Exit Code", fmt.Sprintf("%d", p.ExitCode))
 	}
-	if p.Status == protocol.StatusOnline && p.PID > 0 {
+	if p.Status.Running() && p.PID > 0 {
 		kvLine("CPU", fmt.Sprintf("%.1f%%", p.CPU))
 		kvLine("Memory", protocol.FormatBytes(p.Memory))
 	}
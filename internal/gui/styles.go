@@ -16,6 +16,15 @@ var (
 	statusErrored = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("196"))
 
+	statusScheduled = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("39"))
+
+	statusWaiting = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("170"))
+
+	statusStarting = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("226"))
+
 	selectedStyle = lipgloss.NewStyle().
 			Bold(true).
 			Background(lipgloss.Color("236"))
@@ -3,6 +3,7 @@ package logwriter
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
@@ -15,6 +16,9 @@ type RotatingWriter struct {
 	current  *os.File
 	written  int64
 	mu       sync.Mutex
+
+	// Owner of the log files, set by Chown; -1 leaves them to the writer.
+	uid, gid int
 }
 
 // New creates a new RotatingWriter. maxSize is in bytes, maxFiles is the number
@@ -31,6 +35,8 @@ func New(path string, maxSize int64, maxFiles int) (*RotatingWriter, error) {
 		path:     path,
 		maxSize:  maxSize,
 		maxFiles: maxFiles,
+		uid:      -1,
+		gid:      -1,
 	}
 
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -90,11 +96,32 @@ func (w *RotatingWriter) rotate() error {
 	if err != nil {
 		return err
 	}
+	w.chown(f)
 	w.current = f
 	w.written = 0
 	return nil
 }
 
+// Chown makes uid and gid the owner of the log file, and of the files
+// rotation creates from now on.
+func (w *RotatingWriter) Chown(uid, gid int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.uid, w.gid = uid, gid
+	if w.current == nil {
+		return nil
+	}
+	return w.current.Chown(uid, gid)
+}
+
+// chown gives a newly created file the owner set by Chown, if any. Must be
+// called with w.mu held.
+func (w *RotatingWriter) chown(f *os.File) {
+	if w.uid >= 0 || w.gid >= 0 {
+		f.Chown(w.uid, w.gid)
+	}
+}
+
 // Close closes the underlying file.
 func (w *RotatingWriter) Close() error {
 	w.mu.Lock()
@@ -119,11 +146,42 @@ func (w *RotatingWriter) Truncate() error {
 	if err != nil {
 		return err
 	}
+	w.chown(f)
 	w.current = f
 	w.written = 0
 	return nil
 }
 
+// Rename moves the log file and its rotated files to path, where the
+// writer goes on writing and rotating. The open file moves with it.
+func (w *RotatingWriter) Rename(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := Rename(w.path, path, w.maxFiles); err != nil {
+		return err
+	}
+	w.path = path
+	return nil
+}
+
+// Rename moves the log file at oldPath and up to maxFiles rotated files
+// next to it to newPath. Files that don't exist are skipped.
+func Rename(oldPath, newPath string, maxFiles int) error {
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := 1; i <= maxFiles; i++ {
+		err := os.Rename(fmt.Sprintf("%s.%d", oldPath, i), fmt.Sprintf("%s.%d", newPath, i))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 // Path returns the file path of this writer.
 func (w *RotatingWriter) Path() string {
 	return w.path
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 )
 
@@ -67,6 +68,44 @@ func TestRotatingWriterRotation(t *testing.T) {
 	}
 }
 
+func TestRotatingWriterRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "old.log")
+
+	w, err := New(path, 50, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	line := strings.Repeat("X", 30) + "\n"
+	w.Write([]byte(line))
+	w.Write([]byte(line)) // rotates to .1
+
+	newPath := filepath.Join(dir, "new.log")
+	if err := w.Rename(newPath); err != nil {
+		t.Fatal(err)
+	}
+	if w.Path() != newPath {
+		t.Errorf("Path() = %q, want %q", w.Path(), newPath)
+	}
+	for _, p := range []string{path, path + ".1"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should have been moved", p)
+		}
+	}
+	if _, err := os.Stat(newPath + ".1"); err != nil {
+		t.Errorf("rotated file not moved: %v", err)
+	}
+
+	// Writes go on to the moved file.
+	w.Write([]byte("after\n"))
+	data, _ := os.ReadFile(newPath)
+	if string(data) != line+"after\n" {
+		t.Errorf("file content = %q", data)
+	}
+}
+
 func TestRotatingWriterTruncate(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "test.log")
@@ -131,3 +170,32 @@ func TestTimestampWriter(t *testing.T) {
 		t.Errorf("line 1 content wrong: %q", lines[1])
 	}
 }
+
+func TestRotatingWriterChown(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("needs root")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.log")
+
+	w, err := New(path, 50, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := w.Chown(65534, 65534); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rotated-in file is given to the same owner.
+	w.Write([]byte(strings.Repeat("x", 60) + "\n"))
+	w.Write([]byte("after rotation\n"))
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := fi.Sys().(*syscall.Stat_t); st.Uid != 65534 || st.Gid != 65534 {
+		t.Errorf("owner = %d:%d, want 65534:65534", st.Uid, st.Gid)
+	}
+}
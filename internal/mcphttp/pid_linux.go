@@ -53,7 +53,7 @@ func (s *Server) getGoPMInfoForPid(pid int) *procinspect.GoPMInfo {
 
 	for _, p := range procs {
 		if p.PID == pid {
-			return &procinspect.GoPMInfo{
+			info := &procinspect.GoPMInfo{
 				Managed:     true,
 				DaemonUp:    true,
 				Name:        p.Name,
@@ -63,6 +63,13 @@ func (s *Server) getGoPMInfoForPid(pid int) *procinspect.GoPMInfo {
 				LogOut:      p.LogOut,
 				LogErr:      p.LogErr,
 			}
+			if p.RunAs != nil {
+				info.RunAs = p.RunAs.String()
+			}
+			if p.Limits != nil {
+				info.Limits = procinspect.CompareLimits(pid, p.Limits)
+			}
+			return info
 		}
 	}
 
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/7c/gopm/internal/protocol"
@@ -209,19 +210,29 @@ func (s *Server) handleToolsCall(req *jsonRPCRequest) *jsonRPCResponse {
 	case "gopm_ping":
 		result = s.toolPing()
 	case "gopm_list":
-		result = s.toolCallDaemon(protocol.MethodList, nil)
+		result = s.toolCallDaemon(protocol.MethodList, params.Arguments)
 	case "gopm_start":
 		result = s.toolCallDaemon(protocol.MethodStart, params.Arguments)
 	case "gopm_stop":
 		result = s.toolCallDaemon(protocol.MethodStop, params.Arguments)
 	case "gopm_restart":
 		result = s.toolCallDaemon(protocol.MethodRestart, params.Arguments)
+	case "gopm_reload":
+		result = s.toolCallDaemon(protocol.MethodReload, params.Arguments)
+	case "gopm_signal":
+		result = s.toolCallDaemon(protocol.MethodSignal, params.Arguments)
 	case "gopm_delete":
 		result = s.toolCallDaemon(protocol.MethodDelete, params.Arguments)
+	case "gopm_scale":
+		result = s.toolCallDaemon(protocol.MethodScale, params.Arguments)
+	case "gopm_update":
+		result = s.toolCallDaemon(protocol.MethodUpdate, params.Arguments)
 	case "gopm_describe":
 		result = s.toolCallDaemon(protocol.MethodDescribe, params.Arguments)
 	case "gopm_isrunning":
 		result = s.toolCallDaemon(protocol.MethodIsRunning, params.Arguments)
+	case "gopm_history":
+		result = s.toolCallDaemon(protocol.MethodHistory, params.Arguments)
 	case "gopm_logs":
 		result = s.toolLogs(params.Arguments)
 	case "gopm_flush":
@@ -356,16 +367,17 @@ func (s *Server) toolExport(args json.RawMessage) interface{} {
 		return mcpError("no processes defined")
 	}
 
-	// Filter by target.
+	// Filter by target. An instance group is exported as one app.
 	var selected []protocol.ProcessInfo
 	if p.Target == "all" {
-		selected = procs
+		selected = protocol.GroupInstances(procs)
 	} else {
 		for _, proc := range procs {
-			if proc.Name == p.Target || fmt.Sprintf("%d", proc.ID) == p.Target {
+			if proc.Name == p.Target || proc.App == p.Target || fmt.Sprintf("%d", proc.ID) == p.Target {
 				selected = append(selected, proc)
 			}
 		}
+		selected = protocol.GroupInstances(selected)
 		if len(selected) == 0 {
 			return mcpError(fmt.Sprintf("process %q not found", p.Target))
 		}
@@ -379,6 +391,7 @@ func (s *Server) toolExport(args json.RawMessage) interface{} {
 		Cwd          string            `json:"cwd,omitempty"`
 		Interpreter  string            `json:"interpreter,omitempty"`
 		Env          map[string]string `json:"env,omitempty"`
+		Labels       map[string]string `json:"labels,omitempty"`
 		AutoRestart  string            `json:"autorestart,omitempty"`
 		MaxRestarts  *int              `json:"max_restarts,omitempty"`
 		MinUptime    string            `json:"min_uptime,omitempty"`
@@ -389,6 +402,53 @@ func (s *Server) toolExport(args json.RawMessage) interface{} {
 		LogOut       string            `json:"log_out,omitempty"`
 		LogErr       string            `json:"log_err,omitempty"`
 		MaxLogSize   string            `json:"max_log_size,omitempty"`
+
+		HealthCheck *protocol.HealthCheck `json:"health_check,omitempty"`
+
+		MaxMemory      string `json:"max_memory,omitempty"`
+		MaxMemoryGrace string `json:"max_memory_grace,omitempty"`
+
+		RestartBudget     string  `json:"restart_budget,omitempty"`
+		CrashLoopCooldown string  `json:"crashloop_cooldown,omitempty"`
+		BackoffJitter     float64 `json:"backoff_jitter,omitempty"`
+
+		RestartOnExit   []int `json:"restart_on_exit,omitempty"`
+		NoRestartOnExit []int `json:"no_restart_on_exit,omitempty"`
+
+		KillSignal   string `json:"kill_signal,omitempty"`
+		StopCommand  string `json:"stop_command,omitempty"`
+		ReloadSignal string `json:"reload_signal,omitempty"`
+
+		User   string   `json:"user,omitempty"`
+		Group  string   `json:"group,omitempty"`
+		Groups []string `json:"groups,omitempty"`
+
+		Cgroup *protocol.CgroupLimits `json:"cgroup,omitempty"`
+
+		Limits *protocol.Limits `json:"limits,omitempty"`
+
+		CronRestart string `json:"cron_restart,omitempty"`
+
+		Schedule string `json:"schedule,omitempty"`
+		Overlap  string `json:"overlap,omitempty"`
+
+		Watch       bool     `json:"watch,omitempty"`
+		WatchPaths  []string `json:"watch_paths,omitempty"`
+		IgnoreWatch []string `json:"ignore_watch,omitempty"`
+		WatchDelay  string   `json:"watch_delay,omitempty"`
+
+		DependsOn []protocol.Dependency `json:"depends_on,omitempty"`
+
+		ReadyMessage string `json:"ready_message,omitempty"`
+
+		Sockets []protocol.Socket `json:"sockets,omitempty"`
+
+		Notify      bool   `json:"notify,omitempty"`
+		WatchdogSec string `json:"watchdog_sec,omitempty"`
+
+		Hooks *protocol.Hooks `json:"hooks,omitempty"`
+
+		Instances protocol.Instances `json:"instances,omitempty"`
 	}
 	defaults := protocol.DefaultRestartPolicy()
 
@@ -410,6 +470,9 @@ func (s *Server) toolExport(args json.RawMessage) interface{} {
 		if len(proc.Env) > 0 {
 			app.Env = proc.Env
 		}
+		if len(proc.Labels) > 0 {
+			app.Labels = proc.Labels
+		}
 
 		rp := proc.RestartPolicy
 		if p.Full || rp.AutoRestart != defaults.AutoRestart {
@@ -434,6 +497,25 @@ func (s *Server) toolExport(args json.RawMessage) interface{} {
 		if p.Full || rp.KillTimeout.Duration != defaults.KillTimeout.Duration {
 			app.KillTimeout = rp.KillTimeout.Duration.String()
 		}
+		if rp.KillSignal > 0 && (p.Full || rp.KillSignal != defaults.KillSignal) {
+			app.KillSignal = protocol.SignalName(syscall.Signal(rp.KillSignal))
+		}
+		app.StopCommand = proc.StopCommand
+		if proc.ReloadSignal > 0 {
+			app.ReloadSignal = protocol.SignalName(syscall.Signal(proc.ReloadSignal))
+		}
+		app.RestartOnExit = rp.RestartOnExit
+		app.NoRestartOnExit = rp.NoRestartOnExit
+		app.User = proc.User
+		app.Group = proc.Group
+		app.Groups = proc.Groups
+		if rp.RestartBudget > 0 {
+			app.RestartBudget = protocol.FormatRestartBudget(rp.RestartBudget, rp.RestartWindow.Duration)
+			if p.Full || rp.CrashLoopCooldown.Duration != defaults.CrashLoopCooldown.Duration {
+				app.CrashLoopCooldown = rp.CrashLoopCooldown.Duration.String()
+			}
+		}
+		app.BackoffJitter = rp.BackoffJitter
 		if p.Full {
 			if proc.LogOut != "" {
 				app.LogOut = proc.LogOut
@@ -445,6 +527,31 @@ func (s *Server) toolExport(args json.RawMessage) interface{} {
 				app.MaxLogSize = protocol.FormatSize(proc.MaxLogSize)
 			}
 		}
+		app.HealthCheck = proc.HealthCheck
+		app.Cgroup = proc.Cgroup
+		app.Limits = proc.Limits
+		app.CronRestart = proc.CronRestart
+		app.Schedule = proc.Schedule
+		if proc.Schedule != "" && (p.Full || proc.Overlap != protocol.OverlapSkip) {
+			app.Overlap = string(proc.Overlap)
+		}
+		app.Watch = proc.Watch
+		app.WatchPaths = proc.WatchPaths
+		app.IgnoreWatch = proc.IgnoreWatch
+		app.WatchDelay = proc.WatchDelay
+		app.DependsOn = proc.DependsOn
+		app.ReadyMessage = proc.ReadyMessage
+		app.Sockets = proc.Sockets
+		app.Notify = proc.Notify
+		app.WatchdogSec = proc.WatchdogSec
+		app.Hooks = proc.Hooks
+		app.Instances = proc.Instances
+		if rp.MaxMemory > 0 {
+			app.MaxMemory = protocol.FormatSize(rp.MaxMemory)
+		}
+		if rp.MaxMemoryGrace.Duration > 0 || (p.Full && rp.MaxMemory > 0) {
+			app.MaxMemoryGrace = rp.MaxMemoryGrace.Duration.String()
+		}
 		apps = append(apps, app)
 	}
 
@@ -463,6 +570,7 @@ func (s *Server) toolImport(args json.RawMessage) interface{} {
 			Cwd          string            `json:"cwd,omitempty"`
 			Interpreter  string            `json:"interpreter,omitempty"`
 			Env          map[string]string `json:"env,omitempty"`
+			Labels       map[string]string `json:"labels,omitempty"`
 			AutoRestart  string            `json:"autorestart,omitempty"`
 			MaxRestarts  *int              `json:"max_restarts,omitempty"`
 			MinUptime    string            `json:"min_uptime,omitempty"`
@@ -473,6 +581,53 @@ func (s *Server) toolImport(args json.RawMessage) interface{} {
 			LogOut       string            `json:"log_out,omitempty"`
 			LogErr       string            `json:"log_err,omitempty"`
 			MaxLogSize   string            `json:"max_log_size,omitempty"`
+
+			HealthCheck *protocol.HealthCheck `json:"health_check,omitempty"`
+
+			MaxMemory      string `json:"max_memory,omitempty"`
+			MaxMemoryGrace string `json:"max_memory_grace,omitempty"`
+
+			RestartBudget     string  `json:"restart_budget,omitempty"`
+			CrashLoopCooldown string  `json:"crashloop_cooldown,omitempty"`
+			BackoffJitter     float64 `json:"backoff_jitter,omitempty"`
+
+			RestartOnExit   []int `json:"restart_on_exit,omitempty"`
+			NoRestartOnExit []int `json:"no_restart_on_exit,omitempty"`
+
+			KillSignal   string `json:"kill_signal,omitempty"`
+			StopCommand  string `json:"stop_command,omitempty"`
+			ReloadSignal string `json:"reload_signal,omitempty"`
+
+			User   string   `json:"user,omitempty"`
+			Group  string   `json:"group,omitempty"`
+			Groups []string `json:"groups,omitempty"`
+
+			Cgroup *protocol.CgroupLimits `json:"cgroup,omitempty"`
+
+			Limits *protocol.Limits `json:"limits,omitempty"`
+
+			CronRestart string `json:"cron_restart,omitempty"`
+
+			Schedule string `json:"schedule,omitempty"`
+			Overlap  string `json:"overlap,omitempty"`
+
+			Watch       bool     `json:"watch,omitempty"`
+			WatchPaths  []string `json:"watch_paths,omitempty"`
+			IgnoreWatch []string `json:"ignore_watch,omitempty"`
+			WatchDelay  string   `json:"watch_delay,omitempty"`
+
+			DependsOn []protocol.Dependency `json:"depends_on,omitempty"`
+
+			ReadyMessage string `json:"ready_message,omitempty"`
+
+			Sockets []protocol.Socket `json:"sockets,omitempty"`
+
+			Notify      bool   `json:"notify,omitempty"`
+			WatchdogSec string `json:"watchdog_sec,omitempty"`
+
+			Hooks *protocol.Hooks `json:"hooks,omitempty"`
+
+			Instances protocol.Instances `json:"instances,omitempty"`
 		} `json:"apps"`
 	}
 	if err := json.Unmarshal(args, &p); err != nil {
@@ -498,9 +653,31 @@ func (s *Server) toolImport(args json.RawMessage) interface{} {
 		existingSet[key{proc.Cwd, proc.Command}] = proc.Name
 	}
 
+	// Start apps after the ones they depend on.
+	indexes := make(map[string][]int, len(p.Apps))
+	names := make([]string, 0, len(p.Apps))
+	for i, app := range p.Apps {
+		if len(indexes[app.Name]) == 0 {
+			names = append(names, app.Name)
+		}
+		indexes[app.Name] = append(indexes[app.Name], i)
+	}
+	order, err := protocol.DependencyOrder(names, func(name string) []protocol.Dependency {
+		return p.Apps[indexes[name][0]].DependsOn
+	})
+	if err != nil {
+		return mcpError(fmt.Sprintf("invalid import params: depends_on: %v", err))
+	}
+
+	var ordered []int
+	for _, name := range order {
+		ordered = append(ordered, indexes[name]...)
+	}
+
 	var lines []string
 	imported, skipped := 0, 0
-	for _, app := range p.Apps {
+	for _, i := range ordered {
+		app := p.Apps[i]
 		if app.Command == "" {
 			lines = append(lines, fmt.Sprintf("SKIP %s: command is required", app.Name))
 			skipped++
@@ -520,6 +697,7 @@ func (s *Server) toolImport(args json.RawMessage) interface{} {
 			Cwd:          app.Cwd,
 			Interpreter:  app.Interpreter,
 			Env:          app.Env,
+			Labels:       app.Labels,
 			AutoRestart:  app.AutoRestart,
 			MaxRestarts:  app.MaxRestarts,
 			MinUptime:    app.MinUptime,
@@ -530,6 +708,50 @@ func (s *Server) toolImport(args json.RawMessage) interface{} {
 			LogOut:       app.LogOut,
 			LogErr:       app.LogErr,
 			MaxLogSize:   app.MaxLogSize,
+			HealthCheck:  app.HealthCheck,
+
+			MaxMemory:      app.MaxMemory,
+			MaxMemoryGrace: app.MaxMemoryGrace,
+
+			RestartBudget:     app.RestartBudget,
+			CrashLoopCooldown: app.CrashLoopCooldown,
+			BackoffJitter:     app.BackoffJitter,
+
+			RestartOnExit:   app.RestartOnExit,
+			NoRestartOnExit: app.NoRestartOnExit,
+
+			KillSignal:   app.KillSignal,
+			StopCommand:  app.StopCommand,
+			ReloadSignal: app.ReloadSignal,
+
+			User:   app.User,
+			Group:  app.Group,
+			Groups: app.Groups,
+
+			Cgroup: app.Cgroup,
+			Limits: app.Limits,
+
+			CronRestart: app.CronRestart,
+			Schedule:    app.Schedule,
+			Overlap:     app.Overlap,
+
+			Watch:       app.Watch,
+			WatchPaths:  app.WatchPaths,
+			IgnoreWatch: app.IgnoreWatch,
+			WatchDelay:  app.WatchDelay,
+
+			DependsOn: app.DependsOn,
+
+			ReadyMessage: app.ReadyMessage,
+
+			Sockets: app.Sockets,
+
+			Notify:      app.Notify,
+			WatchdogSec: app.WatchdogSec,
+
+			Hooks: app.Hooks,
+
+			Instances: app.Instances,
 		}
 		raw, _ := json.Marshal(params)
 		startResp := s.daemon.HandleRequest(protocol.Request{Method: protocol.MethodStart, Params: raw})
@@ -538,8 +760,8 @@ func (s *Server) toolImport(args json.RawMessage) interface{} {
 			continue
 		}
 
-		var info protocol.ProcessInfo
-		if err := json.Unmarshal(startResp.Data, &info); err == nil {
+		infos, _ := protocol.DecodeStarted(startResp.Data)
+		for _, info := range infos {
 			lines = append(lines, fmt.Sprintf("OK %s (PID: %d)", info.Name, info.PID))
 		}
 		imported++
@@ -23,8 +23,10 @@ func toolDefs() []toolDef {
 			Name:        "gopm_list",
 			Description: "List all managed processes with their status, PID, CPU, memory, and uptime",
 			InputSchema: map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{},
+				"type": "object",
+				"properties": map[string]interface{}{
+					"selector": map[string]interface{}{"type": "string", "description": "Optional: only processes matching labels or a name pattern, e.g. 'team=payments' or 'api-*'"},
+				},
 			},
 		},
 		{
@@ -33,15 +35,45 @@ func toolDefs() []toolDef {
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
-					"command":       map[string]interface{}{"type": "string", "description": "Path to the script or binary to run"},
-					"name":          map[string]interface{}{"type": "string", "description": "Process name (defaults to binary name)"},
-					"args":          map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Arguments to pass"},
-					"cwd":           map[string]interface{}{"type": "string", "description": "Working directory"},
-					"interpreter":   map[string]interface{}{"type": "string", "description": "Interpreter (e.g. node, python3)"},
-					"env":           map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}, "description": "Environment variables"},
-					"autorestart":   map[string]interface{}{"type": "string", "enum": []string{"always", "on-failure", "never"}, "description": "Restart policy"},
-					"max_restarts":  map[string]interface{}{"type": "integer", "description": "Maximum restart attempts"},
-					"restart_delay": map[string]interface{}{"type": "string", "description": "Delay between restarts (e.g. 1s)"},
+					"command":            map[string]interface{}{"type": "string", "description": "Path to the script or binary to run"},
+					"name":               map[string]interface{}{"type": "string", "description": "Process name (defaults to binary name)"},
+					"args":               map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Arguments to pass"},
+					"cwd":                map[string]interface{}{"type": "string", "description": "Working directory"},
+					"interpreter":        map[string]interface{}{"type": "string", "description": "Interpreter (e.g. node, python3)"},
+					"env":                map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}, "description": "Environment variables"},
+					"labels":             map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}, "description": "Labels (key: value) to select the process by"},
+					"autorestart":        map[string]interface{}{"type": "string", "enum": []string{"always", "on-failure", "never"}, "description": "Restart policy"},
+					"max_restarts":       map[string]interface{}{"type": "integer", "description": "Maximum restart attempts"},
+					"restart_delay":      map[string]interface{}{"type": "string", "description": "Delay between restarts (e.g. 1s)"},
+					"max_memory":         map[string]interface{}{"type": "string", "description": "Restart when RSS exceeds this size (e.g. 512M)"},
+					"restart_budget":     map[string]interface{}{"type": "string", "description": "At most this many restarts in a window, e.g. 10/15m; over it the process enters crashloop"},
+					"crashloop_cooldown": map[string]interface{}{"type": "string", "description": "How long a process in crashloop waits before one more try (default 5m)"},
+					"backoff_jitter":     map[string]interface{}{"type": "number", "description": "Randomize each restart delay by up to ± this fraction of it (0-1)"},
+					"restart_on_exit":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}, "description": "Only restart on these exit codes"},
+					"no_restart_on_exit": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}, "description": "Never restart on these exit codes"},
+					"kill_signal":        map[string]interface{}{"type": "string", "description": "Signal that stops the process, e.g. SIGINT or SIGQUIT (default SIGTERM)"},
+					"stop_command":       map[string]interface{}{"type": "string", "description": "Shell command run to stop the process, before the kill signal"},
+					"reload_signal":      map[string]interface{}{"type": "string", "description": "Signal that makes the process reload in place, e.g. SIGHUP; gopm_reload sends it instead of replacing the process"},
+					"user":               map[string]interface{}{"type": "string", "description": "Run as this user, by name or uid (needs a daemon running as root)"},
+					"group":              map[string]interface{}{"type": "string", "description": "Run with this group, by name or gid (default: the user's primary group)"},
+					"groups":             map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Supplementary groups (default: the user's own)"},
+					"cgroup":             map[string]interface{}{"type": "object", "description": "cgroup v2 limits: {cpu_max: \"50%\", memory_max, memory_high, pids_max, io_weight}"},
+					"limits":             map[string]interface{}{"type": "object", "description": "Per-process settings applied at launch: {nofile, nproc, core, memlock, nice, ionice_class, ionice_level, cpu_affinity, oom_score_adj, umask}"},
+					"health_check":       map[string]interface{}{"type": "object", "description": "Active health check: {type: http|tcp|exec, target, interval, timeout, failure_threshold, start_period}"},
+					"cron_restart":       map[string]interface{}{"type": "string", "description": "Restart on a cron schedule (e.g. \"0 3 * * *\" or @daily)"},
+					"schedule":           map[string]interface{}{"type": "string", "description": "Run as a scheduled job on this cron expression instead of keeping it alive"},
+					"overlap":            map[string]interface{}{"type": "string", "enum": []string{"skip", "queue", "kill"}, "description": "Scheduled job overlap policy (default skip)"},
+					"watch":              map[string]interface{}{"type": "boolean", "description": "Restart when watched files change"},
+					"watch_paths":        map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Files or directories to watch (default: cwd)"},
+					"ignore_watch":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Glob patterns of paths not to watch"},
+					"watch_delay":        map[string]interface{}{"type": "string", "description": "How long changes must settle before restarting (default 1s)"},
+					"depends_on":         map[string]interface{}{"type": "array", "description": "Processes to wait for before starting: names, or {name, condition: started|healthy|completed_successfully}"},
+					"ready_message":      map[string]interface{}{"type": "string", "description": "Text the process prints on stdout once it is ready, used by reload"},
+					"sockets":            map[string]interface{}{"type": "array", "description": "Listening sockets gopm binds and passes as LISTEN_FDS: addresses (tcp:HOST:PORT, unix:/path), or {name, address}"},
+					"notify":             map[string]interface{}{"type": "boolean", "description": "The process speaks the sd_notify protocol and is starting until it sends READY=1"},
+					"watchdog_sec":       map[string]interface{}{"type": "string", "description": "Restart if the process sends no WATCHDOG=1 for this long (e.g. 30s); requires notify"},
+					"hooks":              map[string]interface{}{"type": "object", "description": "Lifecycle hook shell commands: {pre_start, post_start, pre_stop, post_exit, timeout}; a failing pre_start blocks the start"},
+					"instances":          map[string]interface{}{"description": "Run this many instances as a group: a number, or \"max\" for one per CPU"},
 				},
 				"required": []string{"command"},
 			},
@@ -51,7 +83,7 @@ func toolDefs() []toolDef {
 			Description: "Stop a running process by name, ID, or 'all'",
 			InputSchema: map[string]interface{}{
 				"type":       "object",
-				"properties": map[string]interface{}{"target": map[string]interface{}{"type": "string", "description": "Process name, ID, or 'all'"}},
+				"properties": map[string]interface{}{"target": map[string]interface{}{"type": "string", "description": "Process name, ID, 'all', or a selector (labels or name pattern, e.g. 'team=payments', 'api-*')"}},
 				"required":   []string{"target"},
 			},
 		},
@@ -60,19 +92,72 @@ func toolDefs() []toolDef {
 			Description: "Restart a process by name, ID, or 'all'",
 			InputSchema: map[string]interface{}{
 				"type":       "object",
-				"properties": map[string]interface{}{"target": map[string]interface{}{"type": "string", "description": "Process name, ID, or 'all'"}},
+				"properties": map[string]interface{}{"target": map[string]interface{}{"type": "string", "description": "Process name, ID, 'all', or a selector (labels or name pattern, e.g. 'team=payments', 'api-*')"}},
 				"required":   []string{"target"},
 			},
 		},
+		{
+			Name:        "gopm_reload",
+			Description: "Restart a process without downtime: start a replacement, wait until it is ready, then stop the old one. Instances of a group are reloaded one at a time",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"target":  map[string]interface{}{"type": "string", "description": "Process name, ID, instance group, 'all', or a selector (labels or name pattern)"},
+					"timeout": map[string]interface{}{"type": "string", "description": "How long to wait for each replacement to become ready (default 30s)"},
+				},
+				"required": []string{"target"},
+			},
+		},
+		{
+			Name:        "gopm_signal",
+			Description: "Send a signal (e.g. SIGHUP to reload config, SIGUSR1 to reopen logs) to a running process, or to its whole process group",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"target": map[string]interface{}{"type": "string", "description": "Process name, ID, instance group, 'all', or a selector (labels or name pattern)"},
+					"signal": map[string]interface{}{"type": "string", "description": "Signal name (SIGHUP, HUP) or number"},
+					"group":  map[string]interface{}{"type": "boolean", "description": "Signal the whole process group instead of just the process (default false)"},
+				},
+				"required": []string{"target", "signal"},
+			},
+		},
 		{
 			Name:        "gopm_delete",
 			Description: "Delete a process (stops first if running)",
 			InputSchema: map[string]interface{}{
 				"type":       "object",
-				"properties": map[string]interface{}{"target": map[string]interface{}{"type": "string", "description": "Process name, ID, or 'all'"}},
+				"properties": map[string]interface{}{"target": map[string]interface{}{"type": "string", "description": "Process name, ID, 'all', or a selector (labels or name pattern, e.g. 'team=payments', 'api-*')"}},
 				"required":   []string{"target"},
 			},
 		},
+		{
+			Name:        "gopm_scale",
+			Description: "Grow or shrink an instance group to a number of instances",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"target":    map[string]interface{}{"type": "string", "description": "App name of the instance group"},
+					"instances": map[string]interface{}{"description": "Number of instances, or \"max\" for one per CPU"},
+				},
+				"required": []string{"target", "instances"},
+			},
+		},
+		{
+			Name:        "gopm_update",
+			Description: "Change the settings of processes in place, keeping their ID and history. The restart policy applies from the next exit, everything else from the next start unless restart is set",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"target":       map[string]interface{}{"type": "string", "description": "Process name, ID, instance group, 'all', or a selector (labels or name pattern)"},
+					"set":          map[string]interface{}{"type": "object", "description": "Settings to change, by their ecosystem file names, e.g. {\"max_restarts\": 5}. env and labels are merged into the current ones"},
+					"unset":        map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Settings to clear, by their ecosystem file names"},
+					"unset_env":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Environment variables to remove"},
+					"unset_labels": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Labels to remove"},
+					"restart":      map[string]interface{}{"type": "boolean", "description": "Restart the processes if their settings changed (default false)"},
+				},
+				"required": []string{"target"},
+			},
+		},
 		{
 			Name:        "gopm_describe",
 			Description: "Show detailed information about a specific process",
@@ -91,6 +176,18 @@ func toolDefs() []toolDef {
 				"required":   []string{"target"},
 			},
 		},
+		{
+			Name:        "gopm_history",
+			Description: "Show recent starts and exits of a process: exit code or terminating signal, core dump, uptime, and the restart decision with its reason",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"target": map[string]interface{}{"type": "string", "description": "Process name or ID"},
+					"limit":  map[string]interface{}{"type": "integer", "description": "Most recent events to return (default all kept)"},
+				},
+				"required": []string{"target"},
+			},
+		},
 		{
 			Name:        "gopm_logs",
 			Description: "Retrieve log output for a process",
@@ -109,7 +206,7 @@ func toolDefs() []toolDef {
 			Description: "Clear log files for a process",
 			InputSchema: map[string]interface{}{
 				"type":       "object",
-				"properties": map[string]interface{}{"target": map[string]interface{}{"type": "string", "description": "Process name, ID, or 'all'"}},
+				"properties": map[string]interface{}{"target": map[string]interface{}{"type": "string", "description": "Process name, ID, 'all', or a selector (labels or name pattern, e.g. 'team=payments', 'api-*')"}},
 				"required":   []string{"target"},
 			},
 		},
@@ -159,15 +256,45 @@ func toolDefs() []toolDef {
 						"items": map[string]interface{}{
 							"type": "object",
 							"properties": map[string]interface{}{
-								"name":          map[string]interface{}{"type": "string", "description": "Process name"},
-								"command":       map[string]interface{}{"type": "string", "description": "Path to the script or binary"},
-								"args":          map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Arguments"},
-								"cwd":           map[string]interface{}{"type": "string", "description": "Working directory"},
-								"interpreter":   map[string]interface{}{"type": "string", "description": "Interpreter (e.g. node, python3)"},
-								"env":           map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}, "description": "Environment variables"},
-								"autorestart":   map[string]interface{}{"type": "string", "enum": []string{"always", "on-failure", "never"}},
-								"max_restarts":  map[string]interface{}{"type": "integer"},
-								"restart_delay": map[string]interface{}{"type": "string"},
+								"name":               map[string]interface{}{"type": "string", "description": "Process name"},
+								"command":            map[string]interface{}{"type": "string", "description": "Path to the script or binary"},
+								"args":               map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Arguments"},
+								"cwd":                map[string]interface{}{"type": "string", "description": "Working directory"},
+								"interpreter":        map[string]interface{}{"type": "string", "description": "Interpreter (e.g. node, python3)"},
+								"env":                map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}, "description": "Environment variables"},
+								"labels":             map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+								"autorestart":        map[string]interface{}{"type": "string", "enum": []string{"always", "on-failure", "never"}},
+								"max_restarts":       map[string]interface{}{"type": "integer"},
+								"restart_delay":      map[string]interface{}{"type": "string"},
+								"health_check":       map[string]interface{}{"type": "object"},
+								"max_memory":         map[string]interface{}{"type": "string"},
+								"restart_budget":     map[string]interface{}{"type": "string"},
+								"crashloop_cooldown": map[string]interface{}{"type": "string"},
+								"backoff_jitter":     map[string]interface{}{"type": "number"},
+								"restart_on_exit":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+								"no_restart_on_exit": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+								"kill_signal":        map[string]interface{}{"type": "string"},
+								"stop_command":       map[string]interface{}{"type": "string"},
+								"reload_signal":      map[string]interface{}{"type": "string"},
+								"user":               map[string]interface{}{"type": "string"},
+								"group":              map[string]interface{}{"type": "string"},
+								"groups":             map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+								"cgroup":             map[string]interface{}{"type": "object"},
+								"limits":             map[string]interface{}{"type": "object"},
+								"cron_restart":       map[string]interface{}{"type": "string"},
+								"schedule":           map[string]interface{}{"type": "string"},
+								"overlap":            map[string]interface{}{"type": "string"},
+								"watch":              map[string]interface{}{"type": "boolean"},
+								"watch_paths":        map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+								"ignore_watch":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+								"watch_delay":        map[string]interface{}{"type": "string"},
+								"depends_on":         map[string]interface{}{"type": "array"},
+								"ready_message":      map[string]interface{}{"type": "string"},
+								"sockets":            map[string]interface{}{"type": "array"},
+								"notify":             map[string]interface{}{"type": "boolean"},
+								"watchdog_sec":       map[string]interface{}{"type": "string"},
+								"hooks":              map[string]interface{}{"type": "object"},
+								"instances":          map[string]interface{}{},
 							},
 							"required": []string{"name", "command"},
 						},
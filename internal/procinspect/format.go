@@ -192,6 +192,15 @@ func formatGoPM(w io.Writer, g *GoPMInfo) {
 		sectionRow(w, "GoPM ID", fmt.Sprintf("%d", g.ID))
 		sectionRow(w, "Restarts", fmt.Sprintf("%d", g.Restarts))
 		sectionRow(w, "Auto Restart", g.AutoRestart)
+		if g.RunAs != "" {
+			sectionRow(w, "Run As", g.RunAs)
+		}
+		if len(g.Limits) > 0 {
+			sectionRow(w, "Limits", "configured (effective)")
+			for _, l := range g.Limits {
+				sectionRow(w, "  "+l.Name, fmt.Sprintf("%s (%s)", l.Configured, l.Effective))
+			}
+		}
 		sectionRow(w, "Stdout Log", g.LogOut)
 		sectionRow(w, "Stderr Log", g.LogErr)
 	}
@@ -0,0 +1,97 @@
+//go:build linux
+
+package procinspect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// rlimitRows maps the /proc/<pid>/limits rows to protocol.LimitNames.
+var rlimitRows = map[string]string{
+	"Max open files":     "nofile",
+	"Max processes":      "nproc",
+	"Max core file size": "core",
+	"Max locked memory":  "memlock",
+}
+
+// ReadLimits reads the resource settings gopm can configure from a running
+// process, by protocol.LimitNames name, in the canonical form of
+// protocol.Limits.Values so configured and effective values compare equal.
+func ReadLimits(pid int) map[string]string {
+	v := make(map[string]string)
+
+	for _, line := range strings.Split(readProcFile(pid, "limits"), "\n") {
+		for row, name := range rlimitRows {
+			rest, ok := strings.CutPrefix(line, row)
+			if !ok {
+				continue
+			}
+			fields := strings.Fields(rest)
+			if len(fields) < 2 {
+				continue
+			}
+			soft, err1 := parseRlimitField(fields[0])
+			hard, err2 := parseRlimitField(fields[1])
+			if err1 == nil && err2 == nil {
+				v[name] = protocol.FormatRlimit(soft, hard)
+			}
+		}
+	}
+
+	if fields := parseStatFields(readProcFile(pid, "stat")); len(fields) > 18 {
+		v["nice"] = fields[18]
+	}
+	if prio, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_GET, 1, uintptr(pid), 0); errno == 0 {
+		v["ionice"] = protocol.FormatIONice(int(prio>>13), int(prio&0xff))
+	}
+
+	for _, line := range strings.Split(readProcFile(pid, "status"), "\n") {
+		key, val, ok := strings.Cut(line, ":\t")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Cpus_allowed_list":
+			if cpus, err := protocol.ParseCPUList(val); err == nil {
+				v["cpu_affinity"] = protocol.FormatCPUList(cpus)
+			}
+		case "Umask":
+			v["umask"] = strings.TrimSpace(val)
+		}
+	}
+
+	if s := strings.TrimSpace(readProcFile(pid, "oom_score_adj")); s != "" {
+		v["oom_score_adj"] = s
+	}
+	return v
+}
+
+func parseRlimitField(s string) (uint64, error) {
+	if s == "unlimited" {
+		return protocol.RlimInfinity, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid limit %q", s)
+	}
+	return n, nil
+}
+
+// CompareLimits lists the configured settings of l next to the values in
+// effect for the process pid.
+func CompareLimits(pid int, l *protocol.Limits) []LimitInfo {
+	configured := l.Values()
+	effective := ReadLimits(pid)
+	var out []LimitInfo
+	for _, name := range protocol.LimitNames {
+		if v, ok := configured[name]; ok {
+			out = append(out, LimitInfo{Name: name, Configured: v, Effective: effective[name]})
+		}
+	}
+	return out
+}
@@ -91,12 +91,21 @@ type CgroupInfo struct {
 }
 
 type GoPMInfo struct {
-	Managed     bool   `json:"managed"`
-	DaemonUp    bool   `json:"daemon_up"`
-	Name        string `json:"name,omitempty"`
-	ID          int    `json:"id,omitempty"`
-	Restarts    int    `json:"restarts,omitempty"`
-	AutoRestart string `json:"autorestart,omitempty"`
-	LogOut      string `json:"log_out,omitempty"`
-	LogErr      string `json:"log_err,omitempty"`
+	Managed     bool        `json:"managed"`
+	DaemonUp    bool        `json:"daemon_up"`
+	Name        string      `json:"name,omitempty"`
+	ID          int         `json:"id,omitempty"`
+	Restarts    int         `json:"restarts,omitempty"`
+	AutoRestart string      `json:"autorestart,omitempty"`
+	LogOut      string      `json:"log_out,omitempty"`
+	LogErr      string      `json:"log_err,omitempty"`
+	RunAs       string      `json:"run_as,omitempty"` // user and groups gopm started it as
+	Limits      []LimitInfo `json:"limits,omitempty"` // configured per-process limits
+}
+
+// LimitInfo is a configured per-process setting next to the value in effect.
+type LimitInfo struct {
+	Name       string `json:"name"`
+	Configured string `json:"configured"`
+	Effective  string `json:"effective"`
 }
@@ -3,10 +3,16 @@ package protocol
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -22,25 +28,37 @@ func GopmHome() string {
 func SocketPath() string  { return filepath.Join(GopmHome(), "gopm.sock") }
 func PIDFilePath() string { return filepath.Join(GopmHome(), "daemon.pid") }
 func DumpFilePath() string { return filepath.Join(GopmHome(), "dump.json") }
+func HistoryFilePath() string { return filepath.Join(GopmHome(), "history.json") }
 func LogDir() string      { return filepath.Join(GopmHome(), "logs") }
 
 // Method constants
 const (
-	MethodStart     = "start"
-	MethodStop      = "stop"
-	MethodRestart   = "restart"
-	MethodDelete    = "delete"
-	MethodList      = "list"
-	MethodDescribe  = "describe"
-	MethodIsRunning = "isrunning"
-	MethodLogs      = "logs"
-	MethodFlush     = "flush"
-	MethodSave      = "save"
-	MethodResurrect = "resurrect"
-	MethodPing      = "ping"
-	MethodKill      = "kill"
-	MethodReboot    = "reboot"
-	MethodStats     = "stats"
+	MethodStart      = "start"
+	MethodStop       = "stop"
+	MethodRestart    = "restart"
+	MethodDelete     = "delete"
+	MethodList       = "list"
+	MethodDescribe   = "describe"
+	MethodIsRunning  = "isrunning"
+	MethodLogs       = "logs"
+	MethodFlush      = "flush"
+	MethodSave       = "save"
+	MethodResurrect  = "resurrect"
+	MethodPing       = "ping"
+	MethodKill       = "kill"
+	MethodReboot     = "reboot"
+	MethodStats      = "stats"
+	MethodRuns       = "runs"
+	MethodScale      = "scale"
+	MethodReload     = "reload"
+	MethodWait       = "wait"
+	MethodHistory    = "history"
+	MethodSubscribe  = "subscribe"
+	MethodNotifyTest = "notify_test"
+	MethodSignal     = "signal"
+	MethodApply      = "apply"
+	MethodUpdate     = "update"
+	MethodRename     = "rename"
 )
 
 // Request is the IPC message from CLI to daemon.
@@ -60,11 +78,553 @@ type Response struct {
 type Status string
 
 const (
-	StatusOnline  Status = "online"
-	StatusStopped Status = "stopped"
-	StatusErrored Status = "errored"
+	StatusOnline    Status = "online"
+	StatusStopped   Status = "stopped"
+	StatusErrored   Status = "errored"
+	StatusScheduled Status = "scheduled" // scheduled job waiting for its next run
+	StatusWaiting   Status = "waiting"   // waiting for depends_on before starting
+	StatusStarting  Status = "starting"  // running, but has not sent READY=1 yet (notify)
+
+	StatusLaunching      Status = "launching"       // being started again after its restart delay
+	StatusStopping       Status = "stopping"        // sent its kill signal, waiting for it to exit
+	StatusWaitingRestart Status = "waiting_restart" // exited, restarts at RestartAt
+	StatusCrashLoop      Status = "crashloop"       // over its restart budget, tries again at RestartAt
+)
+
+// Running reports whether the process is running: online, started and not
+// ready yet, or being stopped but not exited yet.
+func (s Status) Running() bool {
+	return s == StatusOnline || s == StatusStarting || s == StatusStopping
+}
+
+// Up reports whether the process is running and meant to stay up, as
+// "isrunning" reports it: online or starting, but not stopping.
+func (s Status) Up() bool {
+	return s == StatusOnline || s == StatusStarting
+}
+
+// OverlapPolicy controls what happens when a scheduled job is due while its
+// previous run is still going.
+type OverlapPolicy string
+
+const (
+	OverlapSkip  OverlapPolicy = "skip"  // drop the new run
+	OverlapQueue OverlapPolicy = "queue" // start it when the current run exits
+	OverlapKill  OverlapPolicy = "kill"  // kill the current run, then start
+)
+
+// Valid reports whether o is a known overlap policy.
+func (o OverlapPolicy) Valid() bool {
+	switch o {
+	case OverlapSkip, OverlapQueue, OverlapKill:
+		return true
+	}
+	return false
+}
+
+// RunStatus is the state or outcome of one run of a scheduled job.
+type RunStatus string
+
+const (
+	RunRunning RunStatus = "running"
+	RunSuccess RunStatus = "success"
+	RunFailed  RunStatus = "failed"
+	RunKilled  RunStatus = "killed"
+	RunSkipped RunStatus = "skipped"
+)
+
+// RunRecord describes one execution of a scheduled job.
+type RunRecord struct {
+	ID        int       `json:"id"`
+	Status    RunStatus `json:"status"`
+	Trigger   string    `json:"trigger"` // schedule, queued, or manual
+	StartedAt time.Time `json:"started_at"`
+	Duration  Duration  `json:"duration"`
+	ExitCode  int       `json:"exit_code"`
+	PID       int       `json:"pid,omitempty"`
+	Reason    string    `json:"reason,omitempty"` // why it was killed or skipped
+	Output    string    `json:"output,omitempty"` // tail of combined stdout/stderr
+}
+
+// EventType is the kind of a process history or subscribe event. History
+// only holds start and exit events.
+type EventType string
+
+const (
+	EventStart   EventType = "start"
+	EventExit    EventType = "exit"
+	EventRestart EventType = "restart" // restarted by the daemon or a client
+	EventStatus  EventType = "status"  // status changed
+	EventHealth  EventType = "health"  // health check state changed
+	EventConfig  EventType = "config"  // process added, updated, renamed or deleted
+	EventDaemon  EventType = "daemon"  // daemon shutting down, rebooting or resurrecting
+)
+
+// RestartDecision is what the daemon did after a process exited.
+type RestartDecision string
+
+const (
+	DecisionRestart   RestartDecision = "restart"    // restarted after the restart delay
+	DecisionNoRestart RestartDecision = "no_restart" // left stopped
+	DecisionGiveUp    RestartDecision = "give_up"    // marked errored
+	DecisionCrashLoop RestartDecision = "crashloop"  // over the restart budget, retried after a cool-down
+)
+
+// ProcessEvent is an entry in a process's history: a start, or an exit
+// and what the daemon did about it.
+type ProcessEvent struct {
+	Time     time.Time       `json:"time"`
+	Type     EventType       `json:"type"`
+	PID      int             `json:"pid,omitempty"`
+	ExitCode int             `json:"exit_code,omitempty"` // -1 if killed by a signal
+	Signal   string          `json:"signal,omitempty"`    // terminating signal, e.g. SIGKILL
+	CoreDump bool            `json:"core_dump,omitempty"`
+	Uptime   *Duration       `json:"uptime,omitempty"` // how long the run lasted
+	Decision RestartDecision `json:"decision,omitempty"`
+	Reason   string          `json:"reason,omitempty"`
+}
+
+// Event is a message on the subscribe stream. Start and exit events carry
+// the same fields as in the process history; Reason describes the others.
+type Event struct {
+	ProcessEvent
+	Name       string      `json:"name,omitempty"` // empty for daemon events
+	App        string      `json:"app,omitempty"`  // instance group, if any
+	Status     Status      `json:"status,omitempty"`
+	PrevStatus Status      `json:"prev_status,omitempty"`
+	Health     HealthState `json:"health,omitempty"`
+}
+
+// NotifyEvent is a process event that webhook notifications can be sent
+// for.
+type NotifyEvent string
+
+const (
+	NotifyErrored      NotifyEvent = "errored"       // gave up on the process
+	NotifyRestarted    NotifyEvent = "restarted"     // restarted by the daemon after an exit
+	NotifyCrashLoop    NotifyEvent = "crash-loop"    // restarted repeatedly without reaching min_uptime
+	NotifyHealthFailed NotifyEvent = "health-failed" // health check turned unhealthy
+)
+
+// NotifyEvents lists every notify event.
+var NotifyEvents = []NotifyEvent{NotifyErrored, NotifyRestarted, NotifyCrashLoop, NotifyHealthFailed}
+
+// Valid reports whether e is a known notify event.
+func (e NotifyEvent) Valid() bool {
+	for _, v := range NotifyEvents {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// DependencyCondition is what a process waits for in a dependency before
+// it starts.
+type DependencyCondition string
+
+const (
+	DependsStarted   DependencyCondition = "started"                // online (or armed, for a scheduled job)
+	DependsHealthy   DependencyCondition = "healthy"                // passing its health check
+	DependsCompleted DependencyCondition = "completed_successfully" // exited with code 0
+)
+
+// Valid reports whether c is a known condition. Empty means started.
+func (c DependencyCondition) Valid() bool {
+	switch c {
+	case "", DependsStarted, DependsHealthy, DependsCompleted:
+		return true
+	}
+	return false
+}
+
+// Dependency is one depends_on entry. In JSON it is either a process name,
+// meaning condition "started", or {"name": ..., "condition": ...}.
+type Dependency struct {
+	Name      string              `json:"name"`
+	Condition DependencyCondition `json:"condition,omitempty"`
+}
+
+func (d Dependency) MarshalJSON() ([]byte, error) {
+	if d.Condition == "" || d.Condition == DependsStarted {
+		return json.Marshal(d.Name)
+	}
+	type plain Dependency
+	return json.Marshal(plain(d))
+}
+
+func (d *Dependency) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*d = Dependency{Name: name}
+		return nil
+	}
+	type plain Dependency
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return fmt.Errorf("dependency must be a name or {\"name\", \"condition\"}: %w", err)
+	}
+	*d = Dependency(p)
+	return nil
+}
+
+// String formats the dependency as "name" or "name (condition)".
+func (d Dependency) String() string {
+	if d.Condition == "" || d.Condition == DependsStarted {
+		return d.Name
+	}
+	return fmt.Sprintf("%s (%s)", d.Name, d.Condition)
+}
+
+// ValidateDependsOn checks the depends_on list of the process called name.
+func ValidateDependsOn(name string, deps []Dependency) error {
+	seen := make(map[string]bool, len(deps))
+	for _, dep := range deps {
+		if dep.Name == "" {
+			return fmt.Errorf("dependency name is required")
+		}
+		if dep.Name == name {
+			return fmt.Errorf("%q cannot depend on itself", name)
+		}
+		if seen[dep.Name] {
+			return fmt.Errorf("duplicate dependency %q", dep.Name)
+		}
+		seen[dep.Name] = true
+		if !dep.Condition.Valid() {
+			return fmt.Errorf("invalid condition %q for %q: must be started, healthy, or completed_successfully", dep.Condition, dep.Name)
+		}
+	}
+	return nil
+}
+
+// DependencyOrder returns names ordered so that each comes after the names
+// it depends on. Dependencies outside names are ignored and otherwise the
+// input order is kept. It fails if the dependencies form a cycle.
+func DependencyOrder(names []string, deps func(name string) []Dependency) ([]string, error) {
+	known := make(map[string]bool, len(names))
+	for _, n := range names {
+		known[n] = true
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(names))
+	order := make([]string, 0, len(names))
+	var path []string
+
+	var visit func(n string) error
+	visit = func(n string) error {
+		switch state[n] {
+		case done:
+			return nil
+		case visiting:
+			i := 0
+			for path[i] != n {
+				i++
+			}
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path[i:], " -> "), n)
+		}
+		state[n] = visiting
+		path = append(path, n)
+		for _, dep := range deps(n) {
+			if !known[dep.Name] {
+				continue
+			}
+			if err := visit(dep.Name); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[n] = done
+		order = append(order, n)
+		return nil
+	}
+
+	for _, n := range names {
+		if err := visit(n); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Instances is how many copies of an app run as an instance group. Zero
+// means a single, ungrouped process and InstancesMax one instance per CPU.
+// In JSON it is a number or "max".
+type Instances int
+
+// InstancesMax runs one instance per CPU.
+const InstancesMax Instances = -1
+
+// ParseInstances parses a positive number or "max".
+func ParseInstances(s string) (Instances, error) {
+	if s == "max" {
+		return InstancesMax, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid instances %q: must be a positive number or max", s)
+	}
+	return Instances(n), nil
+}
+
+// Count returns how many instances to run.
+func (n Instances) Count() int {
+	if n == InstancesMax {
+		return runtime.NumCPU()
+	}
+	if n < 1 {
+		return 1
+	}
+	return int(n)
+}
+
+func (n Instances) String() string {
+	if n == InstancesMax {
+		return "max"
+	}
+	return strconv.Itoa(int(n))
+}
+
+func (n Instances) MarshalJSON() ([]byte, error) {
+	if n == InstancesMax {
+		return json.Marshal("max")
+	}
+	return json.Marshal(int(n))
+}
+
+func (n *Instances) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		v, err := ParseInstances(s)
+		if err != nil {
+			return err
+		}
+		*n = v
+		return nil
+	}
+	var i int
+	if err := json.Unmarshal(b, &i); err != nil || i < 0 {
+		return fmt.Errorf("instances must be a positive number or \"max\"")
+	}
+	*n = Instances(i)
+	return nil
+}
+
+// InstanceName is the process name of instance id of app, e.g. "api-2".
+func InstanceName(app string, id int) string {
+	return fmt.Sprintf("%s-%d", app, id)
+}
+
+// InstanceLogPath returns the log file of instance id for a group whose
+// logs would otherwise go to path: "api-out.log" becomes "api-out-2.log".
+func InstanceLogPath(path string, id int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), id, ext)
+}
+
+// GroupLogPath reverses InstanceLogPath.
+func GroupLogPath(path string, id int) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(strings.TrimSuffix(path, ext), fmt.Sprintf("-%d", id)) + ext
+}
+
+// GroupInstances replaces the instances of each group in procs with one
+// entry describing the whole group, as it would be started: named after
+// the app, with the group's log paths. Other processes are kept as-is.
+func GroupInstances(procs []ProcessInfo) []ProcessInfo {
+	var out []ProcessInfo
+	index := make(map[string]int)
+	for _, p := range procs {
+		if p.App == "" {
+			out = append(out, p)
+			continue
+		}
+		if i, ok := index[p.App]; ok {
+			if p.InstanceID < out[i].InstanceID {
+				out[i] = p
+			}
+			continue
+		}
+		index[p.App] = len(out)
+		out = append(out, p)
+	}
+	for _, i := range index {
+		p := &out[i]
+		p.LogOut = GroupLogPath(p.LogOut, p.InstanceID)
+		p.LogErr = GroupLogPath(p.LogErr, p.InstanceID)
+		p.Name = p.App
+		p.App = ""
+		p.InstanceID = 0
+	}
+	return out
+}
+
+// ScaleParams are the parameters for the "scale" method.
+type ScaleParams struct {
+	Target    string    `json:"target"`
+	Instances Instances `json:"instances"`
+}
+
+// ApplyParams are the parameters for the "apply" method.
+type ApplyParams struct {
+	Apps   []StartParams `json:"apps"`              // in start order
+	Prune  bool          `json:"prune,omitempty"`   // delete processes not in Apps
+	DryRun bool          `json:"dry_run,omitempty"` // only report what would change
+}
+
+// ApplyAction is what apply does to one app.
+type ApplyAction string
+
+const (
+	ApplyCreate    ApplyAction = "create"    // new app, started
+	ApplyUpdate    ApplyAction = "update"    // settings changed, restarted with them
+	ApplyScale     ApplyAction = "scale"     // only the instance count changed
+	ApplyUnchanged ApplyAction = "unchanged" // left alone
+	ApplyDelete    ApplyAction = "delete"    // not in the file, deleted with prune
 )
 
+// ApplyChange is one entry of the result of the "apply" method.
+type ApplyChange struct {
+	Name   string        `json:"name"`
+	Action ApplyAction   `json:"action"`
+	Diff   []FieldChange `json:"diff,omitempty"`  // for update and scale
+	Error  string        `json:"error,omitempty"` // the action failed
+}
+
+// FieldChange is a setting that differs between a running process and its
+// app in an ecosystem file, by its ecosystem key. A value is absent when
+// the setting is unset on that side.
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+// UpdateParams are the parameters for the "update" method, which changes
+// the settings of processes in place. Settings are named by their
+// ecosystem keys; env and labels in Set are merged into the process's own.
+type UpdateParams struct {
+	Target      string                     `json:"target"`
+	Set         map[string]json.RawMessage `json:"set,omitempty"`
+	Unset       []string                   `json:"unset,omitempty"` // back to their defaults
+	UnsetEnv    []string                   `json:"unset_env,omitempty"`
+	UnsetLabels []string                   `json:"unset_labels,omitempty"`
+	Restart     bool                       `json:"restart,omitempty"` // restart to use them now
+}
+
+// UpdateResult is one entry of the result of the "update" method.
+type UpdateResult struct {
+	ID        int           `json:"id"`
+	Name      string        `json:"name"`
+	Diff      []FieldChange `json:"diff,omitempty"` // empty if nothing changed
+	Restarted bool          `json:"restarted,omitempty"`
+	Error     string        `json:"error,omitempty"` // the restart failed
+}
+
+// RenameParams are the parameters for the "rename" method. Target is a
+// process name or ID, or the app name of an instance group.
+type RenameParams struct {
+	Target string `json:"target"`
+	Name   string `json:"name"`
+}
+
+// Socket is a listening socket the daemon binds and passes to a process
+// (socket activation). The address is "tcp:HOST:PORT", "unix:/path", or a
+// bare "HOST:PORT" meaning tcp. In JSON it is either the address or
+// {"name": ..., "address": ...}; the name is passed in LISTEN_FDNAMES.
+type Socket struct {
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address"`
+}
+
+func (s Socket) MarshalJSON() ([]byte, error) {
+	if s.Name == "" {
+		return json.Marshal(s.Address)
+	}
+	type plain Socket
+	return json.Marshal(plain(s))
+}
+
+func (s *Socket) UnmarshalJSON(b []byte) error {
+	var addr string
+	if err := json.Unmarshal(b, &addr); err == nil {
+		*s = Socket{Address: addr}
+		return nil
+	}
+	type plain Socket
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return fmt.Errorf("socket must be an address or {\"name\", \"address\"}: %w", err)
+	}
+	*s = Socket(p)
+	return nil
+}
+
+// ParseSocket parses the --socket flag form "[NAME=]ADDRESS".
+func ParseSocket(s string) Socket {
+	if i := strings.Index(s, "="); i > 0 && !strings.ContainsAny(s[:i], ":/") {
+		return Socket{Name: s[:i], Address: s[i+1:]}
+	}
+	return Socket{Address: s}
+}
+
+// Listen returns the network and address to bind, as for net.Listen.
+func (s Socket) Listen() (network, addr string, err error) {
+	switch {
+	case strings.HasPrefix(s.Address, "unix:"):
+		network, addr = "unix", strings.TrimPrefix(s.Address, "unix:")
+		if !filepath.IsAbs(addr) {
+			return "", "", fmt.Errorf("unix socket path %q must be absolute", addr)
+		}
+		return network, filepath.Clean(addr), nil
+	case strings.HasPrefix(s.Address, "tcp:"):
+		network, addr = "tcp", strings.TrimPrefix(s.Address, "tcp:")
+	default:
+		network, addr = "tcp", s.Address
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid address %q: %w", s.Address, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", "", fmt.Errorf("invalid port in %q", s.Address)
+	}
+	return network, addr, nil
+}
+
+func (s Socket) String() string {
+	if s.Name == "" {
+		return s.Address
+	}
+	return s.Name + "=" + s.Address
+}
+
+// ValidateSockets checks a sockets list for errors.
+func ValidateSockets(sockets []Socket) error {
+	seen := make(map[string]bool, len(sockets))
+	for _, s := range sockets {
+		if s.Address == "" {
+			return fmt.Errorf("socket address is required")
+		}
+		if strings.ContainsAny(s.Name, ": \t\n") {
+			return fmt.Errorf("invalid socket name %q: must not contain ':' or spaces", s.Name)
+		}
+		network, addr, err := s.Listen()
+		if err != nil {
+			return err
+		}
+		if seen[network+addr] {
+			return fmt.Errorf("duplicate socket %q", s.Address)
+		}
+		seen[network+addr] = true
+	}
+	return nil
+}
+
 // AutoRestartMode controls when a process is automatically restarted.
 type AutoRestartMode string
 
@@ -86,6 +646,46 @@ type RestartPolicy struct {
 	NoRestartOnExit []int           `json:"no_restart_on_exit,omitempty"`
 	KillSignal      int             `json:"kill_signal"`
 	KillTimeout     Duration        `json:"kill_timeout"`
+	MaxMemory       int64           `json:"max_memory,omitempty"` // RSS limit in bytes, 0 = none
+	MaxMemoryGrace  Duration        `json:"max_memory_grace"`     // how long RSS may exceed MaxMemory
+
+	// At most RestartBudget restarts within RestartWindow, however long
+	// each run lasted; 0 = no budget. A process over budget is put in
+	// crashloop for CrashLoopCooldown, then tried once more.
+	RestartBudget     int      `json:"restart_budget,omitempty"`
+	RestartWindow     Duration `json:"restart_window"`
+	CrashLoopCooldown Duration `json:"crashloop_cooldown"`
+	// BackoffJitter randomizes each restart delay by up to ± this
+	// fraction of it (0-1), so processes don't restart in lockstep.
+	BackoffJitter float64 `json:"backoff_jitter,omitempty"`
+}
+
+// DefaultCrashLoopCooldown is how long a process stays in crashloop when
+// crashloop_cooldown is empty.
+const DefaultCrashLoopCooldown = 5 * time.Minute
+
+// ParseRestartBudget parses a restart budget such as "10/15m": at most 10
+// restarts in 15 minutes.
+func ParseRestartBudget(s string) (int, time.Duration, error) {
+	count, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid restart_budget %q (expected RESTARTS/WINDOW, e.g. 10/15m)", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n < 1 {
+		return 0, 0, fmt.Errorf("invalid restart_budget %q: restarts must be a positive number", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || d <= 0 {
+		return 0, 0, fmt.Errorf("invalid restart_budget %q: window must be a positive duration", s)
+	}
+	return n, d, nil
+}
+
+// FormatRestartBudget formats a restart budget as ParseRestartBudget
+// reads it.
+func FormatRestartBudget(n int, window time.Duration) string {
+	return fmt.Sprintf("%d/%s", n, window)
 }
 
 // DefaultRestartPolicy returns the default restart policy.
@@ -98,9 +698,477 @@ func DefaultRestartPolicy() RestartPolicy {
 		MaxDelay:     Duration{30 * time.Second},
 		KillSignal:   15, // SIGTERM
 		KillTimeout:  Duration{5 * time.Second},
+
+		CrashLoopCooldown: Duration{DefaultCrashLoopCooldown},
 	}
 }
 
+// HealthState is the result of a process's active health check.
+type HealthState string
+
+const (
+	HealthStarting  HealthState = "starting"
+	HealthHealthy   HealthState = "healthy"
+	HealthUnhealthy HealthState = "unhealthy"
+)
+
+// Health check types.
+const (
+	HealthCheckHTTP = "http"
+	HealthCheckTCP  = "tcp"
+	HealthCheckExec = "exec"
+)
+
+// Health check defaults, used when a field is left empty.
+const (
+	DefaultHealthInterval         = 30 * time.Second
+	DefaultHealthTimeout          = 5 * time.Second
+	DefaultHealthFailureThreshold = 3
+)
+
+// HealthCheck configures an active probe the daemon runs against a managed
+// process. Durations are strings in Go duration format (e.g. "10s").
+type HealthCheck struct {
+	Type             string `json:"type"`                        // http, tcp, or exec
+	Target           string `json:"target"`                      // URL, host:port, or shell command
+	Interval         string `json:"interval,omitempty"`          // time between checks (default 30s)
+	Timeout          string `json:"timeout,omitempty"`           // per-check timeout (default 5s)
+	FailureThreshold int    `json:"failure_threshold,omitempty"` // consecutive failures before restart (default 3)
+	StartPeriod      string `json:"start_period,omitempty"`      // grace period after start where failures don't count
+}
+
+// Validate checks the health check for errors.
+func (h *HealthCheck) Validate() error {
+	switch h.Type {
+	case HealthCheckHTTP, HealthCheckTCP, HealthCheckExec:
+	case "":
+		return fmt.Errorf("type is required (http, tcp, or exec)")
+	default:
+		return fmt.Errorf("invalid type %q (expected http, tcp, or exec)", h.Type)
+	}
+	if h.Target == "" {
+		return fmt.Errorf("target is required")
+	}
+	for _, f := range []struct{ name, val string }{
+		{"interval", h.Interval},
+		{"timeout", h.Timeout},
+		{"start_period", h.StartPeriod},
+	} {
+		if f.val == "" {
+			continue
+		}
+		d, err := time.ParseDuration(f.val)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", f.name, f.val, err)
+		}
+		if d < 0 || (d == 0 && f.name != "start_period") {
+			return fmt.Errorf("%s must be positive", f.name)
+		}
+	}
+	if h.FailureThreshold < 0 {
+		return fmt.Errorf("failure_threshold must not be negative")
+	}
+	return nil
+}
+
+// Timings returns the check interval, timeout, start period, and failure
+// threshold with defaults applied. Call Validate first; unparsable values
+// fall back to their defaults.
+func (h *HealthCheck) Timings() (interval, timeout, startPeriod time.Duration, threshold int) {
+	interval = DefaultHealthInterval
+	timeout = DefaultHealthTimeout
+	threshold = DefaultHealthFailureThreshold
+	if d, err := time.ParseDuration(h.Interval); err == nil && d > 0 {
+		interval = d
+	}
+	if d, err := time.ParseDuration(h.Timeout); err == nil && d > 0 {
+		timeout = d
+	}
+	if d, err := time.ParseDuration(h.StartPeriod); err == nil && d > 0 {
+		startPeriod = d
+	}
+	if h.FailureThreshold > 0 {
+		threshold = h.FailureThreshold
+	}
+	return
+}
+
+// Hook names, as used in logs and GOPM_HOOK.
+const (
+	HookPreStart  = "pre_start"
+	HookPostStart = "post_start"
+	HookPreStop   = "pre_stop"
+	HookPostExit  = "post_exit"
+)
+
+// DefaultHookTimeout is how long a hook may run when Hooks.Timeout is empty.
+const DefaultHookTimeout = 30 * time.Second
+
+// Hooks are shell commands the daemon runs at points in a managed process's
+// lifecycle, with sh -c in the process's cwd and environment.
+type Hooks struct {
+	PreStart  string `json:"pre_start,omitempty"`  // before every start; failing blocks it
+	PostStart string `json:"post_start,omitempty"` // in the background after every start
+	PreStop   string `json:"pre_stop,omitempty"`   // before the kill signal when stopping
+	PostExit  string `json:"post_exit,omitempty"`  // in the background after every exit
+	Timeout   string `json:"timeout,omitempty"`    // per hook run (default 30s)
+}
+
+// Validate checks the hooks for errors.
+func (h *Hooks) Validate() error {
+	if h.Timeout != "" {
+		d, err := time.ParseDuration(h.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q: %w", h.Timeout, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive")
+		}
+	}
+	return nil
+}
+
+// Command returns the command for the named hook, or "" if it is not set.
+func (h *Hooks) Command(name string) string {
+	if h == nil {
+		return ""
+	}
+	switch name {
+	case HookPreStart:
+		return h.PreStart
+	case HookPostStart:
+		return h.PostStart
+	case HookPreStop:
+		return h.PreStop
+	case HookPostExit:
+		return h.PostExit
+	}
+	return ""
+}
+
+// TimeoutDuration returns the per-run timeout with the default applied.
+// Call Validate first; an unparsable value falls back to the default.
+func (h *Hooks) TimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(h.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultHookTimeout
+}
+
+// CgroupLimits are cgroup v2 resource limits applied to a managed process.
+// Empty fields leave the kernel default ("max" / default weight).
+type CgroupLimits struct {
+	CPUMax     string `json:"cpu_max,omitempty"`     // "50%" of one CPU, "200%", or raw "quota period"
+	MemoryMax  string `json:"memory_max,omitempty"`  // hard limit, e.g. "1G"
+	MemoryHigh string `json:"memory_high,omitempty"` // throttle threshold, e.g. "768M"
+	PidsMax    int    `json:"pids_max,omitempty"`    // max tasks in the cgroup
+	IOWeight   int    `json:"io_weight,omitempty"`   // 1-10000, kernel default 100
+}
+
+// cgroupCPUPeriod is the cpu.max period used when CPUMax is a percentage.
+const cgroupCPUPeriod = 100000
+
+// CPUMaxValue returns the cpu.max file contents for CPUMax, or "max" if unset.
+func (c *CgroupLimits) CPUMaxValue() (string, error) {
+	v := strings.TrimSpace(c.CPUMax)
+	if v == "" || v == "max" {
+		return "max", nil
+	}
+	if pct, ok := strings.CutSuffix(v, "%"); ok {
+		f, err := strconv.ParseFloat(pct, 64)
+		if err != nil || f <= 0 {
+			return "", fmt.Errorf("invalid cpu_max %q", c.CPUMax)
+		}
+		quota := int64(f / 100 * cgroupCPUPeriod)
+		if quota < 1000 {
+			return "", fmt.Errorf("cpu_max %q is below the 1%% minimum", c.CPUMax)
+		}
+		return fmt.Sprintf("%d %d", quota, cgroupCPUPeriod), nil
+	}
+	fields := strings.Fields(v)
+	if len(fields) == 2 {
+		quotaOK := fields[0] == "max"
+		if quota, err := strconv.ParseInt(fields[0], 10, 64); err == nil && quota > 0 {
+			quotaOK = true
+		}
+		period, err := strconv.ParseInt(fields[1], 10, 64)
+		if quotaOK && err == nil && period > 0 {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid cpu_max %q (expected e.g. \"50%%\" or \"50000 100000\")", c.CPUMax)
+}
+
+// Validate checks the limits for errors.
+func (c *CgroupLimits) Validate() error {
+	if _, err := c.CPUMaxValue(); err != nil {
+		return err
+	}
+	if c.MemoryMax != "" {
+		if _, err := ParseSize(c.MemoryMax); err != nil {
+			return fmt.Errorf("invalid memory_max: %w", err)
+		}
+	}
+	if c.MemoryHigh != "" {
+		if _, err := ParseSize(c.MemoryHigh); err != nil {
+			return fmt.Errorf("invalid memory_high: %w", err)
+		}
+	}
+	if c.PidsMax < 0 {
+		return fmt.Errorf("pids_max must not be negative")
+	}
+	if c.IOWeight != 0 && (c.IOWeight < 1 || c.IOWeight > 10000) {
+		return fmt.Errorf("io_weight must be between 1 and 10000")
+	}
+	return nil
+}
+
+// Limits are per-process resource settings applied when the process starts.
+// Unset fields leave the value the process would inherit from the daemon.
+type Limits struct {
+	Nofile      string `json:"nofile,omitempty"`        // open files: "65536", soft:hard "1024:65536" or "unlimited"
+	Nproc       string `json:"nproc,omitempty"`         // processes of the user, same forms
+	Core        string `json:"core,omitempty"`          // core dump size, e.g. "0", "1G", "unlimited"
+	Memlock     string `json:"memlock,omitempty"`       // locked memory, e.g. "64M", "unlimited"
+	Nice        *int   `json:"nice,omitempty"`          // -20 (most favourable) to 19
+	IONiceClass string `json:"ionice_class,omitempty"`  // realtime, best-effort or idle
+	IONiceLevel *int   `json:"ionice_level,omitempty"`  // 0 (highest) to 7
+	CPUAffinity string `json:"cpu_affinity,omitempty"`  // CPU list, e.g. "0-3,6"
+	OOMScoreAdj *int   `json:"oom_score_adj,omitempty"` // -1000 (never kill) to 1000
+	Umask       string `json:"umask,omitempty"`         // octal, e.g. "027"
+}
+
+// LimitNames are the names of the settings in Limits, in display order, as
+// used by Limits.Values.
+var LimitNames = []string{"nofile", "nproc", "core", "memlock", "nice", "ionice", "cpu_affinity", "oom_score_adj", "umask"}
+
+// RlimInfinity is the "unlimited" resource limit value.
+const RlimInfinity = ^uint64(0)
+
+// ParseRlimit parses a resource limit: one value for both the soft and the
+// hard limit, or "soft:hard". Each is a number, "unlimited", or a size
+// (e.g. "64M") if size is set.
+func ParseRlimit(s string, size bool) (soft, hard uint64, err error) {
+	parse := func(v string) (uint64, error) {
+		v = strings.TrimSpace(v)
+		switch strings.ToLower(v) {
+		case "unlimited", "infinity":
+			return RlimInfinity, nil
+		}
+		if size {
+			n, err := ParseSize(v)
+			if err != nil || n < 0 || v == "" {
+				return 0, fmt.Errorf("invalid limit %q", v)
+			}
+			return uint64(n), nil
+		}
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid limit %q", v)
+		}
+		return n, nil
+	}
+	softStr, hardStr, pair := strings.Cut(s, ":")
+	if soft, err = parse(softStr); err != nil {
+		return 0, 0, err
+	}
+	if !pair {
+		return soft, soft, nil
+	}
+	if hard, err = parse(hardStr); err != nil {
+		return 0, 0, err
+	}
+	if soft > hard {
+		return 0, 0, fmt.Errorf("soft limit above hard limit in %q", s)
+	}
+	return soft, hard, nil
+}
+
+// FormatRlimit is the canonical form of a resource limit: one value if soft
+// and hard are equal, else "soft:hard", with "unlimited" for RlimInfinity.
+func FormatRlimit(soft, hard uint64) string {
+	f := func(v uint64) string {
+		if v == RlimInfinity {
+			return "unlimited"
+		}
+		return strconv.FormatUint(v, 10)
+	}
+	if soft == hard {
+		return f(soft)
+	}
+	return f(soft) + ":" + f(hard)
+}
+
+// MaxCPUs is the number of CPUs a CPU list may name.
+const MaxCPUs = 1024
+
+// ParseCPUList parses a CPU list such as "0-3,6" into CPU numbers.
+func ParseCPUList(s string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		first, err := strconv.Atoi(lo)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(hi)
+		}
+		if err != nil || first < 0 || last < first || last >= MaxCPUs {
+			return nil, fmt.Errorf("invalid CPU list %q (expected e.g. \"0-3,6\")", s)
+		}
+		for c := first; c <= last; c++ {
+			cpus = append(cpus, c)
+		}
+	}
+	return cpus, nil
+}
+
+// FormatCPUList is the canonical form of a CPU list, with ranges collapsed.
+func FormatCPUList(cpus []int) string {
+	set := make([]bool, MaxCPUs)
+	for _, c := range cpus {
+		if c >= 0 && c < MaxCPUs {
+			set[c] = true
+		}
+	}
+	var parts []string
+	for c := 0; c < MaxCPUs; c++ {
+		if !set[c] {
+			continue
+		}
+		first := c
+		for c+1 < MaxCPUs && set[c+1] {
+			c++
+		}
+		if first == c {
+			parts = append(parts, strconv.Itoa(c))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", first, c))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// IONiceClasses maps ionice class names to the kernel's IOPRIO_CLASS values.
+var IONiceClasses = map[string]int{"none": 0, "realtime": 1, "best-effort": 2, "idle": 3}
+
+// IONiceClassName returns the name of an IOPRIO_CLASS value.
+func IONiceClassName(class int) string {
+	for name, c := range IONiceClasses {
+		if c == class {
+			return name
+		}
+	}
+	return strconv.Itoa(class)
+}
+
+// IONice returns the IO scheduling class and level, and whether either is
+// set. A level without a class is best-effort; a class without a level
+// gets level 4, the kernel's default.
+func (l *Limits) IONice() (class, level int, ok bool) {
+	if l.IONiceClass == "" && l.IONiceLevel == nil {
+		return 0, 0, false
+	}
+	class, level = IONiceClasses["best-effort"], 4
+	if l.IONiceClass != "" {
+		class = IONiceClasses[strings.ToLower(l.IONiceClass)]
+	}
+	if l.IONiceLevel != nil {
+		level = *l.IONiceLevel
+	}
+	if class == IONiceClasses["idle"] {
+		level = 0 // idle has no levels
+	}
+	return class, level, true
+}
+
+// FormatIONice is the canonical form of an IO scheduling class and level.
+func FormatIONice(class, level int) string {
+	if class == IONiceClasses["none"] || class == IONiceClasses["idle"] {
+		return IONiceClassName(class)
+	}
+	return fmt.Sprintf("%s:%d", IONiceClassName(class), level)
+}
+
+// ParseUmask parses an octal umask such as "027".
+func ParseUmask(s string) (int, error) {
+	m, err := strconv.ParseUint(strings.TrimSpace(s), 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("invalid umask %q (expected octal, e.g. \"027\")", s)
+	}
+	return int(m), nil
+}
+
+// Validate checks the limits for errors.
+func (l *Limits) Validate() error {
+	for _, r := range []struct {
+		name, value string
+		size        bool
+	}{{"nofile", l.Nofile, false}, {"nproc", l.Nproc, false}, {"core", l.Core, true}, {"memlock", l.Memlock, true}} {
+		if r.value == "" {
+			continue
+		}
+		if _, _, err := ParseRlimit(r.value, r.size); err != nil {
+			return fmt.Errorf("%s: %w", r.name, err)
+		}
+	}
+	if l.Nice != nil && (*l.Nice < -20 || *l.Nice > 19) {
+		return fmt.Errorf("nice must be between -20 and 19")
+	}
+	if l.IONiceClass != "" {
+		if c, ok := IONiceClasses[strings.ToLower(l.IONiceClass)]; !ok || c == 0 {
+			return fmt.Errorf("invalid ionice_class %q (expected realtime, best-effort or idle)", l.IONiceClass)
+		}
+	}
+	if l.IONiceLevel != nil && (*l.IONiceLevel < 0 || *l.IONiceLevel > 7) {
+		return fmt.Errorf("ionice_level must be between 0 and 7")
+	}
+	if l.CPUAffinity != "" {
+		if _, err := ParseCPUList(l.CPUAffinity); err != nil {
+			return err
+		}
+	}
+	if l.OOMScoreAdj != nil && (*l.OOMScoreAdj < -1000 || *l.OOMScoreAdj > 1000) {
+		return fmt.Errorf("oom_score_adj must be between -1000 and 1000")
+	}
+	if l.Umask != "" {
+		if _, err := ParseUmask(l.Umask); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Values returns the settings that are set, by their LimitNames name, in
+// canonical form so they compare equal to the values read back from a
+// running process. Call Validate first; invalid settings are left out.
+func (l *Limits) Values() map[string]string {
+	v := make(map[string]string)
+	for name, r := range map[string]struct {
+		value string
+		size  bool
+	}{"nofile": {l.Nofile, false}, "nproc": {l.Nproc, false}, "core": {l.Core, true}, "memlock": {l.Memlock, true}} {
+		if soft, hard, err := ParseRlimit(r.value, r.size); r.value != "" && err == nil {
+			v[name] = FormatRlimit(soft, hard)
+		}
+	}
+	if l.Nice != nil {
+		v["nice"] = strconv.Itoa(*l.Nice)
+	}
+	if class, level, ok := l.IONice(); ok {
+		v["ionice"] = FormatIONice(class, level)
+	}
+	if cpus, err := ParseCPUList(l.CPUAffinity); l.CPUAffinity != "" && err == nil {
+		v["cpu_affinity"] = FormatCPUList(cpus)
+	}
+	if l.OOMScoreAdj != nil {
+		v["oom_score_adj"] = strconv.Itoa(*l.OOMScoreAdj)
+	}
+	if m, err := ParseUmask(l.Umask); l.Umask != "" && err == nil {
+		v["umask"] = fmt.Sprintf("%04o", m)
+	}
+	return v
+}
+
 // ProcessInfo is the public representation of a managed process, sent over IPC.
 type ProcessInfo struct {
 	ID            int               `json:"id"`
@@ -110,6 +1178,7 @@ type ProcessInfo struct {
 	Cwd           string            `json:"cwd"`
 	Env           map[string]string `json:"env"`
 	Interpreter   string            `json:"interpreter,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
 	Status        Status            `json:"status"`
 	StatusReason  string            `json:"status_reason,omitempty"`
 	PID           int               `json:"pid"`
@@ -124,6 +1193,62 @@ type ProcessInfo struct {
 	LogOut        string            `json:"log_out"`
 	LogErr        string            `json:"log_err"`
 	MaxLogSize    int64             `json:"max_log_size"`
+
+	HealthCheck    *HealthCheck `json:"health_check,omitempty"`
+	Health         HealthState  `json:"health,omitempty"`
+	HealthFailures int          `json:"health_failures,omitempty"`
+	HealthMessage  string       `json:"health_message,omitempty"` // last failed check output
+
+	LastRestartReason string     `json:"last_restart_reason,omitempty"` // why the daemon last restarted it
+	RestartAt         *time.Time `json:"restart_at,omitempty"`          // when it restarts, if waiting_restart
+	MemoryRestarts    int        `json:"memory_restarts,omitempty"`     // total restarts due to max_memory
+
+	Cgroup     *CgroupLimits `json:"cgroup,omitempty"`
+	CgroupPath string        `json:"cgroup_path,omitempty"` // cgroup the process runs in, if any
+
+	Limits          *Limits           `json:"limits,omitempty"`
+	EffectiveLimits map[string]string `json:"effective_limits,omitempty"` // read back from the process, by LimitNames name; describe only
+
+	CronRestart     string     `json:"cron_restart,omitempty"`
+	NextCronRestart *time.Time `json:"next_cron_restart,omitempty"` // next scheduled restart, if online
+
+	Schedule string        `json:"schedule,omitempty"` // cron expression; makes this a scheduled job
+	Overlap  OverlapPolicy `json:"overlap,omitempty"`
+	NextRun  *time.Time    `json:"next_run,omitempty"`
+	LastRun  *RunRecord    `json:"last_run,omitempty"` // without output
+
+	Watch       bool     `json:"watch,omitempty"`        // restart on file changes
+	WatchPaths  []string `json:"watch_paths,omitempty"`  // default: cwd
+	IgnoreWatch []string `json:"ignore_watch,omitempty"` // glob patterns
+	WatchDelay  string   `json:"watch_delay,omitempty"`  // debounce (default 1s)
+
+	DependsOn []Dependency `json:"depends_on,omitempty"`
+
+	ReadyMessage string `json:"ready_message,omitempty"` // stdout text that signals readiness on reload
+
+	Sockets         []Socket `json:"sockets,omitempty"`
+	SocketListeners []string `json:"socket_listeners,omitempty"` // bound addresses, as in Listeners
+
+	Notify       bool   `json:"notify,omitempty"`        // speaks the sd_notify protocol
+	WatchdogSec  string `json:"watchdog_sec,omitempty"`  // max time between WATCHDOG=1 pings
+	NotifyStatus string `json:"notify_status,omitempty"` // last STATUS= text
+
+	Hooks *Hooks `json:"hooks,omitempty"`
+
+	StopCommand  string `json:"stop_command,omitempty"`  // run before the kill signal
+	ReloadSignal int    `json:"reload_signal,omitempty"` // sent by reload instead of replacing the process
+
+	// User, Group and Groups the process runs as, by name or ID; empty
+	// for the daemon's own. RunAs is who it was last started as.
+	User   string      `json:"user,omitempty"`
+	Group  string      `json:"group,omitempty"`
+	Groups []string    `json:"groups,omitempty"`
+	RunAs  *Credential `json:"run_as,omitempty"`
+
+	// Set on each instance of a group started with instances.
+	App        string    `json:"app,omitempty"`
+	InstanceID int       `json:"instance_id,omitempty"`
+	Instances  Instances `json:"instances,omitempty"`
 }
 
 // StartParams are the parameters for the "start" method.
@@ -134,6 +1259,7 @@ type StartParams struct {
 	Cwd          string            `json:"cwd,omitempty"`
 	Env          map[string]string `json:"env,omitempty"`
 	Interpreter  string            `json:"interpreter,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
 	AutoRestart  string            `json:"autorestart,omitempty"`
 	MaxRestarts  *int              `json:"max_restarts,omitempty"`
 	MinUptime    string            `json:"min_uptime,omitempty"`
@@ -144,13 +1270,335 @@ type StartParams struct {
 	LogOut       string            `json:"log_out,omitempty"`
 	LogErr       string            `json:"log_err,omitempty"`
 	MaxLogSize   string            `json:"max_log_size,omitempty"`
+	HealthCheck  *HealthCheck      `json:"health_check,omitempty"`
+
+	RestartOnExit   []int `json:"restart_on_exit,omitempty"`
+	NoRestartOnExit []int `json:"no_restart_on_exit,omitempty"`
+
+	KillSignal   string `json:"kill_signal,omitempty"`   // name (SIGINT) or number
+	StopCommand  string `json:"stop_command,omitempty"`  // shell command run before the kill signal
+	ReloadSignal string `json:"reload_signal,omitempty"` // name (SIGHUP) or number
+
+	User   string   `json:"user,omitempty"`   // name or uid
+	Group  string   `json:"group,omitempty"`  // name or gid; default the user's primary group
+	Groups []string `json:"groups,omitempty"` // supplementary groups; default the user's
+
+	MaxMemory      string `json:"max_memory,omitempty"`
+	MaxMemoryGrace string `json:"max_memory_grace,omitempty"`
+
+	RestartBudget     string  `json:"restart_budget,omitempty"` // e.g. "10/15m"
+	CrashLoopCooldown string  `json:"crashloop_cooldown,omitempty"`
+	BackoffJitter     float64 `json:"backoff_jitter,omitempty"`
+
+	Cgroup *CgroupLimits `json:"cgroup,omitempty"`
+
+	Limits *Limits `json:"limits,omitempty"`
+
+	CronRestart string `json:"cron_restart,omitempty"`
+
+	Schedule string `json:"schedule,omitempty"`
+	Overlap  string `json:"overlap,omitempty"`
+
+	Watch       bool     `json:"watch,omitempty"`
+	WatchPaths  []string `json:"watch_paths,omitempty"`
+	IgnoreWatch []string `json:"ignore_watch,omitempty"`
+	WatchDelay  string   `json:"watch_delay,omitempty"`
+
+	DependsOn []Dependency `json:"depends_on,omitempty"`
+
+	ReadyMessage string `json:"ready_message,omitempty"`
+
+	Sockets []Socket `json:"sockets,omitempty"`
+
+	Notify      bool   `json:"notify,omitempty"`
+	WatchdogSec string `json:"watchdog_sec,omitempty"`
+
+	Hooks *Hooks `json:"hooks,omitempty"`
+
+	Instances Instances `json:"instances,omitempty"`
+
+	// App and InstanceID are set by the daemon when it starts one instance
+	// of a group; they are not part of the RPC.
+	App        string `json:"-"`
+	InstanceID int    `json:"-"`
+}
+
+// DecodeStarted decodes the result of the "start" method: one ProcessInfo,
+// or a list with every instance when an instance group was started.
+func DecodeStarted(data json.RawMessage) ([]ProcessInfo, error) {
+	var infos []ProcessInfo
+	if err := json.Unmarshal(data, &infos); err == nil {
+		return infos, nil
+	}
+	var info ProcessInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return []ProcessInfo{info}, nil
+}
+
+// ValidateInstances checks the instances setting for errors.
+func (sp *StartParams) ValidateInstances() error {
+	if sp.Instances == 0 {
+		return nil
+	}
+	if sp.Instances < 0 && sp.Instances != InstancesMax {
+		return fmt.Errorf("instances must be a positive number or max")
+	}
+	if sp.Schedule != "" {
+		return fmt.Errorf("instances cannot be used with schedule")
+	}
+	return nil
 }
 
-// TargetParams identifies a process by name, ID, or "all".
+// ParseWatchdogSec parses a watchdog_sec value: a duration such as "30s",
+// or a whole number of seconds as in systemd's WatchdogSec.
+func ParseWatchdogSec(s string) (time.Duration, error) {
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid watchdog_sec %q: must be a duration or a number of seconds", s)
+	}
+	return d, nil
+}
+
+// ValidateNotify checks the notify settings for errors.
+func (sp *StartParams) ValidateNotify() error {
+	if sp.WatchdogSec != "" {
+		if !sp.Notify {
+			return fmt.Errorf("watchdog_sec requires notify")
+		}
+		d, err := ParseWatchdogSec(sp.WatchdogSec)
+		if err != nil {
+			return err
+		}
+		if d <= 0 {
+			return fmt.Errorf("watchdog_sec must be positive")
+		}
+	}
+	if sp.Notify && sp.Schedule != "" {
+		return fmt.Errorf("notify cannot be used with schedule")
+	}
+	return nil
+}
+
+// ValidateRestartBudget checks the restart budget and jitter settings for
+// errors.
+func (sp *StartParams) ValidateRestartBudget() error {
+	if sp.RestartBudget != "" {
+		if _, _, err := ParseRestartBudget(sp.RestartBudget); err != nil {
+			return err
+		}
+	}
+	if sp.CrashLoopCooldown != "" {
+		if sp.RestartBudget == "" {
+			return fmt.Errorf("crashloop_cooldown requires restart_budget")
+		}
+		d, err := time.ParseDuration(sp.CrashLoopCooldown)
+		if err != nil {
+			return fmt.Errorf("invalid crashloop_cooldown %q: %w", sp.CrashLoopCooldown, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("crashloop_cooldown must be positive")
+		}
+	}
+	if sp.BackoffJitter < 0 || sp.BackoffJitter > 1 {
+		return fmt.Errorf("backoff_jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// ValidateSignals checks kill_signal and reload_signal for errors.
+func (sp *StartParams) ValidateSignals() error {
+	if sp.KillSignal != "" {
+		if _, err := ParseSignal(sp.KillSignal); err != nil {
+			return fmt.Errorf("invalid kill_signal: %w", err)
+		}
+	}
+	if sp.ReloadSignal != "" {
+		if _, err := ParseSignal(sp.ReloadSignal); err != nil {
+			return fmt.Errorf("invalid reload_signal: %w", err)
+		}
+	}
+	return nil
+}
+
+// DefaultWatchDelay is how long file changes must settle before a watched
+// process is restarted, when watch_delay is empty.
+const DefaultWatchDelay = time.Second
+
+// ValidateWatch checks the watch settings for errors.
+func (sp *StartParams) ValidateWatch() error {
+	if !sp.Watch {
+		switch {
+		case len(sp.WatchPaths) > 0:
+			return fmt.Errorf("watch_paths requires watch")
+		case len(sp.IgnoreWatch) > 0:
+			return fmt.Errorf("ignore_watch requires watch")
+		case sp.WatchDelay != "":
+			return fmt.Errorf("watch_delay requires watch")
+		}
+		return nil
+	}
+	if sp.Schedule != "" {
+		return fmt.Errorf("watch cannot be used with schedule")
+	}
+	for _, pat := range sp.IgnoreWatch {
+		if _, err := filepath.Match(pat, ""); err != nil {
+			return fmt.Errorf("invalid ignore_watch pattern %q: %w", pat, err)
+		}
+	}
+	if sp.WatchDelay != "" {
+		d, err := time.ParseDuration(sp.WatchDelay)
+		if err != nil {
+			return fmt.Errorf("invalid watch_delay %q: %w", sp.WatchDelay, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("watch_delay must not be negative")
+		}
+	}
+	return nil
+}
+
+// TargetParams identifies a process by name, ID, "all", or a selector (see
+// ParseSelector).
 type TargetParams struct {
 	Target string `json:"target"`
 }
 
+// ListParams are the optional parameters for the "list" method.
+type ListParams struct {
+	Selector string `json:"selector,omitempty"` // only processes it picks; see ParseSelector
+}
+
+var (
+	labelKeyRe   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+	labelValueRe = regexp.MustCompile(`^[A-Za-z0-9._/-]*$`)
+)
+
+// ParseLabel parses a "key=value" label.
+func ParseLabel(s string) (key, value string, err error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("invalid label %q (expected key=value)", s)
+	}
+	if err := ValidateLabels(map[string]string{key: value}); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// ValidateLabels checks label keys and values: letters, digits and ". _ / -",
+// so they can be used in selectors.
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if !labelKeyRe.MatchString(k) {
+			return fmt.Errorf("invalid label key %q: must be letters, digits, '.', '_', '/' or '-'", k)
+		}
+		if !labelValueRe.MatchString(v) {
+			return fmt.Errorf("invalid label value %q for %s: must be letters, digits, '.', '_', '/' or '-'", v, k)
+		}
+	}
+	return nil
+}
+
+// FormatLabels renders labels as sorted "key=value" pairs, comma-separated.
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Selector picks processes by name pattern and labels. See ParseSelector.
+type Selector struct {
+	terms []selectorTerm
+}
+
+type selectorTerm struct {
+	key, pattern string // key is empty for a name pattern
+	not          bool
+}
+
+// IsSelector reports whether a target is a selector rather than a name, an
+// ID, or "all": it has a label match or a glob pattern.
+func IsSelector(target string) bool {
+	return strings.ContainsAny(target, "=*?[")
+}
+
+// ParseSelector parses comma-separated terms that must all match:
+// "key=value" and "key!=value" match labels, anything else matches the
+// process name. Names and values may be glob patterns, e.g.
+// "api-*,team=payments" or "tier!=worker".
+func ParseSelector(s string) (*Selector, error) {
+	sel := &Selector{}
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		var t selectorTerm
+		if k, v, ok := strings.Cut(term, "="); ok {
+			t.key, t.pattern = k, v
+			if k, ok := strings.CutSuffix(k, "!"); ok {
+				t.key, t.not = k, true
+			}
+			if !labelKeyRe.MatchString(t.key) {
+				return nil, fmt.Errorf("invalid selector %q: bad label key %q", s, t.key)
+			}
+		} else {
+			t.pattern = term
+		}
+		if term == "" {
+			return nil, fmt.Errorf("invalid selector %q: empty term", s)
+		}
+		if _, err := path.Match(t.pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid selector %q: bad pattern %q", s, t.pattern)
+		}
+		sel.terms = append(sel.terms, t)
+	}
+	return sel, nil
+}
+
+// Matches reports whether a process with the given name and labels is picked.
+func (s *Selector) Matches(name string, labels map[string]string) bool {
+	for _, t := range s.terms {
+		if t.key == "" {
+			if ok, _ := path.Match(t.pattern, name); !ok {
+				return false
+			}
+			continue
+		}
+		v, has := labels[t.key]
+		ok, _ := path.Match(t.pattern, v)
+		if t.not == (has && ok) {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultReloadTimeout is how long a reload waits for the replacement
+// process to become ready, when ReloadParams.Timeout is empty.
+const DefaultReloadTimeout = 30 * time.Second
+
+// ReloadParams are the parameters for the "reload" method.
+type ReloadParams struct {
+	Target  string `json:"target"`
+	Timeout string `json:"timeout,omitempty"` // readiness timeout per process
+}
+
+// DefaultWaitTimeout is how long "wait" waits for a process to become
+// ready, when WaitParams.Timeout is empty.
+const DefaultWaitTimeout = 30 * time.Second
+
+// WaitParams are the parameters for the "wait" method.
+type WaitParams struct {
+	Target  string `json:"target"`
+	Timeout string `json:"timeout,omitempty"`
+}
+
 // LogsParams are the parameters for the "logs" method.
 type LogsParams struct {
 	Target  string `json:"target"`
@@ -158,6 +1606,77 @@ type LogsParams struct {
 	ErrOnly bool   `json:"err_only"`
 }
 
+// RunsParams are the parameters for the "runs" method.
+type RunsParams struct {
+	Target string `json:"target"`
+	Limit  int    `json:"limit,omitempty"` // most recent runs to return, 0 = all kept
+}
+
+// HistoryParams are the parameters for the "history" method.
+type HistoryParams struct {
+	Target string `json:"target"`
+	Limit  int    `json:"limit,omitempty"` // most recent events to return, 0 = all kept
+}
+
+// Credential is the user and groups a process runs as.
+type Credential struct {
+	User   string `json:"user"`
+	UID    int    `json:"uid"`
+	Group  string `json:"group"`
+	GID    int    `json:"gid"`
+	Groups []int  `json:"groups,omitempty"` // supplementary group IDs
+}
+
+// String formats c as "user (uid), group group (gid)".
+func (c *Credential) String() string {
+	s := fmt.Sprintf("%s (%d), group %s (%d)", c.User, c.UID, c.Group, c.GID)
+	if len(c.Groups) > 0 {
+		ids := make([]string, len(c.Groups))
+		for i, g := range c.Groups {
+			ids[i] = strconv.Itoa(g)
+		}
+		s += ", groups " + strings.Join(ids, ",")
+	}
+	return s
+}
+
+// SignalParams are the parameters for the "signal" method.
+type SignalParams struct {
+	Target string `json:"target"`
+	Signal string `json:"signal"`          // name (SIGHUP, HUP) or number
+	Group  bool   `json:"group,omitempty"` // the whole process group, not just the process
+}
+
+// SignalResult is the outcome of sending a signal to one process.
+type SignalResult struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	PID    int    `json:"pid,omitempty"`
+	Signal string `json:"signal"`
+	Group  bool   `json:"group,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SubscribeParams are the parameters for the "subscribe" method.
+type SubscribeParams struct {
+	Target string `json:"target,omitempty"` // name, instance group or id; empty for all
+}
+
+// NotifyTestParams are the parameters for the "notify_test" method.
+type NotifyTestParams struct {
+	Event  NotifyEvent `json:"event,omitempty"`  // default errored
+	Target string      `json:"target,omitempty"` // process to describe; a sample one if empty
+}
+
+// NotifyTestResult is the outcome of sending a test notification to one
+// webhook.
+type NotifyTestResult struct {
+	Webhook string `json:"webhook"`
+	URL     string `json:"url"`
+	Status  int    `json:"status,omitempty"` // HTTP status, 0 if no response
+	Error   string `json:"error,omitempty"`
+}
+
 // PingResult is returned by the "ping" method.
 type PingResult struct {
 	PID          int    `json:"pid"`
@@ -170,13 +1689,14 @@ type PingResult struct {
 
 // IsRunningResult is returned by the "isrunning" method.
 type IsRunningResult struct {
-	Name     string `json:"name"`
-	Running  bool   `json:"running"`
-	Status   Status `json:"status"`
-	PID      int    `json:"pid"`
-	Uptime   string `json:"uptime,omitempty"`
-	ExitCode int    `json:"exit_code,omitempty"`
-	Restarts int    `json:"restarts,omitempty"`
+	Name      string     `json:"name"`
+	Running   bool       `json:"running"`
+	Status    Status     `json:"status"`
+	PID       int        `json:"pid"`
+	Uptime    string     `json:"uptime,omitempty"`
+	ExitCode  int        `json:"exit_code,omitempty"`
+	Restarts  int        `json:"restarts,omitempty"`
+	RestartAt *time.Time `json:"restart_at,omitempty"`
 }
 
 // StatsParams are the parameters for the "stats" method.
@@ -187,12 +1707,13 @@ type StatsParams struct {
 
 // MetricsSnapshot is a single point-in-time observation of a process.
 type MetricsSnapshot struct {
-	Timestamp int64   `json:"ts"`
-	CPU       float64 `json:"cpu"`
-	Memory    uint64  `json:"mem"`
-	Restarts  int     `json:"restarts"`
-	UptimeSec int64   `json:"uptime"`
-	Status    Status  `json:"status"`
+	Timestamp   int64   `json:"ts"`
+	CPU         float64 `json:"cpu"`
+	Memory      uint64  `json:"mem"`
+	Restarts    int     `json:"restarts"`
+	MemRestarts int     `json:"mem_restarts,omitempty"`
+	UptimeSec   int64   `json:"uptime"`
+	Status      Status  `json:"status"`
 }
 
 // StatsResult is returned by the "stats" method.
@@ -278,6 +1799,72 @@ func FormatSize(b int64) string {
 	}
 }
 
+// signalNames maps signals to their names, for the signals a process is
+// likely to be sent or die from.
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP:    "SIGHUP",
+	syscall.SIGINT:    "SIGINT",
+	syscall.SIGQUIT:   "SIGQUIT",
+	syscall.SIGILL:    "SIGILL",
+	syscall.SIGTRAP:   "SIGTRAP",
+	syscall.SIGABRT:   "SIGABRT",
+	syscall.SIGBUS:    "SIGBUS",
+	syscall.SIGFPE:    "SIGFPE",
+	syscall.SIGKILL:   "SIGKILL",
+	syscall.SIGUSR1:   "SIGUSR1",
+	syscall.SIGSEGV:   "SIGSEGV",
+	syscall.SIGUSR2:   "SIGUSR2",
+	syscall.SIGPIPE:   "SIGPIPE",
+	syscall.SIGALRM:   "SIGALRM",
+	syscall.SIGTERM:   "SIGTERM",
+	syscall.SIGCHLD:   "SIGCHLD",
+	syscall.SIGCONT:   "SIGCONT",
+	syscall.SIGSTOP:   "SIGSTOP",
+	syscall.SIGTSTP:   "SIGTSTP",
+	syscall.SIGTTIN:   "SIGTTIN",
+	syscall.SIGTTOU:   "SIGTTOU",
+	syscall.SIGURG:    "SIGURG",
+	syscall.SIGXCPU:   "SIGXCPU",
+	syscall.SIGXFSZ:   "SIGXFSZ",
+	syscall.SIGVTALRM: "SIGVTALRM",
+	syscall.SIGPROF:   "SIGPROF",
+	syscall.SIGWINCH:  "SIGWINCH",
+	syscall.SIGIO:     "SIGIO",
+	syscall.SIGSYS:    "SIGSYS",
+}
+
+// SignalName returns the name of sig, e.g. "SIGKILL", or "SIG<n>" for a
+// signal without one.
+func SignalName(sig syscall.Signal) string {
+	if name, ok := signalNames[sig]; ok {
+		return name
+	}
+	return fmt.Sprintf("SIG%d", int(sig))
+}
+
+// ParseSignal parses a signal name, with or without the SIG prefix and in
+// any case (SIGHUP, hup), or a signal number, bare or as SignalName writes
+// it (9, SIG9).
+func ParseSignal(s string) (syscall.Signal, error) {
+	s = strings.TrimSpace(s)
+	name := strings.ToUpper(s)
+	if n, err := strconv.Atoi(strings.TrimPrefix(name, "SIG")); err == nil {
+		if n <= 0 || n > 64 {
+			return 0, fmt.Errorf("invalid signal number %d", n)
+		}
+		return syscall.Signal(n), nil
+	}
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	for sig, n := range signalNames {
+		if n == name {
+			return sig, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown signal %q", s)
+}
+
 // FormatDuration formats a duration in a human-friendly way.
 func FormatDuration(d time.Duration) string {
 	if d < time.Second {
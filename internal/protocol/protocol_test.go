@@ -2,6 +2,9 @@ package protocol
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -160,3 +163,691 @@ func TestGopmHome(t *testing.T) {
 		t.Errorf("GopmHome() = %q, want /tmp/test-gopm", got)
 	}
 }
+
+func TestHealthCheckValidate(t *testing.T) {
+	tests := []struct {
+		hc  HealthCheck
+		err bool
+	}{
+		{HealthCheck{Type: "http", Target: "http://127.0.0.1:8080/health"}, false},
+		{HealthCheck{Type: "tcp", Target: "127.0.0.1:5432", Interval: "5s", Timeout: "1s"}, false},
+		{HealthCheck{Type: "exec", Target: "./check.sh", StartPeriod: "30s", FailureThreshold: 5}, false},
+		{HealthCheck{Type: "tcp", Target: ":80", StartPeriod: "0s"}, false},
+		{HealthCheck{Target: ":80"}, true},
+		{HealthCheck{Type: "grpc", Target: ":80"}, true},
+		{HealthCheck{Type: "tcp"}, true},
+		{HealthCheck{Type: "tcp", Target: ":80", Interval: "fast"}, true},
+		{HealthCheck{Type: "tcp", Target: ":80", Timeout: "0s"}, true},
+		{HealthCheck{Type: "tcp", Target: ":80", FailureThreshold: -1}, true},
+	}
+	for _, tt := range tests {
+		err := tt.hc.Validate()
+		if tt.err && err == nil {
+			t.Errorf("Validate(%+v) expected error", tt.hc)
+		}
+		if !tt.err && err != nil {
+			t.Errorf("Validate(%+v) unexpected error: %v", tt.hc, err)
+		}
+	}
+}
+
+func TestStartParamsValidateWatch(t *testing.T) {
+	tests := []struct {
+		sp  StartParams
+		err bool
+	}{
+		{StartParams{}, false},
+		{StartParams{Watch: true}, false},
+		{StartParams{Watch: true, WatchPaths: []string{"src"}, IgnoreWatch: []string{"*.log", "tmp"}, WatchDelay: "500ms"}, false},
+		{StartParams{WatchPaths: []string{"src"}}, true},
+		{StartParams{IgnoreWatch: []string{"*.log"}}, true},
+		{StartParams{WatchDelay: "1s"}, true},
+		{StartParams{Watch: true, WatchDelay: "soon"}, true},
+		{StartParams{Watch: true, WatchDelay: "-1s"}, true},
+		{StartParams{Watch: true, IgnoreWatch: []string{"[a-"}}, true},
+		{StartParams{Watch: true, Schedule: "@hourly"}, true},
+	}
+	for _, tt := range tests {
+		err := tt.sp.ValidateWatch()
+		if tt.err && err == nil {
+			t.Errorf("ValidateWatch(%+v) expected error", tt.sp)
+		}
+		if !tt.err && err != nil {
+			t.Errorf("ValidateWatch(%+v) unexpected error: %v", tt.sp, err)
+		}
+	}
+}
+
+func TestDependencyJSON(t *testing.T) {
+	var deps []Dependency
+	in := `["db", {"name": "cache", "condition": "healthy"}, {"name": "queue"}]`
+	if err := json.Unmarshal([]byte(in), &deps); err != nil {
+		t.Fatal(err)
+	}
+	want := []Dependency{{Name: "db"}, {Name: "cache", Condition: DependsHealthy}, {Name: "queue"}}
+	if !reflect.DeepEqual(deps, want) {
+		t.Fatalf("Unmarshal = %+v, want %+v", deps, want)
+	}
+
+	out, err := json.Marshal(deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := `["db",{"name":"cache","condition":"healthy"},"queue"]`; string(out) != got {
+		t.Errorf("Marshal = %s, want %s", out, got)
+	}
+
+	if err := json.Unmarshal([]byte(`[42]`), &deps); err == nil {
+		t.Error("expected error for a numeric dependency")
+	}
+}
+
+func TestValidateDependsOn(t *testing.T) {
+	tests := []struct {
+		deps []Dependency
+		err  bool
+	}{
+		{nil, false},
+		{[]Dependency{{Name: "db"}, {Name: "migrate", Condition: DependsCompleted}}, false},
+		{[]Dependency{{Name: "db", Condition: DependsStarted}, {Name: "cache", Condition: DependsHealthy}}, false},
+		{[]Dependency{{Name: ""}}, true},
+		{[]Dependency{{Name: "api"}}, true},
+		{[]Dependency{{Name: "db"}, {Name: "db", Condition: DependsHealthy}}, true},
+		{[]Dependency{{Name: "db", Condition: "ready"}}, true},
+	}
+	for _, tt := range tests {
+		err := ValidateDependsOn("api", tt.deps)
+		if tt.err && err == nil {
+			t.Errorf("ValidateDependsOn(%+v) expected error", tt.deps)
+		}
+		if !tt.err && err != nil {
+			t.Errorf("ValidateDependsOn(%+v) unexpected error: %v", tt.deps, err)
+		}
+	}
+}
+
+func TestDependencyOrder(t *testing.T) {
+	graph := map[string][]Dependency{
+		"api":     {{Name: "db"}, {Name: "migrate"}},
+		"migrate": {{Name: "db"}},
+		"worker":  {{Name: "api"}, {Name: "external"}},
+	}
+	deps := func(name string) []Dependency { return graph[name] }
+
+	order, err := DependencyOrder([]string{"worker", "web", "api", "migrate", "db"}, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"db", "migrate", "api", "worker", "web"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+
+	graph["db"] = []Dependency{{Name: "worker"}}
+	_, err = DependencyOrder([]string{"worker", "api", "migrate", "db"}, deps)
+	if err == nil {
+		t.Fatal("expected cycle error")
+	}
+	if want := "dependency cycle: worker -> api -> db -> worker"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
+
+func TestInstancesJSON(t *testing.T) {
+	var sp StartParams
+	for in, want := range map[string]Instances{
+		`{"instances":4}`:     4,
+		`{"instances":"max"}`: InstancesMax,
+		`{"instances":"2"}`:   2,
+		`{}`:                  0,
+	} {
+		sp.Instances = 0
+		if err := json.Unmarshal([]byte(in), &sp); err != nil {
+			t.Errorf("Unmarshal(%s): %v", in, err)
+			continue
+		}
+		if sp.Instances != want {
+			t.Errorf("Unmarshal(%s) = %v, want %v", in, sp.Instances, want)
+		}
+	}
+	for _, in := range []string{`{"instances":-2}`, `{"instances":"all"}`, `{"instances":"0"}`} {
+		if err := json.Unmarshal([]byte(in), &sp); err == nil {
+			t.Errorf("Unmarshal(%s) expected error", in)
+		}
+	}
+
+	out, _ := json.Marshal(StartParams{Command: "x", Instances: InstancesMax})
+	if !strings.Contains(string(out), `"instances":"max"`) {
+		t.Errorf("Marshal = %s, want instances \"max\"", out)
+	}
+	if InstancesMax.Count() < 1 || Instances(3).Count() != 3 {
+		t.Errorf("Count = %d, %d", InstancesMax.Count(), Instances(3).Count())
+	}
+}
+
+func TestStartParamsValidateInstances(t *testing.T) {
+	tests := []struct {
+		sp  StartParams
+		err bool
+	}{
+		{StartParams{}, false},
+		{StartParams{Instances: 4}, false},
+		{StartParams{Instances: InstancesMax}, false},
+		{StartParams{Instances: -3}, true},
+		{StartParams{Instances: 2, Schedule: "@hourly"}, true},
+	}
+	for _, tt := range tests {
+		err := tt.sp.ValidateInstances()
+		if tt.err && err == nil {
+			t.Errorf("ValidateInstances(%+v) expected error", tt.sp)
+		}
+		if !tt.err && err != nil {
+			t.Errorf("ValidateInstances(%+v) unexpected error: %v", tt.sp, err)
+		}
+	}
+}
+
+func TestInstanceLogPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/logs/api-out.log", "/logs/api-out-2.log"},
+		{"/var/log/api", "/var/log/api-2"},
+		{"/logs/app.v1/out", "/logs/app.v1/out-2"},
+	}
+	for _, tt := range tests {
+		got := InstanceLogPath(tt.path, 2)
+		if got != tt.want {
+			t.Errorf("InstanceLogPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+		if back := GroupLogPath(got, 2); back != tt.path {
+			t.Errorf("GroupLogPath(%q) = %q, want %q", got, back, tt.path)
+		}
+	}
+}
+
+func TestGroupInstances(t *testing.T) {
+	procs := []ProcessInfo{
+		{ID: 0, Name: "db"},
+		{ID: 3, Name: "api-1", App: "api", InstanceID: 1, Instances: 2, LogOut: "/l/api-out-1.log"},
+		{ID: 4, Name: "web"},
+		{ID: 1, Name: "api-0", App: "api", InstanceID: 0, Instances: 2, LogOut: "/l/api-out-0.log"},
+	}
+	got := GroupInstances(procs)
+	if len(got) != 3 || got[0].Name != "db" || got[1].Name != "api" || got[2].Name != "web" {
+		t.Fatalf("GroupInstances = %+v", got)
+	}
+	api := got[1]
+	if api.ID != 1 || api.App != "" || api.InstanceID != 0 || api.Instances != 2 || api.LogOut != "/l/api-out.log" {
+		t.Errorf("group entry = %+v", api)
+	}
+}
+
+func TestHealthCheckTimings(t *testing.T) {
+	hc := HealthCheck{Type: "tcp", Target: ":80"}
+	interval, timeout, start, threshold := hc.Timings()
+	if interval != DefaultHealthInterval || timeout != DefaultHealthTimeout || start != 0 || threshold != DefaultHealthFailureThreshold {
+		t.Errorf("defaults = %v, %v, %v, %d", interval, timeout, start, threshold)
+	}
+
+	hc = HealthCheck{Type: "tcp", Target: ":80", Interval: "2s", Timeout: "500ms", StartPeriod: "10s", FailureThreshold: 1}
+	interval, timeout, start, threshold = hc.Timings()
+	if interval != 2*time.Second || timeout != 500*time.Millisecond || start != 10*time.Second || threshold != 1 {
+		t.Errorf("Timings() = %v, %v, %v, %d", interval, timeout, start, threshold)
+	}
+}
+
+func TestCgroupLimitsCPUMaxValue(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   bool
+	}{
+		{"", "max", false},
+		{"max", "max", false},
+		{"50%", "50000 100000", false},
+		{"150%", "150000 100000", false},
+		{"25000 50000", "25000 50000", false},
+		{"max 100000", "max 100000", false},
+		{"0.5%", "", true},
+		{"-10%", "", true},
+		{"half", "", true},
+		{"50000", "", true},
+	}
+	for _, tt := range tests {
+		c := CgroupLimits{CPUMax: tt.input}
+		got, err := c.CPUMaxValue()
+		if tt.err {
+			if err == nil {
+				t.Errorf("CPUMaxValue(%q) expected error, got %q", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("CPUMaxValue(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CPUMaxValue(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCgroupLimitsValidate(t *testing.T) {
+	valid := CgroupLimits{CPUMax: "50%", MemoryMax: "1G", MemoryHigh: "768M", PidsMax: 128, IOWeight: 200}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+	for _, c := range []CgroupLimits{
+		{MemoryMax: "lots"},
+		{MemoryHigh: "1X"},
+		{PidsMax: -1},
+		{IOWeight: 20000},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error", c)
+		}
+	}
+}
+
+func TestSocketJSON(t *testing.T) {
+	var sockets []Socket
+	in := `["tcp:127.0.0.1:8080", {"name": "admin", "address": "unix:/run/api.sock"}]`
+	if err := json.Unmarshal([]byte(in), &sockets); err != nil {
+		t.Fatal(err)
+	}
+	want := []Socket{{Address: "tcp:127.0.0.1:8080"}, {Name: "admin", Address: "unix:/run/api.sock"}}
+	if !reflect.DeepEqual(sockets, want) {
+		t.Fatalf("Unmarshal = %+v, want %+v", sockets, want)
+	}
+
+	out, err := json.Marshal(sockets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := `["tcp:127.0.0.1:8080",{"name":"admin","address":"unix:/run/api.sock"}]`; string(out) != got {
+		t.Errorf("Marshal = %s, want %s", out, got)
+	}
+}
+
+func TestParseSocket(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Socket
+	}{
+		{"tcp:127.0.0.1:8080", Socket{Address: "tcp:127.0.0.1:8080"}},
+		{"http=tcp::8080", Socket{Name: "http", Address: "tcp::8080"}},
+		{"unix:/tmp/a=b.sock", Socket{Address: "unix:/tmp/a=b.sock"}},
+		{"admin=unix:/run/api.sock", Socket{Name: "admin", Address: "unix:/run/api.sock"}},
+	}
+	for _, tt := range tests {
+		if got := ParseSocket(tt.in); got != tt.want {
+			t.Errorf("ParseSocket(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSockets(t *testing.T) {
+	tests := []struct {
+		sockets []Socket
+		err     bool
+	}{
+		{nil, false},
+		{[]Socket{{Address: "tcp:127.0.0.1:8080"}, {Name: "admin", Address: "unix:/run/api.sock"}}, false},
+		{[]Socket{{Address: ":8080"}, {Address: "tcp:[::1]:8081"}}, false},
+		{[]Socket{{Address: ""}}, true},
+		{[]Socket{{Address: "tcp:127.0.0.1"}}, true},
+		{[]Socket{{Address: "tcp:127.0.0.1:0"}}, true},
+		{[]Socket{{Address: "tcp:127.0.0.1:http"}}, true},
+		{[]Socket{{Address: "unix:api.sock"}}, true},
+		{[]Socket{{Name: "a:b", Address: ":8080"}}, true},
+		{[]Socket{{Address: ":8080"}, {Address: "tcp::8080"}}, true},
+	}
+	for _, tt := range tests {
+		err := ValidateSockets(tt.sockets)
+		if tt.err && err == nil {
+			t.Errorf("ValidateSockets(%+v) expected error", tt.sockets)
+		}
+		if !tt.err && err != nil {
+			t.Errorf("ValidateSockets(%+v) unexpected error: %v", tt.sockets, err)
+		}
+	}
+}
+
+func TestParseWatchdogSec(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{"30", 30 * time.Second, false},
+		{"30s", 30 * time.Second, false},
+		{"1m30s", 90 * time.Second, false},
+		{"500ms", 500 * time.Millisecond, false},
+		{"", 0, true},
+		{"1.5", 0, true},
+		{"-5", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseWatchdogSec(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseWatchdogSec(%q) expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseWatchdogSec(%q) unexpected error: %v", tt.in, err)
+		} else if got != tt.want {
+			t.Errorf("ParseWatchdogSec(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateNotify(t *testing.T) {
+	tests := []struct {
+		sp  StartParams
+		err bool
+	}{
+		{StartParams{}, false},
+		{StartParams{Notify: true}, false},
+		{StartParams{Notify: true, WatchdogSec: "30"}, false},
+		{StartParams{Notify: true, WatchdogSec: "10s"}, false},
+		{StartParams{WatchdogSec: "30s"}, true},
+		{StartParams{Notify: true, WatchdogSec: "0s"}, true},
+		{StartParams{Notify: true, WatchdogSec: "-1s"}, true},
+		{StartParams{Notify: true, WatchdogSec: "often"}, true},
+		{StartParams{Notify: true, Schedule: "* * * * *"}, true},
+	}
+	for _, tt := range tests {
+		err := tt.sp.ValidateNotify()
+		if tt.err && err == nil {
+			t.Errorf("ValidateNotify(%+v) expected error", tt.sp)
+		}
+		if !tt.err && err != nil {
+			t.Errorf("ValidateNotify(%+v) unexpected error: %v", tt.sp, err)
+		}
+	}
+}
+
+func TestParseRestartBudget(t *testing.T) {
+	tests := []struct {
+		in     string
+		n      int
+		window time.Duration
+		err    bool
+	}{
+		{"10/15m", 10, 15 * time.Minute, false},
+		{"3/1h30m", 3, 90 * time.Minute, false},
+		{" 5 / 30s ", 5, 30 * time.Second, false},
+		{"", 0, 0, true},
+		{"10", 0, 0, true},
+		{"0/15m", 0, 0, true},
+		{"ten/15m", 0, 0, true},
+		{"10/0s", 0, 0, true},
+		{"10/15", 0, 0, true},
+	}
+	for _, tt := range tests {
+		n, window, err := ParseRestartBudget(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseRestartBudget(%q) expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRestartBudget(%q) unexpected error: %v", tt.in, err)
+		} else if n != tt.n || window != tt.window {
+			t.Errorf("ParseRestartBudget(%q) = %d, %v, want %d, %v", tt.in, n, window, tt.n, tt.window)
+		}
+	}
+
+	n, window, err := ParseRestartBudget(FormatRestartBudget(10, 15*time.Minute))
+	if err != nil || n != 10 || window != 15*time.Minute {
+		t.Errorf("FormatRestartBudget round trip = %d, %v, %v", n, window, err)
+	}
+}
+
+func TestValidateRestartBudget(t *testing.T) {
+	tests := []struct {
+		sp  StartParams
+		err bool
+	}{
+		{StartParams{}, false},
+		{StartParams{RestartBudget: "10/15m"}, false},
+		{StartParams{RestartBudget: "10/15m", CrashLoopCooldown: "1m"}, false},
+		{StartParams{BackoffJitter: 0.2}, false},
+		{StartParams{BackoffJitter: 1}, false},
+		{StartParams{RestartBudget: "lots"}, true},
+		{StartParams{CrashLoopCooldown: "1m"}, true},
+		{StartParams{RestartBudget: "10/15m", CrashLoopCooldown: "later"}, true},
+		{StartParams{BackoffJitter: -0.1}, true},
+		{StartParams{BackoffJitter: 1.5}, true},
+	}
+	for _, tt := range tests {
+		err := tt.sp.ValidateRestartBudget()
+		if tt.err && err == nil {
+			t.Errorf("ValidateRestartBudget(%+v) expected error", tt.sp)
+		}
+		if !tt.err && err != nil {
+			t.Errorf("ValidateRestartBudget(%+v) unexpected error: %v", tt.sp, err)
+		}
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want syscall.Signal
+		err  bool
+	}{
+		{"SIGHUP", syscall.SIGHUP, false},
+		{"HUP", syscall.SIGHUP, false},
+		{"usr1", syscall.SIGUSR1, false},
+		{"sigterm", syscall.SIGTERM, false},
+		{"9", syscall.SIGKILL, false},
+		{"SIG9", syscall.SIGKILL, false},
+		{"", 0, true},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"SIGNOPE", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSignal(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseSignal(%q) expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSignal(%q) unexpected error: %v", tt.in, err)
+		} else if got != tt.want {
+			t.Errorf("ParseSignal(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSignals(t *testing.T) {
+	tests := []struct {
+		sp  StartParams
+		err bool
+	}{
+		{StartParams{}, false},
+		{StartParams{KillSignal: "SIGINT", ReloadSignal: "hup"}, false},
+		{StartParams{KillSignal: "3"}, false},
+		{StartParams{KillSignal: "SIGNOPE"}, true},
+		{StartParams{ReloadSignal: "99"}, true},
+	}
+	for _, tt := range tests {
+		err := tt.sp.ValidateSignals()
+		if tt.err && err == nil {
+			t.Errorf("ValidateSignals(%+v) expected error", tt.sp)
+		}
+		if !tt.err && err != nil {
+			t.Errorf("ValidateSignals(%+v) unexpected error: %v", tt.sp, err)
+		}
+	}
+}
+
+func TestParseRlimit(t *testing.T) {
+	tests := []struct {
+		input      string
+		size       bool
+		soft, hard uint64
+		err        bool
+	}{
+		{"65536", false, 65536, 65536, false},
+		{"1024:65536", false, 1024, 65536, false},
+		{"unlimited", false, RlimInfinity, RlimInfinity, false},
+		{"0:infinity", true, 0, RlimInfinity, false},
+		{"64M", true, 64 << 20, 64 << 20, false},
+		{"64M", false, 0, 0, true},
+		{"2048:1024", false, 0, 0, true},
+		{"", false, 0, 0, true},
+		{"-1", false, 0, 0, true},
+		{"1024:", false, 0, 0, true},
+	}
+	for _, tt := range tests {
+		soft, hard, err := ParseRlimit(tt.input, tt.size)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseRlimit(%q) expected error, got %d:%d", tt.input, soft, hard)
+			}
+			continue
+		}
+		if err != nil || soft != tt.soft || hard != tt.hard {
+			t.Errorf("ParseRlimit(%q) = %d, %d, %v; want %d, %d", tt.input, soft, hard, err, tt.soft, tt.hard)
+		}
+	}
+	if got := FormatRlimit(1024, RlimInfinity); got != "1024:unlimited" {
+		t.Errorf("FormatRlimit = %q, want 1024:unlimited", got)
+	}
+}
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := ParseCPUList("6, 0-3,2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatCPUList(cpus); got != "0-3,6" {
+		t.Errorf("FormatCPUList = %q, want 0-3,6", got)
+	}
+	for _, bad := range []string{"", "a", "3-1", "-1", "0-1024"} {
+		if _, err := ParseCPUList(bad); err == nil {
+			t.Errorf("ParseCPUList(%q) expected error", bad)
+		}
+	}
+}
+
+func TestLimitsValidate(t *testing.T) {
+	n := func(v int) *int { return &v }
+	valid := Limits{Nofile: "1024:65536", Nproc: "unlimited", Core: "0", Memlock: "64M", Nice: n(-5),
+		IONiceClass: "best-effort", IONiceLevel: n(7), CPUAffinity: "0", OOMScoreAdj: n(-1000), Umask: "027"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+	for _, l := range []Limits{
+		{Nofile: "lots"},
+		{Memlock: "1X"},
+		{Nice: n(20)},
+		{IONiceClass: "none"},
+		{IONiceLevel: n(8)},
+		{CPUAffinity: "0-"},
+		{OOMScoreAdj: n(1001)},
+		{Umask: "0999"},
+	} {
+		if err := l.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error", l)
+		}
+	}
+}
+
+func TestLimitsValues(t *testing.T) {
+	n := func(v int) *int { return &v }
+	l := Limits{Nofile: "4096", Core: "1K:unlimited", Nice: n(0), IONiceLevel: n(2), CPUAffinity: "1,0", Umask: "27"}
+	want := map[string]string{
+		"nofile":       "4096",
+		"core":         "1024:unlimited",
+		"nice":         "0",
+		"ionice":       "best-effort:2",
+		"cpu_affinity": "0-1",
+		"umask":        "0027",
+	}
+	if got := l.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+	idle := Limits{IONiceClass: "idle", IONiceLevel: n(3)}
+	if got := idle.Values()["ionice"]; got != "idle" {
+		t.Errorf("idle ionice = %q, want idle", got)
+	}
+}
+
+func TestParseLabel(t *testing.T) {
+	k, v, err := ParseLabel("team=payments")
+	if err != nil || k != "team" || v != "payments" {
+		t.Errorf("ParseLabel = %q, %q, %v", k, v, err)
+	}
+	if _, v, err := ParseLabel("canary="); err != nil || v != "" {
+		t.Errorf("empty value: %q, %v", v, err)
+	}
+	for _, bad := range []string{"team", "=x", "team=a b", "te am=x", "-team=x"} {
+		if _, _, err := ParseLabel(bad); err == nil {
+			t.Errorf("ParseLabel(%q) expected error", bad)
+		}
+	}
+	if got := FormatLabels(map[string]string{"tier": "web", "team": "payments"}); got != "team=payments,tier=web" {
+		t.Errorf("FormatLabels = %q", got)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	for _, bad := range []string{"", "a,", "=x", "te am=x", "api-[", "team=[x"} {
+		if _, err := ParseSelector(bad); err == nil {
+			t.Errorf("ParseSelector(%q) expected error", bad)
+		}
+	}
+	for _, target := range []string{"api", "3", "all"} {
+		if IsSelector(target) {
+			t.Errorf("IsSelector(%q) = true", target)
+		}
+	}
+	for _, target := range []string{"api-*", "team=x", "tier!=web", "api?"} {
+		if !IsSelector(target) {
+			t.Errorf("IsSelector(%q) = false", target)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"team": "payments", "tier": "web"}
+	tests := []struct {
+		sel  string
+		name string
+		want bool
+	}{
+		{"team=payments", "api", true},
+		{"team=search", "api", false},
+		{"team=pay*", "api", true},
+		{"team!=payments", "api", false},
+		{"region!=eu", "api", true},
+		{"region=", "api", false},
+		{"team=", "api", false},
+		{"team=*", "api", true},
+		{"api-*", "api-1", true},
+		{"api-*", "worker", false},
+		{"api-*,tier=web", "api-1", true},
+		{"api-*,tier=worker", "api-1", false},
+		{" team=payments , tier=web ", "api", true},
+	}
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.sel)
+		if err != nil {
+			t.Fatalf("ParseSelector(%q): %v", tt.sel, err)
+		}
+		if got := sel.Matches(tt.name, labels); got != tt.want {
+			t.Errorf("%q.Matches(%q) = %v, want %v", tt.sel, tt.name, got, tt.want)
+		}
+	}
+}
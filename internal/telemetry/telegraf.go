@@ -79,7 +79,7 @@ func (e *TelegrafEmitter) processLine(p protocol.ProcessInfo, now int64) string
 		escapeTag(string(p.Status)),
 	)
 
-	if p.Status == protocol.StatusOnline {
+	if p.Status.Running() {
 		uptime := int64(0)
 		if !p.Uptime.IsZero() {
 			uptime = int64(time.Since(p.Uptime).Seconds())
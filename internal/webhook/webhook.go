@@ -0,0 +1,180 @@
+// Package webhook delivers process notifications to HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+// Payload is the JSON body POSTed to a webhook.
+type Payload struct {
+	Event   protocol.NotifyEvent `json:"event"`
+	Time    time.Time            `json:"time"`
+	Host    string               `json:"host"`
+	Reason  string               `json:"reason"`
+	Process Process              `json:"process"`
+	Stderr  []string             `json:"stderr,omitempty"` // last lines of the stderr log
+	Test    bool                 `json:"test,omitempty"`   // sent by gopm notify test
+}
+
+// Process is the part of a process's info a payload carries. Its command
+// line and environment are left out, as they may hold secrets.
+type Process struct {
+	ID           int             `json:"id"`
+	Name         string          `json:"name"`
+	App          string          `json:"app,omitempty"` // instance group, if any
+	Status       protocol.Status `json:"status"`
+	StatusReason string          `json:"status_reason,omitempty"`
+	PID          int             `json:"pid"`
+	ExitCode     int             `json:"exit_code"`
+	Restarts     int             `json:"restarts"`
+}
+
+// ProcessOf returns the payload's view of info.
+func ProcessOf(info protocol.ProcessInfo) Process {
+	return Process{
+		ID:           info.ID,
+		Name:         info.Name,
+		App:          info.App,
+		Status:       info.Status,
+		StatusReason: info.StatusReason,
+		PID:          info.PID,
+		ExitCode:     info.ExitCode,
+		Restarts:     info.Restarts,
+	}
+}
+
+const (
+	queueSize      = 100              // deliveries waiting for a worker
+	workers        = 4                // concurrent deliveries
+	maxAttempts    = 5                // tries per delivery
+	maxBackoff     = time.Minute      // longest wait between tries
+	requestTimeout = 10 * time.Second // per try
+)
+
+var client = &http.Client{Timeout: requestTimeout}
+
+// Post sends p to url once and returns the HTTP status.
+func Post(url string, headers map[string]string, p Payload) (int, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return 0, fmt.Errorf("marshal payload: %w", err)
+	}
+	return post(url, headers, body)
+}
+
+func post(url string, headers map[string]string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gopm")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed try is worth repeating: network
+// errors, 5xx and 429 are; other 4xx are not.
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
+
+type delivery struct {
+	name    string
+	url     string
+	headers map[string]string
+	event   protocol.NotifyEvent
+	body    []byte
+}
+
+// Notifier delivers payloads in the background, retrying failed tries with
+// exponential backoff.
+type Notifier struct {
+	queue   chan delivery
+	backoff time.Duration // wait before the first retry, doubled after each
+	stopCh  chan struct{}
+}
+
+// New starts a Notifier.
+func New() *Notifier {
+	n := &Notifier{
+		queue:   make(chan delivery, queueSize),
+		backoff: time.Second,
+		stopCh:  make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		go n.run()
+	}
+	return n
+}
+
+// Enqueue queues p for delivery to the webhook without blocking. It is
+// dropped, with a warning, if the queue is full.
+func (n *Notifier) Enqueue(name, url string, headers map[string]string, p Payload) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		slog.Error("webhook payload", "webhook", name, "error", err)
+		return
+	}
+	select {
+	case n.queue <- delivery{name: name, url: url, headers: headers, event: p.Event, body: body}:
+	default:
+		slog.Warn("webhook queue full, notification dropped", "webhook", name, "event", p.Event, "process", p.Process.Name)
+	}
+}
+
+// Close stops delivery; queued and retrying notifications are dropped.
+func (n *Notifier) Close() {
+	close(n.stopCh)
+}
+
+func (n *Notifier) run() {
+	for {
+		select {
+		case d := <-n.queue:
+			n.deliver(d)
+		case <-n.stopCh:
+			return
+		}
+	}
+}
+
+func (n *Notifier) deliver(d delivery) {
+	wait := n.backoff
+	for attempt := 1; ; attempt++ {
+		status, err := post(d.url, d.headers, d.body)
+		if err == nil {
+			slog.Debug("webhook delivered", "webhook", d.name, "event", d.event, "status", status)
+			return
+		}
+		if attempt == maxAttempts || !retryable(status) {
+			slog.Warn("webhook delivery failed", "webhook", d.name, "event", d.event, "attempts", attempt, "error", err)
+			return
+		}
+		select {
+		case <-time.After(wait):
+		case <-n.stopCh:
+			return
+		}
+		wait = min(wait*2, maxBackoff)
+	}
+}
@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/7c/gopm/internal/protocol"
+)
+
+func TestNotifierRetries(t *testing.T) {
+	var tries atomic.Int32
+	got := make(chan Payload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer x" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		if tries.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var p Payload
+		json.NewDecoder(r.Body).Decode(&p)
+		got <- p
+	}))
+	defer srv.Close()
+
+	n := New()
+	n.backoff = time.Millisecond
+	defer n.Close()
+	n.Enqueue("test", srv.URL, map[string]string{"Authorization": "Bearer x"}, Payload{
+		Event:   protocol.NotifyErrored,
+		Reason:  "max restarts reached",
+		Process: Process{Name: "api"},
+	})
+
+	select {
+	case p := <-got:
+		if p.Event != protocol.NotifyErrored || p.Process.Name != "api" || p.Reason != "max restarts reached" {
+			t.Errorf("payload = %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification not delivered")
+	}
+	if n := tries.Load(); n != 3 {
+		t.Errorf("delivered after %d tries, want 3", n)
+	}
+}
+
+func TestNotifierNoRetryOnClientError(t *testing.T) {
+	var tries atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tries.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	status, err := Post(srv.URL, nil, Payload{Event: protocol.NotifyRestarted})
+	if status != http.StatusNotFound || err == nil {
+		t.Errorf("Post = %d, %v; want 404 and an error", status, err)
+	}
+
+	n := New()
+	n.backoff = time.Millisecond
+	defer n.Close()
+	n.Enqueue("test", srv.URL, nil, Payload{Event: protocol.NotifyRestarted})
+	time.Sleep(100 * time.Millisecond)
+	if n := tries.Load(); n != 2 {
+		t.Errorf("got %d tries, want 1 for Post and 1 for the 404 delivery", n)
+	}
+}
+
+func TestProcessOfLeavesOutSecrets(t *testing.T) {
+	info := protocol.ProcessInfo{
+		ID:      3,
+		Name:    "api",
+		Command: "/srv/api",
+		Args:    []string{"--token", "s3cret"},
+		Env:     map[string]string{"DB_PASSWORD": "s3cret"},
+		Status:  protocol.StatusErrored,
+	}
+	data, _ := json.Marshal(Payload{Process: ProcessOf(info)})
+	if strings.Contains(string(data), "s3cret") {
+		t.Errorf("payload carries secrets: %s", data)
+	}
+	p := ProcessOf(info)
+	if p.ID != 3 || p.Name != "api" || p.Status != protocol.StatusErrored {
+		t.Errorf("ProcessOf = %+v", p)
+	}
+}
@@ -1,9 +1,16 @@
 package test
 
 import (
+	"bufio"
 	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"syscall"
@@ -236,6 +243,34 @@ func TestAutoRestartOnFailure(t *testing.T) {
 	}
 }
 
+func TestCrashLoop(t *testing.T) {
+	env := NewTestEnv(t)
+
+	env.MustGopm("start", env.TestappBin, "--name", "looper",
+		"--restart-delay", "100ms",
+		"--restart-budget", "2/1m",
+		"--crashloop-cooldown", "2s",
+		"--", "--crash-after", "200ms")
+
+	// Two restarts fit the budget; the third crash puts it in crashloop.
+	env.WaitForStatus("looper", "crashloop", 10*time.Second)
+	if restarts := env.GetProcessField("looper", "restarts"); restarts != "2" {
+		t.Errorf("restarts in crashloop = %s, want 2", restarts)
+	}
+	if reason := env.GetProcessField("looper", "status_reason"); !strings.Contains(reason, "2 restarts in 1m") {
+		t.Errorf("status_reason = %q, want the spent budget", reason)
+	}
+
+	// After the cool-down it gets one more try, and lands back in crashloop.
+	env.WaitForRestartCount("looper", 3, 10*time.Second)
+	env.WaitForStatus("looper", "crashloop", 10*time.Second)
+
+	env.MustGopm("stop", "looper")
+	if status := env.GetProcessField("looper", "status"); status != "stopped" {
+		t.Errorf("status after stop = %q, want stopped", status)
+	}
+}
+
 func TestAutoRestartNever(t *testing.T) {
 	env := NewTestEnv(t)
 
@@ -772,3 +807,1123 @@ func TestReboot(t *testing.T) {
 		t.Errorf("expected 2 processes after reboot, got %d", count)
 	}
 }
+
+func TestScheduledJob(t *testing.T) {
+	env := NewTestEnv(t)
+
+	out := env.MustGopm("start", env.TestappBin, "--name", "job", "--schedule", "*/2 * * * * *",
+		"--", "--stdout-msg", "job ran", "--stdout-every", "100ms", "--exit-after", "300ms")
+	if !strings.Contains(out, "scheduled") {
+		t.Errorf("start output unexpected: %q", out)
+	}
+	if status := env.GetProcessField("job", "status"); status != "scheduled" {
+		t.Errorf("status = %q, want scheduled", status)
+	}
+
+	// Wait for at least one completed run.
+	var runs []map[string]interface{}
+	deadline := time.Now().Add(8 * time.Second)
+	for time.Now().Before(deadline) {
+		out = env.MustGopm("runs", "job", "--json")
+		runs = nil
+		json.Unmarshal([]byte(out), &runs)
+		if len(runs) > 0 && runs[len(runs)-1]["status"] == "success" {
+			break
+		}
+		time.Sleep(300 * time.Millisecond)
+	}
+	if len(runs) == 0 {
+		t.Fatal("no runs recorded")
+	}
+	first := runs[len(runs)-1]
+	if first["status"] != "success" {
+		t.Fatalf("first run status = %v, want success", first["status"])
+	}
+	if !strings.Contains(first["output"].(string), "job ran") {
+		t.Errorf("run output = %q, want it to contain %q", first["output"], "job ran")
+	}
+
+	// Between runs the job goes back to scheduled, not stopped.
+	env.WaitForStatus("job", "scheduled", 5*time.Second)
+
+	env.MustGopm("stop", "job")
+	env.WaitForStatus("job", "stopped", 5*time.Second)
+}
+
+func TestScheduledJobOverlapSkip(t *testing.T) {
+	env := NewTestEnv(t)
+
+	env.MustGopm("start", env.TestappBin, "--name", "slow", "--schedule", "* * * * * *",
+		"--overlap", "skip", "--", "--exit-after", "2500ms")
+
+	deadline := time.Now().Add(8 * time.Second)
+	for time.Now().Before(deadline) {
+		out := env.MustGopm("runs", "slow", "--json")
+		var runs []map[string]interface{}
+		json.Unmarshal([]byte(out), &runs)
+		for _, r := range runs {
+			if r["status"] == "skipped" {
+				return
+			}
+		}
+		time.Sleep(300 * time.Millisecond)
+	}
+	t.Fatal("expected a skipped run while the previous run was still going")
+}
+
+func TestWatchRestart(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("watch requires inotify")
+	}
+	env := NewTestEnv(t)
+	dir := filepath.Join(env.Home, "app")
+	os.MkdirAll(filepath.Join(dir, "tmp"), 0755)
+
+	env.MustGopm("start", env.TestappBin, "--name", "watched", "--cwd", dir,
+		"--watch", "--ignore-watch", "tmp", "--watch-delay", "200ms", "--", "--run-forever")
+	env.WaitForStatus("watched", "online", 5*time.Second)
+	pidBefore := env.GetProcessField("watched", "pid")
+
+	// Give the watcher a moment to set up, then touch an ignored path.
+	time.Sleep(300 * time.Millisecond)
+	os.WriteFile(filepath.Join(dir, "tmp", "scratch"), []byte("x"), 0644)
+	time.Sleep(700 * time.Millisecond)
+	if pid := env.GetProcessField("watched", "pid"); pid != pidBefore {
+		t.Fatalf("ignored change restarted the process (PID %s -> %s)", pidBefore, pid)
+	}
+
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte("{}"), 0644)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if pid := env.GetProcessField("watched", "pid"); pid != pidBefore && pid != "0" {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if pid := env.GetProcessField("watched", "pid"); pid == pidBefore {
+		t.Fatal("PID did not change after file change")
+	}
+	if reason := env.GetProcessField("watched", "last_restart_reason"); reason != "file change detected: config.json" {
+		t.Errorf("last_restart_reason = %q", reason)
+	}
+
+	logs := env.MustGopm("logs", "watched", "--err", "--lines", "50")
+	if !strings.Contains(logs, "[gopm] file change detected: config.json") {
+		t.Errorf("logs missing watch action line:\n%s", logs)
+	}
+}
+
+func TestDependsOnStartOrder(t *testing.T) {
+	env := NewTestEnv(t)
+
+	// api is listed first but must wait for migrate to exit cleanly.
+	ecoPath := env.WriteEcosystem(map[string]interface{}{
+		"apps": []map[string]interface{}{
+			{
+				"name":       "api",
+				"command":    env.TestappBin,
+				"args":       []string{"--run-forever"},
+				"depends_on": []interface{}{map[string]string{"name": "migrate", "condition": "completed_successfully"}},
+			},
+			{
+				"name":        "migrate",
+				"command":     env.TestappBin,
+				"args":        []string{"--exit-after", "1s"},
+				"autorestart": "never",
+			},
+		},
+	})
+
+	out := env.MustGopm("start", ecoPath)
+	if strings.Index(out, "migrate") > strings.Index(out, "api") {
+		t.Errorf("migrate should be started before api:\n%s", out)
+	}
+	if status := env.GetProcessField("api", "status"); status != "waiting" {
+		t.Errorf("api status = %q, want waiting", status)
+	}
+
+	env.WaitForStatus("api", "online", 10*time.Second)
+	if status := env.GetProcessField("migrate", "status"); status != "stopped" {
+		t.Errorf("migrate status = %q, want stopped", status)
+	}
+	logs := env.MustGopm("logs", "api", "--err", "--lines", "50")
+	if !strings.Contains(logs, "[gopm] dependencies ready") {
+		t.Errorf("logs missing dependencies ready line:\n%s", logs)
+	}
+
+	// A dependency that exits non-zero and won't be restarted fails the dependent.
+	ecoPath = env.WriteEcosystem(map[string]interface{}{
+		"apps": []map[string]interface{}{
+			{
+				"name":        "bad-migrate",
+				"command":     env.TestappBin,
+				"args":        []string{"--crash-after", "200ms"},
+				"autorestart": "never",
+			},
+			{
+				"name":       "bad-api",
+				"command":    env.TestappBin,
+				"args":       []string{"--run-forever"},
+				"depends_on": []interface{}{map[string]string{"name": "bad-migrate", "condition": "completed_successfully"}},
+			},
+		},
+	})
+	env.MustGopm("start", ecoPath)
+	env.WaitForStatus("bad-api", "errored", 10*time.Second)
+	if reason := env.GetProcessField("bad-api", "status_reason"); !strings.Contains(reason, "bad-migrate") {
+		t.Errorf("status_reason = %q", reason)
+	}
+}
+
+func TestInstancesScale(t *testing.T) {
+	env := NewTestEnv(t)
+
+	out := env.MustGopm("start", env.TestappBin, "--name", "api", "--instances", "3",
+		"--", "--run-forever", "--print-env", "GOPM_INSTANCE_ID")
+	for _, name := range []string{"api-0", "api-1", "api-2"} {
+		if !strings.Contains(out, name) {
+			t.Errorf("start output missing %s:\n%s", name, out)
+		}
+		env.WaitForStatus(name, "online", 5*time.Second)
+	}
+	if n := env.ProcessCount(); n != 3 {
+		t.Fatalf("expected 3 processes, got %d", n)
+	}
+	if app := env.GetProcessField("api-1", "app"); app != "api" {
+		t.Errorf("app = %q, want api", app)
+	}
+	if logOut := env.GetProcessField("api-1", "log_out"); !strings.HasSuffix(logOut, "api-out-1.log") {
+		t.Errorf("log_out = %q", logOut)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if logs := env.MustGopm("logs", "api-2"); !strings.Contains(logs, "GOPM_INSTANCE_ID=2") {
+		t.Errorf("api-2 logs missing instance ID:\n%s", logs)
+	}
+
+	// The group name can't be reused, and the list groups the instances.
+	if _, _, code := env.Gopm("start", env.TestappBin, "--name", "api"); code == 0 {
+		t.Error("expected starting a process named after a group to fail")
+	}
+	if list := env.MustGopm("list"); !strings.Contains(list, "3/3 online") {
+		t.Errorf("list missing group summary:\n%s", list)
+	}
+
+	env.MustGopm("scale", "api", "5")
+	env.WaitForStatus("api-4", "online", 5*time.Second)
+	if n := env.ProcessCount(); n != 5 {
+		t.Fatalf("expected 5 processes after scale up, got %d", n)
+	}
+
+	env.MustGopm("scale", "api", "2")
+	if n := env.ProcessCount(); n != 2 {
+		t.Fatalf("expected 2 processes after scale down, got %d", n)
+	}
+	if status := env.GetProcessField("api-1", "status"); status != "online" {
+		t.Errorf("api-1 status = %q, want online", status)
+	}
+
+	// Group-wide actions reach every instance.
+	env.MustGopm("stop", "api")
+	for _, name := range []string{"api-0", "api-1"} {
+		if status := env.GetProcessField(name, "status"); status != "stopped" {
+			t.Errorf("%s status = %q, want stopped", name, status)
+		}
+	}
+
+	env.MustGopm("start", env.TestappBin, "--name", "solo", "--", "--run-forever")
+	if _, _, code := env.Gopm("scale", "solo", "2"); code == 0 {
+		t.Error("expected scaling a process that is not a group to fail")
+	}
+}
+
+func TestReload(t *testing.T) {
+	env := NewTestEnv(t)
+
+	env.MustGopm("start", env.TestappBin, "--name", "api", "--ready-message", "PID=",
+		"--", "--run-forever", "--start-delay", "500ms", "--print-pid")
+	env.WaitForStatus("api", "online", 5*time.Second)
+	time.Sleep(time.Second)
+	oldPID := env.GetProcessField("api", "pid")
+
+	env.MustGopm("reload", "api")
+	if status := env.GetProcessField("api", "status"); status != "online" {
+		t.Fatalf("status after reload = %q, want online", status)
+	}
+	newPID := env.GetProcessField("api", "pid")
+	if newPID == oldPID {
+		t.Fatalf("pid unchanged after reload: %s", newPID)
+	}
+	if n := env.ProcessCount(); n != 1 {
+		t.Errorf("expected 1 process after reload, got %d", n)
+	}
+	if reason := env.GetProcessField("api", "last_restart_reason"); reason != "reload" {
+		t.Errorf("last_restart_reason = %q, want reload", reason)
+	}
+	pid, _ := strconv.Atoi(oldPID)
+	if syscall.Kill(pid, 0) == nil {
+		t.Errorf("old process %d still running after reload", pid)
+	}
+
+	// The history of the old process is kept.
+	var events []struct {
+		Type string `json:"type"`
+		PID  int    `json:"pid"`
+	}
+	out := env.MustGopm("history", "api", "--json")
+	if err := json.Unmarshal([]byte(out), &events); err != nil {
+		t.Fatalf("parse history: %v\n%s", err, out)
+	}
+	kept := false
+	for _, ev := range events {
+		if ev.Type == "start" && ev.PID == pid {
+			kept = true
+		}
+	}
+	if !kept {
+		t.Errorf("history lost the start of PID %d on reload:\n%s", pid, out)
+	}
+
+	// A replacement that never becomes ready is stopped; the old one stays.
+	env.MustGopm("start", env.TestappBin, "--name", "slow", "--ready-message", "never printed", "--", "--run-forever")
+	env.WaitForStatus("slow", "online", 5*time.Second)
+	slowPID := env.GetProcessField("slow", "pid")
+	_, stderr, code := env.Gopm("reload", "slow", "--timeout", "1s")
+	if code == 0 {
+		t.Fatal("expected reload to fail")
+	}
+	if !strings.Contains(stderr, "not ready after 1s") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+	if pid := env.GetProcessField("slow", "pid"); pid != slowPID {
+		t.Errorf("pid = %s after failed reload, want %s", pid, slowPID)
+	}
+	if status := env.GetProcessField("slow", "status"); status != "online" {
+		t.Errorf("status after failed reload = %q, want online", status)
+	}
+
+	// Instances of a group are reloaded one at a time.
+	env.MustGopm("start", env.TestappBin, "--name", "web", "--instances", "2", "--ready-message", "PID=",
+		"--", "--run-forever", "--print-pid")
+	env.WaitForStatus("web-1", "online", 5*time.Second)
+	before := []string{env.GetProcessField("web-0", "pid"), env.GetProcessField("web-1", "pid")}
+	env.MustGopm("reload", "web")
+	for i, name := range []string{"web-0", "web-1"} {
+		if pid := env.GetProcessField(name, "pid"); pid == before[i] || pid == "" {
+			t.Errorf("%s pid = %q, was %s", name, pid, before[i])
+		}
+	}
+}
+
+func TestSocketActivation(t *testing.T) {
+	env := NewTestEnv(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	env.MustGopm("start", env.TestappBin, "--name", "api", "--socket", "http=tcp:"+addr,
+		"--", "--run-forever", "--print-pid", "--print-env", "LISTEN_PID")
+	env.WaitForStatus("api", "online", 5*time.Second)
+	if got := env.GetProcessField("api", "socket_listeners"); got != `["tcp@`+addr+`"]` {
+		t.Errorf("socket_listeners = %s", got)
+	}
+	time.Sleep(300 * time.Millisecond)
+	pid := env.GetProcessField("api", "pid")
+	if logs := env.MustGopm("logs", "api"); !strings.Contains(logs, "LISTEN_PID="+pid) {
+		t.Errorf("logs missing LISTEN_PID=%s:\n%s", pid, logs)
+	}
+	if list := env.MustGopm("list", "-p"); !strings.Contains(list, "tcp@"+addr+"*") {
+		t.Errorf("list -p missing held socket:\n%s", list)
+	}
+
+	// The daemon keeps the socket open while the process is down, so
+	// connections queue instead of being refused.
+	env.MustGopm("stop", "api")
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial while stopped: %v", err)
+	}
+	c.Close()
+
+	env.MustGopm("restart", "api")
+	env.WaitForStatus("api", "online", 5*time.Second)
+	if got := env.GetProcessField("api", "socket_listeners"); got != `["tcp@`+addr+`"]` {
+		t.Errorf("socket_listeners after restart = %s", got)
+	}
+
+	// Deleting the process closes the socket.
+	env.MustGopm("delete", "api")
+	if c, err := net.Dial("tcp", addr); err == nil {
+		c.Close()
+		t.Error("socket still open after delete")
+	}
+
+	// A socket that can't be bound fails the start.
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if _, _, code := env.Gopm("start", env.TestappBin, "--name", "busy", "--socket", ln.Addr().String(), "--", "--run-forever"); code == 0 {
+		t.Error("expected start to fail for a socket in use")
+	}
+}
+
+func TestNotifyReadiness(t *testing.T) {
+	env := NewTestEnv(t)
+
+	// Running but not ready until it sends READY=1.
+	env.MustGopm("start", env.TestappBin, "--name", "slow", "--notify",
+		"--", "--start-delay", "1s", "--notify-ready", "--run-forever")
+	if got := env.GetProcessField("slow", "status"); got != "starting" {
+		t.Errorf("status before READY=1 = %s, want starting", got)
+	}
+	env.WaitForStatus("slow", "online", 5*time.Second)
+	if desc := env.MustGopm("describe", "slow"); !strings.Contains(desc, "App Status") || !strings.Contains(desc, "ready") {
+		t.Errorf("describe missing STATUS= text:\n%s", desc)
+	}
+
+	// --wait blocks until READY=1.
+	out := env.MustGopm("start", env.TestappBin, "--name", "api", "--notify", "--wait",
+		"--", "--start-delay", "500ms", "--notify-ready", "--run-forever")
+	if !strings.Contains(out, "ready") {
+		t.Errorf("start --wait output missing ready:\n%s", out)
+	}
+	if got := env.GetProcessField("api", "status"); got != "online" {
+		t.Errorf("status after start --wait = %s, want online", got)
+	}
+
+	// --wait fails for a process that exits before it is ready.
+	_, stderr, code := env.Gopm("start", env.TestappBin, "--name", "bad", "--notify", "--wait",
+		"--autorestart", "never", "--", "--crash-after", "300ms")
+	if code == 0 {
+		t.Error("expected start --wait to fail for a process that exits before READY=1")
+	}
+	if !strings.Contains(stderr, "before it was ready") {
+		t.Errorf("unexpected error: %s", stderr)
+	}
+
+	// --wait fails when the process never becomes ready in time.
+	if _, _, code := env.Gopm("start", env.TestappBin, "--name", "mute", "--notify", "--wait",
+		"--wait-timeout", "500ms", "--", "--run-forever"); code == 0 {
+		t.Error("expected start --wait to time out for a process that never sends READY=1")
+	}
+}
+
+func TestNotifyWatchdog(t *testing.T) {
+	env := NewTestEnv(t)
+
+	env.MustGopm("start", env.TestappBin, "--name", "hung", "--notify", "--watchdog-sec", "1s",
+		"--restart-delay", "100ms", "--", "--notify-ready", "--run-forever", "--print-env", "WATCHDOG_USEC")
+	env.WaitForRestartCount("hung", 1, 5*time.Second)
+	if logs := env.MustGopm("logs", "hung"); !strings.Contains(logs, "WATCHDOG_USEC=1000000") {
+		t.Errorf("logs missing WATCHDOG_USEC:\n%s", logs)
+	}
+	if logs := env.MustGopm("logs", "hung", "--err", "--lines", "50"); !strings.Contains(logs, "watchdog timeout") {
+		t.Errorf("logs missing watchdog timeout:\n%s", logs)
+	}
+
+	env.MustGopm("start", env.TestappBin, "--name", "alive", "--notify", "--watchdog-sec", "1s",
+		"--", "--notify-ready", "--watchdog-every", "200ms", "--run-forever")
+	env.WaitForStatus("alive", "online", 5*time.Second)
+	time.Sleep(2500 * time.Millisecond)
+	if got := env.GetProcessField("alive", "restarts"); got != "0" {
+		t.Errorf("restarts = %s, want 0 for a process sending WATCHDOG=1", got)
+	}
+}
+
+func TestStopDuringRestartDelay(t *testing.T) {
+	env := NewTestEnv(t)
+
+	env.MustGopm("start", env.TestappBin, "--name", "crasher", "--restart-delay", "3s",
+		"--", "--crash-after", "300ms")
+	env.WaitForStatus("crasher", "waiting_restart", 5*time.Second)
+	if got := env.GetProcessField("crasher", "restart_at"); got == "" {
+		t.Error("restart_at not set while waiting to restart")
+	}
+	if _, _, code := env.Gopm("isrunning", "crasher"); code != 1 {
+		t.Errorf("isrunning exit code = %d while waiting to restart, want 1", code)
+	}
+
+	// Stopping cancels the pending restart.
+	env.MustGopm("stop", "crasher")
+	if got := env.GetProcessField("crasher", "status"); got != "stopped" {
+		t.Errorf("status after stop = %s, want stopped", got)
+	}
+	time.Sleep(4 * time.Second)
+	if got := env.GetProcessField("crasher", "status"); got != "stopped" {
+		t.Errorf("status after the restart delay = %s, want stopped", got)
+	}
+	if got := env.GetProcessField("crasher", "restarts"); got != "0" {
+		t.Errorf("restarts = %s, want 0", got)
+	}
+}
+
+func TestStoppingStatus(t *testing.T) {
+	env := NewTestEnv(t)
+
+	// Ignores SIGTERM, so it is stopping until the SIGKILL.
+	env.MustGopm("start", "/bin/sh", "--name", "slow", "--kill-timeout", "2s",
+		"--", "-c", `trap "" TERM; sleep 60`)
+	env.WaitForStatus("slow", "online", 5*time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		env.Gopm("stop", "slow")
+		close(done)
+	}()
+	env.WaitForStatus("slow", "stopping", 2*time.Second)
+	if _, _, code := env.Gopm("isrunning", "slow"); code != 1 {
+		t.Errorf("isrunning exit code = %d while stopping, want 1", code)
+	}
+	<-done
+	if got := env.GetProcessField("slow", "status"); got != "stopped" {
+		t.Errorf("status after stop = %s, want stopped", got)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	env := NewTestEnv(t)
+
+	env.MustGopm("start", env.TestappBin, "--name", "victim", "--restart-delay", "200ms",
+		"--", "--run-forever")
+	env.WaitForStatus("victim", "online", 5*time.Second)
+	pid, _ := strconv.Atoi(env.GetProcessField("victim", "pid"))
+	syscall.Kill(pid, syscall.SIGKILL)
+	env.WaitForRestartCount("victim", 1, 5*time.Second)
+
+	history := func() []map[string]interface{} {
+		t.Helper()
+		var events []map[string]interface{}
+		out := env.MustGopm("history", "victim", "--json")
+		if err := json.Unmarshal([]byte(out), &events); err != nil {
+			t.Fatalf("parse history: %v\n%s", err, out)
+		}
+		return events
+	}
+	events := history()
+	if len(events) != 3 {
+		t.Fatalf("expected start, exit, start events, got %v", events)
+	}
+	exit := events[1]
+	if exit["type"] != "exit" || exit["signal"] != "SIGKILL" || exit["exit_code"] != float64(-1) {
+		t.Errorf("exit event = %v, want SIGKILL with exit code -1", exit)
+	}
+	if exit["decision"] != "restart" || exit["pid"] != float64(pid) || exit["uptime"] == nil {
+		t.Errorf("exit event = %v, want a restart of PID %d with its uptime", exit, pid)
+	}
+
+	// The history survives a daemon restart.
+	env.Gopm("kill")
+	time.Sleep(time.Second)
+	env.MustGopm("ping")
+	env.WaitForStatus("victim", "online", 10*time.Second)
+	found := false
+	for _, ev := range history() {
+		if ev["signal"] == "SIGKILL" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("SIGKILL exit missing from history after daemon restart")
+	}
+}
+
+func TestEvents(t *testing.T) {
+	env := NewTestEnv(t)
+	env.MustGopm("ping")
+
+	cmd := exec.Command(env.GopmBin, "events", "--json", "--filter", "victim")
+	cmd.Env = append(os.Environ(), "GOPM_HOME="+env.Home)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan map[string]interface{}, 100)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			var ev map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &ev); err == nil {
+				events <- ev
+			}
+		}
+	}()
+	time.Sleep(300 * time.Millisecond) // let it subscribe
+
+	env.MustGopm("start", env.TestappBin, "--name", "other", "--", "--run-forever")
+	env.MustGopm("start", env.TestappBin, "--name", "victim", "--restart-delay", "200ms",
+		"--", "--run-forever")
+	env.WaitForStatus("victim", "online", 5*time.Second)
+	pid, _ := strconv.Atoi(env.GetProcessField("victim", "pid"))
+	syscall.Kill(pid, syscall.SIGKILL)
+	env.WaitForRestartCount("victim", 1, 5*time.Second)
+	env.Gopm("kill")
+
+	var got []string
+	timeout := time.After(10 * time.Second)
+	for done := false; !done; {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				done = true
+				break
+			}
+			if name := ev["name"]; name != nil && name != "victim" {
+				t.Errorf("filtered stream got event of %v: %v", name, ev)
+			}
+			desc := ev["type"].(string)
+			switch desc {
+			case "status":
+				desc += ":" + ev["status"].(string)
+			case "exit":
+				if ev["signal"] != "SIGKILL" || ev["decision"] != "restart" {
+					t.Errorf("exit event = %v, want SIGKILL and a restart", ev)
+				}
+			case "daemon":
+				desc += ":" + ev["reason"].(string)
+			}
+			got = append(got, desc)
+		case <-timeout:
+			t.Fatalf("event stream did not end after the daemon stopped; got %v", got)
+		}
+	}
+	cmd.Wait()
+
+	want := []string{"start", "status:online", "config", "exit", "status:waiting_restart",
+		"status:launching", "start", "status:online", "restart", "daemon:shutting down"}
+	for i, w := range want {
+		if i >= len(got) || got[i] != w {
+			t.Fatalf("events = %v, want them to begin with %v", got, want)
+		}
+	}
+}
+
+func TestWebhookNotifications(t *testing.T) {
+	env := NewTestEnv(t)
+
+	got := make(chan map[string]interface{}, 20)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&p); err == nil {
+			got <- p
+		}
+	}))
+	defer srv.Close()
+
+	cfg := fmt.Sprintf(`{
+		"mcpserver": null,
+		"notifications": {
+			"webhooks": [{"name": "test", "url": %q, "events": ["errored", "restarted", "crash-loop"]}],
+			"crash_loop_restarts": 2,
+			"stderr_lines": 5
+		}
+	}`, srv.URL)
+	if err := os.WriteFile(filepath.Join(env.Home, "gopm.config.json"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := env.MustGopm("notify", "test")
+	if !strings.Contains(out, "OK") {
+		t.Errorf("notify test output = %q, want OK", out)
+	}
+	if p := <-got; p["test"] != true || p["event"] != "errored" {
+		t.Errorf("test payload = %v", p)
+	}
+
+	env.MustGopm("start", env.TestappBin, "--name", "crasher", "--max-restarts", "3", "--restart-delay", "100ms",
+		"--", "--crash-after", "200ms", "--stderr-every", "50ms", "--stderr-msg", "boom")
+	env.WaitForStatus("crasher", "errored", 15*time.Second)
+
+	counts := map[string]int{}
+	var errored map[string]interface{}
+	timeout := time.After(5 * time.Second)
+	for counts["errored"] == 0 {
+		select {
+		case p := <-got:
+			counts[p["event"].(string)]++
+			if p["event"] == "errored" {
+				errored = p
+			}
+		case <-timeout:
+			t.Fatalf("no errored notification; got %v", counts)
+		}
+	}
+	if counts["restarted"] != 3 || counts["crash-loop"] != 1 {
+		t.Errorf("notifications = %v, want 3 restarted and 1 crash-loop", counts)
+	}
+	if !strings.Contains(errored["reason"].(string), "max restarts reached") {
+		t.Errorf("errored reason = %v", errored["reason"])
+	}
+	if proc, _ := errored["process"].(map[string]interface{}); proc == nil || proc["name"] != "crasher" {
+		t.Errorf("errored process = %v", errored["process"])
+	}
+	if stderr := fmt.Sprint(errored["stderr"]); !strings.Contains(stderr, "boom") {
+		t.Errorf("errored stderr = %v, want the last boom lines", stderr)
+	}
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	env := NewTestEnv(t)
+	trace := filepath.Join(env.Home, "hooks.log")
+	hook := func(name string) string {
+		return fmt.Sprintf(`echo "%s pid=${GOPM_PID:-} exit=${GOPM_EXIT_CODE:-} restarts=$GOPM_RESTARTS" >> %s`, name, trace)
+	}
+
+	out := env.MustGopm("start", env.TestappBin, "--name", "hooked", "--restart-delay", "100ms",
+		"--pre-start", hook("pre_start")+"; echo migrating",
+		"--post-start", hook("post_start"),
+		"--pre-stop", hook("pre_stop"),
+		"--post-exit", hook("post_exit"),
+		"--", "--crash-after", "300ms")
+	if !strings.Contains(out, "started") {
+		t.Fatalf("start output = %q", out)
+	}
+	env.WaitForRestartCount("hooked", 1, 10*time.Second)
+	env.MustGopm("stop", "hooked")
+	env.WaitForStatus("hooked", "stopped", 5*time.Second)
+
+	var lines []string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		data, _ := os.ReadFile(trace)
+		lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+		if strings.HasPrefix(lines[len(lines)-1], "post_exit") && strings.Count(string(data), "post_exit") >= 2 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	joined := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"pre_start pid= exit= restarts=0",
+		"post_exit pid=",
+		"exit=1 restarts=0",
+		"pre_start pid= exit= restarts=1",
+		"pre_stop pid=",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("hook trace missing %q:\n%s", want, joined)
+		}
+	}
+	if !strings.HasPrefix(lines[0], "pre_start") {
+		t.Errorf("first hook = %q, want pre_start", lines[0])
+	}
+
+	errLog, _ := os.ReadFile(filepath.Join(env.Home, "logs", "hooked-err.log"))
+	if !strings.Contains(string(errLog), "[gopm hook] migrating") {
+		t.Errorf("stderr log has no hook output:\n%s", errLog)
+	}
+
+	// A failing pre_start keeps the process from starting.
+	out = env.MustGopm("start", env.TestappBin, "--name", "blocked", "--pre-start", "echo no database >&2; exit 2", "--", "--run-forever")
+	if !strings.Contains(out, "errored") || !strings.Contains(out, "pre_start hook failed: exit status 2") {
+		t.Errorf("start output = %q, want errored by pre_start", out)
+	}
+	if status := env.GetProcessField("blocked", "status"); status != "errored" {
+		t.Errorf("blocked status = %q, want errored", status)
+	}
+	errLog, _ = os.ReadFile(filepath.Join(env.Home, "logs", "blocked-err.log"))
+	if !strings.Contains(string(errLog), "[gopm hook] no database") {
+		t.Errorf("stderr log has no hook output:\n%s", errLog)
+	}
+}
+
+func TestSignal(t *testing.T) {
+	env := NewTestEnv(t)
+	env.MustGopm("start", env.TestappBin, "--name", "sig", "--restart-delay", "100ms", "--", "--run-forever")
+	env.WaitForStatus("sig", "online", 5*time.Second)
+	pid := env.GetProcessField("sig", "pid")
+
+	// testapp exits on SIGINT, and is restarted.
+	out := env.MustGopm("signal", "int", "sig")
+	if !strings.Contains(out, "sent SIGINT") || !strings.Contains(out, pid) {
+		t.Errorf("signal output = %q", out)
+	}
+	env.WaitForRestartCount("sig", 1, 5*time.Second)
+
+	errLog, _ := os.ReadFile(filepath.Join(env.Home, "logs", "sig-err.log"))
+	if !strings.Contains(string(errLog), "[gopm] sent SIGINT (PID "+pid+")") {
+		t.Errorf("stderr log has no signal line:\n%s", errLog)
+	}
+
+	env.WaitForStatus("sig", "online", 5*time.Second)
+	out = env.MustGopm("signal", "SIGTERM", "sig", "--group")
+	if !strings.Contains(out, "process group") {
+		t.Errorf("group signal output = %q", out)
+	}
+	env.WaitForRestartCount("sig", 2, 5*time.Second)
+
+	if _, _, code := env.Gopm("signal", "SIGNOPE", "sig"); code == 0 {
+		t.Error("unknown signal: expected a non-zero exit")
+	}
+	env.MustGopm("stop", "sig")
+	if _, stderr, code := env.Gopm("signal", "HUP", "sig"); code == 0 || !strings.Contains(stderr, "not running") {
+		t.Errorf("signal to a stopped process: exit %d, stderr %q", code, stderr)
+	}
+}
+
+func TestStopBehaviour(t *testing.T) {
+	env := NewTestEnv(t)
+	script := `trap "" TERM; trap "echo reloaded" HUP; trap "echo got INT; exit 0" INT; while :; do sleep 0.1; done`
+	env.MustGopm("start", "/bin/sh", "--name", "shy",
+		"--kill-signal", "SIGINT",
+		"--kill-timeout", "10s",
+		"--stop-command", "echo stopping $GOPM_PID",
+		"--reload-signal", "hup",
+		"--no-restart-on-exit", "78",
+		"--", "-c", script)
+	env.WaitForStatus("shy", "online", 5*time.Second)
+	pid := env.GetProcessField("shy", "pid")
+
+	out := env.MustGopm("export", "shy")
+	for _, want := range []string{`"kill_signal": "SIGINT"`, `"reload_signal": "SIGHUP"`, `"stop_command": "echo stopping $GOPM_PID"`, `"no_restart_on_exit": [`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("export missing %s:\n%s", want, out)
+		}
+	}
+
+	// With a reload signal, reload keeps the process and signals it.
+	env.MustGopm("reload", "shy")
+	time.Sleep(500 * time.Millisecond)
+	if got := env.GetProcessField("shy", "pid"); got != pid {
+		t.Errorf("pid after reload = %s, want %s", got, pid)
+	}
+	outLog, _ := os.ReadFile(filepath.Join(env.Home, "logs", "shy-out.log"))
+	if !strings.Contains(string(outLog), "reloaded") {
+		t.Errorf("stdout log has no reload:\n%s", outLog)
+	}
+
+	// SIGTERM is ignored, so a quick stop means SIGINT was sent.
+	start := time.Now()
+	env.MustGopm("stop", "shy")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stop took %v, want the kill signal to stop it", elapsed)
+	}
+	errLog, _ := os.ReadFile(filepath.Join(env.Home, "logs", "shy-err.log"))
+	for _, want := range []string{"[gopm] sent SIGHUP (PID " + pid + ")", "[gopm stop] stopping " + pid} {
+		if !strings.Contains(string(errLog), want) {
+			t.Errorf("stderr log missing %q:\n%s", want, errLog)
+		}
+	}
+	outLog, _ = os.ReadFile(filepath.Join(env.Home, "logs", "shy-out.log"))
+	if !strings.Contains(string(outLog), "got INT") {
+		t.Errorf("stdout log has no SIGINT:\n%s", outLog)
+	}
+}
+
+func TestRunAsUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("needs root")
+	}
+	env := NewTestEnv(t)
+	env.MustGopm("start", "/bin/sh", "--name", "limited",
+		"--user", "nobody", "--group", "0", "--cwd", "/",
+		"--autorestart", "never",
+		"--", "-c", `echo "uid=$(id -u) gid=$(id -g) user=$USER home=$HOME"; sleep 30`)
+	env.WaitForStatus("limited", "online", 5*time.Second)
+	time.Sleep(300 * time.Millisecond)
+
+	outPath := filepath.Join(env.Home, "logs", "limited-out.log")
+	outLog, _ := os.ReadFile(outPath)
+	if !strings.Contains(string(outLog), "uid=65534 gid=0 user=nobody home=/nonexistent") {
+		t.Errorf("stdout log = %q, want the process to run as nobody", outLog)
+	}
+	fi, err := os.Stat(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := fi.Sys().(*syscall.Stat_t); st.Uid != 65534 || st.Gid != 0 {
+		t.Errorf("log owner = %d:%d, want 65534:0", st.Uid, st.Gid)
+	}
+
+	out := env.MustGopm("describe", "limited")
+	if !strings.Contains(out, "nobody (65534), group root (0)") {
+		t.Errorf("describe missing the user:\n%s", out)
+	}
+
+	_, stderr, code := env.Gopm("start", "/bin/true", "--name", "ghost", "--user", "no-such-user-xyz")
+	if code == 0 || !strings.Contains(stderr, `unknown user "no-such-user-xyz"`) {
+		t.Errorf("start with unknown user: code=%d stderr=%s", code, stderr)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("limits are applied on Linux")
+	}
+	env := NewTestEnv(t)
+	env.MustGopm("start", "/bin/sh", "--name", "tuned",
+		"--limit-nofile", "512:1024",
+		"--limit-core", "0",
+		"--nice", "5",
+		"--cpu-affinity", "0",
+		"--oom-score-adj", "300",
+		"--umask", "077",
+		"--", "-c", `echo "nofile=$(ulimit -n) umask=$(umask)"; exec sleep 30`)
+	env.WaitForStatus("tuned", "online", 5*time.Second)
+	time.Sleep(300 * time.Millisecond)
+
+	outLog, _ := os.ReadFile(filepath.Join(env.Home, "logs", "tuned-out.log"))
+	if !strings.Contains(string(outLog), "nofile=512 umask=0077") {
+		t.Errorf("stdout log = %q, want the limits in effect from the start", outLog)
+	}
+
+	out := env.MustGopm("describe", "tuned")
+	for _, want := range []string{"512:1024 (effective 512:1024)", "5 (effective 5)", "300 (effective 300)", "0077 (effective 0077)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("describe missing %q:\n%s", want, out)
+		}
+	}
+
+	pid := env.GetProcessField("tuned", "pid")
+	out = env.MustGopm("pid", pid)
+	if !strings.Contains(out, "nofile") || !strings.Contains(out, "512:1024 (512:1024)") {
+		t.Errorf("pid GoPM section missing limits:\n%s", out)
+	}
+
+	out = env.MustGopm("export", "tuned")
+	if !strings.Contains(out, `"nofile": "512:1024"`) || !strings.Contains(out, `"oom_score_adj": 300`) {
+		t.Errorf("export missing limits:\n%s", out)
+	}
+
+	_, stderr, code := env.Gopm("start", "/bin/true", "--name", "bad", "--nice", "40")
+	if code == 0 || !strings.Contains(stderr, "nice must be between -20 and 19") {
+		t.Errorf("start with bad nice: code=%d stderr=%s", code, stderr)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	env := NewTestEnv(t)
+	env.MustGopm("start", "/bin/sh", "--name", "api-1", "--label", "team=payments", "--label", "tier=web", "--", "-c", "echo up; exec sleep 30")
+	env.MustGopm("start", "/bin/sh", "--name", "api-2", "--label", "team=search", "--label", "tier=web", "--", "-c", "echo up; exec sleep 30")
+	env.MustGopm("start", "/bin/sleep", "--name", "worker", "--label", "team=payments", "--label", "tier=worker", "--", "30")
+	for _, name := range []string{"api-1", "api-2", "worker"} {
+		env.WaitForStatus(name, "online", 5*time.Second)
+	}
+
+	out := env.MustGopm("list", "-l", "team=payments")
+	if !strings.Contains(out, "api-1") || !strings.Contains(out, "worker") || strings.Contains(out, "api-2") {
+		t.Errorf("list -l team=payments:\n%s", out)
+	}
+
+	env.MustGopm("stop", "-l", "team=payments,tier=worker")
+	env.WaitForStatus("worker", "stopped", 5*time.Second)
+	if status := env.GetProcessField("api-1", "status"); status != "online" {
+		t.Errorf("api-1 status = %s, want online", status)
+	}
+
+	out = env.MustGopm("logs", "api-*", "--lines", "5")
+	if !strings.Contains(out, "==> api-1 <==") || !strings.Contains(out, "==> api-2 <==") || strings.Contains(out, "worker") {
+		t.Errorf("logs api-*:\n%s", out)
+	}
+
+	env.MustGopm("stop", "api-*")
+	env.WaitForStatus("api-1", "stopped", 5*time.Second)
+	env.WaitForStatus("api-2", "stopped", 5*time.Second)
+
+	out = env.MustGopm("describe", "api-1")
+	if !strings.Contains(out, "team=payments,tier=web") {
+		t.Errorf("describe missing labels:\n%s", out)
+	}
+
+	out = env.MustGopm("export", "worker")
+	if !strings.Contains(out, `"team": "payments"`) || !strings.Contains(out, `"tier": "worker"`) {
+		t.Errorf("export missing labels:\n%s", out)
+	}
+
+	_, stderr, code := env.Gopm("restart", "-l", "team=nobody")
+	if code == 0 || !strings.Contains(stderr, `no process matches "team=nobody"`) {
+		t.Errorf("restart with no match: code=%d stderr=%s", code, stderr)
+	}
+	_, stderr, code = env.Gopm("start", "/bin/true", "--name", "bad", "--label", "team")
+	if code == 0 || !strings.Contains(stderr, "expected key=value") {
+		t.Errorf("start with bad label: code=%d stderr=%s", code, stderr)
+	}
+}
+
+func TestApply(t *testing.T) {
+	env := NewTestEnv(t)
+	app := func(name string, extra map[string]interface{}) map[string]interface{} {
+		a := map[string]interface{}{"name": name, "command": env.TestappBin, "args": []string{"--run-forever"}}
+		for k, v := range extra {
+			a[k] = v
+		}
+		return a
+	}
+
+	ecoPath := env.WriteEcosystem(map[string]interface{}{"apps": []map[string]interface{}{
+		app("api", map[string]interface{}{"env": map[string]string{"MODE": "a"}}),
+		app("web", map[string]interface{}{"instances": 2}),
+		app("old", nil),
+	}})
+	out := env.MustGopm("apply", ecoPath)
+	if !strings.Contains(out, "3 started") {
+		t.Errorf("first apply:\n%s", out)
+	}
+	env.WaitForStatus("api", "online", 5*time.Second)
+	apiID := env.GetProcessField("api", "id")
+	webPID := env.GetProcessField("web-0", "pid")
+
+	// Applying the same file again changes nothing.
+	out = env.MustGopm("apply", ecoPath)
+	if !strings.Contains(out, "3 unchanged") {
+		t.Errorf("second apply:\n%s", out)
+	}
+
+	ecoPath = env.WriteEcosystem(map[string]interface{}{"apps": []map[string]interface{}{
+		app("api", map[string]interface{}{"env": map[string]string{"MODE": "b"}}),
+		app("web", map[string]interface{}{"instances": 3}),
+		app("new", nil),
+	}})
+	out = env.MustGopm("apply", ecoPath, "--prune", "--dry-run", "--json")
+	var changes []struct{ Name, Action string }
+	if err := json.Unmarshal([]byte(out), &changes); err != nil {
+		t.Fatalf("dry run JSON: %v\n%s", err, out)
+	}
+	want := map[string]string{"api": "update", "web": "scale", "new": "create", "old": "delete"}
+	if len(changes) != len(want) {
+		t.Errorf("dry run changes = %+v", changes)
+	}
+	for _, c := range changes {
+		if want[c.Name] != c.Action {
+			t.Errorf("dry run: %s %s, want %s", c.Name, c.Action, want[c.Name])
+		}
+	}
+	if env.ProcessCount() != 4 {
+		t.Errorf("dry run changed the process table: %d processes", env.ProcessCount())
+	}
+
+	out = env.MustGopm("apply", ecoPath, "--prune", "--dry-run")
+	if !strings.Contains(out, `env: {"MODE":"a"} → {"MODE":"b"}`) || !strings.Contains(out, "instances: 2 → 3") {
+		t.Errorf("dry run diff:\n%s", out)
+	}
+
+	env.MustGopm("apply", ecoPath, "--prune")
+	env.WaitForStatus("api", "online", 5*time.Second)
+	env.WaitForStatus("new", "online", 5*time.Second)
+	env.WaitForStatus("web-2", "online", 5*time.Second)
+	if id := env.GetProcessField("api", "id"); id != apiID {
+		t.Errorf("api ID = %s after apply, want %s", id, apiID)
+	}
+	if pid := env.GetProcessField("web-0", "pid"); pid != webPID {
+		t.Errorf("web-0 was restarted by a scale: PID %s, was %s", pid, webPID)
+	}
+	if _, _, code := env.Gopm("describe", "old"); code == 0 {
+		t.Error("old should have been pruned")
+	}
+	out = env.MustGopm("describe", "api")
+	if !strings.Contains(out, "MODE=b") {
+		t.Errorf("api env not updated:\n%s", out)
+	}
+}
+
+func TestSet(t *testing.T) {
+	env := NewTestEnv(t)
+	env.MustGopm("start", env.TestappBin, "--name", "api", "--env", "MODE=a", "--env", "DEBUG=1", "--", "--run-forever")
+	env.WaitForStatus("api", "online", 5*time.Second)
+	id := env.GetProcessField("api", "id")
+	pid := env.GetProcessField("api", "pid")
+
+	// Without --restart the process keeps running with its old settings.
+	out := env.MustGopm("set", "api", "--max-restarts", "5")
+	if !strings.Contains(out, "settings changed") || !strings.Contains(out, "max_restarts:") {
+		t.Errorf("set max-restarts:\n%s", out)
+	}
+	if p := env.GetProcessField("api", "pid"); p != pid {
+		t.Errorf("set without --restart restarted the process: PID %s, was %s", p, pid)
+	}
+
+	out = env.MustGopm("set", "api", "--env", "MODE=b", "--unset-env", "DEBUG", "--restart")
+	if !strings.Contains(out, "(restarted)") {
+		t.Errorf("set --restart:\n%s", out)
+	}
+	env.WaitForStatus("api", "online", 5*time.Second)
+	if got := env.GetProcessField("api", "id"); got != id {
+		t.Errorf("api ID = %s after set, want %s", got, id)
+	}
+	out = env.MustGopm("describe", "api")
+	if !strings.Contains(out, "MODE=b") || strings.Contains(out, "DEBUG=1") {
+		t.Errorf("api env not updated:\n%s", out)
+	}
+	out = env.MustGopm("history", "api", "--json")
+	var events []map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &events); err != nil {
+		t.Fatalf("parse history: %v\n%s", err, out)
+	}
+	if len(events) < 2 {
+		t.Errorf("history lost by set: %d events", len(events))
+	}
+
+	// Setting the same again changes nothing.
+	out = env.MustGopm("set", "api", "--env", "MODE=b")
+	if !strings.Contains(out, "nothing changed") {
+		t.Errorf("set same env:\n%s", out)
+	}
+
+	// Invalid settings are rejected and change nothing.
+	for _, args := range [][]string{
+		{"set", "api", "--autorestart", "sometimes"},
+		{"set", "api", "--set", "restart_dealy=1s"},
+		{"set", "api", "--set", "name=other"},
+	} {
+		if _, _, code := env.Gopm(args...); code == 0 {
+			t.Errorf("gopm %v should fail", args)
+		}
+	}
+	if got := env.GetProcessField("api", "restart_policy"); !strings.Contains(got, `"autorestart":"always"`) {
+		t.Errorf("restart_policy = %s after rejected changes", got)
+	}
+}
+
+func TestRenameKeepsIDs(t *testing.T) {
+	env := NewTestEnv(t)
+	for _, name := range []string{"first", "second", "third"} {
+		env.MustGopm("start", env.TestappBin, "--name", name, "--", "--run-forever")
+		env.WaitForStatus(name, "online", 5*time.Second)
+	}
+	env.MustGopm("delete", "first")
+	id := env.GetProcessField("second", "id")
+
+	env.MustGopm("rename", "second", "api")
+	if got := env.GetProcessField("api", "id"); got != id {
+		t.Errorf("api ID = %s after rename, want %s", got, id)
+	}
+	if _, _, code := env.Gopm("describe", "second"); code == 0 {
+		t.Error("second should be gone after rename")
+	}
+	if got := env.GetProcessField("api", "log_out"); got != filepath.Join(env.Home, "logs", "api-out.log") {
+		t.Errorf("log_out = %s, want the default of the new name", got)
+	}
+	if _, err := os.Stat(filepath.Join(env.Home, "logs", "api-out.log")); err != nil {
+		t.Errorf("log file not moved: %v", err)
+	}
+	if _, _, code := env.Gopm("rename", "api", "third"); code == 0 {
+		t.Error("rename to a taken name should fail")
+	}
+
+	// IDs survive a daemon restart, and the deleted process's is not reused.
+	env.Gopm("kill")
+	time.Sleep(1 * time.Second)
+	env.MustGopm("ping")
+	env.WaitForStatus("api", "online", 10*time.Second)
+	env.WaitForStatus("third", "online", 10*time.Second)
+	if got := env.GetProcessField("api", "id"); got != id {
+		t.Errorf("api ID = %s after daemon restart, want %s", got, id)
+	}
+	if got := env.GetProcessField("third", "id"); got != "2" {
+		t.Errorf("third ID = %s after daemon restart, want 2", got)
+	}
+	env.MustGopm("start", env.TestappBin, "--name", "fourth", "--", "--run-forever")
+	if got := env.GetProcessField("fourth", "id"); got != "3" {
+		t.Errorf("fourth ID = %s, want 3", got)
+	}
+	env.MustGopm("stop", id)
+	env.WaitForStatus("api", "stopped", 5*time.Second)
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"math/rand"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -39,6 +40,8 @@ func main() {
 	startDelay := flag.Duration("start-delay", 0, "Sleep this long before doing anything")
 	printPID := flag.Bool("print-pid", false, "Print PID to stdout on startup")
 	printEnv := flag.String("print-env", "", "Print this env var's value to stdout on startup")
+	notifyReady := flag.Bool("notify-ready", false, "Send READY=1 to $NOTIFY_SOCKET on startup")
+	watchdogEvery := flag.Duration("watchdog-every", 0, "Send WATCHDOG=1 to $NOTIFY_SOCKET at this interval")
 
 	flag.Parse()
 
@@ -52,6 +55,17 @@ func main() {
 	if *printEnv != "" {
 		fmt.Fprintf(os.Stdout, "%s=%s\n", *printEnv, os.Getenv(*printEnv))
 	}
+	if *notifyReady {
+		sdNotify("READY=1\nSTATUS=ready")
+	}
+	if *watchdogEvery > 0 {
+		go func() {
+			tick := time.NewTicker(*watchdogEvery)
+			for range tick.C {
+				sdNotify("WATCHDOG=1")
+			}
+		}()
+	}
 
 	// --- Memory allocation ---
 	var memhold []byte
@@ -164,3 +178,19 @@ func main() {
 	signal.Notify(waitCh, syscall.SIGINT, syscall.SIGTERM)
 	<-waitCh
 }
+
+// sdNotify sends msg to $NOTIFY_SOCKET, as sd_notify(3) does.
+func sdNotify(msg string) {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "NOTIFY_SOCKET not set")
+		return
+	}
+	c, err := net.Dial("unixgram", path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "notify: %v\n", err)
+		return
+	}
+	defer c.Close()
+	c.Write([]byte(msg))
+}